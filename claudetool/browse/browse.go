@@ -2,28 +2,41 @@
 package browse
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/cdproto/tracing"
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
+	_ "golang.org/x/image/webp"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/llm/imageutil"
 )
@@ -40,9 +53,43 @@ const ConsoleLogsDir = "/tmp/shelley-console-logs"
 // ConsoleLogSizeThreshold is the size in bytes above which console logs are written to a file
 const ConsoleLogSizeThreshold = 1024
 
+// DefaultRedactPatterns match common secret formats (API keys, tokens, JWTs)
+// that are masked in console logs before they are returned or written to disk.
+var DefaultRedactPatterns = []string{
+	`(?i)bearer\s+[a-z0-9._~+/-]+=*`,                    // Authorization bearer tokens
+	`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`, // JWTs
+	`sk-[A-Za-z0-9_-]{20,}`,                             // OpenAI/Anthropic API keys
+	`gh[pousr]_[A-Za-z0-9]{36,}`,                        // GitHub tokens
+	`github_pat_[A-Za-z0-9_]{22,}`,                      // GitHub fine-grained tokens
+	`xox[abprs]-[A-Za-z0-9-]{10,}`,                      // Slack tokens
+	`AKIA[0-9A-Z]{16}`,                                  // AWS access key IDs
+	`AIza[0-9A-Za-z_-]{35}`,                             // Google API keys
+}
+
+var defaultRedactRegexps = func() []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, p := range DefaultRedactPatterns {
+		res = append(res, regexp.MustCompile(p))
+	}
+	return res
+}()
+
+// redactedPlaceholder replaces secrets matched by the redaction patterns
+const redactedPlaceholder = "[REDACTED]"
+
 // DefaultIdleTimeout is how long to wait before shutting down an idle browser
 const DefaultIdleTimeout = 30 * time.Minute
 
+// DefaultViewportWidth and DefaultViewportHeight are the initial viewport size (16:9 widescreen)
+const (
+	DefaultViewportWidth  = 1280
+	DefaultViewportHeight = 720
+)
+
+// navFailureThreshold is how many navigations in a row may fail before the
+// browser is assumed to be broken and restarted.
+const navFailureThreshold = 3
+
 // DownloadInfo tracks information about a completed download
 type DownloadInfo struct {
 	GUID              string
@@ -56,7 +103,6 @@ type DownloadInfo struct {
 // BrowseTools contains all browser tools and manages a shared browser instance
 type BrowseTools struct {
 	ctx              context.Context
-	allocCtx         context.Context
 	allocCancel      context.CancelFunc
 	browserCtx       context.Context
 	browserCtxCancel context.CancelFunc
@@ -68,13 +114,29 @@ type BrowseTools struct {
 	consoleLogs      []*runtime.EventConsoleAPICalled
 	consoleLogsMutex sync.Mutex
 	maxConsoleLogs   int
+	redactPatterns   []*regexp.Regexp // guarded by consoleLogsMutex
 	// Idle timeout management
 	idleTimeout time.Duration
 	idleTimer   *time.Timer
 	// Max image dimension for resizing (0 means use default)
 	maxImageDimension int
+	// Limits on reading a directory with read_image
+	maxDirImages     int
+	maxDirImageBytes int64
+	// Shared browser pool; nil means this BrowseTools launches its own browser
+	pool *Pool
+	// Returns the conversation's working directory, which relative upload
+	// paths are resolved against; nil uses the process's
+	workingDir func() string
+	// Initial viewport size, applied whenever the browser starts
+	viewportWidth  int
+	viewportHeight int
+	// Navigations that failed in a row, guarded by mux
+	navFailures int
 	// Download tracking
 	downloads      map[string]*DownloadInfo // keyed by GUID
+	downloadLog    []*DownloadInfo          // every download this session, oldest first
+	maxDownloadLog int
 	downloadsMutex sync.Mutex
 	downloadCond   *sync.Cond
 	// Network monitoring
@@ -82,6 +144,7 @@ type BrowseTools struct {
 	networkRequests    []*NetworkRequest
 	networkMutex       sync.Mutex
 	maxNetworkRequests int
+	blockedRequests    map[network.RequestID]bool // requests failed by navigate's block option
 	// Profiling state
 	profilingActive bool
 	tracingActive   bool
@@ -90,15 +153,26 @@ type BrowseTools struct {
 	traceMutex      sync.Mutex
 	// Screencast state
 	screencast screencastState
+	// In-flight action tracking, so CancelCurrentAction can abort them
+	actionCancels map[uint64]context.CancelFunc
+	nextActionID  uint64
+	actionMutex   sync.Mutex
 }
 
 // NewBrowseTools creates a new set of browser automation tools.
 // idleTimeout is how long to wait before shutting down an idle browser (0 uses default).
 // maxImageDimension is the max pixel dimension for images (0 means unlimited).
-func NewBrowseTools(ctx context.Context, idleTimeout time.Duration, maxImageDimension int) *BrowseTools {
+// viewportWidth and viewportHeight set the initial viewport (0 uses the 1280x720 default).
+func NewBrowseTools(ctx context.Context, idleTimeout time.Duration, maxImageDimension, viewportWidth, viewportHeight int) *BrowseTools {
 	if idleTimeout <= 0 {
 		idleTimeout = DefaultIdleTimeout
 	}
+	if viewportWidth <= 0 {
+		viewportWidth = DefaultViewportWidth
+	}
+	if viewportHeight <= 0 {
+		viewportHeight = DefaultViewportHeight
+	}
 	for _, dir := range []string{ScreenshotDir, DownloadDir, ConsoleLogsDir} {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			log.Printf("Failed to create directory %s: %v", dir, err)
@@ -107,12 +181,19 @@ func NewBrowseTools(ctx context.Context, idleTimeout time.Duration, maxImageDime
 
 	bt := &BrowseTools{
 		ctx:               ctx,
+		redactPatterns:    defaultRedactRegexps,
 		screenshots:       make(map[string]time.Time),
 		consoleLogs:       make([]*runtime.EventConsoleAPICalled, 0),
 		maxConsoleLogs:    100,
 		maxImageDimension: maxImageDimension,
+		maxDirImages:      DefaultMaxDirImages,
+		maxDirImageBytes:  DefaultMaxDirImageBytes,
+		viewportWidth:     viewportWidth,
+		viewportHeight:    viewportHeight,
 		idleTimeout:       idleTimeout,
 		downloads:         make(map[string]*DownloadInfo),
+		maxDownloadLog:    100,
+		actionCancels:     make(map[uint64]context.CancelFunc),
 	}
 	bt.downloadCond = sync.NewCond(&bt.downloadsMutex)
 	return bt
@@ -123,37 +204,42 @@ func (b *BrowseTools) GetBrowserContext() (context.Context, error) {
 	b.mux.Lock()
 	defer b.mux.Unlock()
 
-	// If browser exists, check if it's still alive
-	if b.browserCtx != nil {
-		// Check if the browser context has been cancelled (e.g., due to crash)
-		if b.browserCtx.Err() != nil {
-			log.Printf("Browser context is dead (err: %v), restarting browser", b.browserCtx.Err())
-			b.closeBrowserLocked()
-			// Fall through to create a new browser
-		} else {
-			b.resetIdleTimerLocked()
+	if b.liveBrowserLocked() {
+		return b.browserCtx, nil
+	}
+
+	// Initialize a new browser, or take a tab in the shared pooled browser
+	var browserCtx context.Context
+	var browserCancel, allocCancel context.CancelFunc
+	if b.pool != nil {
+		// Waiting for a free slot can take minutes; don't hold the mux
+		// meanwhile, so Close and idle shutdown aren't blocked behind it.
+		b.mux.Unlock()
+		err := b.pool.reserve(b.ctx)
+		b.mux.Lock()
+		if err != nil {
+			return nil, err
+		}
+		// Another caller may have started the browser while we waited
+		if b.liveBrowserLocked() {
+			b.pool.unreserve()
 			return b.browserCtx, nil
 		}
+		browserCtx, browserCancel, err = b.pool.newTab()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var allocCtx context.Context
+		allocCtx, allocCancel = chromedp.NewExecAllocator(b.ctx, allocatorOptions()...)
+		browserCtx, browserCancel = chromedp.NewContext(allocCtx, contextOptions()...)
+	}
+	abort := func() {
+		browserCancel()
+		if allocCancel != nil {
+			allocCancel()
+		}
 	}
-
-	// Initialize a new browser
-	opts := chromedp.DefaultExecAllocatorOptions[:]
-	opts = append(opts, chromedp.NoSandbox)
-	opts = append(opts, chromedp.Flag("--disable-dbus", true))
-	opts = append(opts, chromedp.WSURLReadTimeout(60*time.Second))
-	// Disable WebAuthn to prevent segfaults on FIDO/WebAuthn sites (issue #78)
-	// Must include all default disabled features plus WebAuthentication
-	// (chromedp v0.14.1 defaults: site-per-process,Translate,BlinkGenPropertyTrees)
-	opts = append(opts, chromedp.Flag("disable-features",
-		"site-per-process,Translate,BlinkGenPropertyTrees,WebAuthentication"))
-
-	allocCtx, allocCancel := chromedp.NewExecAllocator(b.ctx, opts...)
-	browserCtx, browserCancel := chromedp.NewContext(
-		allocCtx,
-		chromedp.WithLogf(log.Printf),
-		chromedp.WithErrorf(log.Printf),
-		chromedp.WithBrowserOption(chromedp.WithDialTimeout(60*time.Second)),
-	)
 
 	// Set up event listeners for console logs, downloads, network, and tracing.
 	// All listeners are registered once at browser startup and gated by enable flags.
@@ -161,29 +247,29 @@ func (b *BrowseTools) GetBrowserContext() (context.Context, error) {
 
 	// Start the browser
 	if err := chromedp.Run(browserCtx); err != nil {
-		allocCancel()
+		abort()
 		return nil, fmt.Errorf("failed to start browser (please apt get chromium or equivalent): %w", err)
 	}
 
-	// Set default viewport size to 1280x720 (16:9 widescreen)
-	if err := chromedp.Run(browserCtx, chromedp.EmulateViewport(1280, 720)); err != nil {
-		browserCancel()
-		allocCancel()
+	// Set the configured default viewport size
+	if err := chromedp.Run(browserCtx, chromedp.EmulateViewport(int64(b.viewportWidth), int64(b.viewportHeight))); err != nil {
+		abort()
 		return nil, fmt.Errorf("failed to set default viewport: %w", err)
 	}
 
-	// Configure download behavior to allow downloads and emit events
-	if err := chromedp.Run(browserCtx,
-		browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
-			WithDownloadPath(DownloadDir).
-			WithEventsEnabled(true),
-	); err != nil {
-		browserCancel()
-		allocCancel()
+	// Configure download behavior to allow downloads and emit events.
+	// Isolated pool contexts have their own browser context, which needs its own setting.
+	downloadBehavior := browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+		WithDownloadPath(DownloadDir).
+		WithEventsEnabled(true)
+	if id := chromedp.FromContext(browserCtx).BrowserContextID; id != "" {
+		downloadBehavior = downloadBehavior.WithBrowserContextID(id)
+	}
+	if err := chromedp.Run(browserCtx, downloadBehavior); err != nil {
+		abort()
 		return nil, fmt.Errorf("failed to configure download behavior: %w", err)
 	}
 
-	b.allocCtx = allocCtx
 	b.allocCancel = allocCancel
 	b.browserCtx = browserCtx
 	b.browserCtxCancel = browserCancel
@@ -193,6 +279,45 @@ func (b *BrowseTools) GetBrowserContext() (context.Context, error) {
 	return b.browserCtx, nil
 }
 
+// allocatorOptions returns the exec allocator options used to launch chromium.
+func allocatorOptions() []chromedp.ExecAllocatorOption {
+	opts := chromedp.DefaultExecAllocatorOptions[:]
+	opts = append(opts, chromedp.NoSandbox)
+	opts = append(opts, chromedp.Flag("--disable-dbus", true))
+	opts = append(opts, chromedp.WSURLReadTimeout(60*time.Second))
+	// Disable WebAuthn to prevent segfaults on FIDO/WebAuthn sites (issue #78)
+	// Must include all default disabled features plus WebAuthentication
+	// (chromedp v0.14.1 defaults: site-per-process,Translate,BlinkGenPropertyTrees)
+	opts = append(opts, chromedp.Flag("disable-features",
+		"site-per-process,Translate,BlinkGenPropertyTrees,WebAuthentication"))
+	return opts
+}
+
+// contextOptions returns the chromedp context options used for browser and tab contexts.
+func contextOptions() []chromedp.ContextOption {
+	return []chromedp.ContextOption{
+		chromedp.WithLogf(log.Printf),
+		chromedp.WithErrorf(log.Printf),
+		chromedp.WithBrowserOption(chromedp.WithDialTimeout(60 * time.Second)),
+	}
+}
+
+// liveBrowserLocked reports whether the browser is running, resetting its idle
+// timer if so. A browser that has died (e.g. crashed) is cleaned up so a new
+// one can be started. Caller must hold b.mux.
+func (b *BrowseTools) liveBrowserLocked() bool {
+	if b.browserCtx == nil {
+		return false
+	}
+	if b.browserCtx.Err() != nil {
+		log.Printf("Browser context is dead (err: %v), restarting browser", b.browserCtx.Err())
+		b.closeBrowserLocked()
+		return false
+	}
+	b.resetIdleTimerLocked()
+	return true
+}
+
 // resetIdleTimerLocked resets or starts the idle timer. Caller must hold b.mux.
 func (b *BrowseTools) resetIdleTimerLocked() {
 	if b.idleTimer != nil {
@@ -268,7 +393,6 @@ func (b *BrowseTools) closeBrowserLocked() {
 	b.browserCtxCancel = nil
 	b.allocCancel = nil
 	b.browserCtx = nil
-	b.allocCtx = nil
 
 	// Release the lock before calling cancel functions. allocCancel in
 	// particular can block waiting for the chrome process to exit, and
@@ -292,6 +416,40 @@ func (b *BrowseTools) Close() {
 	b.closeBrowserLocked()
 }
 
+// actionContext returns a timeout context for a browser action derived from browserCtx.
+// The action is tracked until the returned cancel func is called, so that
+// CancelCurrentAction can abort it before the timeout elapses.
+func (b *BrowseTools) actionContext(browserCtx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(browserCtx, timeout)
+
+	b.actionMutex.Lock()
+	id := b.nextActionID
+	b.nextActionID++
+	b.actionCancels[id] = cancel
+	b.actionMutex.Unlock()
+
+	return ctx, func() {
+		b.actionMutex.Lock()
+		delete(b.actionCancels, id)
+		b.actionMutex.Unlock()
+		cancel()
+	}
+}
+
+// CancelCurrentAction aborts any in-flight browser actions (navigate, eval, ...).
+// The browser itself is left running. It reports whether anything was cancelled.
+func (b *BrowseTools) CancelCurrentAction() bool {
+	b.actionMutex.Lock()
+	cancels := b.actionCancels
+	b.actionCancels = make(map[uint64]context.CancelFunc)
+	b.actionMutex.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels) > 0
+}
+
 // handleBrowserEvent is the unified event handler for all CDP events.
 func (b *BrowseTools) handleBrowserEvent(ev any) {
 	switch e := ev.(type) {
@@ -315,6 +473,13 @@ func (b *BrowseTools) handleBrowserEvent(ev any) {
 		if enabled {
 			b.captureNetworkResponse(e)
 		}
+	case *network.EventLoadingFailed:
+		b.networkMutex.Lock()
+		enabled := b.networkEnabled
+		b.networkMutex.Unlock()
+		if enabled {
+			b.captureNetworkFailed(e)
+		}
 	case *network.EventLoadingFinished:
 		b.networkMutex.Lock()
 		enabled := b.networkEnabled
@@ -346,8 +511,106 @@ func (b *BrowseTools) handleBrowserEvent(ev any) {
 
 // navigateInput is the input for the navigate action.
 type navigateInput struct {
-	URL     string `json:"url"`
-	Timeout string `json:"timeout,omitempty"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Block     []string          `json:"block,omitempty"`
+	Timeout   string            `json:"timeout,omitempty"`
+}
+
+// blockableResources maps the navigate action's block categories to CDP resource types.
+var blockableResources = map[string]network.ResourceType{
+	"image":      network.ResourceTypeImage,
+	"font":       network.ResourceTypeFont,
+	"stylesheet": network.ResourceTypeStylesheet,
+	"media":      network.ResourceTypeMedia,
+	"script":     network.ResourceTypeScript,
+}
+
+// blockPatterns converts block categories to fetch interception patterns.
+func blockPatterns(block []string) ([]*fetch.RequestPattern, error) {
+	var patterns []*fetch.RequestPattern
+	for _, category := range block {
+		resourceType, ok := blockableResources[category]
+		if !ok {
+			return nil, fmt.Errorf("unknown block category %q (valid: image, font, stylesheet, media, script)", category)
+		}
+		patterns = append(patterns, &fetch.RequestPattern{URLPattern: "*", ResourceType: resourceType})
+	}
+	return patterns, nil
+}
+
+// blockResources intercepts requests matching patterns and fails them before
+// they are sent. Blocked requests are kept out of the network log.
+// The returned function disables the interception and must be called once
+// navigation completes so blocking doesn't leak into later actions.
+func (b *BrowseTools) blockResources(browserCtx context.Context, patterns []*fetch.RequestPattern) (func(), error) {
+	b.networkMutex.Lock()
+	b.blockedRequests = make(map[network.RequestID]bool)
+	b.networkMutex.Unlock()
+
+	listenCtx, stopListening := context.WithCancel(browserCtx)
+	chromedp.ListenTarget(listenCtx, func(ev any) {
+		e, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+		b.networkMutex.Lock()
+		if b.blockedRequests != nil {
+			b.blockedRequests[e.NetworkID] = true
+		}
+		b.networkRequests = slices.DeleteFunc(b.networkRequests, func(r *NetworkRequest) bool {
+			return r.RequestID == string(e.NetworkID)
+		})
+		b.networkMutex.Unlock()
+		// Listeners must not block, so respond to the paused request asynchronously.
+		go func() {
+			if err := chromedp.Run(browserCtx, fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient)); err != nil {
+				log.Printf("failed to block request %s: %v", e.Request.URL, err)
+			}
+		}()
+	})
+
+	if err := chromedp.Run(browserCtx, network.Enable(), fetch.Enable().WithPatterns(patterns)); err != nil {
+		stopListening()
+		return nil, fmt.Errorf("enable request blocking: %w", err)
+	}
+
+	return func() {
+		if err := chromedp.Run(browserCtx, fetch.Disable()); err != nil {
+			log.Printf("failed to disable request blocking: %v", err)
+		}
+		stopListening()
+		b.networkMutex.Lock()
+		b.blockedRequests = nil
+		b.networkMutex.Unlock()
+	}, nil
+}
+
+// applyRequestOverrides sets extra HTTP headers and a user agent override for
+// the browser context. Both persist for later requests until overwritten;
+// an empty (non-nil) headers map clears previously set headers.
+func (input navigateInput) applyRequestOverrides() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if input.Headers != nil {
+			headers := make(network.Headers, len(input.Headers))
+			for k, v := range input.Headers {
+				headers[k] = v
+			}
+			if err := network.Enable().Do(ctx); err != nil {
+				return fmt.Errorf("enable network: %w", err)
+			}
+			if err := network.SetExtraHTTPHeaders(headers).Do(ctx); err != nil {
+				return fmt.Errorf("set extra headers: %w", err)
+			}
+		}
+		if input.UserAgent != "" {
+			if err := emulation.SetUserAgentOverride(input.UserAgent).Do(ctx); err != nil {
+				return fmt.Errorf("set user agent: %w", err)
+			}
+		}
+		return nil
+	})
 }
 
 // isPort80 reports whether urlStr definitely uses port 80.
@@ -370,15 +633,32 @@ func (b *BrowseTools) navigateRun(ctx context.Context, m json.RawMessage) llm.To
 		return llm.ErrorToolOut(fmt.Errorf("port 80 is not the port you're looking for--port 80 is the main sketch server"))
 	}
 
+	patterns, err := blockPatterns(input.Block)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
 	browserCtx, err := b.GetBrowserContext()
 	if err != nil {
 		return llm.ErrorToolOut(err)
 	}
 
+	if len(patterns) > 0 {
+		unblock, err := b.blockResources(browserCtx, patterns)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		defer unblock()
+	}
+
 	// Create a timeout context for this operation
-	timeoutCtx, cancel := context.WithTimeout(browserCtx, parseTimeout(input.Timeout))
+	timeoutCtx, cancel := b.actionContext(browserCtx, parseTimeout(input.Timeout))
 	defer cancel()
 
+	if err := chromedp.Run(timeoutCtx, input.applyRequestOverrides()); err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
 	err = chromedp.Run(timeoutCtx,
 		chromedp.Navigate(input.URL),
 		chromedp.WaitReady("body"),
@@ -400,18 +680,66 @@ func (b *BrowseTools) navigateRun(ctx context.Context, m json.RawMessage) llm.To
 						sb.WriteString(fmt.Sprintf("\n  - %s (from %s) saved to: %s", d.SuggestedFilename, d.URL, d.FinalPath))
 					}
 				}
+				b.recordNavigation(nil)
 				return llm.ToolOut{LLMContent: llm.TextContent(sb.String())}
 			}
 		}
-		return llm.ErrorToolOut(err)
+		return llm.ErrorToolOut(b.recordNavigation(err))
 	}
 
+	b.recordNavigation(nil)
 	return b.toolOutWithDownloads("done")
 }
 
+// recordNavigation tracks consecutive navigation failures. Once
+// navFailureThreshold navigations in a row have failed in the browser itself,
+// the browser is probably up but unable to load pages, so it is closed (the
+// next action starts a fresh one) and err is returned with a hint pointing at
+// the likely cause. Cancelled navigations and errors the page load reported,
+// such as a refused connection or unknown host, don't count: restarting
+// wouldn't help and would lose the session's cookies and overrides.
+func (b *BrowseTools) recordNavigation(err error) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if err == nil {
+		b.navFailures = 0
+		return nil
+	}
+	if !isBrowserFailure(err) {
+		return err
+	}
+	b.navFailures++
+	if b.navFailures < navFailureThreshold {
+		return err
+	}
+	n := b.navFailures
+	b.navFailures = 0
+	log.Printf("%d consecutive navigations failed, restarting browser", n)
+	b.closeBrowserLocked()
+	return fmt.Errorf("%w (browser started but %d consecutive navigations failed; check sandbox/display config, fonts and GPU support; the browser has been restarted)", err, n)
+}
+
+// isBrowserFailure reports whether a navigation failed because of the browser
+// or its DevTools connection (a dead target, a timeout) rather than because
+// the page couldn't be loaded (net::ERR_*).
+func isBrowserFailure(err error) bool {
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "net::err_") {
+		return false
+	}
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, chromedp.ErrInvalidContext) ||
+		errors.Is(err, chromedp.ErrInvalidTarget) ||
+		errors.Is(err, chromedp.ErrChannelClosed) ||
+		strings.Contains(msg, "target closed") ||
+		strings.Contains(msg, "target crashed")
+}
+
 type resizeInput struct {
 	Width   int    `json:"width"`
 	Height  int    `json:"height"`
+	Device  string `json:"device,omitempty"`
 	Timeout string `json:"timeout,omitempty"`
 }
 
@@ -421,8 +749,30 @@ func (b *BrowseTools) resizeRun(ctx context.Context, m json.RawMessage) llm.Tool
 		return llm.ErrorfToolOut("invalid input: %w", err)
 	}
 
-	if input.Width <= 0 || input.Height <= 0 {
-		return llm.ErrorToolOut(fmt.Errorf("invalid dimensions: width and height must be positive"))
+	// A device preset sets the viewport, scale factor, touch and user agent;
+	// otherwise only the viewport size changes.
+	var action chromedp.Action
+	var result string
+	if input.Device != "" {
+		info, err := lookupDevice(input.Device)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		action = chromedp.Emulate(info)
+		kind := "desktop"
+		if info.Mobile {
+			kind = "mobile"
+		}
+		if info.Touch {
+			kind += ", touch"
+		}
+		result = fmt.Sprintf("Emulating %s: %dx%d @ %.3gx device scale factor (%s)", info.Name, info.Width, info.Height, info.Scale, kind)
+	} else {
+		if input.Width <= 0 || input.Height <= 0 {
+			return llm.ErrorToolOut(fmt.Errorf("invalid dimensions: width and height must be positive"))
+		}
+		action = chromedp.EmulateViewport(int64(input.Width), int64(input.Height))
+		result = fmt.Sprintf("Viewport resized to %dx%d @ 1x device scale factor", input.Width, input.Height)
 	}
 
 	browserCtx, err := b.GetBrowserContext()
@@ -430,17 +780,14 @@ func (b *BrowseTools) resizeRun(ctx context.Context, m json.RawMessage) llm.Tool
 		return llm.ErrorToolOut(err)
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(browserCtx, parseTimeout(input.Timeout))
+	timeoutCtx, cancel := b.actionContext(browserCtx, parseTimeout(input.Timeout))
 	defer cancel()
 
-	err = chromedp.Run(timeoutCtx,
-		chromedp.EmulateViewport(int64(input.Width), int64(input.Height)),
-	)
-	if err != nil {
+	if err := chromedp.Run(timeoutCtx, action); err != nil {
 		return llm.ErrorToolOut(err)
 	}
 
-	return llm.ToolOut{LLMContent: llm.TextContent("done")}
+	return llm.ToolOut{LLMContent: llm.TextContent(result)}
 }
 
 type evalInput struct {
@@ -461,11 +808,17 @@ func (b *BrowseTools) evalRun(ctx context.Context, m json.RawMessage) llm.ToolOu
 	}
 
 	// Create a timeout context for this operation
-	timeoutCtx, cancel := context.WithTimeout(browserCtx, parseTimeout(input.Timeout))
+	timeoutCtx, cancel := b.actionContext(browserCtx, parseTimeout(input.Timeout))
 	defer cancel()
 
-	var result any
-	var evalOps []chromedp.EvaluateOption
+	// Ask for the raw RemoteObject (by value) so undefined, null, and
+	// unserializable values can be told apart.
+	var result *runtime.RemoteObject
+	evalOps := []chromedp.EvaluateOption{
+		func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithReturnByValue(true)
+		},
+	}
 
 	await := true
 	if input.Await != nil {
@@ -481,14 +834,14 @@ func (b *BrowseTools) evalRun(ctx context.Context, m json.RawMessage) llm.ToolOu
 
 	err = chromedp.Run(timeoutCtx, evalAction)
 	if err != nil {
+		var exception *runtime.ExceptionDetails
+		if errors.As(err, &exception) {
+			return llm.ErrorToolOut(errors.New(formatJSException(exception)))
+		}
 		return llm.ErrorToolOut(err)
 	}
 
-	// Return the result as JSON
-	response, err := json.Marshal(result)
-	if err != nil {
-		return llm.ErrorfToolOut("failed to marshal response: %w", err)
-	}
+	response := formatJSResult(result)
 
 	// If output exceeds threshold, write to file
 	if len(response) > ConsoleLogSizeThreshold {
@@ -505,16 +858,191 @@ func (b *BrowseTools) evalRun(ctx context.Context, m json.RawMessage) llm.ToolOu
 	return b.toolOutWithDownloads("<javascript_result>" + string(response) + "</javascript_result>")
 }
 
+// formatJSResult renders an evaluation result for the agent. Values are JSON;
+// undefined and unserializable values (NaN, Infinity, bigint) are rendered as
+// their JavaScript literals so they can't be mistaken for null.
+func formatJSResult(obj *runtime.RemoteObject) []byte {
+	switch {
+	case obj == nil || obj.Type == runtime.TypeUndefined:
+		return []byte("undefined")
+	case obj.UnserializableValue != "":
+		return []byte(obj.UnserializableValue)
+	case len(obj.Value) == 0:
+		// Values that can't be returned by value (e.g. functions, symbols).
+		return []byte(obj.Description)
+	}
+	return []byte(obj.Value)
+}
+
+// formatJSException describes a thrown JavaScript exception, including the
+// exception message and its (1-based) location.
+func formatJSException(e *runtime.ExceptionDetails) string {
+	message := e.Text
+	if e.Exception != nil && e.Exception.Description != "" {
+		message = e.Exception.Description
+	}
+	return fmt.Sprintf("JavaScript exception at line %d, column %d: %s", e.LineNumber+1, e.ColumnNumber+1, message)
+}
+
+type clickInput struct {
+	Selector string `json:"selector"`
+	Timeout  string `json:"timeout,omitempty"`
+}
+
+func (b *BrowseTools) clickRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var input clickInput
+	if err := json.Unmarshal(m, &input); err != nil {
+		return llm.ErrorfToolOut("invalid input: %w", err)
+	}
+	if input.Selector == "" {
+		return llm.ErrorfToolOut("selector is required")
+	}
+
+	browserCtx, err := b.GetBrowserContext()
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	timeout := parseTimeout(input.Timeout)
+	timeoutCtx, cancel := b.actionContext(browserCtx, timeout)
+	defer cancel()
+
+	err = chromedp.Run(timeoutCtx,
+		chromedp.WaitVisible(input.Selector),
+		chromedp.Click(input.Selector, chromedp.NodeVisible),
+	)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return llm.ErrorfToolOut("no visible element matching %q within %v", input.Selector, timeout)
+	}
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	return b.toolOutWithDownloads("Clicked " + input.Selector)
+}
+
+type waitForInput struct {
+	Selector   string `json:"selector,omitempty"`
+	Expression string `json:"expression,omitempty"`
+	Timeout    string `json:"timeout,omitempty"`
+}
+
+// waitForPollInterval is how often wait_for re-evaluates its expression.
+const waitForPollInterval = 100 * time.Millisecond
+
+func (b *BrowseTools) waitForRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var input waitForInput
+	if err := json.Unmarshal(m, &input); err != nil {
+		return llm.ErrorfToolOut("invalid input: %w", err)
+	}
+	if (input.Selector == "") == (input.Expression == "") {
+		return llm.ErrorfToolOut("exactly one of selector or expression is required")
+	}
+
+	browserCtx, err := b.GetBrowserContext()
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	timeout := parseTimeout(input.Timeout)
+	timeoutCtx, cancel := b.actionContext(browserCtx, timeout)
+	defer cancel()
+
+	if input.Selector != "" {
+		err = chromedp.Run(timeoutCtx, chromedp.WaitVisible(input.Selector))
+		if errors.Is(err, context.DeadlineExceeded) {
+			return llm.ErrorfToolOut("no visible element matching %q within %v", input.Selector, timeout)
+		}
+	} else {
+		// Give the in-page poll the same timeout so it stops polling in the
+		// page once the action context gives up.
+		err = chromedp.Run(timeoutCtx, chromedp.Poll(input.Expression, nil,
+			chromedp.WithPollingInterval(waitForPollInterval),
+			chromedp.WithPollingTimeout(timeout),
+		))
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, chromedp.ErrPollingTimeout) {
+			return llm.ErrorfToolOut("expression %q did not become truthy within %v", input.Expression, timeout)
+		}
+		var exception *runtime.ExceptionDetails
+		if errors.As(err, &exception) {
+			return llm.ErrorToolOut(errors.New(formatJSException(exception)))
+		}
+	}
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	return b.toolOutWithDownloads("ready")
+}
+
 type screenshotInput struct {
 	Selector string `json:"selector,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Quality  int64  `json:"quality,omitempty"`
+	FullPage bool   `json:"full_page,omitempty"`
 	Timeout  string `json:"timeout,omitempty"`
 }
 
+// screenshotFormat maps a screenshot format name to its CDP format. Empty means png.
+func screenshotFormat(name string) (page.CaptureScreenshotFormat, error) {
+	switch name {
+	case "", "png":
+		return page.CaptureScreenshotFormatPng, nil
+	case "jpeg":
+		return page.CaptureScreenshotFormatJpeg, nil
+	case "webp":
+		return page.CaptureScreenshotFormatWebp, nil
+	default:
+		return "", fmt.Errorf("unsupported screenshot format %q (want png, jpeg or webp)", name)
+	}
+}
+
+// captureElementScreenshot captures the element matching sel, like
+// chromedp.Screenshot but in the given format and quality.
+func captureElementScreenshot(sel string, format page.CaptureScreenshotFormat, quality int64, buf *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var clip page.Viewport
+		expr := fmt.Sprintf(`(() => {
+			const e = document.querySelector(%q).getBoundingClientRect();
+			const d = document.documentElement.getBoundingClientRect();
+			return {x: e.left - d.left, y: e.top - d.top, width: e.width, height: e.height};
+		})()`, sel)
+		if err := chromedp.Evaluate(expr, &clip).Do(ctx); err != nil {
+			return err
+		}
+		// CDP does not handle fractional clips well; round as chromedp does.
+		x, y := math.Round(clip.X), math.Round(clip.Y)
+		clip.Width, clip.Height = math.Round(clip.Width+clip.X-x), math.Round(clip.Height+clip.Y-y)
+		clip.X, clip.Y = x, y
+		clip.Scale = 1
+
+		var err error
+		*buf, err = page.CaptureScreenshot().
+			WithFormat(format).
+			WithQuality(quality).
+			WithCaptureBeyondViewport(true).
+			WithFromSurface(true).
+			WithClip(&clip).
+			Do(ctx)
+		return err
+	})
+}
+
 func (b *BrowseTools) screenshotRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
 	var input screenshotInput
 	if err := json.Unmarshal(m, &input); err != nil {
 		return llm.ErrorfToolOut("invalid input: %w", err)
 	}
+	format, err := screenshotFormat(input.Format)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	if input.Quality < 0 || input.Quality > 100 {
+		return llm.ErrorfToolOut("quality must be between 1 and 100, got %d", input.Quality)
+	}
+	if input.Quality != 0 && format == page.CaptureScreenshotFormatPng {
+		return llm.ErrorToolOut(errors.New("quality applies only to jpeg and webp screenshots"))
+	}
 
 	// Try to get a browser context; if unavailable, return an error
 	browserCtx, err := b.GetBrowserContext()
@@ -523,7 +1051,7 @@ func (b *BrowseTools) screenshotRun(ctx context.Context, m json.RawMessage) llm.
 	}
 
 	// Create a timeout context for this operation
-	timeoutCtx, cancel := context.WithTimeout(browserCtx, parseTimeout(input.Timeout))
+	timeoutCtx, cancel := b.actionContext(browserCtx, parseTimeout(input.Timeout))
 	defer cancel()
 
 	var buf []byte
@@ -532,12 +1060,22 @@ func (b *BrowseTools) screenshotRun(ctx context.Context, m json.RawMessage) llm.
 	if input.Selector != "" {
 		// Take screenshot of specific element
 		actions = append(actions,
-			chromedp.WaitReady(input.Selector),
-			chromedp.Screenshot(input.Selector, &buf, chromedp.NodeVisible),
+			chromedp.WaitVisible(input.Selector),
+			captureElementScreenshot(input.Selector, format, input.Quality, &buf),
 		)
 	} else {
-		// Take full page screenshot
-		actions = append(actions, chromedp.CaptureScreenshot(&buf))
+		// Take a screenshot of the viewport, or of the entire scroll height
+		// when full_page is set (as chromedp.FullScreenshot does)
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			buf, err = page.CaptureScreenshot().
+				WithFormat(format).
+				WithQuality(input.Quality).
+				WithCaptureBeyondViewport(input.FullPage).
+				WithFromSurface(true).
+				Do(ctx)
+			return err
+		}))
 	}
 
 	err = chromedp.Run(timeoutCtx, actions...)
@@ -545,29 +1083,35 @@ func (b *BrowseTools) screenshotRun(ctx context.Context, m json.RawMessage) llm.
 		return llm.ErrorToolOut(err)
 	}
 
+	// Chrome can hand back an empty or truncated buffer (e.g. on timeouts);
+	// don't save and report a broken image as a successful screenshot.
+	if err := validateScreenshot(buf); err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
 	// Save the screenshot and get its ID for potential future reference
-	id := b.SaveScreenshot(buf)
+	id := b.SaveScreenshot(buf, string(format))
 	if id == "" {
 		return llm.ErrorToolOut(fmt.Errorf("failed to save screenshot"))
 	}
 
 	// Get the full path to the screenshot
-	screenshotPath := GetScreenshotPath(id)
+	screenshotPath := GetScreenshotPath(id, string(format))
 
 	// Resize image if needed to fit within model's image dimension limits
 	imageData := buf
-	format := "png"
+	mediaFormat := string(format)
 	resized := false
 	if b.maxImageDimension > 0 {
 		var err error
-		imageData, format, resized, err = imageutil.ResizeImage(buf, b.maxImageDimension)
+		imageData, mediaFormat, resized, err = imageutil.ResizeImage(buf, b.maxImageDimension)
 		if err != nil {
 			return llm.ErrorToolOut(fmt.Errorf("failed to resize screenshot: %w", err))
 		}
 	}
 
 	base64Data := base64.StdEncoding.EncodeToString(imageData)
-	mediaType := "image/" + format
+	mediaType := "image/" + mediaFormat
 
 	display := map[string]any{
 		"type":     "screenshot",
@@ -595,6 +1139,65 @@ func (b *BrowseTools) screenshotRun(ctx context.Context, m json.RawMessage) llm.
 	}, Display: display}
 }
 
+type uploadFileInput struct {
+	Selector string `json:"selector"`
+	Path     string `json:"path"`
+	Timeout  string `json:"timeout,omitempty"`
+}
+
+func (b *BrowseTools) uploadFileRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var input uploadFileInput
+	if err := json.Unmarshal(m, &input); err != nil {
+		return llm.ErrorfToolOut("invalid input: %w", err)
+	}
+	if input.Selector == "" {
+		return llm.ErrorfToolOut("selector is required")
+	}
+	if input.Path == "" {
+		return llm.ErrorfToolOut("path is required")
+	}
+
+	path := input.Path
+	if !filepath.IsAbs(path) && b.workingDir != nil {
+		path = filepath.Join(b.workingDir(), path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return llm.ErrorfToolOut("invalid path: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return llm.ErrorfToolOut("file not found: %w", err)
+	}
+	if info.IsDir() {
+		return llm.ErrorfToolOut("path is a directory, not a file: %s", path)
+	}
+
+	browserCtx, err := b.GetBrowserContext()
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	timeoutCtx, cancel := b.actionContext(browserCtx, parseTimeout(input.Timeout))
+	defer cancel()
+
+	var nodes []*cdp.Node
+	if err := chromedp.Run(timeoutCtx, chromedp.Nodes(input.Selector, &nodes, chromedp.NodeReady)); err != nil {
+		return llm.ErrorfToolOut("failed to find %q: %w", input.Selector, err)
+	}
+	node := nodes[0]
+	if node.NodeName != "INPUT" || !strings.EqualFold(node.AttributeValue("type"), "file") {
+		return llm.ErrorfToolOut("element %q is not a file input (<input type=file>)", input.Selector)
+	}
+
+	err = chromedp.Run(timeoutCtx, dom.SetFileInputFiles([]string{path}).WithBackendNodeID(node.BackendNodeID))
+	if err != nil {
+		return llm.ErrorfToolOut("failed to attach file: %w", err)
+	}
+
+	return b.toolOutWithDownloads(fmt.Sprintf("Attached %s (%d bytes) to %s", path, info.Size(), input.Selector))
+}
+
 // GetTools returns all browser tools.
 func (b *BrowseTools) GetTools() []*llm.Tool {
 	return []*llm.Tool{
@@ -613,19 +1216,34 @@ func (b *BrowseTools) CombinedTool() *llm.Tool {
 
 - action: "navigate"
   Navigate the browser to a specific URL and wait for page to load.
-  Parameters: url (string, required), timeout (string, optional)
+  Parameters: url (string, required), headers (object, optional), user_agent (string, optional), block (array, optional), timeout (string, optional)
+  headers and user_agent persist for later requests; pass headers: {} to clear them.
+  block skips loading resource types during this navigation (e.g. ["image", "font", "stylesheet", "media"]), which speeds up text extraction.
 
 - action: "eval"
   Evaluate JavaScript in the browser context. Your go-to for interacting with content: clicking buttons, typing, getting content, scrolling, waiting for content/selector to be ready, etc.
   Parameters: expression (string, required), timeout (string, optional), await (boolean, default true)
 
+- action: "click"
+  Wait for an element to be visible, then click it.
+  Parameters: selector (string, required), timeout (string, optional)
+
+- action: "wait_for"
+  Wait until an element is visible or a JavaScript expression is truthy (polled every 100ms). Prefer this over repeated eval calls.
+  Parameters: selector (string) or expression (string), exactly one required; timeout (string, optional)
+
 - action: "resize"
-  Resize the browser viewport to a specific width and height.
-  Parameters: width (integer, required), height (integer, required), timeout (string, optional)
+  Resize the browser viewport to a specific width and height, or emulate a device preset.
+  Parameters: width (integer), height (integer), device (string, e.g. "iphone-13", "pixel-7"; sets scale factor, touch and mobile user agent and takes precedence over width/height), timeout (string, optional)
 
 - action: "screenshot"
-  Take a screenshot of the page or a specific element.
-  Parameters: selector (string, optional), timeout (string, optional)
+  Take a screenshot of the page or a specific element. Use jpeg or webp with a lower quality to keep large, photo-heavy captures small.
+  Set full_page to capture the entire scrollable page instead of just the viewport; selector takes precedence over full_page.
+  Parameters: selector (string, optional), full_page (boolean, optional), format (string, "png", "jpeg" or "webp", default "png"), quality (integer, 1-100, jpeg/webp only), timeout (string, optional)
+
+- action: "upload_file"
+  Attach a local file to an <input type=file> element. Relative paths are resolved against the working directory.
+  Parameters: selector (string, required), path (string, required), timeout (string, optional)
 
 - action: "console_logs"
   Get recent browser console logs.
@@ -635,6 +1253,22 @@ func (b *BrowseTools) CombinedTool() *llm.Tool {
   Clear all captured browser console logs.
   No additional parameters.
 
+- action: "configure"
+  Change the browser idle timeout or console log buffer size for this session. Returns the effective configuration.
+  Parameters: idle_timeout (string, Go duration, optional), max_console_logs (integer, optional)
+
+- action: "downloads"
+  List downloads from this session with their status and saved path, or look one up by GUID.
+  Parameters: guid (string, optional), limit (integer, optional, default 20)
+
+- action: "wait_download"
+  Wait for a download to finish and return where it was saved. Use this after triggering a large download instead of polling.
+  Parameters: timeout (string, optional, default 15s)
+
+- action: "cookies"
+  Get the current page's cookies as JSON, set cookies (e.g. to seed a session), or clear all cookies.
+  Parameters: operation (string, "get", "set" or "clear", required), cookies (array of {name, value, domain, path, secure, httpOnly}, required for set; cookies without a domain apply to the current page), timeout (string, optional)
+
 - action: "screencast_start"
   Start recording a screencast. Frames are piped directly into ffmpeg to produce an MP4 file.
   Auto-stops after 30 minutes or 10000 frames. Requires ffmpeg to be installed.
@@ -654,15 +1288,29 @@ func (b *BrowseTools) CombinedTool() *llm.Tool {
 			"action": {
 				"type": "string",
 				"description": "The browser action to perform",
-				"enum": ["navigate", "eval", "resize", "screenshot", "console_logs", "clear_console_logs", "screencast_start", "screencast_stop", "screencast_status"]
+				"enum": ["navigate", "eval", "click", "wait_for", "resize", "screenshot", "upload_file", "console_logs", "clear_console_logs", "configure", "downloads", "wait_download", "cookies", "screencast_start", "screencast_stop", "screencast_status"]
 			},
 			"url": {
 				"type": "string",
 				"description": "URL to navigate to (navigate action)"
 			},
+			"headers": {
+				"type": "object",
+				"additionalProperties": {"type": "string"},
+				"description": "Extra HTTP headers sent with this and later requests; {} clears them (navigate action)"
+			},
+			"block": {
+				"type": "array",
+				"items": {"type": "string", "enum": ["image", "font", "stylesheet", "media", "script"]},
+				"description": "Resource types to block while navigating (navigate action)"
+			},
+			"user_agent": {
+				"type": "string",
+				"description": "User-Agent override for this and later requests (navigate action)"
+			},
 			"expression": {
 				"type": "string",
-				"description": "JavaScript expression to evaluate (eval action)"
+				"description": "JavaScript expression to evaluate (eval action) or wait to become truthy (wait_for action)"
 			},
 			"await": {
 				"type": "boolean",
@@ -676,13 +1324,58 @@ func (b *BrowseTools) CombinedTool() *llm.Tool {
 				"type": "integer",
 				"description": "Viewport height in pixels (resize action)"
 			},
+			"device": {
+				"type": "string",
+				"description": "Device preset to emulate instead of width/height, e.g. 'iphone-13', 'pixel-7' (resize action)"
+			},
 			"limit": {
 				"type": "integer",
-				"description": "Max log entries to return (console_logs action, default 100)"
+				"description": "Max entries to return (console_logs action, default 100; downloads action, default 20)"
+			},
+			"guid": {
+				"type": "string",
+				"description": "Download GUID to look up (downloads action)"
+			},
+			"idle_timeout": {
+				"type": "string",
+				"description": "Shut the browser down after this long without use, as a Go duration (configure action)"
+			},
+			"max_console_logs": {
+				"type": "integer",
+				"description": "Number of console log entries to keep (configure action, default 100)"
+			},
+			"operation": {
+				"type": "string",
+				"enum": ["get", "set", "clear"],
+				"description": "Cookie operation (cookies action)"
+			},
+			"cookies": {
+				"type": "array",
+				"description": "Cookies to set (cookies action, set operation)",
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string"},
+						"value": {"type": "string"},
+						"domain": {"type": "string"},
+						"path": {"type": "string"},
+						"secure": {"type": "boolean"},
+						"httpOnly": {"type": "boolean"}
+					},
+					"required": ["name", "value"]
+				}
 			},
 			"selector": {
 				"type": "string",
-				"description": "CSS selector for element to screenshot (screenshot action)"
+				"description": "CSS selector for the target element (click, wait_for, screenshot, upload_file actions)"
+			},
+			"path": {
+				"type": "string",
+				"description": "Local file to attach; relative paths are resolved against the working directory (upload_file action)"
+			},
+			"full_page": {
+				"type": "boolean",
+				"description": "Capture the entire scrollable page instead of the viewport (screenshot action without selector)"
 			},
 			"timeout": {
 				"type": "string",
@@ -690,11 +1383,11 @@ func (b *BrowseTools) CombinedTool() *llm.Tool {
 			},
 			"format": {
 				"type": "string",
-				"description": "Image format for screencast frames: 'jpeg' or 'png' (screencast_start action, default 'jpeg')"
+				"description": "Image format: 'png', 'jpeg' or 'webp' for screenshot (default 'png'); 'jpeg' or 'png' for screencast_start (default 'jpeg')"
 			},
 			"quality": {
 				"type": "integer",
-				"description": "Image quality 0-100 for screencast frames (screencast_start action, default 60)"
+				"description": "Image quality: 1-100 for jpeg/webp screenshots; 0-100 for screencast frames (screencast_start action, default 60)"
 			},
 			"max_width": {
 				"type": "integer",
@@ -724,13 +1417,21 @@ func (b *BrowseTools) CombinedTool() *llm.Tool {
 func (b *BrowseTools) ReadImageTool() *llm.Tool {
 	return &llm.Tool{
 		Name:        "read_image",
-		Description: "Read an image file (such as a screenshot) and encode it for sending to the LLM",
+		Description: "Read an image file (such as a screenshot) and encode it for sending to the LLM. Given a directory, reads the images in it in name order.",
 		InputSchema: json.RawMessage(`{
 			"type": "object",
 			"properties": {
 				"path": {
 					"type": "string",
-					"description": "Path to the image file to read"
+					"description": "Path to the image file to read, or a directory of images"
+				},
+				"pattern": {
+					"type": "string",
+					"description": "For a directory, a glob matched against file names (e.g. \"*.png\"); defaults to common image extensions"
+				},
+				"limit": {
+					"type": "integer",
+					"description": "For a directory, the most images to read (at most 20 by default)"
 				},
 				"timeout": {
 					"type": "string",
@@ -752,7 +1453,9 @@ type combinedInput struct {
 	Width         int    `json:"width,omitempty"`
 	Height        int    `json:"height,omitempty"`
 	Limit         int    `json:"limit,omitempty"`
+	GUID          string `json:"guid,omitempty"`
 	Selector      string `json:"selector,omitempty"`
+	Path          string `json:"path,omitempty"`
 	Timeout       string `json:"timeout,omitempty"`
 	Format        string `json:"format,omitempty"`
 	Quality       int64  `json:"quality,omitempty"`
@@ -773,14 +1476,28 @@ func (b *BrowseTools) combinedRun() func(context.Context, json.RawMessage) llm.T
 			return b.navigateRun(ctx, m)
 		case "eval":
 			return b.evalRun(ctx, m)
+		case "click":
+			return b.clickRun(ctx, m)
+		case "wait_for":
+			return b.waitForRun(ctx, m)
 		case "resize":
 			return b.resizeRun(ctx, m)
 		case "screenshot":
 			return b.screenshotRun(ctx, m)
+		case "upload_file":
+			return b.uploadFileRun(ctx, m)
 		case "console_logs":
 			return b.recentConsoleLogsRun(ctx, m)
 		case "clear_console_logs":
 			return b.clearConsoleLogsRun(ctx, m)
+		case "configure":
+			return b.configureRun(ctx, m)
+		case "downloads":
+			return b.downloadsRun(ctx, m)
+		case "wait_download":
+			return b.waitDownloadRun(ctx, m)
+		case "cookies":
+			return b.cookiesRun(ctx, m)
 		case "screencast_start":
 			sessionID, err := b.screencastStart(input.Format, input.Quality, input.MaxWidth, input.MaxHeight, input.EveryNthFrame)
 			if err != nil {
@@ -822,13 +1539,25 @@ func (b *BrowseTools) combinedRun() func(context.Context, json.RawMessage) llm.T
 	}
 }
 
-// SaveScreenshot saves a screenshot to disk and returns its ID
-func (b *BrowseTools) SaveScreenshot(data []byte) string {
+// validateScreenshot checks that a captured screenshot buffer decodes as a complete image.
+func validateScreenshot(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("screenshot failed: browser returned an empty image")
+	}
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("screenshot failed: browser returned a corrupt or truncated image (%d bytes): %w", len(data), err)
+	}
+	return nil
+}
+
+// SaveScreenshot saves a screenshot in the given format (png, jpeg or webp)
+// to disk and returns its ID
+func (b *BrowseTools) SaveScreenshot(data []byte, format string) string {
 	// Generate a unique ID
 	id := uuid.New().String()
 
 	// Save the file
-	filePath := filepath.Join(ScreenshotDir, id+".png")
+	filePath := GetScreenshotPath(id, format)
 	if err := os.WriteFile(filePath, data, 0o644); err != nil {
 		log.Printf("Failed to save screenshot: %v", err)
 		return ""
@@ -842,13 +1571,45 @@ func (b *BrowseTools) SaveScreenshot(data []byte) string {
 	return id
 }
 
-// GetScreenshotPath returns the full path to a screenshot by ID
-func GetScreenshotPath(id string) string {
-	return filepath.Join(ScreenshotDir, id+".png")
+// GetScreenshotPath returns the full path to a screenshot by ID and format
+func GetScreenshotPath(id, format string) string {
+	return filepath.Join(ScreenshotDir, id+"."+format)
+}
+
+// DefaultMaxDirImages and DefaultMaxDirImageBytes bound how many images, and
+// how many bytes of image files, read_image reads from a directory.
+const (
+	DefaultMaxDirImages     = 20
+	DefaultMaxDirImageBytes = 20 << 20
+)
+
+// dirImageExtensions are the files read_image reads from a directory when no
+// pattern is given.
+var dirImageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".heic": true, ".heif": true,
+}
+
+// SetWorkingDir makes relative upload_file paths resolve against the
+// directory dir returns, such as the conversation's working directory.
+func (b *BrowseTools) SetWorkingDir(dir func() string) {
+	b.workingDir = dir
+}
+
+// SetDirImageLimits sets how many images, and how many bytes of image files,
+// read_image reads from a directory. Zero keeps the current limit.
+func (b *BrowseTools) SetDirImageLimits(count int, totalBytes int64) {
+	if count > 0 {
+		b.maxDirImages = count
+	}
+	if totalBytes > 0 {
+		b.maxDirImageBytes = totalBytes
+	}
 }
 
 type readImageInput struct {
 	Path    string `json:"path"`
+	Pattern string `json:"pattern,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
 	Timeout string `json:"timeout,omitempty"`
 }
 
@@ -859,14 +1620,105 @@ func (b *BrowseTools) readImageRun(ctx context.Context, m json.RawMessage) llm.T
 	}
 
 	// Check if the path exists
-	if _, err := os.Stat(input.Path); os.IsNotExist(err) {
+	info, err := os.Stat(input.Path)
+	if os.IsNotExist(err) {
 		return llm.ErrorfToolOut("image file not found: %s", input.Path)
 	}
+	if err == nil && info.IsDir() {
+		return b.readImageDir(input)
+	}
+	if input.Pattern != "" {
+		return llm.ErrorfToolOut("pattern only applies when path is a directory")
+	}
+
+	contents, err := b.readImage(input.Path)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	return llm.ToolOut{LLMContent: contents}
+}
+
+// readImageDir reads the images in a directory, in name order, up to the
+// count and size limits.
+func (b *BrowseTools) readImageDir(input readImageInput) llm.ToolOut {
+	entries, err := os.ReadDir(input.Path)
+	if err != nil {
+		return llm.ErrorfToolOut("failed to read directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if input.Pattern != "" {
+			ok, err := filepath.Match(input.Pattern, e.Name())
+			if err != nil {
+				return llm.ErrorfToolOut("invalid pattern %q: %w", input.Pattern, err)
+			}
+			if !ok {
+				continue
+			}
+		} else if !dirImageExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	if len(names) == 0 {
+		if input.Pattern != "" {
+			return llm.ErrorfToolOut("no files matching %q in %s", input.Pattern, input.Path)
+		}
+		return llm.ErrorfToolOut("no images in %s", input.Path)
+	}
 
+	limit := b.maxDirImages
+	if input.Limit > 0 && input.Limit < limit {
+		limit = input.Limit
+	}
+	var images []llm.Content
+	var notes []string
+	var totalBytes int64
+	read := 0
+	for i, name := range names {
+		if read == limit {
+			notes = append(notes, fmt.Sprintf("stopped at the limit of %d images; %d more not read", limit, len(names)-i))
+			break
+		}
+		path := filepath.Join(input.Path, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		if totalBytes+info.Size() > b.maxDirImageBytes {
+			notes = append(notes, fmt.Sprintf("stopped at the %d byte size limit; %d more not read", b.maxDirImageBytes, len(names)-i))
+			break
+		}
+		contents, err := b.readImage(path)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		totalBytes += info.Size()
+		read++
+		images = append(images, contents...)
+	}
+	if read == 0 {
+		return llm.ErrorfToolOut("no images could be read from %s:\n%s", input.Path, strings.Join(notes, "\n"))
+	}
+
+	summary := fmt.Sprintf("Read %d of %d files in %s", read, len(names), input.Path)
+	if len(notes) > 0 {
+		summary += ":\n" + strings.Join(notes, "\n")
+	}
+	return llm.ToolOut{LLMContent: append([]llm.Content{{Type: llm.ContentTypeText, Text: summary}}, images...)}
+}
+
+// readImage reads an image file into a description and the encoded image.
+func (b *BrowseTools) readImage(path string) ([]llm.Content, error) {
 	// Read the file
-	imageData, err := os.ReadFile(input.Path)
+	imageData, err := os.ReadFile(path)
 	if err != nil {
-		return llm.ErrorfToolOut("failed to read image file: %w", err)
+		return nil, fmt.Errorf("failed to read image file: %w", err)
 	}
 
 	// Convert HEIC to PNG if needed (Go's image library doesn't support HEIC)
@@ -874,14 +1726,20 @@ func (b *BrowseTools) readImageRun(ctx context.Context, m json.RawMessage) llm.T
 	if imageutil.IsHEIC(imageData) {
 		imageData, err = imageutil.ConvertHEICToPNG(imageData)
 		if err != nil {
-			return llm.ErrorfToolOut("failed to convert HEIC image: %w", err)
+			return nil, fmt.Errorf("failed to convert HEIC image: %w", err)
 		}
 		converted = true
 	}
 
+	// Turn phone photos upright; models can't read the EXIF orientation
+	imageData, rotated, err := imageutil.FixOrientation(imageData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply image orientation: %w", err)
+	}
+
 	detectedType := http.DetectContentType(imageData)
 	if !strings.HasPrefix(detectedType, "image/") {
-		return llm.ErrorfToolOut("file is not an image: %s", detectedType)
+		return nil, fmt.Errorf("file is not an image: %s", detectedType)
 	}
 
 	// Resize image if needed to fit within model's image dimension limits
@@ -891,22 +1749,25 @@ func (b *BrowseTools) readImageRun(ctx context.Context, m json.RawMessage) llm.T
 		var err error
 		imageData, format, resized, err = imageutil.ResizeImage(imageData, b.maxImageDimension)
 		if err != nil {
-			return llm.ErrorToolOut(fmt.Errorf("failed to resize image: %w", err))
+			return nil, fmt.Errorf("failed to resize image: %w", err)
 		}
 	}
 
 	base64Data := base64.StdEncoding.EncodeToString(imageData)
 	mediaType := "image/" + format
 
-	description := fmt.Sprintf("Image from %s (type: %s)", input.Path, mediaType)
+	description := fmt.Sprintf("Image from %s (type: %s)", path, mediaType)
 	if converted {
 		description += " [converted from HEIC]"
 	}
+	if rotated {
+		description += " [rotated upright]"
+	}
 	if resized {
 		description += " [resized]"
 	}
 
-	return llm.ToolOut{LLMContent: []llm.Content{
+	return []llm.Content{
 		{
 			Type: llm.ContentTypeText,
 			Text: description,
@@ -916,7 +1777,7 @@ func (b *BrowseTools) readImageRun(ctx context.Context, m json.RawMessage) llm.T
 			MediaType: mediaType,
 			Data:      base64Data,
 		},
-	}}
+	}, nil
 }
 
 // parseTimeout parses a timeout string and returns a time.Duration
@@ -929,6 +1790,40 @@ func parseTimeout(timeout string) time.Duration {
 	return dur
 }
 
+// compileRedactPatterns compiles regexes for console log redaction.
+func compileRedactPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// SetRedactPatterns replaces the regexes used to mask secrets in console logs.
+// An empty list disables redaction.
+func (b *BrowseTools) SetRedactPatterns(patterns []string) error {
+	compiled, err := compileRedactPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	b.consoleLogsMutex.Lock()
+	b.redactPatterns = compiled
+	b.consoleLogsMutex.Unlock()
+	return nil
+}
+
+// redact masks every match of patterns in data.
+func redact(data []byte, patterns []*regexp.Regexp) []byte {
+	for _, re := range patterns {
+		data = re.ReplaceAll(data, []byte(redactedPlaceholder))
+	}
+	return data
+}
+
 // captureConsoleLog captures a console log event and stores it
 func (b *BrowseTools) captureConsoleLog(e *runtime.EventConsoleAPICalled) {
 	// Add to logs with mutex protection
@@ -947,10 +1842,20 @@ func (b *BrowseTools) handleDownloadWillBegin(e *browser.EventDownloadWillBegin)
 	b.downloadsMutex.Lock()
 	defer b.downloadsMutex.Unlock()
 
-	b.downloads[e.GUID] = &DownloadInfo{
+	b.trackDownloadLocked(&DownloadInfo{
 		GUID:              e.GUID,
 		URL:               e.URL,
 		SuggestedFilename: e.SuggestedFilename,
+	})
+}
+
+// trackDownloadLocked records a new download in both the pending map and the
+// session download log. Caller must hold b.downloadsMutex.
+func (b *BrowseTools) trackDownloadLocked(info *DownloadInfo) {
+	b.downloads[info.GUID] = info
+	b.downloadLog = append(b.downloadLog, info)
+	if len(b.downloadLog) > b.maxDownloadLog {
+		b.downloadLog = b.downloadLog[len(b.downloadLog)-b.maxDownloadLog:]
 	}
 }
 
@@ -963,7 +1868,7 @@ func (b *BrowseTools) handleDownloadProgress(e *browser.EventDownloadProgress) {
 	if !ok {
 		// Download started before we started tracking, create entry
 		info = &DownloadInfo{GUID: e.GUID}
-		b.downloads[e.GUID] = info
+		b.trackDownloadLocked(info)
 	}
 
 	switch e.State {
@@ -1027,6 +1932,217 @@ func (b *BrowseTools) GetRecentDownloads() []*DownloadInfo {
 	return completed
 }
 
+// WaitForDownload blocks until a download completes (or fails) and returns
+// it, removing it from the set returned by GetRecentDownloads. A download
+// that already completed is returned immediately. It returns ctx.Err() if
+// ctx is done first.
+func (b *BrowseTools) WaitForDownload(ctx context.Context) (*DownloadInfo, error) {
+	// Cond.Wait can't select on ctx, so wake waiters when ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		b.downloadsMutex.Lock()
+		b.downloadCond.Broadcast()
+		b.downloadsMutex.Unlock()
+	})
+	defer stop()
+
+	b.downloadsMutex.Lock()
+	defer b.downloadsMutex.Unlock()
+	for {
+		// Prefer the oldest completed download, in session log order.
+		for _, info := range b.downloadLog {
+			if pending, ok := b.downloads[info.GUID]; ok && pending.Completed {
+				delete(b.downloads, info.GUID)
+				return pending, nil
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		b.downloadCond.Wait()
+	}
+}
+
+// DownloadLog returns a snapshot of every download seen this session, oldest first.
+// Unlike GetRecentDownloads, it does not clear anything.
+func (b *BrowseTools) DownloadLog() []DownloadInfo {
+	b.downloadsMutex.Lock()
+	defer b.downloadsMutex.Unlock()
+
+	res := make([]DownloadInfo, len(b.downloadLog))
+	for i, info := range b.downloadLog {
+		res[i] = *info
+	}
+	return res
+}
+
+// LookupDownload returns a snapshot of the download with the given GUID from the session download log.
+func (b *BrowseTools) LookupDownload(guid string) (DownloadInfo, bool) {
+	b.downloadsMutex.Lock()
+	defer b.downloadsMutex.Unlock()
+
+	for _, info := range b.downloadLog {
+		if info.GUID == guid {
+			return *info, true
+		}
+	}
+	return DownloadInfo{}, false
+}
+
+// downloadStatus describes a download's state for the LLM
+func downloadStatus(d DownloadInfo) string {
+	switch {
+	case !d.Completed:
+		return "in progress"
+	case d.Error != "":
+		return "failed: " + d.Error
+	default:
+		return "saved to: " + d.FinalPath
+	}
+}
+
+type downloadsInput struct {
+	GUID  string `json:"guid,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+func (b *BrowseTools) downloadsRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var input downloadsInput
+	if err := json.Unmarshal(m, &input); err != nil {
+		return llm.ErrorfToolOut("invalid input: %w", err)
+	}
+
+	if input.GUID != "" {
+		d, ok := b.LookupDownload(input.GUID)
+		if !ok {
+			return llm.ErrorfToolOut("no download with guid %q", input.GUID)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("%s (from %s): %s", d.SuggestedFilename, d.URL, downloadStatus(d)))}
+	}
+
+	downloads := b.DownloadLog()
+	if len(downloads) == 0 {
+		return llm.ToolOut{LLMContent: llm.TextContent("No downloads this session.")}
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if len(downloads) > limit {
+		downloads = downloads[len(downloads)-limit:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Downloads (oldest first):")
+	for _, d := range downloads {
+		sb.WriteString(fmt.Sprintf("\n  - [%s] %s (from %s): %s", d.GUID, d.SuggestedFilename, d.URL, downloadStatus(d)))
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(sb.String())}
+}
+
+type waitDownloadInput struct {
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func (b *BrowseTools) waitDownloadRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var input waitDownloadInput
+	if err := json.Unmarshal(m, &input); err != nil {
+		return llm.ErrorfToolOut("invalid input: %w", err)
+	}
+
+	timeout := parseTimeout(input.Timeout)
+	timeoutCtx, cancel := b.actionContext(ctx, timeout)
+	defer cancel()
+
+	d, err := b.WaitForDownload(timeoutCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return llm.ErrorfToolOut("no download completed within %v", timeout)
+	}
+	if err != nil {
+		return llm.ErrorfToolOut("waiting for download: %w", err)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("[%s] %s (from %s): %s", d.GUID, d.SuggestedFilename, d.URL, downloadStatus(*d)))}
+}
+
+type cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+}
+
+type cookiesInput struct {
+	Operation string   `json:"operation"`
+	Cookies   []cookie `json:"cookies,omitempty"`
+	Timeout   string   `json:"timeout,omitempty"`
+}
+
+func (b *BrowseTools) cookiesRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var input cookiesInput
+	if err := json.Unmarshal(m, &input); err != nil {
+		return llm.ErrorfToolOut("invalid input: %w", err)
+	}
+	switch input.Operation {
+	case "get":
+		return b.networkCookiesRun()
+	case "set":
+		if len(input.Cookies) == 0 {
+			return llm.ErrorfToolOut("cookies is required for the set operation")
+		}
+		for _, c := range input.Cookies {
+			if c.Name == "" {
+				return llm.ErrorfToolOut("every cookie needs a name")
+			}
+		}
+	case "clear":
+	default:
+		return llm.ErrorfToolOut("operation must be get, set or clear, got %q", input.Operation)
+	}
+
+	browserCtx, err := b.GetBrowserContext()
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	timeoutCtx, cancel := b.actionContext(browserCtx, parseTimeout(input.Timeout))
+	defer cancel()
+
+	if input.Operation == "clear" {
+		if err := chromedp.Run(timeoutCtx, network.ClearBrowserCookies()); err != nil {
+			return llm.ErrorfToolOut("failed to clear cookies: %w", err)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent("Cleared all browser cookies.")}
+	}
+
+	err = chromedp.Run(timeoutCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		// Cookies without a domain apply to the current page.
+		var pageURL string
+		if err := chromedp.Location(&pageURL).Do(ctx); err != nil {
+			return err
+		}
+		params := make([]*network.CookieParam, len(input.Cookies))
+		for i, c := range input.Cookies {
+			params[i] = &network.CookieParam{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Secure:   c.Secure,
+				HTTPOnly: c.HTTPOnly,
+			}
+			if c.Domain == "" {
+				params[i].URL = pageURL
+			}
+		}
+		return network.SetCookies(params).Do(ctx)
+	}))
+	if err != nil {
+		return llm.ErrorfToolOut("failed to set cookies: %w", err)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("Set %d cookies.", len(input.Cookies)))}
+}
+
 // toolOutWithDownloads creates a tool output that includes any completed downloads
 func (b *BrowseTools) toolOutWithDownloads(message string) llm.ToolOut {
 	downloads := b.GetRecentDownloads()
@@ -1077,6 +2193,7 @@ func (b *BrowseTools) recentConsoleLogsRun(ctx context.Context, m json.RawMessag
 		start = len(b.consoleLogs) - limit
 	}
 	logs = append(logs, b.consoleLogs[start:]...)
+	redactPatterns := b.redactPatterns
 	b.consoleLogsMutex.Unlock()
 
 	// Format the logs as JSON
@@ -1085,6 +2202,9 @@ func (b *BrowseTools) recentConsoleLogsRun(ctx context.Context, m json.RawMessag
 		return llm.ErrorfToolOut("failed to serialize logs: %w", err)
 	}
 
+	// Mask secrets before the logs are written to disk or returned to the model
+	logData = redact(logData, redactPatterns)
+
 	// If output exceeds threshold, write to file
 	if len(logData) > ConsoleLogSizeThreshold {
 		filename := fmt.Sprintf("console_logs_%s.json", uuid.New().String()[:8])
@@ -1133,3 +2253,65 @@ func (b *BrowseTools) clearConsoleLogsRun(ctx context.Context, m json.RawMessage
 
 	return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("Cleared %d console log entries.", logCount))}
 }
+
+type configureInput struct {
+	IdleTimeout    string `json:"idle_timeout"`
+	MaxConsoleLogs *int   `json:"max_console_logs"`
+}
+
+// browserConfig is the effective configuration reported by the configure action.
+type browserConfig struct {
+	IdleTimeout    string `json:"idle_timeout"`
+	MaxConsoleLogs int    `json:"max_console_logs"`
+}
+
+// configureRun updates the idle timeout and console log buffer size without
+// restarting the browser. Omitted fields are left unchanged.
+func (b *BrowseTools) configureRun(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var input configureInput
+	if err := json.Unmarshal(m, &input); err != nil {
+		return llm.ErrorfToolOut("invalid input: %w", err)
+	}
+
+	var idleTimeout time.Duration
+	if input.IdleTimeout != "" {
+		d, err := time.ParseDuration(input.IdleTimeout)
+		if err != nil {
+			return llm.ErrorfToolOut("invalid idle_timeout %q: %w", input.IdleTimeout, err)
+		}
+		if d <= 0 {
+			return llm.ErrorfToolOut("idle_timeout must be positive, got %v", d)
+		}
+		idleTimeout = d
+	}
+	if input.MaxConsoleLogs != nil && *input.MaxConsoleLogs <= 0 {
+		return llm.ErrorfToolOut("max_console_logs must be positive, got %d", *input.MaxConsoleLogs)
+	}
+
+	b.mux.Lock()
+	if idleTimeout > 0 {
+		b.idleTimeout = idleTimeout
+		// Restart a running timer so the new timeout applies right away.
+		if b.idleTimer != nil {
+			b.resetIdleTimerLocked()
+		}
+	}
+	config := browserConfig{IdleTimeout: b.idleTimeout.String()}
+	b.mux.Unlock()
+
+	b.consoleLogsMutex.Lock()
+	if input.MaxConsoleLogs != nil {
+		b.maxConsoleLogs = *input.MaxConsoleLogs
+		if len(b.consoleLogs) > b.maxConsoleLogs {
+			b.consoleLogs = b.consoleLogs[len(b.consoleLogs)-b.maxConsoleLogs:]
+		}
+	}
+	config.MaxConsoleLogs = b.maxConsoleLogs
+	b.consoleLogsMutex.Unlock()
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return llm.ErrorfToolOut("failed to serialize configuration: %w", err)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(string(data))}
+}
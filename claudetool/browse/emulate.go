@@ -9,6 +9,7 @@ import (
 
 	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
 	"shelley.exe.dev/llm"
 )
 
@@ -57,6 +58,38 @@ var devicePresets = map[string]devicePreset{
 	},
 }
 
+// lookupDevice finds a device for the resize action by slug, e.g. "iphone-13"
+// or "pixel-7". chromedp's device registry is searched first, then devicePresets.
+func lookupDevice(name string) (device.Info, error) {
+	slug := deviceSlug(name)
+	for d := device.Reset + 1; d <= device.MotoG4landscape; d++ {
+		if info := d.Device(); deviceSlug(info.Name) == slug {
+			return info, nil
+		}
+	}
+	if preset, ok := devicePresets[strings.ReplaceAll(slug, "-", "_")]; ok {
+		return device.Info{
+			Name:      name,
+			UserAgent: preset.UserAgent,
+			Width:     preset.Width,
+			Height:    preset.Height,
+			Scale:     preset.DPR,
+			Mobile:    preset.Mobile,
+			Touch:     preset.Touch,
+		}, nil
+	}
+	return device.Info{}, fmt.Errorf("unknown device %q; use a name like \"iphone-13\", \"iphone-15-pro\", \"ipad-pro\", \"pixel-5\" or \"pixel-7\"", name)
+}
+
+// deviceSlug lowercases a device name and joins its words with dashes,
+// so "iPhone 13 Pro", "iphone_13_pro" and "iphone-13-pro" all match.
+func deviceSlug(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	})
+	return strings.Join(words, "-")
+}
+
 // EmulateTool returns a tool for device and display emulation.
 func (b *BrowseTools) EmulateTool() *llm.Tool {
 	description := "Device and display emulation. Actions: help, device, custom, reset, dark_mode, media."
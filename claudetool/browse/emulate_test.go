@@ -47,7 +47,7 @@ func newEmulateTestEnv(t *testing.T) *emulateTestEnv {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	t.Cleanup(cancel)
 
-	tools := NewBrowseTools(ctx, 0, 0)
+	tools := NewBrowseTools(ctx, 0, 0, 0, 0)
 	t.Cleanup(func() { tools.Close() })
 
 	browserTool := tools.CombinedTool()
@@ -168,3 +168,45 @@ func TestEmulateDeviceToCustomClearsUserAgent(t *testing.T) {
 	}
 	t.Logf("After custom: UA=%s", ua)
 }
+
+func TestLookupDevice(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantName   string
+		wantWidth  int64
+		wantHeight int64
+		wantScale  float64
+		wantErr    bool
+	}{
+		{name: "iphone-13", wantName: "iPhone 13", wantWidth: 390, wantHeight: 844, wantScale: 3},
+		{name: "iPhone 13 Pro", wantName: "iPhone 13 Pro", wantWidth: 390, wantHeight: 844, wantScale: 3},
+		{name: "iphone-13-landscape", wantName: "iPhone 13 landscape", wantWidth: 844, wantHeight: 390, wantScale: 3},
+		{name: "pixel-5", wantName: "Pixel 5", wantWidth: 393, wantHeight: 851, wantScale: 3},
+		// Not in chromedp's registry; falls back to devicePresets.
+		{name: "pixel-7", wantName: "pixel-7", wantWidth: 412, wantHeight: 915, wantScale: 2.625},
+		{name: "galaxy_s23", wantName: "galaxy_s23", wantWidth: 360, wantHeight: 780, wantScale: 3},
+		{name: "nokia-3310", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := lookupDevice(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if info.Name != tt.wantName || info.Width != tt.wantWidth || info.Height != tt.wantHeight || info.Scale != tt.wantScale {
+				t.Errorf("got %s %dx%d @ %v, want %s %dx%d @ %v",
+					info.Name, info.Width, info.Height, info.Scale, tt.wantName, tt.wantWidth, tt.wantHeight, tt.wantScale)
+			}
+			if !info.Mobile || !info.Touch || info.UserAgent == "" {
+				t.Errorf("expected a mobile touch device with a user agent, got %+v", info)
+			}
+		})
+	}
+}
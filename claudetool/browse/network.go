@@ -27,6 +27,10 @@ type NetworkRequest struct {
 	StartTime  float64 `json:"start_time"`
 	EndTime    float64 `json:"end_time,omitempty"`
 	Size       float64 `json:"encoded_size,omitempty"`
+	// ErrorText and BlockedReason are set for requests that failed to load,
+	// e.g. net::ERR_CONNECTION_REFUSED or a CORS or mixed-content block.
+	ErrorText     string `json:"error_text,omitempty"`
+	BlockedReason string `json:"blocked_reason,omitempty"`
 }
 
 // timeToSeconds converts a *cdp.MonotonicTime to a float64 seconds value.
@@ -39,6 +43,10 @@ func (b *BrowseTools) captureNetworkRequest(e *network.EventRequestWillBeSent) {
 	b.networkMutex.Lock()
 	defer b.networkMutex.Unlock()
 
+	if b.blockedRequests[e.RequestID] {
+		return
+	}
+
 	req := &NetworkRequest{
 		RequestID: string(e.RequestID),
 		URL:       e.Request.URL,
@@ -89,6 +97,26 @@ func (b *BrowseTools) captureNetworkFinished(e *network.EventLoadingFinished) {
 	}
 }
 
+// captureNetworkFailed handles a LoadingFailed event by updating the matching
+// request with the error and, if the browser blocked it, the reason.
+func (b *BrowseTools) captureNetworkFailed(e *network.EventLoadingFailed) {
+	b.networkMutex.Lock()
+	defer b.networkMutex.Unlock()
+
+	for i := len(b.networkRequests) - 1; i >= 0; i-- {
+		if b.networkRequests[i].RequestID == string(e.RequestID) {
+			b.networkRequests[i].ErrorText = e.ErrorText
+			if e.BlockedReason != "" {
+				b.networkRequests[i].BlockedReason = e.BlockedReason.String()
+			} else if e.CorsErrorStatus != nil {
+				b.networkRequests[i].BlockedReason = "cors: " + e.CorsErrorStatus.CorsError.String()
+			}
+			b.networkRequests[i].EndTime = timeToSeconds(e.Timestamp.Time())
+			break
+		}
+	}
+}
+
 // networkInput is the input schema for the browser_network tool.
 type networkInput struct {
 	Action string `json:"action"`
@@ -163,7 +191,9 @@ Actions:
   disable   — Stop capturing network requests. Previously captured requests
               are retained until cleared.
 
-  get_log   — Retrieve captured network requests as JSON.
+  get_log   — Retrieve captured network requests as JSON. Failed requests
+              carry error_text, and blocked_reason when the browser
+              blocked them (e.g. CORS or mixed content).
               Parameters:
                 limit  (int, default 50) — max entries to return (most recent)
                 filter (string)         — only include requests whose URL
@@ -265,6 +295,12 @@ func (b *BrowseTools) networkGetLogRun(limit int, filter string) llm.ToolOut {
 		return llm.ErrorfToolOut("failed to serialize network requests: %w", err)
 	}
 
+	// URLs can carry tokens; mask them the same way as console logs
+	b.consoleLogsMutex.Lock()
+	redactPatterns := b.redactPatterns
+	b.consoleLogsMutex.Unlock()
+	logData = redact(logData, redactPatterns)
+
 	// If output exceeds threshold, write to file
 	if len(logData) > ConsoleLogSizeThreshold {
 		filename := fmt.Sprintf("network_log_%s.json", uuid.New().String()[:8])
@@ -0,0 +1,146 @@
+package browse
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// PoolAcquireTimeout is how long to wait for a free context when the pool is exhausted
+const PoolAcquireTimeout = 2 * time.Minute
+
+// Pool shares a single browser process among many BrowseTools, handing each
+// one its own tab context. At most size contexts are handed out at once;
+// further requests wait for a context to be released. Each BrowseTools keeps
+// its own idle timer and releases its context when idle; the shared browser
+// is shut down once every context has been released.
+//
+// If isolated, each context is a separate incognito browser context, so
+// cookies, storage and sessions are not shared between BrowseTools.
+type Pool struct {
+	ctx           context.Context
+	slots         chan struct{}
+	isolated      bool
+	mu            sync.Mutex
+	allocCancel   context.CancelFunc
+	browserCtx    context.Context
+	browserCancel context.CancelFunc
+	inUse         int
+}
+
+// NewPool creates a pool of at most size browser contexts.
+// The shared browser is started lazily and lives no longer than ctx.
+// If isolated, each context gets its own incognito browser context.
+func NewPool(ctx context.Context, size int, isolated bool) *Pool {
+	if size <= 0 {
+		size = 1
+	}
+	return &Pool{
+		ctx:      ctx,
+		slots:    make(chan struct{}, size),
+		isolated: isolated,
+	}
+}
+
+// Size returns the maximum number of concurrent contexts.
+func (p *Pool) Size() int {
+	return cap(p.slots)
+}
+
+// reserve waits for a free slot. A reserved slot is used by newTab or given
+// back with unreserve.
+func (p *Pool) reserve(ctx context.Context) error {
+	timer := time.NewTimer(PoolAcquireTimeout)
+	defer timer.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("all %d pooled browser contexts are in use", p.Size())
+	}
+}
+
+// unreserve gives back a slot taken by reserve.
+func (p *Pool) unreserve() {
+	<-p.slots
+}
+
+// newTab returns a new tab context in the shared browser, using a slot the
+// caller has reserved; on error the slot is given back.
+// The returned release function closes the tab and frees the slot; it is safe to call more than once.
+func (p *Pool) newTab() (context.Context, context.CancelFunc, error) {
+	var stops []func()
+	p.mu.Lock()
+	defer func() {
+		p.mu.Unlock()
+		for _, stop := range stops {
+			stop()
+		}
+	}()
+
+	if p.browserCtx != nil && p.browserCtx.Err() != nil {
+		log.Printf("Pooled browser is dead (err: %v), restarting browser", p.browserCtx.Err())
+		stops = append(stops, p.detachLocked())
+	}
+	if p.browserCtx == nil {
+		allocCtx, allocCancel := chromedp.NewExecAllocator(p.ctx, allocatorOptions()...)
+		browserCtx, browserCancel := chromedp.NewContext(allocCtx, contextOptions()...)
+		if err := chromedp.Run(browserCtx); err != nil {
+			stops = append(stops, browserCancel, allocCancel)
+			p.unreserve()
+			return nil, nil, fmt.Errorf("failed to start browser (please apt get chromium or equivalent): %w", err)
+		}
+		p.allocCancel = allocCancel
+		p.browserCtx = browserCtx
+		p.browserCancel = browserCancel
+	}
+
+	opts := contextOptions()
+	if p.isolated {
+		opts = append(opts, chromedp.WithNewBrowserContext())
+	}
+	tabCtx, tabCancel := chromedp.NewContext(p.browserCtx, opts...)
+	p.inUse++
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			tabCancel()
+			p.mu.Lock()
+			p.inUse--
+			var stop func()
+			if p.inUse == 0 {
+				stop = p.detachLocked()
+			}
+			p.mu.Unlock()
+			if stop != nil {
+				stop()
+			}
+			p.unreserve()
+		})
+	}
+	return tabCtx, release, nil
+}
+
+// detachLocked forgets the shared browser and returns a function that shuts
+// it down. Caller must hold p.mu and should call the returned function after
+// unlocking, since waiting for the browser to exit can take a while.
+func (p *Pool) detachLocked() func() {
+	browserCancel, allocCancel := p.browserCancel, p.allocCancel
+	p.browserCtx = nil
+	p.browserCancel = nil
+	p.allocCancel = nil
+	return func() {
+		if browserCancel != nil {
+			browserCancel()
+		}
+		if allocCancel != nil {
+			allocCancel()
+		}
+	}
+}
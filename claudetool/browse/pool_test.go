@@ -0,0 +1,153 @@
+package browse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoolAcquireWaitsForFreeSlot(t *testing.T) {
+	pool := NewPool(context.Background(), 1, false)
+	if pool.Size() != 1 {
+		t.Fatalf("expected size 1, got %d", pool.Size())
+	}
+
+	// Occupy the only slot.
+	pool.slots <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pool.reserve(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected reserve to wait until the deadline, got %v", err)
+	}
+}
+
+func TestGetBrowserContextWaitsWithoutLock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := NewPool(ctx, 1, false)
+	pool.slots <- struct{}{}
+	tools := NewBrowseTools(ctx, 0, 0, 0, 0)
+	tools.pool = pool
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := tools.GetBrowserContext()
+		errc <- err
+	}()
+
+	// Close takes the mux, so it must not wait for a pool slot.
+	closed := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		tools.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked while GetBrowserContext waited for a pool slot")
+	}
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected GetBrowserContext to stop waiting when canceled, got %v", err)
+	}
+}
+
+func TestPoolSharesBrowser(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping browser pool test in short mode")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pool := NewPool(ctx, 2, false)
+	tools1 := NewBrowseTools(ctx, 0, 0, 0, 0)
+	tools1.pool = pool
+	tools2 := NewBrowseTools(ctx, 0, 0, 0, 0)
+	tools2.pool = pool
+
+	if _, err := tools1.GetBrowserContext(); err != nil {
+		if strings.Contains(err.Error(), "failed to start browser") {
+			t.Skip("Browser automation not available in this environment")
+		}
+		t.Fatalf("Failed to get browser context: %v", err)
+	}
+	if _, err := tools2.GetBrowserContext(); err != nil {
+		t.Fatalf("Failed to get second browser context: %v", err)
+	}
+	if len(pool.slots) != 2 {
+		t.Fatalf("expected 2 contexts in use, got %d", len(pool.slots))
+	}
+
+	for _, tools := range []*BrowseTools{tools1, tools2} {
+		toolOut := tools.CombinedTool().Run(ctx, []byte(`{"action": "navigate", "url": "about:blank"}`))
+		if toolOut.Error != nil {
+			t.Fatalf("Navigate failed: %v", toolOut.Error)
+		}
+	}
+
+	tools1.Close()
+	if len(pool.slots) != 1 {
+		t.Fatalf("expected closed context to be returned to the pool, got %d in use", len(pool.slots))
+	}
+	tools2.Close()
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.browserCtx != nil {
+		t.Error("expected shared browser to shut down once all contexts are released")
+	}
+}
+
+func TestPoolIsolatesCookies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping browser pool test in short mode")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<!DOCTYPE html><html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pool := NewPool(ctx, 2, true)
+	tools1 := NewBrowseTools(ctx, 0, 0, 0, 0)
+	tools1.pool = pool
+	t.Cleanup(func() { tools1.Close() })
+	tools2 := NewBrowseTools(ctx, 0, 0, 0, 0)
+	tools2.pool = pool
+	t.Cleanup(func() { tools2.Close() })
+
+	navInput := []byte(fmt.Sprintf(`{"action": "navigate", "url": %q}`, server.URL))
+	toolOut := tools1.CombinedTool().Run(ctx, navInput)
+	if toolOut.Error != nil {
+		if strings.Contains(toolOut.Error.Error(), "failed to start browser") {
+			t.Skip("Browser automation not available in this environment")
+		}
+		t.Fatalf("Navigation error: %v", toolOut.Error)
+	}
+	toolOut = tools1.CombinedTool().Run(ctx, []byte(`{"action": "eval", "expression": "document.cookie = 'session=secret'"}`))
+	if toolOut.Error != nil {
+		t.Fatalf("Eval error: %v", toolOut.Error)
+	}
+
+	if toolOut := tools2.CombinedTool().Run(ctx, navInput); toolOut.Error != nil {
+		t.Fatalf("Navigation error: %v", toolOut.Error)
+	}
+	toolOut = tools2.CombinedTool().Run(ctx, []byte(`{"action": "eval", "expression": "document.cookie"}`))
+	if toolOut.Error != nil {
+		t.Fatalf("Eval error: %v", toolOut.Error)
+	}
+	if text := toolOut.LLMContent[0].Text; strings.Contains(text, "secret") {
+		t.Errorf("expected cookies to be isolated, second context saw %s", text)
+	}
+}
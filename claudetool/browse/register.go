@@ -7,15 +7,16 @@ import (
 )
 
 // RegisterBrowserTools returns browser tools (combined browser tool + read_image) ready to be added to an agent.
-// It also returns a cleanup function that should be called when done to properly close the browser.
+// It also returns the underlying BrowseTools; call its Close method when done to properly close the browser,
+// and CancelCurrentAction to abort an in-flight action.
 // The browser will be initialized lazily when a browser tool is first used.
 // maxImageDimension is the max pixel dimension for images (0 uses default of 2000).
-func RegisterBrowserTools(ctx context.Context, maxImageDimension int) ([]*llm.Tool, func()) {
-	browserTools := NewBrowseTools(ctx, 0, maxImageDimension)
-
-	return browserTools.GetTools(), func() {
-		browserTools.Close()
-	}
+// viewportWidth and viewportHeight set the initial viewport (0 uses the 1280x720 default).
+// If pool is non-nil, the browser is a context in the pool's shared browser instead of its own process.
+func RegisterBrowserTools(ctx context.Context, maxImageDimension, viewportWidth, viewportHeight int, pool *Pool) ([]*llm.Tool, *BrowseTools) {
+	browserTools := NewBrowseTools(ctx, 0, maxImageDimension, viewportWidth, viewportHeight)
+	browserTools.pool = pool
+	return browserTools.GetTools(), browserTools
 }
 
 // Tool is an alias for llm.Tool to make the documentation clearer
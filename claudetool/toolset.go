@@ -45,6 +45,22 @@ type ToolSetConfig struct {
 	EnableJITInstall bool
 	// EnableBrowser enables browser tools.
 	EnableBrowser bool
+	// BrowserPool, if set, bounds browser use by sharing one browser among conversations.
+	// If nil, each conversation launches its own browser.
+	BrowserPool *browse.Pool
+	// BrowserViewportWidth and BrowserViewportHeight set the browser's initial
+	// viewport. Zero uses browse.DefaultViewportWidth and DefaultViewportHeight.
+	BrowserViewportWidth  int
+	BrowserViewportHeight int
+	// MaxDirImages and MaxDirImageBytes bound how many images, and how many
+	// bytes of image files, read_image reads from a directory. Zero uses
+	// browse.DefaultMaxDirImages and browse.DefaultMaxDirImageBytes.
+	MaxDirImages     int
+	MaxDirImageBytes int64
+	// RedactPatterns are the regexps whose matches the browser tools mask in
+	// console and network logs. Nil uses browse.DefaultRedactPatterns; an
+	// empty, non-nil list disables redaction.
+	RedactPatterns []string
 	// ModelID is the model being used for this conversation.
 	ModelID string
 	// OnWorkingDirChange is called when the working directory changes.
@@ -87,7 +103,7 @@ type ToolSet struct {
 	mu             sync.RWMutex
 	tools          []*llm.Tool
 	deferredGroups map[string]MCPToolGroup // name -> group
-	cleanup        func()
+	browser        *browse.BrowseTools     // nil when browser tools are disabled
 	wd             *MutableWorkingDir
 }
 
@@ -129,8 +145,16 @@ func (ts *ToolSet) ActivateGroup(name string) []*llm.Tool {
 
 // Cleanup releases resources held by the tools (e.g., browser).
 func (ts *ToolSet) Cleanup() {
-	if ts.cleanup != nil {
-		ts.cleanup()
+	if ts.browser != nil {
+		ts.browser.Close()
+	}
+}
+
+// CancelActions aborts in-flight tool actions that don't observe the tool
+// context on their own (e.g., a stuck browser navigation).
+func (ts *ToolSet) CancelActions() {
+	if ts.browser != nil && ts.browser.CancelCurrentAction() {
+		slog.Info("cancelled in-flight browser action")
 	}
 }
 
@@ -161,6 +185,18 @@ type OrchestratorToolSetConfig struct {
 	OnWorkingDirChange func(newDir string)
 	// EnableBrowser enables browser tools (for read_image / screenshot viewing).
 	EnableBrowser bool
+	// BrowserPool, if set, is the shared browser pool (see ToolSetConfig.BrowserPool).
+	BrowserPool *browse.Pool
+	// BrowserViewportWidth and BrowserViewportHeight set the initial viewport
+	// (see ToolSetConfig.BrowserViewportWidth).
+	BrowserViewportWidth  int
+	BrowserViewportHeight int
+	// MaxDirImages and MaxDirImageBytes bound read_image on directories
+	// (see ToolSetConfig.MaxDirImages).
+	MaxDirImages     int
+	MaxDirImageBytes int64
+	// RedactPatterns mask secrets in browser logs (see ToolSetConfig.RedactPatterns).
+	RedactPatterns []string
 	// CLIAgent, if non-empty, uses a CLI subagent tool instead of native subagent.
 	// Valid values: "claude-cli", "codex-cli".
 	CLIAgent string
@@ -235,7 +271,7 @@ func NewOrchestratorToolSet(ctx context.Context, cfg OrchestratorToolSetConfig)
 	}
 
 	// Browser tools for read_image (screenshot viewing)
-	var cleanup func()
+	var browserTools *browse.BrowseTools
 	if cfg.EnableBrowser {
 		maxImageDimension := 0
 		if cfg.LLMProvider != nil && cfg.ModelID != "" {
@@ -243,19 +279,26 @@ func NewOrchestratorToolSet(ctx context.Context, cfg OrchestratorToolSetConfig)
 				maxImageDimension = svc.MaxImageDimension()
 			}
 		}
-		browserTools, browserCleanup := browse.RegisterBrowserTools(ctx, maxImageDimension)
+		var browserToolList []*llm.Tool
+		browserToolList, browserTools = browse.RegisterBrowserTools(ctx, maxImageDimension, cfg.BrowserViewportWidth, cfg.BrowserViewportHeight, cfg.BrowserPool)
+		browserTools.SetWorkingDir(wd.Get)
+		browserTools.SetDirImageLimits(cfg.MaxDirImages, cfg.MaxDirImageBytes)
+		if cfg.RedactPatterns != nil {
+			if err := browserTools.SetRedactPatterns(cfg.RedactPatterns); err != nil {
+				slog.Error("failed to set browser log redaction patterns", "error", err)
+			}
+		}
 		// Only include read_image from browser tools, not the full browser
-		for _, bt := range browserTools {
+		for _, bt := range browserToolList {
 			if bt.Name == "read_image" {
 				tools = append(tools, bt)
 			}
 		}
-		cleanup = browserCleanup
 	}
 
 	return &ToolSet{
 		tools:   tools,
-		cleanup: cleanup,
+		browser: browserTools,
 		wd:      wd,
 	}
 }
@@ -339,7 +382,7 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 		tools = append(tools, slackTool.Tool())
 	}
 
-	var cleanup func()
+	var browserTools *browse.BrowseTools
 	if cfg.EnableBrowser {
 		// Get max image dimension from the LLM service
 		maxImageDimension := 0
@@ -348,11 +391,18 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 				maxImageDimension = svc.MaxImageDimension()
 			}
 		}
-		browserTools, browserCleanup := browse.RegisterBrowserTools(ctx, maxImageDimension)
-		if len(browserTools) > 0 {
-			tools = append(tools, browserTools...)
+		var browserToolList []*llm.Tool
+		browserToolList, browserTools = browse.RegisterBrowserTools(ctx, maxImageDimension, cfg.BrowserViewportWidth, cfg.BrowserViewportHeight, cfg.BrowserPool)
+		browserTools.SetWorkingDir(wd.Get)
+		browserTools.SetDirImageLimits(cfg.MaxDirImages, cfg.MaxDirImageBytes)
+		if cfg.RedactPatterns != nil {
+			if err := browserTools.SetRedactPatterns(cfg.RedactPatterns); err != nil {
+				slog.Error("failed to set browser log redaction patterns", "error", err)
+			}
+		}
+		if len(browserToolList) > 0 {
+			tools = append(tools, browserToolList...)
 		}
-		cleanup = browserCleanup
 	}
 
 	// Append any MCP tools.
@@ -362,7 +412,7 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 
 	ts := &ToolSet{
 		tools:   tools,
-		cleanup: cleanup,
+		browser: browserTools,
 		wd:      wd,
 	}
 
@@ -1,9 +1,17 @@
 package claudetool
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"image"
+	"image/png"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"shelley.exe.dev/llm"
 )
 
 func TestNewToolSet(t *testing.T) {
@@ -337,3 +345,47 @@ func TestToolDescriptions(t *testing.T) {
 		}
 	}
 }
+
+func TestToolSet_DirImageLimits(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for _, name := range []string{"a.png", "b.png"} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := NewToolSet(context.Background(), ToolSetConfig{
+		LLMProvider:   &mockLLMProvider{},
+		ModelID:       "test-model",
+		WorkingDir:    dir,
+		EnableBrowser: true,
+		MaxDirImages:  1,
+	})
+	defer ts.Cleanup()
+
+	var readImage *llm.Tool
+	for _, tool := range ts.Tools() {
+		if tool.Name == "read_image" {
+			readImage = tool
+		}
+	}
+	if readImage == nil {
+		t.Fatal("expected a read_image tool")
+	}
+	out := readImage.Run(context.Background(), []byte(fmt.Sprintf(`{"path": %q}`, dir)))
+	if out.Error != nil {
+		t.Fatalf("read_image failed: %v", out.Error)
+	}
+	var text strings.Builder
+	for _, c := range out.LLMContent {
+		text.WriteString(c.Text)
+	}
+	if !strings.Contains(text.String(), "limit of 1 images") {
+		t.Errorf("expected read_image to stop at the configured limit, got %q", text.String())
+	}
+}
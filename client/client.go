@@ -6,14 +6,18 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // DefaultSocketPath returns the default Unix socket path (~/.config/shelley/shelley.sock).
@@ -57,8 +61,10 @@ func (f *multiFlag) Set(value string) error {
 }
 
 type clientConfig struct {
-	serverURL string
-	headers   map[string]string
+	serverURL  string
+	headers    map[string]string
+	retries    int           // extra attempts after a connection failure
+	retryDelay time.Duration // wait before the first retry; doubles after each
 }
 
 func (cc *clientConfig) newHTTPClient() (*http.Client, string, error) {
@@ -67,19 +73,87 @@ func (cc *clientConfig) newHTTPClient() (*http.Client, string, error) {
 		return nil, "", err
 	}
 
+	var transport http.RoundTripper
 	switch scheme {
 	case "unix":
-		transport := &http.Transport{
+		transport = &http.Transport{
 			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
 				return net.Dial("unix", address)
 			},
 		}
-		return &http.Client{Transport: transport}, "http://localhost", nil
+		address = "http://localhost"
 	case "http", "https":
-		return &http.Client{}, address, nil
+		transport = http.DefaultTransport
 	default:
 		return nil, "", fmt.Errorf("unsupported scheme: %s", scheme)
 	}
+	if cc.retries > 0 {
+		transport = &retryTransport{base: transport, retries: cc.retries, delay: cc.retryDelay}
+	}
+	return &http.Client{Transport: transport}, address, nil
+}
+
+// retryTransport retries requests that never got a response, such as while
+// the server is restarting or its socket doesn't exist yet. HTTP error
+// statuses are responses, so they are returned without retrying. Requests
+// that were sent before the connection dropped are only retried if they are
+// safe to repeat: the server may already have acted on a POST.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	delay   time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay := t.delay
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil || attempt >= t.retries || !isRetryable(req, err) {
+			return resp, err
+		}
+		// The failed attempt may have consumed the body
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		delay *= 2
+	}
+}
+
+// isRetryable reports whether req can be sent again after failing with err:
+// always if the server couldn't be reached, so nothing was sent, and for
+// requests that are safe to repeat if the connection dropped instead.
+func isRetryable(req *http.Request, err error) bool {
+	if isDialError(err) {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	return false
+}
+
+// isDialError reports whether err means the server couldn't be reached, so
+// the request was never sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ENOENT) // unix socket not created yet
 }
 
 func (cc *clientConfig) newRequest(method, url string, body *strings.Reader) (*http.Request, error) {
@@ -108,18 +182,25 @@ func Run(args []string) {
 	urlFlag := fs.String("url", defaultClientURL(), "Server URL (unix:///path, http://host:port, https://host:port)")
 	var headerFlags multiFlag
 	fs.Var(&headerFlags, "H", `Extra HTTP header ("Name: Value", can be repeated)`)
+	retries := fs.Int("retry", 0, "Times to retry a request the server couldn't be reached for")
+	retryDelay := fs.Duration("retry-delay", 500*time.Millisecond, "Wait before the first retry (doubles after each)")
 	fs.Usage = func() {
 		fmt.Fprintf(fs.Output(), "EXPERIMENTAL: Shelley CLI client\n\n")
 		fmt.Fprintf(fs.Output(), "Usage: shelley client [flags] <subcommand> [args...]\n\n")
 		fmt.Fprintf(fs.Output(), "Flags:\n")
 		fs.PrintDefaults()
 		fmt.Fprintf(fs.Output(), "\nSubcommands:\n")
-		fmt.Fprintf(fs.Output(), "  chat     Send a message (new or existing conversation)\n")
-		fmt.Fprintf(fs.Output(), "  read     Read conversation messages\n")
-		fmt.Fprintf(fs.Output(), "  list     List conversations\n")
-		fmt.Fprintf(fs.Output(), "  search   Search conversations by content\n")
-		fmt.Fprintf(fs.Output(), "  archive  Archive a conversation\n")
-		fmt.Fprintf(fs.Output(), "  help     Print detailed help\n")
+		fmt.Fprintf(fs.Output(), "  chat       Send a message (new or existing conversation)\n")
+		fmt.Fprintf(fs.Output(), "  read       Read conversation messages\n")
+		fmt.Fprintf(fs.Output(), "  list       List conversations\n")
+		fmt.Fprintf(fs.Output(), "  search     Search conversations by content\n")
+		fmt.Fprintf(fs.Output(), "  models     List the models the server offers\n")
+		fmt.Fprintf(fs.Output(), "  archive    Archive a conversation\n")
+		fmt.Fprintf(fs.Output(), "  unarchive  Restore an archived conversation\n")
+		fmt.Fprintf(fs.Output(), "  rename     Change a conversation's slug\n")
+		fmt.Fprintf(fs.Output(), "  delete     Permanently delete a conversation\n")
+		fmt.Fprintf(fs.Output(), "  cancel     Stop a conversation's running agent turn\n")
+		fmt.Fprintf(fs.Output(), "  help       Print detailed help\n")
 	}
 	fs.Parse(args)
 
@@ -133,7 +214,7 @@ func Run(args []string) {
 		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
 	}
 
-	cc := &clientConfig{serverURL: *urlFlag, headers: headers}
+	cc := &clientConfig{serverURL: *urlFlag, headers: headers, retries: *retries, retryDelay: *retryDelay}
 
 	subArgs := fs.Args()
 	if len(subArgs) == 0 {
@@ -150,8 +231,18 @@ func Run(args []string) {
 		cmdList(cc, subArgs[1:])
 	case "search":
 		cmdSearch(cc, subArgs[1:])
+	case "models":
+		cmdModels(cc, subArgs[1:])
 	case "archive":
 		cmdArchive(cc, subArgs[1:])
+	case "unarchive":
+		cmdUnarchive(cc, subArgs[1:])
+	case "rename":
+		cmdRename(cc, subArgs[1:])
+	case "delete":
+		cmdDelete(cc, subArgs[1:])
+	case "cancel":
+		cmdCancel(cc, subArgs[1:])
 	case "help":
 		cmdHelp()
 	default:
@@ -267,13 +358,19 @@ type streamEvent struct {
 func cmdRead(cc *clientConfig, args []string) {
 	fs := flag.NewFlagSet("client read", flag.ExitOnError)
 	wait := fs.Bool("wait", false, "Wait for agent turn to finish (stream new messages)")
+	format := fs.String("format", "json", "Output format: json (JSON lines) or text (human-readable)")
 	fs.Parse(args)
 
 	if fs.NArg() == 0 {
-		fmt.Fprintf(os.Stderr, "Usage: shelley client read [-wait] CONVERSATION_ID\n")
+		fmt.Fprintf(os.Stderr, "Usage: shelley client read [-wait] [-format json|text] CONVERSATION_ID\n")
 		os.Exit(1)
 	}
 	conversationID := fs.Arg(0)
+	if *format != "json" && *format != "text" {
+		fmt.Fprintf(os.Stderr, "Error: unknown -format %q (use json or text)\n", *format)
+		os.Exit(1)
+	}
+	printer := &eventPrinter{w: os.Stdout, text: *format == "text", color: isTerminal(os.Stdout)}
 
 	client, baseURL, err := cc.newHTTPClient()
 	if err != nil {
@@ -282,13 +379,13 @@ func cmdRead(cc *clientConfig, args []string) {
 	}
 
 	if *wait {
-		readStream(cc, client, baseURL, conversationID)
+		readStream(cc, client, baseURL, conversationID, printer)
 	} else {
-		readSnapshot(cc, client, baseURL, conversationID)
+		readSnapshot(cc, client, baseURL, conversationID, printer)
 	}
 }
 
-func readSnapshot(cc *clientConfig, client *http.Client, baseURL, conversationID string) {
+func readSnapshot(cc *clientConfig, client *http.Client, baseURL, conversationID string, printer *eventPrinter) {
 	req, err := cc.newRequest("GET", baseURL+"/api/conversation/"+conversationID, nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
@@ -314,11 +411,11 @@ func readSnapshot(cc *clientConfig, client *http.Client, baseURL, conversationID
 	}
 
 	for _, msg := range sr.Messages {
-		json.NewEncoder(os.Stdout).Encode(simplifyMessage(msg))
+		printer.print(simplifyMessage(msg))
 	}
 }
 
-func readStream(cc *clientConfig, client *http.Client, baseURL, conversationID string) {
+func readStream(cc *clientConfig, client *http.Client, baseURL, conversationID string, printer *eventPrinter) {
 	req, err := cc.newRequest("GET", baseURL+"/api/conversation/"+conversationID+"/stream", nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
@@ -365,7 +462,7 @@ func readStream(cc *clientConfig, client *http.Client, baseURL, conversationID s
 			seenSeqIDs[msg.SequenceID] = true
 
 			event := simplifyMessage(msg)
-			json.NewEncoder(os.Stdout).Encode(event)
+			printer.print(event)
 
 			if (msg.Type == "agent" || msg.Type == "error") && event.EndOfTurn {
 				return
@@ -379,6 +476,83 @@ func readStream(cc *clientConfig, client *http.Client, baseURL, conversationID s
 	}
 }
 
+// textWrapWidth is the column text-format message bodies are wrapped at.
+const textWrapWidth = 80
+
+// ANSI escapes for text-format headers, keyed by message type.
+var eventColors = map[string]string{
+	"user":  "\x1b[32m", // green
+	"agent": "\x1b[36m", // cyan
+	"tool":  "\x1b[33m", // yellow
+	"error": "\x1b[31m", // red
+}
+
+const colorReset = "\x1b[0m"
+
+// eventPrinter writes the events read prints, as JSON lines or, in text
+// mode, as a header line per message followed by its indented, wrapped text.
+type eventPrinter struct {
+	w     io.Writer
+	text  bool
+	color bool // color text-mode headers
+}
+
+func (p *eventPrinter) print(event streamEvent) {
+	if !p.text {
+		json.NewEncoder(p.w).Encode(event)
+		return
+	}
+
+	header := fmt.Sprintf("#%d %s", event.SequenceID, event.Type)
+	if event.ToolName != "" {
+		header += " [" + event.ToolName + "]"
+	}
+	if event.EndOfTurn {
+		header += " (end of turn)"
+	}
+	if c, ok := eventColors[event.Type]; ok && p.color {
+		header = c + header + colorReset
+	}
+	fmt.Fprintln(p.w, header)
+	for _, line := range wrapText(event.Text, textWrapWidth-2) {
+		fmt.Fprintln(p.w, "  "+line)
+	}
+}
+
+// wrapText splits text into lines of at most width columns, breaking at
+// spaces and keeping existing line breaks. Words longer than width get a
+// line of their own.
+func wrapText(text string, width int) []string {
+	if text == "" {
+		return nil
+	}
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
 func cmdList(cc *clientConfig, args []string) {
 	fs := flag.NewFlagSet("client list", flag.ExitOnError)
 	archived := fs.Bool("archived", false, "List archived conversations instead")
@@ -441,6 +615,51 @@ func cmdList(cc *clientConfig, args []string) {
 	}
 }
 
+func cmdModels(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client models", flag.ExitOnError)
+	fs.Parse(args)
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	req, err := cc.newRequest("GET", baseURL+"/api/models", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: HTTP %d\n", resp.StatusCode)
+		os.Exit(1)
+	}
+
+	var models []struct {
+		ID               string `json:"id"`
+		DisplayName      string `json:"display_name,omitempty"`
+		Ready            bool   `json:"ready"`
+		MaxContextTokens int    `json:"max_context_tokens,omitempty"`
+		Default          bool   `json:"default"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, m := range models {
+		json.NewEncoder(os.Stdout).Encode(m)
+	}
+}
+
 func cmdSearch(cc *clientConfig, args []string) {
 	fs := flag.NewFlagSet("client search", flag.ExitOnError)
 	limit := fs.Int("limit", 20, "Maximum number of results")
@@ -514,31 +733,158 @@ func cmdArchive(cc *clientConfig, args []string) {
 	}
 	conversationID := fs.Arg(0)
 
+	resp := postConversation(cc, conversationID, "archive", nil)
+	resp.Body.Close()
+
+	fmt.Fprintf(os.Stderr, "Archived %s\n", conversationID)
+}
+
+func cmdUnarchive(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client unarchive", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: shelley client unarchive CONVERSATION_ID\n")
+		os.Exit(1)
+	}
+	conversationID := fs.Arg(0)
+
+	resp := postConversation(cc, conversationID, "unarchive", nil)
+	resp.Body.Close()
+
+	fmt.Fprintf(os.Stderr, "Unarchived %s\n", conversationID)
+}
+
+func cmdDelete(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client delete", flag.ExitOnError)
+	force := fs.Bool("force", false, "Confirm the deletion (required)")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: shelley client delete -force CONVERSATION_ID\n")
+		os.Exit(1)
+	}
+	conversationID := fs.Arg(0)
+
+	// Deleting can't be undone, unlike archiving
+	if !*force {
+		fmt.Fprintf(os.Stderr, "Error: deleting %s permanently removes it and its messages; pass -force to confirm (or use archive)\n", conversationID)
+		os.Exit(1)
+	}
+
+	resp := postConversation(cc, conversationID, "delete", nil)
+	resp.Body.Close()
+
+	fmt.Fprintf(os.Stderr, "Deleted %s\n", conversationID)
+}
+
+func cmdRename(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client rename", flag.ExitOnError)
+	newSlug := fs.String("slug", "", "New slug (required)")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || *newSlug == "" {
+		fmt.Fprintf(os.Stderr, "Usage: shelley client rename -slug SLUG CONVERSATION_ID\n")
+		os.Exit(1)
+	}
+	conversationID := fs.Arg(0)
+
+	resp := postConversation(cc, conversationID, "rename", map[string]string{"slug": *newSlug})
+	defer resp.Body.Close()
+
+	// The server sanitizes the slug, so report the one it stored
+	var conv struct {
+		Slug *string `json:"slug"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&conv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	output := map[string]any{"conversation_id": conversationID}
+	if conv.Slug != nil {
+		output["slug"] = *conv.Slug
+	}
+	json.NewEncoder(os.Stdout).Encode(output)
+}
+
+func cmdCancel(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client cancel", flag.ExitOnError)
+	reason := fs.String("reason", "", "Why the turn is being cancelled (shown to the agent)")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: shelley client cancel [-reason REASON] CONVERSATION_ID\n")
+		os.Exit(1)
+	}
+	conversationID := fs.Arg(0)
+
+	resp := postConversation(cc, conversationID, "cancel", map[string]string{"reason": *reason})
+	defer resp.Body.Close()
+
+	var respBody struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Nothing running isn't an error: the turn may have just finished.
+	if respBody.Status == "no_active_conversation" {
+		fmt.Fprintf(os.Stderr, "No agent turn running in %s\n", conversationID)
+	}
+	json.NewEncoder(os.Stdout).Encode(map[string]string{
+		"conversation_id": conversationID,
+		"status":          respBody.Status,
+	})
+}
+
+// postConversation POSTs body, if not nil, as JSON to
+// /api/conversation/<id>/<action>. It exits on any error or a non-200
+// status; the caller closes the response body.
+func postConversation(cc *clientConfig, conversationID, action string, body any) *http.Response {
 	client, baseURL, err := cc.newHTTPClient()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	req, err := cc.newRequest("POST", baseURL+"/api/conversation/"+conversationID+"/archive", nil)
+	var reqBody *strings.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		reqBody = strings.NewReader(string(bodyBytes))
+	}
+
+	req, err := cc.newRequest("POST", baseURL+"/api/conversation/"+conversationID+"/"+action, reqBody)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
 		os.Exit(1)
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "Error: HTTP %d\n", resp.StatusCode)
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		if text := strings.TrimSpace(string(msg)); text != "" {
+			fmt.Fprintf(os.Stderr, "Error (HTTP %d): %s\n", resp.StatusCode, text)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: HTTP %d\n", resp.StatusCode)
+		}
 		os.Exit(1)
 	}
-
-	fmt.Fprintf(os.Stderr, "Archived %s\n", conversationID)
+	return resp
 }
 
 // --- Wire types for JSON parsing ---
@@ -622,15 +968,21 @@ Usage:
 Flags:
   -url URL     Server URL (default: unix://%s)
   -H HEADER    Extra HTTP header "Name: Value" (can be repeated)
+  -retry N     Retry requests up to N times if the server can't be reached
+               (e.g. while it restarts); HTTP errors aren't retried
+  -retry-delay DURATION
+               Wait before the first retry, doubling after each (default 500ms)
 
 Subcommands:
   chat -p PROMPT [-c CONVERSATION_ID] [-model MODEL] [-cwd DIR]
       Send a message. Creates a new conversation unless -c is given.
       Prints JSON with conversation_id to stdout.
 
-  read [-wait] CONVERSATION_ID
+  read [-wait] [-format json|text] CONVERSATION_ID
       Read all messages in a conversation as JSON lines.
       With -wait, streams via SSE until the agent turn ends.
+      With -format text, prints each message as a header line
+      (sequence ID, type, tool name) followed by its wrapped text.
 
   list [-archived] [-limit N] [-q QUERY]
       List conversations as JSON lines.
@@ -639,9 +991,29 @@ Subcommands:
       Search conversations by slug and message content.
       Prints matching conversations as JSON lines.
 
+  models
+      List the models the server offers as JSON lines, for chat -model.
+      The one new conversations use by default has "default": true.
+
   archive CONVERSATION_ID
       Archive a conversation.
 
+  unarchive CONVERSATION_ID
+      Restore an archived conversation.
+
+  rename -slug SLUG CONVERSATION_ID
+      Change a conversation's slug. The server sanitizes it; prints
+      JSON with the slug it stored.
+
+  delete -force CONVERSATION_ID
+      Permanently delete a conversation and its messages.
+      -force is required, since this can't be undone.
+
+  cancel [-reason REASON] CONVERSATION_ID
+      Stop the agent turn running in a conversation.
+      Prints JSON with the status: "cancelled", or
+      "no_active_conversation" if nothing was running.
+
   help
       Print this help text.
 
@@ -649,6 +1021,9 @@ Connecting over HTTP with auth headers:
   shelley client -url http://localhost:9999 -H "X-Exedev-Userid: user" list
 
 Examples:
+  # Wait up to ~15s for a just-started server
+  shelley client -retry 5 -retry-delay 500ms list
+
   # Start a conversation and wait for the agent
   ID=$(shelley client chat -p "list files" | jq -r .conversation_id)
   shelley client read -wait "$ID"
@@ -659,6 +1034,12 @@ Examples:
   # Read current state
   shelley client read "$ID"
 
+  # Follow the agent in a terminal
+  shelley client read -wait -format text "$ID"
+
+  # Stop the agent mid-turn
+  shelley client cancel -reason "wrong directory" "$ID"
+
 NOTE: This feature is EXPERIMENTAL and may change without notice.
 `, DefaultSocketPath())
 }
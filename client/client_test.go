@@ -0,0 +1,182 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a server that drops the connection on the first
+// `failures` requests and otherwise answers with status.
+func newFlakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("hijack: %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryTransientFailure(t *testing.T) {
+	t.Parallel()
+	srv, calls := newFlakyServer(t, 1, http.StatusOK)
+
+	cc := &clientConfig{serverURL: srv.URL, retries: 2, retryDelay: time.Millisecond}
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := cc.newRequest("GET", baseURL+"/api/models", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
+
+func TestRetrySkipsDroppedPost(t *testing.T) {
+	t.Parallel()
+	srv, calls := newFlakyServer(t, 1, http.StatusOK)
+
+	cc := &clientConfig{serverURL: srv.URL, retries: 2, retryDelay: time.Millisecond}
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server may have acted on the message before the connection dropped
+	req, err := cc.newRequest("POST", baseURL+"/api/conversations/new", strings.NewReader(`{"message":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected a dropped POST not to be retried")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+}
+
+func TestRetryDisabled(t *testing.T) {
+	t.Parallel()
+	srv, calls := newFlakyServer(t, 1, http.StatusOK)
+
+	cc := &clientConfig{serverURL: srv.URL}
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := cc.newRequest("POST", baseURL+"/api/conversations/new", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected an error without -retry")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+}
+
+func TestRetrySkipsHTTPErrors(t *testing.T) {
+	t.Parallel()
+	srv, calls := newFlakyServer(t, 0, http.StatusNotFound)
+
+	cc := &clientConfig{serverURL: srv.URL, retries: 3, retryDelay: time.Millisecond}
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := cc.newRequest("GET", baseURL+"/api/conversation/missing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 4xx not to be retried, got %d calls", got)
+	}
+}
+
+func TestRetryMissingSocket(t *testing.T) {
+	t.Parallel()
+	cc := &clientConfig{serverURL: "unix://" + t.TempDir() + "/missing.sock", retries: 2, retryDelay: time.Millisecond}
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A POST: nothing was sent, so even it is safe to retry
+	req, err := cc.newRequest("POST", baseURL+"/api/conversations/new", strings.NewReader(`{"message":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Do(req)
+	if err == nil {
+		t.Fatal("expected an error for a missing socket")
+	}
+	if !isRetryable(req, err) {
+		t.Errorf("expected a missing socket to be retryable, got %v", err)
+	}
+}
+
+func TestEventPrinterText(t *testing.T) {
+	t.Parallel()
+	var buf strings.Builder
+	p := &eventPrinter{w: &buf, text: true}
+	p.print(streamEvent{SequenceID: 1, Type: "user", Text: "list the files"})
+	p.print(streamEvent{SequenceID: 2, Type: "agent", ToolName: "bash", Text: "Listing them.\nDone"})
+	p.print(streamEvent{SequenceID: 3, Type: "agent", Text: strings.Repeat("word ", 20), EndOfTurn: true})
+
+	want := "#1 user\n" +
+		"  list the files\n" +
+		"#2 agent [bash]\n" +
+		"  Listing them.\n" +
+		"  Done\n" +
+		"#3 agent (end of turn)\n" +
+		"  " + strings.Repeat("word ", 14) + "word\n" +
+		"  " + strings.Repeat("word ", 4) + "word\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected text output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEventPrinterJSON(t *testing.T) {
+	t.Parallel()
+	var buf strings.Builder
+	p := &eventPrinter{w: &buf}
+	p.print(streamEvent{SequenceID: 1, Type: "user", Text: "hi"})
+
+	want := `{"sequence_id":1,"type":"user","text":"hi","end_of_turn":false}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
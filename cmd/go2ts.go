@@ -80,22 +80,43 @@ func TS() *go2ts.Go2TS {
 }
 
 type apiMessageForTS struct {
-	MessageID      string    `json:"message_id"`
-	ConversationID string    `json:"conversation_id"`
-	SequenceID     int64     `json:"sequence_id"`
-	Type           string    `json:"type"`
-	LlmData        *string   `json:"llm_data,omitempty"`
-	UserData       *string   `json:"user_data,omitempty"`
-	UsageData      *string   `json:"usage_data,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
-	DisplayData    *string   `json:"display_data,omitempty"`
-	EndOfTurn      *bool     `json:"end_of_turn,omitempty"`
+	MessageID      string              `json:"message_id"`
+	ConversationID string              `json:"conversation_id"`
+	SequenceID     int64               `json:"sequence_id"`
+	Type           string              `json:"type"`
+	LlmData        *string             `json:"llm_data,omitempty"`
+	UserData       *string             `json:"user_data,omitempty"`
+	UsageData      *string             `json:"usage_data,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	DisplayData    *string             `json:"display_data,omitempty"`
+	EndOfTurn      *bool               `json:"end_of_turn,omitempty"`
+	Timing         *messageTimingForTS `json:"timing,omitempty"`
+	ToolCalls      []apiToolCallForTS  `json:"tool_calls,omitempty"`
+}
+
+type apiToolCallForTS struct {
+	ToolUseID        string  `json:"tool_use_id"`
+	ToolName         string  `json:"tool_name,omitempty"`
+	ToolUseMessageID string  `json:"tool_use_message_id,omitempty"`
+	ToolUseIndex     int     `json:"tool_use_index"`
+	ResultIndex      int     `json:"result_index"`
+	Error            bool    `json:"error,omitempty"`
+	StartTime        *string `json:"start_time,omitempty"`
+	EndTime          *string `json:"end_time,omitempty"`
+}
+
+type messageTimingForTS struct {
+	SincePreviousMs *int64           `json:"since_previous_ms,omitempty"`
+	LLMMs           *int64           `json:"llm_ms,omitempty"`
+	ToolMs          *int64           `json:"tool_ms,omitempty"`
+	Tools           map[string]int64 `json:"tools,omitempty"`
 }
 
 type conversationStateForTS struct {
 	ConversationID string `json:"conversation_id"`
 	Working        bool   `json:"working"`
 	Model          string `json:"model,omitempty"`
+	WorkingSince   string `json:"working_since,omitempty"`
 }
 
 type conversationWithStateForTS struct {
@@ -110,12 +131,14 @@ type conversationWithStateForTS struct {
 	Model                *string `json:"model"`
 	ConversationOptions  string  `json:"conversation_options"`
 	Working              bool    `json:"working"`
+	WorkingSince         string  `json:"working_since,omitempty"`
 	GitRepoRoot          string  `json:"git_repo_root,omitempty"`
 	GitWorktreeRoot      string  `json:"git_worktree_root,omitempty"`
 	GitCommit            string  `json:"git_commit,omitempty"`
 	GitSubject           string          `json:"git_subject,omitempty"`
 	SubagentCount        int64           `json:"subagent_count"`
 	PRInfo               *gitstate.PRInfo `json:"pr_info,omitempty"`
+	Tags                 []string         `json:"tags,omitempty"`
 }
 
 type streamResponseForTS struct {
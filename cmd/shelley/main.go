@@ -9,10 +9,13 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/claudetool/browse"
 	"shelley.exe.dev/client"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/mcp"
@@ -21,6 +24,7 @@ import (
 	_ "shelley.exe.dev/server/notifications/channels" // register channel types
 	"shelley.exe.dev/skills"
 	shellslack "shelley.exe.dev/slack"
+	"shelley.exe.dev/slug"
 	"shelley.exe.dev/templates"
 	"shelley.exe.dev/version"
 	"shelley.exe.dev/ui"
@@ -155,19 +159,77 @@ func runSkill(args []string) {
 	}
 }
 
+// stringsFlag is a flag that may be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// viewportFlag is a browser viewport size given as WIDTHxHEIGHT.
+type viewportFlag struct{ width, height int }
+
+func (f *viewportFlag) String() string { return fmt.Sprintf("%dx%d", f.width, f.height) }
+
+func (f *viewportFlag) Set(v string) error {
+	w, h, ok := strings.Cut(v, "x")
+	width, werr := strconv.Atoi(w)
+	height, herr := strconv.Atoi(h)
+	if !ok || werr != nil || herr != nil || width <= 0 || height <= 0 {
+		return fmt.Errorf("want WIDTHxHEIGHT, e.g. 1280x720")
+	}
+	f.width, f.height = width, height
+	return nil
+}
+
 func runServe(global GlobalConfig, args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	port := fs.String("port", "9000", "Port to listen on")
 	portFile := fs.String("port-file", "", "Write the actual listening port to this file (useful with --port 0)")
 	systemdActivation := fs.Bool("systemd-activation", false, "Use systemd socket activation (listen on fd from systemd)")
 	requireHeader := fs.String("require-header", "", "Require this header on all API requests (e.g., X-Exedev-Userid)")
+	basePath := fs.String("base-path", "", "Serve the UI and API under this URL prefix (e.g., /shelley) when behind a reverse proxy")
 	socketPath := fs.String("socket", client.DefaultSocketPath(), "Path to Unix socket for local CLI client access (set to 'none' to disable)")
+	maxBrowserContexts := fs.Int("max-browser-contexts", 0, "Share one browser among conversations, with at most this many concurrent contexts (0 = one browser per conversation)")
+	maxStreamSubscribers := fs.Int("max-stream-subscribers", server.DefaultMaxStreamSubscribers, "Reject streams of a conversation that already has this many clients with 503 (0 = no limit)")
+	maxStreamDuration := fs.Duration("max-stream-duration", 30*time.Minute, "Close conversation streams after this long, asking clients to reconnect (0 = no limit)")
+	readExtensions := fs.String("read-extensions", strings.Join(server.DefaultReadExtensions, ","), "Comma-separated file extensions that /api/read serves from the screenshot and browser output directories; saved CPU profiles are always served")
+	thumbnailCacheDir := fs.String("thumbnail-cache-dir", filepath.Join(os.TempDir(), "shelley-thumbnails"), "Directory to keep screenshot thumbnails generated for /api/read?thumb=N in (empty to regenerate them each time)")
+	uploadTypes := fs.String("upload-types", strings.Join(server.DefaultUploadTypes, ","), "Comma-separated media types that /api/upload accepts, detected from the file content (e.g., add application/pdf)")
+	heuristicSlugs := fs.Bool("heuristic-slugs", true, "Derive a conversation's slug from its first message when no model can generate one")
+	regenerateSlugOnEdit := fs.Bool("regenerate-slug-on-edit", true, "Regenerate a conversation's slug when its first message is edited, unless the user renamed it")
+	conversationIdleTimeout := fs.Duration("conversation-idle-timeout", server.DefaultConversationIdleTimeout, "Unload conversations with no viewers after they have been idle this long")
+	maxConcurrentHydrations := fs.Int("max-concurrent-hydrations", server.DefaultMaxConcurrentHydrations, "Load at most this many conversations from the database at once, e.g. when clients reconnect after a restart")
+	metrics := fs.Bool("metrics", false, "Serve Prometheus metrics at /metrics")
+	staticIndex := fs.Bool("static-index", false, "Serve index.html without injected init data, for CDN or static hosting; the UI fetches it from /api/config")
+	var authTokens stringsFlag
+	fs.Var(&authTokens, "auth-token", "Require API, debug and control requests over TCP to send this token as \"Authorization: Bearer <token>\" (repeatable)")
+	corsOrigin := fs.String("cors-origin", "", "Comma-separated origins (e.g., https://app.example.com) whose browser clients may call the API with credentials")
+	rateLimit := fs.Float64("rate-limit", 0, "Allow each client this many API write requests per second on average, e.g. new conversations and messages; excess requests get 429 (0 = no limit)")
+	rateLimitBurst := fs.Int("rate-limit-burst", 10, "Allow each client bursts of this many API write requests (with --rate-limit)")
+	readRateLimit := fs.Float64("read-rate-limit", 0, "Allow each client this many API read requests (GET, including streams) per second on average (0 = no limit)")
+	readRateLimitBurst := fs.Int("read-rate-limit-burst", 50, "Allow each client bursts of this many API read requests (with --read-rate-limit)")
+	isolateBrowserContexts := fs.Bool("isolate-browser-contexts", false, "Give each conversation in the shared browser its own incognito context (requires --max-browser-contexts)")
+	maxDirImages := fs.Int("max-dir-images", browse.DefaultMaxDirImages, "Read at most this many images when read_image is given a directory")
+	maxDirImageBytes := fs.Int64("max-dir-image-bytes", browse.DefaultMaxDirImageBytes, "Read at most this many bytes of image files when read_image is given a directory")
+	browserViewport := viewportFlag{browse.DefaultViewportWidth, browse.DefaultViewportHeight}
+	fs.Var(&browserViewport, "browser-viewport", "Initial browser viewport size as WIDTHxHEIGHT")
+	var redactPatterns stringsFlag
+	fs.Var(&redactPatterns, "redact-pattern", "Mask text matching this regexp in browser console and network logs instead of the default secret patterns (repeatable)")
+	noRedact := fs.Bool("no-redact", false, "Don't mask secrets in browser console and network logs")
 	fs.Parse(args)
 
 	// Only `serve` actually uses the embedded UI, so the staleness check lives
 	// here (not in ui.init()) so scheduled `client` invocations don't break
 	// when a developer edits ui/src without rebuilding.
 	ui.EnforceFreshBuild()
+	if err := ui.VerifyChecksums(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\nRebuild the UI: cd ui && pnpm run build\n", err)
+		os.Exit(1)
+	}
 
 	logger := setupLogging(global.Debug)
 
@@ -188,6 +250,25 @@ func runServe(global GlobalConfig, args []string) {
 	logger.Info("Available models", "models", strings.Join(availableModels, ", "))
 
 	toolSetConfig := setupToolSetConfig(llmManager, llmManager)
+	if *maxBrowserContexts > 0 {
+		toolSetConfig.BrowserPool = browse.NewPool(context.Background(), *maxBrowserContexts, *isolateBrowserContexts)
+	}
+	toolSetConfig.BrowserViewportWidth = browserViewport.width
+	toolSetConfig.BrowserViewportHeight = browserViewport.height
+	toolSetConfig.MaxDirImages = *maxDirImages
+	toolSetConfig.MaxDirImageBytes = *maxDirImageBytes
+	switch {
+	case *noRedact:
+		toolSetConfig.RedactPatterns = []string{}
+	case len(redactPatterns) > 0:
+		for _, p := range redactPatterns {
+			if _, err := regexp.Compile(p); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --redact-pattern %q: %v\n", p, err)
+				os.Exit(1)
+			}
+		}
+		toolSetConfig.RedactPatterns = redactPatterns
+	}
 
 	// Start MCP servers and discover their tools.
 	var mcpManager *claudetool.MCPManager
@@ -207,6 +288,27 @@ func runServe(global GlobalConfig, args []string) {
 	// Create server
 	svr := server.NewServer(database, llmManager, toolSetConfig, logger, global.PredictableOnly, llmConfig.TerminalURL, llmConfig.DefaultModel, *requireHeader, llmConfig.Links)
 	svr.SetAlwaysOnSkills(llmConfig.AlwaysOnSkills)
+	svr.SetSlugPrompt(llmConfig.SlugPrompt)
+	svr.SetMaxRepeatedToolErrors(llmConfig.MaxRepeatedToolErrors)
+	svr.SetConversationTemplate(llmConfig.NewConversation)
+	svr.SetMaxStreamDuration(*maxStreamDuration)
+	svr.SetMaxStreamSubscribers(*maxStreamSubscribers)
+	svr.SetConversationIdleTimeout(*conversationIdleTimeout)
+	svr.SetMaxConcurrentHydrations(*maxConcurrentHydrations)
+	svr.SetMetricsEnabled(*metrics)
+	svr.SetBasePath(*basePath)
+	svr.SetCORSOrigins(strings.Split(*corsOrigin, ","))
+	svr.SetAuthTokens(authTokens)
+	svr.SetRateLimits(
+		server.RateLimit{Rate: *rateLimit, Burst: *rateLimitBurst},
+		server.RateLimit{Rate: *readRateLimit, Burst: *readRateLimitBurst},
+	)
+	svr.SetStaticIndex(*staticIndex)
+	svr.SetReadExtensions(strings.Split(*readExtensions, ","))
+	svr.SetUploadTypes(strings.Split(*uploadTypes, ","))
+	svr.SetThumbnailCacheDir(*thumbnailCacheDir)
+	svr.SetRegenerateSlugOnEdit(*regenerateSlugOnEdit)
+	svr.SetHeuristicSlugs(*heuristicSlugs)
 
 	// Seed notification channels from config file if DB is empty (one-time migration)
 	svr.SeedNotificationChannelsFromConfig(llmConfig.NotificationChannels)
@@ -448,15 +550,19 @@ func buildLLMConfig(logger *slog.Logger, configPath, terminalURL, defaultModel s
 		}
 
 		var cfg struct {
-			LLMGateway           string                `json:"llm_gateway"`
-			TerminalURL          string                `json:"terminal_url"`
-			DefaultModel         string                `json:"default_model"`
-			Links                []server.Link         `json:"links"`
-			NotificationChannels []map[string]any      `json:"notification_channels"`
-			SlackBotToken        string                `json:"slack_bot_token"`
-			SlackAppToken        string                `json:"slack_app_token"`
-			MCPServers           []mcpServerJSONConfig `json:"mcp_servers"`
-			AlwaysOnSkills       []string              `json:"always_on_skills"`
+			LLMGateway           string                      `json:"llm_gateway"`
+			TerminalURL          string                      `json:"terminal_url"`
+			DefaultModel         string                      `json:"default_model"`
+			ModelAliases         map[string]string           `json:"model_aliases"`
+			Links                []server.Link               `json:"links"`
+			NotificationChannels []map[string]any            `json:"notification_channels"`
+			SlackBotToken        string                      `json:"slack_bot_token"`
+			SlackAppToken        string                      `json:"slack_app_token"`
+			MCPServers           []mcpServerJSONConfig       `json:"mcp_servers"`
+			AlwaysOnSkills       []string                    `json:"always_on_skills"`
+			SlugPrompt           string                      `json:"slug_prompt"`
+			MaxRepeatedToolErrs  int                         `json:"max_repeated_tool_errors"`
+			NewConversation      server.ConversationTemplate `json:"new_conversation"`
 		}
 		if err := json.Unmarshal(data, &cfg); err != nil {
 			logger.Warn("Failed to parse config file", "path", configPath, "error", err)
@@ -495,6 +601,11 @@ func buildLLMConfig(logger *slog.Logger, configPath, terminalURL, defaultModel s
 			logger.Info("Using default model from config", "model", cfg.DefaultModel)
 		}
 
+		if len(cfg.ModelAliases) > 0 {
+			llmCfg.ModelAliases = cfg.ModelAliases
+			logger.Info("Model aliases configured", "aliases", cfg.ModelAliases)
+		}
+
 		// Load links from config file if present
 		if len(cfg.Links) > 0 {
 			llmCfg.Links = cfg.Links
@@ -537,6 +648,25 @@ func buildLLMConfig(logger *slog.Logger, configPath, terminalURL, defaultModel s
 			llmCfg.AlwaysOnSkills = cfg.AlwaysOnSkills
 			logger.Info("Always-on skills configured", "skills", cfg.AlwaysOnSkills)
 		}
+
+		if cfg.SlugPrompt != "" {
+			if err := slug.ValidatePromptTemplate(cfg.SlugPrompt); err != nil {
+				logger.Warn("Ignoring slug_prompt from config", "error", err)
+			} else {
+				llmCfg.SlugPrompt = cfg.SlugPrompt
+				logger.Info("Using custom slug prompt from config")
+			}
+		}
+
+		if cfg.MaxRepeatedToolErrs != 0 {
+			llmCfg.MaxRepeatedToolErrors = cfg.MaxRepeatedToolErrs
+			logger.Info("Repeated tool error limit configured", "limit", cfg.MaxRepeatedToolErrs)
+		}
+
+		llmCfg.NewConversation = cfg.NewConversation
+		if nc := cfg.NewConversation; nc.Model != "" || nc.Cwd != "" || len(nc.Tools) > 0 || nc.SystemNote != "" {
+			logger.Info("New conversation template configured", "model", nc.Model, "cwd", nc.Cwd, "tools", nc.Tools)
+		}
 	}
 
 	// Environment variables override config file for Slack tokens
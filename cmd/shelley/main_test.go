@@ -89,6 +89,18 @@ func TestCLICommands(t *testing.T) {
 	})
 }
 
+func TestViewportFlag(t *testing.T) {
+	var f viewportFlag
+	if err := f.Set("1920x1080"); err != nil || f.width != 1920 || f.height != 1080 {
+		t.Errorf("Set(1920x1080) = %v, got %s", err, f.String())
+	}
+	for _, v := range []string{"", "1920", "1920x", "x1080", "0x720", "-1x720", "wide x tall"} {
+		if err := f.Set(v); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", v)
+		}
+	}
+}
+
 func TestSystemdListenerErrors(t *testing.T) {
 	// Save original environment
 	origPID := os.Getenv("LISTEN_PID")
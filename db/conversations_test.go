@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -198,6 +200,132 @@ func TestConversationService_List(t *testing.T) {
 	// may be identical, so we just verify we got the expected count
 }
 
+func TestConversationService_ListAfterCursor(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The last two share a timestamp, so the conversation ID breaks the tie.
+	times := []string{"2024-01-01 10:00:05", "2024-01-01 10:00:04", "2024-01-01 10:00:03", "2024-01-01 10:00:02", "2024-01-01 10:00:02"}
+	want := make(map[string]bool)
+	for i, ts := range times {
+		conv, err := db.CreateConversation(ctx, stringPtr("cursor-"+string(rune('a'+i))), true, nil, nil, ConversationOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create conversation %d: %v", i, err)
+		}
+		if err := db.Pool().Exec(ctx, "UPDATE conversations SET updated_at = ? WHERE conversation_id = ?", ts, conv.ConversationID); err != nil {
+			t.Fatalf("Failed to set updated_at: %v", err)
+		}
+		want[conv.ConversationID] = true
+	}
+
+	page, err := db.ListConversations(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("ListConversations() error = %v", err)
+	}
+
+	// A conversation created between pages would shift an offset by one.
+	created, err := db.CreateConversation(ctx, stringPtr("cursor-new"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for len(page) > 0 {
+		for _, conv := range page {
+			if seen[conv.ConversationID] {
+				t.Errorf("conversation %s repeated", *conv.Slug)
+			}
+			seen[conv.ConversationID] = true
+		}
+		last := page[len(page)-1]
+		page, err = db.ListConversationsAfter(ctx, last.Pinned, last.UpdatedAt, last.ConversationID, 2)
+		if err != nil {
+			t.Fatalf("ListConversationsAfter() error = %v", err)
+		}
+	}
+	for id := range want {
+		if !seen[id] {
+			t.Errorf("conversation %s skipped", id)
+		}
+	}
+	if seen[created.ConversationID] {
+		t.Error("conversation created after the first page should not appear on later pages")
+	}
+}
+
+func TestConversationService_Pinned(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	times := []string{"2024-01-01 10:00:03", "2024-01-01 10:00:02", "2024-01-01 10:00:01"}
+	var ids []string
+	for i, ts := range times {
+		conv, err := db.CreateConversation(ctx, stringPtr("pinned-"+string(rune('a'+i))), true, nil, nil, ConversationOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create conversation %d: %v", i, err)
+		}
+		if err := db.Pool().Exec(ctx, "UPDATE conversations SET updated_at = ? WHERE conversation_id = ?", ts, conv.ConversationID); err != nil {
+			t.Fatalf("Failed to set updated_at: %v", err)
+		}
+		ids = append(ids, conv.ConversationID)
+	}
+
+	// Pin the two oldest; they should come first, still newest first among themselves
+	for _, id := range ids[1:] {
+		conv, err := db.PinConversation(ctx, id)
+		if err != nil {
+			t.Fatalf("PinConversation() error = %v", err)
+		}
+		if !conv.Pinned {
+			t.Errorf("conversation %s not marked pinned", id)
+		}
+	}
+	wantOrder := []string{ids[1], ids[2], ids[0]}
+
+	conversations, err := db.ListConversations(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListConversations() error = %v", err)
+	}
+	var got []string
+	for _, conv := range conversations {
+		got = append(got, conv.ConversationID)
+	}
+	if !slices.Equal(got, wantOrder) {
+		t.Errorf("ListConversations() order = %v, want %v", got, wantOrder)
+	}
+
+	// Cursor pages follow the same order across the pinned/unpinned boundary
+	got = nil
+	page, err := db.ListConversations(ctx, 1, 0)
+	for err == nil && len(page) > 0 {
+		got = append(got, page[0].ConversationID)
+		page, err = db.ListConversationsAfter(ctx, page[0].Pinned, page[0].UpdatedAt, page[0].ConversationID, 1)
+	}
+	if err != nil {
+		t.Fatalf("ListConversationsAfter() error = %v", err)
+	}
+	if !slices.Equal(got, wantOrder) {
+		t.Errorf("cursor page order = %v, want %v", got, wantOrder)
+	}
+
+	if _, err := db.UnpinConversation(ctx, ids[1]); err != nil {
+		t.Fatalf("UnpinConversation() error = %v", err)
+	}
+	conversations, err = db.ListConversations(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListConversations() error = %v", err)
+	}
+	if conversations[0].ConversationID != ids[2] || conversations[1].ConversationID != ids[0] {
+		t.Errorf("after unpinning, expected %s then %s first, got %s then %s", ids[2], ids[0], conversations[0].ConversationID, conversations[1].ConversationID)
+	}
+}
+
 func TestConversationService_Search(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -827,3 +955,142 @@ func TestUnarchivePreservesSortOrder(t *testing.T) {
 		}
 	}
 }
+
+func TestForkConversation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cwd := "/tmp/project"
+	source, err := db.CreateConversation(ctx, stringPtr("source"), true, &cwd, stringPtr("predictable"), ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	var seqs []int64
+	for i, msgType := range []MessageType{MessageTypeUser, MessageTypeAgent, MessageTypeUser, MessageTypeAgent} {
+		msg, err := db.CreateMessage(ctx, CreateMessageParams{
+			ConversationID: source.ConversationID,
+			Type:           msgType,
+			UserData:       map[string]int{"index": i},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		seqs = append(seqs, msg.SequenceID)
+	}
+
+	fork, err := db.ForkConversation(ctx, source.ConversationID, seqs[1])
+	if err != nil {
+		t.Fatalf("ForkConversation() error = %v", err)
+	}
+	if fork.ConversationID == source.ConversationID {
+		t.Fatal("Expected the fork to get a new conversation ID")
+	}
+	if fork.ForkedFrom == nil || *fork.ForkedFrom != source.ConversationID {
+		t.Errorf("Expected forked_from %q, got %v", source.ConversationID, fork.ForkedFrom)
+	}
+	if fork.Slug != nil {
+		t.Errorf("Expected the fork to have no slug, got %q", *fork.Slug)
+	}
+	if fork.Cwd == nil || *fork.Cwd != cwd || fork.Model == nil || *fork.Model != "predictable" {
+		t.Errorf("Expected the fork to keep cwd and model, got %v, %v", fork.Cwd, fork.Model)
+	}
+
+	sourceMessages, err := db.ListMessages(ctx, source.ConversationID)
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	forkMessages, err := db.ListMessages(ctx, fork.ConversationID)
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	if len(sourceMessages) != 4 {
+		t.Errorf("Expected the source to keep 4 messages, got %d", len(sourceMessages))
+	}
+	if len(forkMessages) != 2 {
+		t.Fatalf("Expected 2 forked messages, got %d", len(forkMessages))
+	}
+	for i, m := range forkMessages {
+		src := sourceMessages[i]
+		if m.MessageID == src.MessageID {
+			t.Errorf("Message %d: expected a new message ID", i)
+		}
+		if m.SequenceID != src.SequenceID || m.Type != src.Type || *m.UserData != *src.UserData {
+			t.Errorf("Message %d: expected a copy of %+v, got %+v", i, src, m)
+		}
+	}
+
+	// New messages continue the fork's sequence
+	next, err := db.CreateMessage(ctx, CreateMessageParams{ConversationID: fork.ConversationID, Type: MessageTypeUser})
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if next.SequenceID != seqs[1]+1 {
+		t.Errorf("Expected sequence ID %d, got %d", seqs[1]+1, next.SequenceID)
+	}
+
+	if _, err := db.ForkConversation(ctx, source.ConversationID, seqs[3]+1); !errors.Is(err, ErrForkPointNotFound) {
+		t.Errorf("Expected ErrForkPointNotFound, got %v", err)
+	}
+	if _, err := db.ForkConversation(ctx, "missing", 1); err == nil {
+		t.Error("Expected an error forking a missing conversation")
+	}
+}
+
+func TestConversationUsageFollowsMessages(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	source, err := db.CreateConversation(ctx, stringPtr("usage-source"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	var seqs []int64
+	for i := range 3 {
+		msg, err := db.CreateMessage(ctx, CreateMessageParams{
+			ConversationID: source.ConversationID,
+			Type:           MessageTypeAgent,
+			UsageData: map[string]any{
+				"input_tokens":                100,
+				"cache_creation_input_tokens": 10,
+				"cache_read_input_tokens":     1000,
+				"output_tokens":               50,
+				"cost_usd":                    0.25 * float64(i+1),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		seqs = append(seqs, msg.SequenceID)
+	}
+
+	check := func(what string, conv *generated.Conversation, messages int64, cost float64) {
+		t.Helper()
+		if conv.InputTokensTotal != 110*messages || conv.OutputTokensTotal != 50*messages || conv.CachedTokensTotal != 1000*messages {
+			t.Errorf("%s: totals %d/%d/%d, want the usage of %d messages", what, conv.InputTokensTotal, conv.OutputTokensTotal, conv.CachedTokensTotal, messages)
+		}
+		if conv.CostUsdTotal != cost {
+			t.Errorf("%s: CostUsdTotal = %v, want %v", what, conv.CostUsdTotal, cost)
+		}
+	}
+
+	fork, err := db.ForkConversation(ctx, source.ConversationID, seqs[1])
+	if err != nil {
+		t.Fatalf("ForkConversation() error = %v", err)
+	}
+	check("fork", fork, 2, 0.75)
+
+	if _, err := db.TruncateConversationAfter(ctx, source.ConversationID, seqs[0]); err != nil {
+		t.Fatalf("TruncateConversationAfter() error = %v", err)
+	}
+	truncated, err := db.GetConversationByID(ctx, source.ConversationID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	check("truncated", truncated, 1, 0.25)
+}
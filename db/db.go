@@ -12,12 +12,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"shelley.exe.dev/db/generated"
@@ -91,6 +94,14 @@ func (db *DB) Close() error {
 	return db.pool.Close()
 }
 
+// Ping checks that the database can serve a read
+func (db *DB) Ping(ctx context.Context) error {
+	return db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var one int
+		return rx.QueryRow("SELECT 1").Scan(&one)
+	})
+}
+
 // Migrate runs the database migrations
 func (db *DB) Migrate(ctx context.Context) error {
 	// Read all migration files
@@ -236,8 +247,9 @@ func WithTxRes[T any](db *DB, ctx context.Context, fn func(*generated.Queries) (
 
 // ConversationOptions holds extensible conversation settings stored as JSON.
 type ConversationOptions struct {
-	Type            string `json:"type,omitempty"`             // "normal" (default) or "orchestrator"
-	SubagentBackend string `json:"subagent_backend,omitempty"` // "shelley" (default), "claude-cli", "codex-cli"
+	Type            string   `json:"type,omitempty"`             // "normal" (default) or "orchestrator"
+	SubagentBackend string   `json:"subagent_backend,omitempty"` // "shelley" (default), "claude-cli", "codex-cli"
+	Tools           []string `json:"tools,omitempty"`            // names of the tools the agent may use; empty allows all
 }
 
 // IsOrchestrator returns true if the conversation is in orchestrator mode.
@@ -245,6 +257,11 @@ func (o ConversationOptions) IsOrchestrator() bool {
 	return o.Type == "orchestrator"
 }
 
+// AllowsTool reports whether the conversation's agent may use the named tool.
+func (o ConversationOptions) AllowsTool(name string) bool {
+	return len(o.Tools) == 0 || slices.Contains(o.Tools, name)
+}
+
 // ParseConversationOptions parses a JSON string into ConversationOptions.
 // Returns zero-value options (type=normal) for empty or invalid input.
 func ParseConversationOptions(s string) ConversationOptions {
@@ -311,7 +328,7 @@ func (db *DB) GetConversationBySlug(ctx context.Context, slug string) (*generate
 	return &conversation, err
 }
 
-// ListConversations retrieves conversations with pagination
+// ListConversations retrieves conversations with pagination, pinned ones first
 func (db *DB) ListConversations(ctx context.Context, limit, offset int64) ([]generated.Conversation, error) {
 	var conversations []generated.Conversation
 	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
@@ -326,6 +343,42 @@ func (db *DB) ListConversations(ctx context.Context, limit, offset int64) ([]gen
 	return conversations, err
 }
 
+// sqliteTimestampFormat is the text format of CURRENT_TIMESTAMP, which is how
+// conversation timestamps are stored.
+const sqliteTimestampFormat = "2006-01-02 15:04:05"
+
+// ListConversationsAfter returns up to limit conversations that come after the
+// one with the given pinned state, updated_at and ID in ListConversations
+// order. Unlike an offset, this cursor doesn't shift when conversations are
+// created between pages.
+func (db *DB) ListConversationsAfter(ctx context.Context, pinned bool, updatedAt time.Time, conversationID string, limit int64) ([]generated.Conversation, error) {
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListConversationsAfter(ctx, generated.ListConversationsAfterParams{
+			CursorPinned:    pinned,
+			CursorUpdatedAt: updatedAt.UTC().Format(sqliteTimestampFormat),
+			CursorID:        conversationID,
+			Limit:           limit,
+		})
+		return err
+	})
+	return conversations, err
+}
+
+// ListConversationsWithoutSlug returns up to limit top-level conversations that have no slug, newest first
+func (db *DB) ListConversationsWithoutSlug(ctx context.Context, limit int64) ([]generated.Conversation, error) {
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListConversationsWithoutSlug(ctx, limit)
+		return err
+	})
+	return conversations, err
+}
+
 // SearchConversations searches for conversations containing the given query in their slug
 func (db *DB) SearchConversations(ctx context.Context, query string, limit, offset int64) ([]generated.Conversation, error) {
 	queryPtr := &query
@@ -362,6 +415,32 @@ func (db *DB) SearchConversationsWithMessages(ctx context.Context, query string,
 	return conversations, err
 }
 
+// CountConversations counts the conversations ListConversations pages
+// through, or if query is set, those SearchConversations (or with
+// searchContent, SearchConversationsWithMessages) matches.
+func (db *DB) CountConversations(ctx context.Context, query string, searchContent bool) (int64, error) {
+	queryPtr := &query
+	var count int64
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		switch {
+		case query == "":
+			count, err = q.CountConversations(ctx)
+		case searchContent:
+			count, err = q.CountSearchConversationsWithMessages(ctx, generated.CountSearchConversationsWithMessagesParams{
+				Column1: queryPtr,
+				Column2: queryPtr,
+				Column3: queryPtr,
+			})
+		default:
+			count, err = q.CountSearchConversations(ctx, queryPtr)
+		}
+		return err
+	})
+	return count, err
+}
+
 // UpdateConversationSlug updates the slug of a conversation
 func (db *DB) UpdateConversationSlug(ctx context.Context, conversationID, slug string) (*generated.Conversation, error) {
 	var conversation generated.Conversation
@@ -377,6 +456,67 @@ func (db *DB) UpdateConversationSlug(ctx context.Context, conversationID, slug s
 	return &conversation, err
 }
 
+// ErrSlugUserSet is returned by UpdateConversationAutoSlug when the user has
+// named the conversation, so its slug must not be replaced.
+var ErrSlugUserSet = errors.New("conversation slug was set by the user")
+
+// UpdateConversationAutoSlug sets a generated slug on a conversation. It
+// returns ErrSlugUserSet, and leaves the slug alone, if the user has renamed
+// the conversation.
+func (db *DB) UpdateConversationAutoSlug(ctx context.Context, conversationID, slug string) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.UpdateConversationAutoSlug(ctx, generated.UpdateConversationAutoSlugParams{
+			Slug:           &slug,
+			ConversationID: conversationID,
+		})
+		if err != sql.ErrNoRows {
+			return err
+		}
+		// Nothing was updated: either the conversation doesn't exist or
+		// its slug is user-set.
+		conversation, err = q.GetConversation(ctx, conversationID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("conversation not found: %s", conversationID)
+		} else if err != nil {
+			return err
+		}
+		return ErrSlugUserSet
+	})
+	return &conversation, err
+}
+
+// RenameConversation sets a slug chosen by the user and marks it as
+// user-set, so it is kept when the first message is edited.
+func (db *DB) RenameConversation(ctx context.Context, conversationID, slug string) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.RenameConversation(ctx, generated.RenameConversationParams{
+			Slug:           &slug,
+			ConversationID: conversationID,
+		})
+		return err
+	})
+	return &conversation, err
+}
+
+// MarkConversationSlugAuto marks a conversation's slug as generated rather
+// than user-set, so slug generation may replace it.
+func (db *DB) MarkConversationSlugAuto(ctx context.Context, conversationID string) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.MarkConversationSlugAuto(ctx, conversationID)
+		return err
+	})
+	return &conversation, err
+}
+
 // ClearConversationSlug removes the slug from a conversation.
 func (db *DB) ClearConversationSlug(ctx context.Context, conversationID string) (*generated.Conversation, error) {
 	var conversation generated.Conversation
@@ -404,18 +544,53 @@ func (db *DB) UpdateConversationCwd(ctx context.Context, conversationID, cwd str
 	})
 }
 
-// UpdateConversationModel sets the model for a conversation that doesn't have one yet.
+// UpdateConversationSystemNote sets the pinned system note of a conversation.
+// An empty note clears it.
+func (db *DB) UpdateConversationSystemNote(ctx context.Context, conversationID, note string) (*generated.Conversation, error) {
+	var notePtr *string
+	if note != "" {
+		notePtr = &note
+	}
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.UpdateConversationSystemNote(ctx, generated.UpdateConversationSystemNoteParams{
+			SystemNote:     notePtr,
+			ConversationID: conversationID,
+		})
+		return err
+	})
+	return &conversation, err
+}
+
+// SetConversationModelIfUnset sets the model for a conversation that doesn't have one yet.
 // This is used to backfill the model for conversations created before the model column existed.
-func (db *DB) UpdateConversationModel(ctx context.Context, conversationID, model string) error {
+func (db *DB) SetConversationModelIfUnset(ctx context.Context, conversationID, model string) error {
 	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
 		q := generated.New(tx.Conn())
-		return q.UpdateConversationModel(ctx, generated.UpdateConversationModelParams{
+		return q.SetConversationModelIfUnset(ctx, generated.SetConversationModelIfUnsetParams{
 			Model:          &model,
 			ConversationID: conversationID,
 		})
 	})
 }
 
+// UpdateConversationModel switches a conversation to a different model.
+func (db *DB) UpdateConversationModel(ctx context.Context, conversationID, model string) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.UpdateConversationModel(ctx, generated.UpdateConversationModelParams{
+			Model:          &model,
+			ConversationID: conversationID,
+		})
+		return err
+	})
+	return &conversation, err
+}
+
 // Message methods (moved from MessageService)
 
 // MessageType represents the type of message
@@ -553,6 +728,50 @@ func (db *DB) ListMessages(ctx context.Context, conversationID string) ([]genera
 	return messages, err
 }
 
+// MessagePageOptions selects a page of a conversation's messages. The zero
+// value selects all messages in ascending sequence order.
+type MessagePageOptions struct {
+	// AfterSequenceID, if non-zero, selects messages with a greater sequence ID.
+	AfterSequenceID int64
+	// BeforeSequenceID, if non-zero, selects messages with a smaller sequence ID.
+	BeforeSequenceID int64
+	// Limit, if non-zero, caps the number of messages returned.
+	Limit int64
+	// Descending returns messages newest first.
+	Descending bool
+}
+
+// ListMessagesPage retrieves the page of a conversation's messages selected by opts using q
+func ListMessagesPage(ctx context.Context, q *generated.Queries, conversationID string, opts MessagePageOptions) ([]generated.Message, error) {
+	params := generated.ListMessagesPageParams{
+		ConversationID:   conversationID,
+		AfterSequenceID:  opts.AfterSequenceID,
+		BeforeSequenceID: opts.BeforeSequenceID,
+		Limit:            opts.Limit,
+	}
+	if params.BeforeSequenceID == 0 {
+		params.BeforeSequenceID = math.MaxInt64
+	}
+	if params.Limit == 0 {
+		params.Limit = -1 // SQLite treats a negative limit as no limit
+	}
+	if opts.Descending {
+		return q.ListMessagesPageDesc(ctx, generated.ListMessagesPageDescParams(params))
+	}
+	return q.ListMessagesPage(ctx, params)
+}
+
+// ListMessagesPage retrieves the page of a conversation's messages selected by opts
+func (db *DB) ListMessagesPage(ctx context.Context, conversationID string, opts MessagePageOptions) ([]generated.Message, error) {
+	var messages []generated.Message
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		messages, err = ListMessagesPage(ctx, generated.New(rx.Conn()), conversationID, opts)
+		return err
+	})
+	return messages, err
+}
+
 // ListMessagesForContext retrieves messages that should be sent to the LLM (excludes excluded_from_context=true)
 func (db *DB) ListMessagesForContext(ctx context.Context, conversationID string) ([]generated.Message, error) {
 	var messages []generated.Message
@@ -652,6 +871,23 @@ func (db *DB) ListArchivedConversations(ctx context.Context, limit, offset int64
 	return conversations, err
 }
 
+// CountArchivedConversations counts archived conversations, or if query is
+// set, those SearchArchivedConversations matches.
+func (db *DB) CountArchivedConversations(ctx context.Context, query string) (int64, error) {
+	var count int64
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		if query == "" {
+			count, err = q.CountArchivedConversations(ctx)
+		} else {
+			count, err = q.CountSearchArchivedConversations(ctx, &query)
+		}
+		return err
+	})
+	return count, err
+}
+
 // SearchArchivedConversations searches for archived conversations containing the given query in their slug
 func (db *DB) SearchArchivedConversations(ctx context.Context, query string, limit, offset int64) ([]generated.Conversation, error) {
 	queryPtr := &query
@@ -693,6 +929,30 @@ func (db *DB) UnarchiveConversation(ctx context.Context, conversationID string)
 	return &conversation, err
 }
 
+// PinConversation pins a conversation to the top of the conversation list
+func (db *DB) PinConversation(ctx context.Context, conversationID string) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.PinConversation(ctx, conversationID)
+		return err
+	})
+	return &conversation, err
+}
+
+// UnpinConversation unpins a conversation
+func (db *DB) UnpinConversation(ctx context.Context, conversationID string) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.UnpinConversation(ctx, conversationID)
+		return err
+	})
+	return &conversation, err
+}
+
 // DeleteConversation deletes a conversation and all its messages
 func (db *DB) DeleteConversation(ctx context.Context, conversationID string) error {
 	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
@@ -705,6 +965,30 @@ func (db *DB) DeleteConversation(ctx context.Context, conversationID string) err
 	})
 }
 
+// TruncateConversationAfter deletes a conversation's messages with a sequence
+// ID after sequenceID, and returns how many were deleted. New messages reuse
+// the freed sequence IDs. The conversation's token and cost totals are
+// recomputed to count only the messages that are left.
+func (db *DB) TruncateConversationAfter(ctx context.Context, conversationID string, sequenceID int64) (int64, error) {
+	var deleted int64
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		deleted, err = q.DeleteMessagesAfter(ctx, generated.DeleteMessagesAfterParams{
+			ConversationID: conversationID,
+			SequenceID:     sequenceID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.RecomputeConversationUsage(ctx, conversationID); err != nil {
+			return err
+		}
+		return q.UpdateConversationTimestamp(ctx, conversationID)
+	})
+	return deleted, err
+}
+
 // CreateSubagentConversation creates a new subagent conversation with a parent
 func (db *DB) CreateSubagentConversation(ctx context.Context, slug, parentID string, cwd *string) (*generated.Conversation, error) {
 	conversationID, err := generateConversationID()
@@ -796,6 +1080,78 @@ func (db *DB) DistillReplaceSwap(ctx context.Context, sourceConvID, newConvID, n
 	})
 }
 
+// ErrForkPointNotFound is returned by ForkConversation when the source
+// conversation has no message with the given sequence ID.
+var ErrForkPointNotFound = errors.New("no message with that sequence ID to fork from")
+
+// ForkConversation creates a new conversation holding copies of the source
+// conversation's messages up to and including uptoSequenceID, with the same
+// sequence IDs. The fork keeps the source's working directory, model, options
+// and system note, records the source in forked_from, and starts without a slug.
+// Its token and cost totals count the copied messages.
+func (db *DB) ForkConversation(ctx context.Context, sourceID string, uptoSequenceID int64) (*generated.Conversation, error) {
+	conversationID, err := generateConversationID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate conversation ID: %w", err)
+	}
+	var conversation generated.Conversation
+	err = db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		source, err := q.GetConversation(ctx, sourceID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("conversation not found: %s", sourceID)
+		} else if err != nil {
+			return err
+		}
+		messages, err := q.ListMessages(ctx, sourceID)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(messages, func(m generated.Message) bool { return m.SequenceID == uptoSequenceID }) {
+			return ErrForkPointNotFound
+		}
+
+		conversation, err = q.CreateForkedConversation(ctx, generated.CreateForkedConversationParams{
+			ConversationID:      conversationID,
+			Cwd:                 source.Cwd,
+			Model:               source.Model,
+			ConversationOptions: source.ConversationOptions,
+			SystemNote:          source.SystemNote,
+			ForkedFrom:          &sourceID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			if m.SequenceID > uptoSequenceID {
+				break
+			}
+			if _, err := q.CreateMessage(ctx, generated.CreateMessageParams{
+				MessageID:           uuid.New().String(),
+				ConversationID:      conversationID,
+				SequenceID:          m.SequenceID,
+				Type:                m.Type,
+				LlmData:             m.LlmData,
+				UserData:            m.UserData,
+				UsageData:           m.UsageData,
+				DisplayData:         m.DisplayData,
+				ExcludedFromContext: m.ExcludedFromContext,
+			}); err != nil {
+				return fmt.Errorf("copy message %d: %w", m.SequenceID, err)
+			}
+		}
+		if err := q.RecomputeConversationUsage(ctx, conversationID); err != nil {
+			return err
+		}
+		conversation, err = q.GetConversation(ctx, conversationID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
 // GetSubagents retrieves all subagent conversations for a parent conversation
 func (db *DB) GetSubagents(ctx context.Context, parentID string) ([]generated.Conversation, error) {
 	var conversations []generated.Conversation
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"shelley.exe.dev/db/generated"
+)
+
+// ErrFolderNotFound is returned when a folder ID doesn't name a folder.
+var ErrFolderNotFound = errors.New("folder not found")
+
+// ErrFolderCycle is returned by UpdateFolder when the new parent is the
+// folder itself or one of its descendants.
+var ErrFolderCycle = errors.New("a folder can't be moved into itself or its subfolders")
+
+// generateFolderID generates a folder ID in the format "fXXXXXX"
+func generateFolderID() (string, error) {
+	text := rand.Text()
+	if len(text) < 6 {
+		return "", fmt.Errorf("rand.Text() returned insufficient characters: %d", len(text))
+	}
+	return "f" + text[:6], nil
+}
+
+// getFolder is GetFolder within a transaction, mapping a missing folder to
+// ErrFolderNotFound.
+func getFolder(ctx context.Context, q *generated.Queries, folderID string) (generated.Folder, error) {
+	folder, err := q.GetFolder(ctx, folderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return folder, ErrFolderNotFound
+	}
+	return folder, err
+}
+
+// CreateFolder creates a folder inside parentID, or at the top level if
+// parentID is nil.
+func (db *DB) CreateFolder(ctx context.Context, name string, parentID *string) (*generated.Folder, error) {
+	id, err := generateFolderID()
+	if err != nil {
+		return nil, err
+	}
+	var folder generated.Folder
+	err = db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if parentID != nil {
+			if _, err := getFolder(ctx, q, *parentID); err != nil {
+				return err
+			}
+		}
+		var err error
+		folder, err = q.CreateFolder(ctx, generated.CreateFolderParams{
+			ID:       id,
+			Name:     name,
+			ParentID: parentID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// GetFolder returns a folder, or ErrFolderNotFound.
+func (db *DB) GetFolder(ctx context.Context, folderID string) (*generated.Folder, error) {
+	var folder generated.Folder
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		folder, err = getFolder(ctx, generated.New(rx.Conn()), folderID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// ListFolders returns every folder, sorted by name. Callers build the tree
+// from the parent IDs.
+func (db *DB) ListFolders(ctx context.Context) ([]generated.Folder, error) {
+	var folders []generated.Folder
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		folders, err = q.ListFolders(ctx)
+		return err
+	})
+	return folders, err
+}
+
+// UpdateFolder renames a folder and moves it inside parentID (nil for the top
+// level). It returns ErrFolderCycle if parentID is the folder or one of its
+// descendants.
+func (db *DB) UpdateFolder(ctx context.Context, folderID, name string, parentID *string) (*generated.Folder, error) {
+	var folder generated.Folder
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if _, err := getFolder(ctx, q, folderID); err != nil {
+			return err
+		}
+		// Walk up from the new parent; reaching the folder means a cycle
+		for ancestor := parentID; ancestor != nil; {
+			if *ancestor == folderID {
+				return ErrFolderCycle
+			}
+			parent, err := getFolder(ctx, q, *ancestor)
+			if err != nil {
+				return err
+			}
+			ancestor = parent.ParentID
+		}
+		var err error
+		folder, err = q.UpdateFolder(ctx, generated.UpdateFolderParams{
+			Name:     name,
+			ParentID: parentID,
+			ID:       folderID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// DeleteFolder deletes a folder. Its subfolders and conversations move up to
+// the folder's parent rather than being deleted with it.
+func (db *DB) DeleteFolder(ctx context.Context, folderID string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		folder, err := getFolder(ctx, q, folderID)
+		if err != nil {
+			return err
+		}
+		if err := q.ReparentFolderChildren(ctx, generated.ReparentFolderChildrenParams{
+			NewParentID: folder.ParentID,
+			FolderID:    &folderID,
+		}); err != nil {
+			return err
+		}
+		if err := q.MoveFolderConversations(ctx, generated.MoveFolderConversationsParams{
+			NewFolderID: folder.ParentID,
+			FolderID:    &folderID,
+		}); err != nil {
+			return err
+		}
+		return q.DeleteFolder(ctx, folderID)
+	})
+}
+
+// MoveConversation puts a conversation in a folder, or takes it out of any
+// folder if folderID is nil.
+func (db *DB) MoveConversation(ctx context.Context, conversationID string, folderID *string) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if folderID != nil {
+			if _, err := getFolder(ctx, q, *folderID); err != nil {
+				return err
+			}
+		}
+		var err error
+		conversation, err = q.MoveConversationToFolder(ctx, generated.MoveConversationToFolderParams{
+			FolderID:       folderID,
+			ConversationID: conversationID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// ListConversationsInFolder lists the unarchived top-level conversations
+// directly in a folder, in ListConversations order.
+func (db *DB) ListConversationsInFolder(ctx context.Context, folderID string, limit, offset int64) ([]generated.Conversation, error) {
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListConversationsInFolder(ctx, generated.ListConversationsInFolderParams{
+			FolderID: &folderID,
+			Limit:    limit,
+			Offset:   offset,
+		})
+		return err
+	})
+	return conversations, err
+}
+
+// CountConversationsInFolder counts the conversations ListConversationsInFolder
+// would list.
+func (db *DB) CountConversationsInFolder(ctx context.Context, folderID string) (int64, error) {
+	var count int64
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		count, err = q.CountConversationsInFolder(ctx, &folderID)
+		return err
+	})
+	return count, err
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFolders_MoveConversation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	work, err := db.CreateFolder(ctx, "work", nil)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	personal, err := db.CreateFolder(ctx, "personal", nil)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	conv, err := db.CreateConversation(ctx, stringPtr("filed"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("CreateConversation() error = %v", err)
+	}
+
+	inFolder := func(folderID string) []string {
+		t.Helper()
+		convs, err := db.ListConversationsInFolder(ctx, folderID, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversationsInFolder() error = %v", err)
+		}
+		var ids []string
+		for _, c := range convs {
+			ids = append(ids, c.ConversationID)
+		}
+		return ids
+	}
+
+	moved, err := db.MoveConversation(ctx, conv.ConversationID, &work.ID)
+	if err != nil {
+		t.Fatalf("MoveConversation() error = %v", err)
+	}
+	if moved.FolderID == nil || *moved.FolderID != work.ID {
+		t.Errorf("FolderID = %v, want %s", moved.FolderID, work.ID)
+	}
+	if got := inFolder(work.ID); len(got) != 1 || got[0] != conv.ConversationID {
+		t.Errorf("work folder = %v, want [%s]", got, conv.ConversationID)
+	}
+
+	if _, err := db.MoveConversation(ctx, conv.ConversationID, &personal.ID); err != nil {
+		t.Fatalf("MoveConversation() error = %v", err)
+	}
+	if got := inFolder(work.ID); len(got) != 0 {
+		t.Errorf("work folder after move = %v, want empty", got)
+	}
+	if got := inFolder(personal.ID); len(got) != 1 || got[0] != conv.ConversationID {
+		t.Errorf("personal folder = %v, want [%s]", got, conv.ConversationID)
+	}
+
+	missing := "fmissing"
+	if _, err := db.MoveConversation(ctx, conv.ConversationID, &missing); !errors.Is(err, ErrFolderNotFound) {
+		t.Errorf("MoveConversation() to a missing folder error = %v, want ErrFolderNotFound", err)
+	}
+
+	moved, err = db.MoveConversation(ctx, conv.ConversationID, nil)
+	if err != nil {
+		t.Fatalf("MoveConversation(nil) error = %v", err)
+	}
+	if moved.FolderID != nil {
+		t.Errorf("FolderID after removing from folder = %v, want nil", *moved.FolderID)
+	}
+}
+
+func TestFolders_RejectCycle(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// a > b > c
+	a, err := db.CreateFolder(ctx, "a", nil)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	b, err := db.CreateFolder(ctx, "b", &a.ID)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	c, err := db.CreateFolder(ctx, "c", &b.ID)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+
+	for _, parent := range []string{a.ID, c.ID} {
+		if _, err := db.UpdateFolder(ctx, a.ID, "a", &parent); !errors.Is(err, ErrFolderCycle) {
+			t.Errorf("UpdateFolder(a, parent %s) error = %v, want ErrFolderCycle", parent, err)
+		}
+	}
+	got, err := db.GetFolder(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("GetFolder() error = %v", err)
+	}
+	if got.ParentID != nil {
+		t.Errorf("a's parent = %s after rejected update, want none", *got.ParentID)
+	}
+
+	// Moving c to the top level and a under it is fine
+	if _, err := db.UpdateFolder(ctx, c.ID, "c", nil); err != nil {
+		t.Fatalf("UpdateFolder(c, top level) error = %v", err)
+	}
+	if _, err := db.UpdateFolder(ctx, a.ID, "a", &c.ID); err != nil {
+		t.Fatalf("UpdateFolder(a, parent c) error = %v", err)
+	}
+}
+
+func TestFolders_DeleteMovesContentsUp(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	parent, err := db.CreateFolder(ctx, "parent", nil)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	doomed, err := db.CreateFolder(ctx, "doomed", &parent.ID)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	child, err := db.CreateFolder(ctx, "child", &doomed.ID)
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	conv, err := db.CreateConversation(ctx, stringPtr("orphan"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("CreateConversation() error = %v", err)
+	}
+	if _, err := db.MoveConversation(ctx, conv.ConversationID, &doomed.ID); err != nil {
+		t.Fatalf("MoveConversation() error = %v", err)
+	}
+
+	if err := db.DeleteFolder(ctx, doomed.ID); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if _, err := db.GetFolder(ctx, doomed.ID); !errors.Is(err, ErrFolderNotFound) {
+		t.Errorf("GetFolder() of deleted folder error = %v, want ErrFolderNotFound", err)
+	}
+	got, err := db.GetFolder(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetFolder() error = %v", err)
+	}
+	if got.ParentID == nil || *got.ParentID != parent.ID {
+		t.Errorf("child's parent = %v, want %s", got.ParentID, parent.ID)
+	}
+	movedConv, err := db.GetConversationByID(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("GetConversationByID() error = %v", err)
+	}
+	if movedConv.FolderID == nil || *movedConv.FolderID != parent.ID {
+		t.Errorf("conversation's folder = %v, want %s", movedConv.FolderID, parent.ID)
+	}
+}
@@ -9,11 +9,39 @@ import (
 	"context"
 )
 
+const addConversationUsage = `-- name: AddConversationUsage :exec
+UPDATE conversations
+SET input_tokens_total = input_tokens_total + ?,
+    output_tokens_total = output_tokens_total + ?,
+    cached_tokens_total = cached_tokens_total + ?,
+    cost_usd_total = cost_usd_total + ?
+WHERE conversation_id = ?
+`
+
+type AddConversationUsageParams struct {
+	InputTokensTotal  int64   `json:"input_tokens_total"`
+	OutputTokensTotal int64   `json:"output_tokens_total"`
+	CachedTokensTotal int64   `json:"cached_tokens_total"`
+	CostUsdTotal      float64 `json:"cost_usd_total"`
+	ConversationID    string  `json:"conversation_id"`
+}
+
+func (q *Queries) AddConversationUsage(ctx context.Context, arg AddConversationUsageParams) error {
+	_, err := q.db.ExecContext(ctx, addConversationUsage,
+		arg.InputTokensTotal,
+		arg.OutputTokensTotal,
+		arg.CachedTokensTotal,
+		arg.CostUsdTotal,
+		arg.ConversationID,
+	)
+	return err
+}
+
 const archiveConversation = `-- name: ArchiveConversation :one
 UPDATE conversations
 SET archived = TRUE
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 func (q *Queries) ArchiveConversation(ctx context.Context, conversationID string) (Conversation, error) {
@@ -30,6 +58,15 @@ func (q *Queries) ArchiveConversation(ctx context.Context, conversationID string
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
@@ -56,10 +93,70 @@ func (q *Queries) CountConversations(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const countConversationsInFolder = `-- name: CountConversationsInFolder :one
+SELECT COUNT(*) FROM conversations WHERE folder_id = ? AND archived = FALSE AND parent_conversation_id IS NULL
+`
+
+func (q *Queries) CountConversationsInFolder(ctx context.Context, folderID *string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countConversationsInFolder, folderID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSearchArchivedConversations = `-- name: CountSearchArchivedConversations :one
+SELECT COUNT(*) FROM conversations
+WHERE slug LIKE '%' || ? || '%' AND archived = TRUE
+`
+
+func (q *Queries) CountSearchArchivedConversations(ctx context.Context, dollar_1 *string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSearchArchivedConversations, dollar_1)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSearchConversations = `-- name: CountSearchConversations :one
+SELECT COUNT(*) FROM conversations
+WHERE slug LIKE '%' || ? || '%' AND archived = FALSE AND parent_conversation_id IS NULL
+`
+
+func (q *Queries) CountSearchConversations(ctx context.Context, dollar_1 *string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSearchConversations, dollar_1)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSearchConversationsWithMessages = `-- name: CountSearchConversationsWithMessages :one
+SELECT COUNT(DISTINCT c.conversation_id) FROM conversations c
+LEFT JOIN messages m ON c.conversation_id = m.conversation_id AND m.type IN ('user', 'agent')
+WHERE c.archived = FALSE
+  AND (
+    c.slug LIKE '%' || ? || '%'
+    OR json_extract(m.user_data, '$.text') LIKE '%' || ? || '%'
+    OR m.llm_data LIKE '%' || ? || '%'
+  )
+`
+
+type CountSearchConversationsWithMessagesParams struct {
+	Column1 *string `json:"column_1"`
+	Column2 *string `json:"column_2"`
+	Column3 *string `json:"column_3"`
+}
+
+// Counts the conversations SearchConversationsWithMessages matches
+func (q *Queries) CountSearchConversationsWithMessages(ctx context.Context, arg CountSearchConversationsWithMessagesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSearchConversationsWithMessages, arg.Column1, arg.Column2, arg.Column3)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createConversation = `-- name: CreateConversation :one
 INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, model, conversation_options)
 VALUES (?, ?, ?, ?, ?, ?)
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 type CreateConversationParams struct {
@@ -92,6 +189,64 @@ func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversation
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
+const createForkedConversation = `-- name: CreateForkedConversation :one
+INSERT INTO conversations (conversation_id, user_initiated, cwd, model, conversation_options, system_note, forked_from)
+VALUES (?, TRUE, ?, ?, ?, ?, ?)
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+type CreateForkedConversationParams struct {
+	ConversationID      string  `json:"conversation_id"`
+	Cwd                 *string `json:"cwd"`
+	Model               *string `json:"model"`
+	ConversationOptions string  `json:"conversation_options"`
+	SystemNote          *string `json:"system_note"`
+	ForkedFrom          *string `json:"forked_from"`
+}
+
+func (q *Queries) CreateForkedConversation(ctx context.Context, arg CreateForkedConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, createForkedConversation,
+		arg.ConversationID,
+		arg.Cwd,
+		arg.Model,
+		arg.ConversationOptions,
+		arg.SystemNote,
+		arg.ForkedFrom,
+	)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
@@ -99,7 +254,7 @@ func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversation
 const createSubagentConversation = `-- name: CreateSubagentConversation :one
 INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, parent_conversation_id)
 VALUES (?, ?, FALSE, ?, ?)
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 type CreateSubagentConversationParams struct {
@@ -128,6 +283,15 @@ func (q *Queries) CreateSubagentConversation(ctx context.Context, arg CreateSuba
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
@@ -143,7 +307,7 @@ func (q *Queries) DeleteConversation(ctx context.Context, conversationID string)
 }
 
 const getConversation = `-- name: GetConversation :one
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE conversation_id = ?
 `
 
@@ -161,12 +325,21 @@ func (q *Queries) GetConversation(ctx context.Context, conversationID string) (C
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
 
 const getConversationBySlug = `-- name: GetConversationBySlug :one
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE slug = ?
 `
 
@@ -184,12 +357,21 @@ func (q *Queries) GetConversationBySlug(ctx context.Context, slug *string) (Conv
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
 
 const getConversationBySlugAndParent = `-- name: GetConversationBySlugAndParent :one
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE slug = ? AND parent_conversation_id = ?
 `
 
@@ -212,6 +394,15 @@ func (q *Queries) GetConversationBySlugAndParent(ctx context.Context, arg GetCon
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
@@ -264,7 +455,7 @@ func (q *Queries) GetSubagentCounts(ctx context.Context) ([]GetSubagentCountsRow
 }
 
 const getSubagents = `-- name: GetSubagents :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE parent_conversation_id = ?
 ORDER BY created_at ASC
 `
@@ -289,6 +480,15 @@ func (q *Queries) GetSubagents(ctx context.Context, parentConversationID *string
 			&i.ParentConversationID,
 			&i.Model,
 			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -304,7 +504,7 @@ func (q *Queries) GetSubagents(ctx context.Context, parentConversationID *string
 }
 
 const listArchivedConversations = `-- name: ListArchivedConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE archived = TRUE
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?
@@ -335,6 +535,15 @@ func (q *Queries) ListArchivedConversations(ctx context.Context, arg ListArchive
 			&i.ParentConversationID,
 			&i.Model,
 			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -350,9 +559,9 @@ func (q *Queries) ListArchivedConversations(ctx context.Context, arg ListArchive
 }
 
 const listConversations = `-- name: ListConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE archived = FALSE AND parent_conversation_id IS NULL
-ORDER BY updated_at DESC
+ORDER BY pinned DESC, updated_at DESC, conversation_id DESC
 LIMIT ? OFFSET ?
 `
 
@@ -381,6 +590,137 @@ func (q *Queries) ListConversations(ctx context.Context, arg ListConversationsPa
 			&i.ParentConversationID,
 			&i.Model,
 			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsAfter = `-- name: ListConversationsAfter :many
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
+WHERE archived = FALSE AND parent_conversation_id IS NULL
+  AND (pinned, updated_at, conversation_id) < (CAST(? AS BOOLEAN), CAST(? AS TEXT), CAST(? AS TEXT))
+ORDER BY pinned DESC, updated_at DESC, conversation_id DESC
+LIMIT ?
+`
+
+type ListConversationsAfterParams struct {
+	CursorPinned    bool   `json:"cursor_pinned"`
+	CursorUpdatedAt string `json:"cursor_updated_at"`
+	CursorID        string `json:"cursor_id"`
+	Limit           int64  `json:"limit"`
+}
+
+// Keyset page of ListConversations: the conversations after the cursor row.
+// cursor_updated_at is in CURRENT_TIMESTAMP's text format so it compares
+// with the stored values.
+func (q *Queries) ListConversationsAfter(ctx context.Context, arg ListConversationsAfterParams) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationsAfter,
+		arg.CursorPinned,
+		arg.CursorUpdatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Conversation{}
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Slug,
+			&i.UserInitiated,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Cwd,
+			&i.Archived,
+			&i.ParentConversationID,
+			&i.Model,
+			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsInFolder = `-- name: ListConversationsInFolder :many
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
+WHERE folder_id = ? AND archived = FALSE AND parent_conversation_id IS NULL
+ORDER BY pinned DESC, updated_at DESC, conversation_id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListConversationsInFolderParams struct {
+	FolderID *string `json:"folder_id"`
+	Limit    int64   `json:"limit"`
+	Offset   int64   `json:"offset"`
+}
+
+func (q *Queries) ListConversationsInFolder(ctx context.Context, arg ListConversationsInFolderParams) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationsInFolder, arg.FolderID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Conversation{}
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Slug,
+			&i.UserInitiated,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Cwd,
+			&i.Archived,
+			&i.ParentConversationID,
+			&i.Model,
+			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -395,8 +735,244 @@ func (q *Queries) ListConversations(ctx context.Context, arg ListConversationsPa
 	return items, nil
 }
 
+const listConversationsWithoutSlug = `-- name: ListConversationsWithoutSlug :many
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
+WHERE slug IS NULL AND parent_conversation_id IS NULL
+ORDER BY created_at DESC
+LIMIT ?
+`
+
+func (q *Queries) ListConversationsWithoutSlug(ctx context.Context, limit int64) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationsWithoutSlug, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Conversation{}
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Slug,
+			&i.UserInitiated,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Cwd,
+			&i.Archived,
+			&i.ParentConversationID,
+			&i.Model,
+			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markConversationSlugAuto = `-- name: MarkConversationSlugAuto :one
+UPDATE conversations
+SET slug_user_set = FALSE
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+// Lets automatic slug generation replace a slug the user chose
+func (q *Queries) MarkConversationSlugAuto(ctx context.Context, conversationID string) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, markConversationSlugAuto, conversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
+const moveConversationToFolder = `-- name: MoveConversationToFolder :one
+UPDATE conversations
+SET folder_id = ?
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+type MoveConversationToFolderParams struct {
+	FolderID       *string `json:"folder_id"`
+	ConversationID string  `json:"conversation_id"`
+}
+
+func (q *Queries) MoveConversationToFolder(ctx context.Context, arg MoveConversationToFolderParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, moveConversationToFolder, arg.FolderID, arg.ConversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
+const moveFolderConversations = `-- name: MoveFolderConversations :exec
+UPDATE conversations
+SET folder_id = ?
+WHERE folder_id = ?
+`
+
+type MoveFolderConversationsParams struct {
+	NewFolderID *string `json:"new_folder_id"`
+	FolderID    *string `json:"folder_id"`
+}
+
+func (q *Queries) MoveFolderConversations(ctx context.Context, arg MoveFolderConversationsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFolderConversations, arg.NewFolderID, arg.FolderID)
+	return err
+}
+
+const pinConversation = `-- name: PinConversation :one
+UPDATE conversations
+SET pinned = TRUE
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+func (q *Queries) PinConversation(ctx context.Context, conversationID string) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, pinConversation, conversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
+const recomputeConversationUsage = `-- name: RecomputeConversationUsage :exec
+UPDATE conversations
+SET input_tokens_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.input_tokens') + json_extract(m.usage_data, '$.cache_creation_input_tokens')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL),
+    output_tokens_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.output_tokens')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL),
+    cached_tokens_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.cache_read_input_tokens')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL),
+    cost_usd_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.cost_usd')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL)
+WHERE conversation_id = ?
+`
+
+func (q *Queries) RecomputeConversationUsage(ctx context.Context, conversationID string) error {
+	_, err := q.db.ExecContext(ctx, recomputeConversationUsage, conversationID)
+	return err
+}
+
+const renameConversation = `-- name: RenameConversation :one
+UPDATE conversations
+SET slug = ?, slug_user_set = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+type RenameConversationParams struct {
+	Slug           *string `json:"slug"`
+	ConversationID string  `json:"conversation_id"`
+}
+
+// Sets a slug the user chose, which automatic slug generation won't replace
+func (q *Queries) RenameConversation(ctx context.Context, arg RenameConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, renameConversation, arg.Slug, arg.ConversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
 const searchArchivedConversations = `-- name: SearchArchivedConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE slug LIKE '%' || ? || '%' AND archived = TRUE
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?
@@ -428,6 +1004,15 @@ func (q *Queries) SearchArchivedConversations(ctx context.Context, arg SearchArc
 			&i.ParentConversationID,
 			&i.Model,
 			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -443,7 +1028,7 @@ func (q *Queries) SearchArchivedConversations(ctx context.Context, arg SearchArc
 }
 
 const searchConversations = `-- name: SearchConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total FROM conversations
 WHERE slug LIKE '%' || ? || '%' AND archived = FALSE AND parent_conversation_id IS NULL
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?
@@ -475,6 +1060,15 @@ func (q *Queries) SearchConversations(ctx context.Context, arg SearchConversatio
 			&i.ParentConversationID,
 			&i.Model,
 			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -490,7 +1084,7 @@ func (q *Queries) SearchConversations(ctx context.Context, arg SearchConversatio
 }
 
 const searchConversationsWithMessages = `-- name: SearchConversationsWithMessages :many
-SELECT DISTINCT c.conversation_id, c.slug, c.user_initiated, c.created_at, c.updated_at, c.cwd, c.archived, c.parent_conversation_id, c.model, c.conversation_options FROM conversations c
+SELECT DISTINCT c.conversation_id, c.slug, c.user_initiated, c.created_at, c.updated_at, c.cwd, c.archived, c.parent_conversation_id, c.model, c.conversation_options, c.system_note, c.slug_user_set, c.forked_from, c.input_tokens_total, c.output_tokens_total, c.cached_tokens_total, c.pinned, c.folder_id, c.cost_usd_total FROM conversations c
 LEFT JOIN messages m ON c.conversation_id = m.conversation_id AND m.type IN ('user', 'agent')
 WHERE c.archived = FALSE
   AND (
@@ -538,6 +1132,15 @@ func (q *Queries) SearchConversationsWithMessages(ctx context.Context, arg Searc
 			&i.ParentConversationID,
 			&i.Model,
 			&i.ConversationOptions,
+			&i.SystemNote,
+			&i.SlugUserSet,
+			&i.ForkedFrom,
+			&i.InputTokensTotal,
+			&i.OutputTokensTotal,
+			&i.CachedTokensTotal,
+			&i.Pinned,
+			&i.FolderID,
+			&i.CostUsdTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -552,11 +1155,27 @@ func (q *Queries) SearchConversationsWithMessages(ctx context.Context, arg Searc
 	return items, nil
 }
 
+const setConversationModelIfUnset = `-- name: SetConversationModelIfUnset :exec
+UPDATE conversations
+SET model = ?
+WHERE conversation_id = ? AND model IS NULL
+`
+
+type SetConversationModelIfUnsetParams struct {
+	Model          *string `json:"model"`
+	ConversationID string  `json:"conversation_id"`
+}
+
+func (q *Queries) SetConversationModelIfUnset(ctx context.Context, arg SetConversationModelIfUnsetParams) error {
+	_, err := q.db.ExecContext(ctx, setConversationModelIfUnset, arg.Model, arg.ConversationID)
+	return err
+}
+
 const unarchiveConversation = `-- name: UnarchiveConversation :one
 UPDATE conversations
 SET archived = FALSE
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 func (q *Queries) UnarchiveConversation(ctx context.Context, conversationID string) (Conversation, error) {
@@ -573,6 +1192,89 @@ func (q *Queries) UnarchiveConversation(ctx context.Context, conversationID stri
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
+const unpinConversation = `-- name: UnpinConversation :one
+UPDATE conversations
+SET pinned = FALSE
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+func (q *Queries) UnpinConversation(ctx context.Context, conversationID string) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, unpinConversation, conversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
+const updateConversationAutoSlug = `-- name: UpdateConversationAutoSlug :one
+UPDATE conversations
+SET slug = ?, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ? AND slug_user_set = FALSE
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+type UpdateConversationAutoSlugParams struct {
+	Slug           *string `json:"slug"`
+	ConversationID string  `json:"conversation_id"`
+}
+
+// Sets a generated slug, unless the user has named the conversation
+func (q *Queries) UpdateConversationAutoSlug(ctx context.Context, arg UpdateConversationAutoSlugParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, updateConversationAutoSlug, arg.Slug, arg.ConversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
@@ -581,7 +1283,7 @@ const updateConversationCwd = `-- name: UpdateConversationCwd :one
 UPDATE conversations
 SET cwd = ?, updated_at = CURRENT_TIMESTAMP
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 type UpdateConversationCwdParams struct {
@@ -603,14 +1305,24 @@ func (q *Queries) UpdateConversationCwd(ctx context.Context, arg UpdateConversat
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
 
-const updateConversationModel = `-- name: UpdateConversationModel :exec
+const updateConversationModel = `-- name: UpdateConversationModel :one
 UPDATE conversations
-SET model = ?
-WHERE conversation_id = ? AND model IS NULL
+SET model = ?, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 type UpdateConversationModelParams struct {
@@ -618,16 +1330,38 @@ type UpdateConversationModelParams struct {
 	ConversationID string  `json:"conversation_id"`
 }
 
-func (q *Queries) UpdateConversationModel(ctx context.Context, arg UpdateConversationModelParams) error {
-	_, err := q.db.ExecContext(ctx, updateConversationModel, arg.Model, arg.ConversationID)
-	return err
+func (q *Queries) UpdateConversationModel(ctx context.Context, arg UpdateConversationModelParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, updateConversationModel, arg.Model, arg.ConversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
 }
 
 const updateConversationParent = `-- name: UpdateConversationParent :one
 UPDATE conversations
 SET parent_conversation_id = ?, updated_at = CURRENT_TIMESTAMP
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 type UpdateConversationParentParams struct {
@@ -649,6 +1383,15 @@ func (q *Queries) UpdateConversationParent(ctx context.Context, arg UpdateConver
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
@@ -657,7 +1400,7 @@ const updateConversationSlug = `-- name: UpdateConversationSlug :one
 UPDATE conversations
 SET slug = ?, updated_at = CURRENT_TIMESTAMP
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
 `
 
 type UpdateConversationSlugParams struct {
@@ -679,6 +1422,54 @@ func (q *Queries) UpdateConversationSlug(ctx context.Context, arg UpdateConversa
 		&i.ParentConversationID,
 		&i.Model,
 		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
+	)
+	return i, err
+}
+
+const updateConversationSystemNote = `-- name: UpdateConversationSystemNote :one
+UPDATE conversations
+SET system_note = ?, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, system_note, slug_user_set, forked_from, input_tokens_total, output_tokens_total, cached_tokens_total, pinned, folder_id, cost_usd_total
+`
+
+type UpdateConversationSystemNoteParams struct {
+	SystemNote     *string `json:"system_note"`
+	ConversationID string  `json:"conversation_id"`
+}
+
+func (q *Queries) UpdateConversationSystemNote(ctx context.Context, arg UpdateConversationSystemNoteParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, updateConversationSystemNote, arg.SystemNote, arg.ConversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.ConversationOptions,
+		&i.SystemNote,
+		&i.SlugUserSet,
+		&i.ForkedFrom,
+		&i.InputTokensTotal,
+		&i.OutputTokensTotal,
+		&i.CachedTokensTotal,
+		&i.Pinned,
+		&i.FolderID,
+		&i.CostUsdTotal,
 	)
 	return i, err
 }
@@ -149,6 +149,61 @@ func (q *Queries) InsertLLMRequest(ctx context.Context, arg InsertLLMRequestPara
 	return i, err
 }
 
+const listLLMRequestsForConversation = `-- name: ListLLMRequestsForConversation :many
+SELECT
+    id,
+    model,
+    provider,
+    status_code,
+    error,
+    duration_ms,
+    created_at
+FROM llm_requests
+WHERE conversation_id = ?
+ORDER BY id ASC
+`
+
+type ListLLMRequestsForConversationRow struct {
+	ID         int64     `json:"id"`
+	Model      string    `json:"model"`
+	Provider   string    `json:"provider"`
+	StatusCode *int64    `json:"status_code"`
+	Error      *string   `json:"error"`
+	DurationMs *int64    `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (q *Queries) ListLLMRequestsForConversation(ctx context.Context, conversationID *string) ([]ListLLMRequestsForConversationRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLLMRequestsForConversation, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLLMRequestsForConversationRow{}
+	for rows.Next() {
+		var i ListLLMRequestsForConversationRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Model,
+			&i.Provider,
+			&i.StatusCode,
+			&i.Error,
+			&i.DurationMs,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRecentLLMRequests = `-- name: ListRecentLLMRequests :many
 SELECT
     r.id,
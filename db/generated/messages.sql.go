@@ -104,6 +104,24 @@ func (q *Queries) DeleteMessage(ctx context.Context, messageID string) error {
 	return err
 }
 
+const deleteMessagesAfter = `-- name: DeleteMessagesAfter :execrows
+DELETE FROM messages
+WHERE conversation_id = ? AND sequence_id > ?
+`
+
+type DeleteMessagesAfterParams struct {
+	ConversationID string `json:"conversation_id"`
+	SequenceID     int64  `json:"sequence_id"`
+}
+
+func (q *Queries) DeleteMessagesAfter(ctx context.Context, arg DeleteMessagesAfterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMessagesAfter, arg.ConversationID, arg.SequenceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getLatestAgentMessagesForConversations = `-- name: GetLatestAgentMessagesForConversations :many
 SELECT m.message_id, m.conversation_id, m.sequence_id, m.type, m.llm_data, m.user_data, m.usage_data, m.created_at, m.display_data, m.excluded_from_context FROM messages m
 INNER JOIN (
@@ -176,6 +194,31 @@ func (q *Queries) GetLatestMessage(ctx context.Context, conversationID string) (
 	return i, err
 }
 
+const getLatestMessageWithUsage = `-- name: GetLatestMessageWithUsage :one
+SELECT message_id, conversation_id, sequence_id, type, llm_data, user_data, usage_data, created_at, display_data, excluded_from_context FROM messages
+WHERE conversation_id = ? AND usage_data IS NOT NULL
+ORDER BY sequence_id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestMessageWithUsage(ctx context.Context, conversationID string) (Message, error) {
+	row := q.db.QueryRowContext(ctx, getLatestMessageWithUsage, conversationID)
+	var i Message
+	err := row.Scan(
+		&i.MessageID,
+		&i.ConversationID,
+		&i.SequenceID,
+		&i.Type,
+		&i.LlmData,
+		&i.UserData,
+		&i.UsageData,
+		&i.CreatedAt,
+		&i.DisplayData,
+		&i.ExcludedFromContext,
+	)
+	return i, err
+}
+
 const getMessage = `-- name: GetMessage :one
 SELECT message_id, conversation_id, sequence_id, type, llm_data, user_data, usage_data, created_at, display_data, excluded_from_context FROM messages
 WHERE message_id = ?
@@ -337,6 +380,116 @@ func (q *Queries) ListMessagesForContext(ctx context.Context, conversationID str
 	return items, nil
 }
 
+const listMessagesPage = `-- name: ListMessagesPage :many
+SELECT message_id, conversation_id, sequence_id, type, llm_data, user_data, usage_data, created_at, display_data, excluded_from_context FROM messages
+WHERE conversation_id = ?
+  AND sequence_id > ?
+  AND sequence_id < ?
+ORDER BY sequence_id ASC
+LIMIT ?
+`
+
+type ListMessagesPageParams struct {
+	ConversationID   string `json:"conversation_id"`
+	AfterSequenceID  int64  `json:"after_sequence_id"`
+	BeforeSequenceID int64  `json:"before_sequence_id"`
+	Limit            int64  `json:"limit"`
+}
+
+func (q *Queries) ListMessagesPage(ctx context.Context, arg ListMessagesPageParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, listMessagesPage,
+		arg.ConversationID,
+		arg.AfterSequenceID,
+		arg.BeforeSequenceID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.ConversationID,
+			&i.SequenceID,
+			&i.Type,
+			&i.LlmData,
+			&i.UserData,
+			&i.UsageData,
+			&i.CreatedAt,
+			&i.DisplayData,
+			&i.ExcludedFromContext,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessagesPageDesc = `-- name: ListMessagesPageDesc :many
+SELECT message_id, conversation_id, sequence_id, type, llm_data, user_data, usage_data, created_at, display_data, excluded_from_context FROM messages
+WHERE conversation_id = ?
+  AND sequence_id > ?
+  AND sequence_id < ?
+ORDER BY sequence_id DESC
+LIMIT ?
+`
+
+type ListMessagesPageDescParams struct {
+	ConversationID   string `json:"conversation_id"`
+	AfterSequenceID  int64  `json:"after_sequence_id"`
+	BeforeSequenceID int64  `json:"before_sequence_id"`
+	Limit            int64  `json:"limit"`
+}
+
+func (q *Queries) ListMessagesPageDesc(ctx context.Context, arg ListMessagesPageDescParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, listMessagesPageDesc,
+		arg.ConversationID,
+		arg.AfterSequenceID,
+		arg.BeforeSequenceID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.ConversationID,
+			&i.SequenceID,
+			&i.Type,
+			&i.LlmData,
+			&i.UserData,
+			&i.UsageData,
+			&i.CreatedAt,
+			&i.DisplayData,
+			&i.ExcludedFromContext,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMessagesPaginated = `-- name: ListMessagesPaginated :many
 SELECT message_id, conversation_id, sequence_id, type, llm_data, user_data, usage_data, created_at, display_data, excluded_from_context FROM messages
 WHERE conversation_id = ?
@@ -19,6 +19,29 @@ type Conversation struct {
 	ParentConversationID *string   `json:"parent_conversation_id"`
 	Model                *string   `json:"model"`
 	ConversationOptions  string    `json:"conversation_options"`
+	SystemNote           *string   `json:"system_note"`
+	SlugUserSet          bool      `json:"slug_user_set"`
+	ForkedFrom           *string   `json:"forked_from"`
+	InputTokensTotal     int64     `json:"input_tokens_total"`
+	OutputTokensTotal    int64     `json:"output_tokens_total"`
+	CachedTokensTotal    int64     `json:"cached_tokens_total"`
+	Pinned               bool      `json:"pinned"`
+	FolderID             *string   `json:"folder_id"`
+	CostUsdTotal         float64   `json:"cost_usd_total"`
+}
+
+type ConversationTag struct {
+	ConversationID string    `json:"conversation_id"`
+	Tag            string    `json:"tag"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Folder struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  *string   `json:"parent_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type LlmRequest struct {
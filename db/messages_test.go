@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -341,6 +342,76 @@ func TestMessageService_Delete(t *testing.T) {
 	}
 }
 
+func TestMessageService_TruncateConversationAfter(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conv, err := db.CreateConversation(ctx, stringPtr("test-conversation"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create test conversation: %v", err)
+	}
+	other, err := db.CreateConversation(ctx, stringPtr("other-conversation"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create other conversation: %v", err)
+	}
+	var seqs []int64
+	for i := range 5 {
+		for _, c := range []string{conv.ConversationID, other.ConversationID} {
+			msg, err := db.CreateMessage(ctx, CreateMessageParams{
+				ConversationID: c,
+				Type:           MessageTypeUser,
+				LLMData:        map[string]string{"content": fmt.Sprintf("message %d", i)},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+			if c == conv.ConversationID {
+				seqs = append(seqs, msg.SequenceID)
+			}
+		}
+	}
+
+	deleted, err := db.TruncateConversationAfter(ctx, conv.ConversationID, seqs[1])
+	if err != nil {
+		t.Fatalf("TruncateConversationAfter() error = %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("Expected 3 messages deleted, got %d", deleted)
+	}
+
+	messages, err := db.ListMessages(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	var got []int64
+	for _, m := range messages {
+		got = append(got, m.SequenceID)
+	}
+	if !slices.Equal(got, seqs[:2]) {
+		t.Errorf("Expected sequence IDs %v to remain, got %v", seqs[:2], got)
+	}
+	if *messages[1].LlmData != `{"content":"message 1"}` {
+		t.Errorf("Expected earlier messages to be preserved, got %s", *messages[1].LlmData)
+	}
+
+	// Other conversations are untouched
+	if otherMessages, err := db.ListMessages(ctx, other.ConversationID); err != nil || len(otherMessages) != 5 {
+		t.Errorf("Expected 5 messages in the other conversation, got %d (%v)", len(otherMessages), err)
+	}
+
+	// The next message takes the first freed sequence ID
+	next, err := db.CreateMessage(ctx, CreateMessageParams{ConversationID: conv.ConversationID, Type: MessageTypeUser})
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if next.SequenceID != seqs[2] {
+		t.Errorf("Expected sequence ID %d, got %d", seqs[2], next.SequenceID)
+	}
+}
+
 func TestMessageService_CountInConversation(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -518,3 +589,55 @@ func TestMessageService_ListMessagesByConversationPaginated(t *testing.T) {
 		messageIDs[msg.MessageID] = true
 	}
 }
+
+func TestMessageService_ListMessagesPage(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conv, err := db.CreateConversation(ctx, stringPtr("test-conversation-page"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create test conversation: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		_, err := db.CreateMessage(ctx, CreateMessageParams{
+			ConversationID: conv.ConversationID,
+			Type:           MessageTypeUser,
+			LLMData:        map[string]string{"text": fmt.Sprintf("test message %d", i)},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create test message %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts MessagePageOptions
+		want []int64
+	}{
+		{name: "default lists all ascending", want: []int64{1, 2, 3, 4, 5}},
+		{name: "limit", opts: MessagePageOptions{Limit: 2}, want: []int64{1, 2}},
+		{name: "after", opts: MessagePageOptions{AfterSequenceID: 3}, want: []int64{4, 5}},
+		{name: "before", opts: MessagePageOptions{BeforeSequenceID: 3}, want: []int64{1, 2}},
+		{name: "after and before", opts: MessagePageOptions{AfterSequenceID: 1, BeforeSequenceID: 5}, want: []int64{2, 3, 4}},
+		{name: "latest page descending", opts: MessagePageOptions{Limit: 2, Descending: true}, want: []int64{5, 4}},
+		{name: "older page descending", opts: MessagePageOptions{BeforeSequenceID: 4, Limit: 2, Descending: true}, want: []int64{3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := db.ListMessagesPage(ctx, conv.ConversationID, tt.opts)
+			if err != nil {
+				t.Fatalf("ListMessagesPage() error = %v", err)
+			}
+			var got []int64
+			for _, msg := range messages {
+				got = append(got, msg.SequenceID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListMessagesPage() sequence IDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
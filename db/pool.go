@@ -103,10 +103,34 @@ func InitPoolDB(db *sql.DB, numConns int) error {
 	return nil
 }
 
+// Close closes the pool. database/sql leaves the connections the pool holds
+// open, so the idle ones are closed here too; transactions started after
+// Close then fail rather than quietly using a closed database.
 func (p *Pool) Close() error {
+	closeIdleConns(p.writer)
+	closeIdleConns(p.readers)
 	return p.db.Close()
 }
 
+// closeIdleConns closes the connections waiting in ch and puts them back, so
+// that later users get an error instead of blocking.
+func closeIdleConns(ch chan *sql.Conn) {
+	var conns []*sql.Conn
+idle:
+	for range cap(ch) {
+		select {
+		case conn := <-ch:
+			conn.Close()
+			conns = append(conns, conn)
+		default:
+			break idle
+		}
+	}
+	for _, conn := range conns {
+		ch <- conn
+	}
+}
+
 type ctxKeyType int
 
 // CtxKey is the context value key used to store the current *Tx or *Rx.
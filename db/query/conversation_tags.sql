@@ -0,0 +1,29 @@
+-- name: AddConversationTag :exec
+INSERT INTO conversation_tags (conversation_id, tag)
+VALUES (?, ?)
+ON CONFLICT(conversation_id, tag) DO NOTHING;
+
+-- name: RemoveConversationTag :exec
+DELETE FROM conversation_tags
+WHERE conversation_id = ? AND tag = ?;
+
+-- name: ListConversationTags :many
+SELECT tag FROM conversation_tags
+WHERE conversation_id = ?
+ORDER BY tag;
+
+-- name: ListAllConversationTags :many
+SELECT conversation_id, tag FROM conversation_tags
+ORDER BY conversation_id, tag;
+
+-- name: ListConversationsByTag :many
+SELECT c.* FROM conversations c
+JOIN conversation_tags t ON t.conversation_id = c.conversation_id
+WHERE t.tag = ? AND c.archived = FALSE AND c.parent_conversation_id IS NULL
+ORDER BY c.updated_at DESC, c.conversation_id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountConversationsByTag :one
+SELECT COUNT(*) FROM conversations c
+JOIN conversation_tags t ON t.conversation_id = c.conversation_id
+WHERE t.tag = ? AND c.archived = FALSE AND c.parent_conversation_id IS NULL;
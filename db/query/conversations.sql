@@ -14,7 +14,23 @@ WHERE slug = ?;
 -- name: ListConversations :many
 SELECT * FROM conversations
 WHERE archived = FALSE AND parent_conversation_id IS NULL
-ORDER BY updated_at DESC
+ORDER BY pinned DESC, updated_at DESC, conversation_id DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListConversationsAfter :many
+-- Keyset page of ListConversations: the conversations after the cursor row.
+-- cursor_updated_at is in CURRENT_TIMESTAMP's text format so it compares
+-- with the stored values.
+SELECT * FROM conversations
+WHERE archived = FALSE AND parent_conversation_id IS NULL
+  AND (pinned, updated_at, conversation_id) < (CAST(sqlc.arg(cursor_pinned) AS BOOLEAN), CAST(sqlc.arg(cursor_updated_at) AS TEXT), CAST(sqlc.arg(cursor_id) AS TEXT))
+ORDER BY pinned DESC, updated_at DESC, conversation_id DESC
+LIMIT sqlc.arg(limit);
+
+-- name: ListConversationsInFolder :many
+SELECT * FROM conversations
+WHERE folder_id = ? AND archived = FALSE AND parent_conversation_id IS NULL
+ORDER BY pinned DESC, updated_at DESC, conversation_id DESC
 LIMIT ? OFFSET ?;
 
 -- name: ListArchivedConversations :many
@@ -49,17 +65,68 @@ WHERE slug LIKE '%' || ? || '%' AND archived = TRUE
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?;
 
+-- name: ListConversationsWithoutSlug :many
+SELECT * FROM conversations
+WHERE slug IS NULL AND parent_conversation_id IS NULL
+ORDER BY created_at DESC
+LIMIT ?;
+
 -- name: UpdateConversationSlug :one
 UPDATE conversations
 SET slug = ?, updated_at = CURRENT_TIMESTAMP
 WHERE conversation_id = ?
 RETURNING *;
 
+-- name: UpdateConversationAutoSlug :one
+-- Sets a generated slug, unless the user has named the conversation
+UPDATE conversations
+SET slug = ?, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ? AND slug_user_set = FALSE
+RETURNING *;
+
+-- name: RenameConversation :one
+-- Sets a slug the user chose, which automatic slug generation won't replace
+UPDATE conversations
+SET slug = ?, slug_user_set = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ?
+RETURNING *;
+
+-- name: MarkConversationSlugAuto :one
+-- Lets automatic slug generation replace a slug the user chose
+UPDATE conversations
+SET slug_user_set = FALSE
+WHERE conversation_id = ?
+RETURNING *;
+
 -- name: UpdateConversationTimestamp :exec
 UPDATE conversations
 SET updated_at = CURRENT_TIMESTAMP
 WHERE conversation_id = ?;
 
+-- name: AddConversationUsage :exec
+UPDATE conversations
+SET input_tokens_total = input_tokens_total + ?,
+    output_tokens_total = output_tokens_total + ?,
+    cached_tokens_total = cached_tokens_total + ?,
+    cost_usd_total = cost_usd_total + ?
+WHERE conversation_id = ?;
+
+-- name: RecomputeConversationUsage :exec
+UPDATE conversations
+SET input_tokens_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.input_tokens') + json_extract(m.usage_data, '$.cache_creation_input_tokens')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL),
+    output_tokens_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.output_tokens')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL),
+    cached_tokens_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.cache_read_input_tokens')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL),
+    cost_usd_total = (
+        SELECT COALESCE(SUM(json_extract(m.usage_data, '$.cost_usd')), 0)
+        FROM messages m WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL)
+WHERE conversation_id = ?;
+
 -- name: DeleteConversation :exec
 DELETE FROM conversations
 WHERE conversation_id = ?;
@@ -67,9 +134,31 @@ WHERE conversation_id = ?;
 -- name: CountConversations :one
 SELECT COUNT(*) FROM conversations WHERE archived = FALSE AND parent_conversation_id IS NULL;
 
+-- name: CountConversationsInFolder :one
+SELECT COUNT(*) FROM conversations WHERE folder_id = ? AND archived = FALSE AND parent_conversation_id IS NULL;
+
 -- name: CountArchivedConversations :one
 SELECT COUNT(*) FROM conversations WHERE archived = TRUE;
 
+-- name: CountSearchConversations :one
+SELECT COUNT(*) FROM conversations
+WHERE slug LIKE '%' || ? || '%' AND archived = FALSE AND parent_conversation_id IS NULL;
+
+-- name: CountSearchConversationsWithMessages :one
+-- Counts the conversations SearchConversationsWithMessages matches
+SELECT COUNT(DISTINCT c.conversation_id) FROM conversations c
+LEFT JOIN messages m ON c.conversation_id = m.conversation_id AND m.type IN ('user', 'agent')
+WHERE c.archived = FALSE
+  AND (
+    c.slug LIKE '%' || ? || '%'
+    OR json_extract(m.user_data, '$.text') LIKE '%' || ? || '%'
+    OR m.llm_data LIKE '%' || ? || '%'
+  );
+
+-- name: CountSearchArchivedConversations :one
+SELECT COUNT(*) FROM conversations
+WHERE slug LIKE '%' || ? || '%' AND archived = TRUE;
+
 -- name: ArchiveConversation :one
 UPDATE conversations
 SET archived = TRUE
@@ -82,6 +171,29 @@ SET archived = FALSE
 WHERE conversation_id = ?
 RETURNING *;
 
+-- name: PinConversation :one
+UPDATE conversations
+SET pinned = TRUE
+WHERE conversation_id = ?
+RETURNING *;
+
+-- name: UnpinConversation :one
+UPDATE conversations
+SET pinned = FALSE
+WHERE conversation_id = ?
+RETURNING *;
+
+-- name: MoveConversationToFolder :one
+UPDATE conversations
+SET folder_id = ?
+WHERE conversation_id = ?
+RETURNING *;
+
+-- name: MoveFolderConversations :exec
+UPDATE conversations
+SET folder_id = sqlc.narg(new_folder_id)
+WHERE folder_id = sqlc.arg(folder_id);
+
 -- name: UpdateConversationCwd :one
 UPDATE conversations
 SET cwd = ?, updated_at = CURRENT_TIMESTAMP
@@ -89,6 +201,11 @@ WHERE conversation_id = ?
 RETURNING *;
 
 
+-- name: CreateForkedConversation :one
+INSERT INTO conversations (conversation_id, user_initiated, cwd, model, conversation_options, system_note, forked_from)
+VALUES (?, TRUE, ?, ?, ?, ?, ?)
+RETURNING *;
+
 -- name: CreateSubagentConversation :one
 INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, parent_conversation_id)
 VALUES (?, ?, FALSE, ?, ?)
@@ -109,11 +226,23 @@ FROM conversations
 WHERE parent_conversation_id IS NOT NULL
 GROUP BY parent_conversation_id;
 
--- name: UpdateConversationModel :exec
+-- name: UpdateConversationSystemNote :one
+UPDATE conversations
+SET system_note = ?, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ?
+RETURNING *;
+
+-- name: SetConversationModelIfUnset :exec
 UPDATE conversations
 SET model = ?
 WHERE conversation_id = ? AND model IS NULL;
 
+-- name: UpdateConversationModel :one
+UPDATE conversations
+SET model = ?, updated_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ?
+RETURNING *;
+
 -- name: GetConversationOptions :one
 SELECT conversation_options FROM conversations
 WHERE conversation_id = ?;
@@ -0,0 +1,27 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, name, parent_id)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetFolder :one
+SELECT * FROM folders
+WHERE id = ?;
+
+-- name: ListFolders :many
+SELECT * FROM folders
+ORDER BY name, id;
+
+-- name: UpdateFolder :one
+UPDATE folders
+SET name = ?, parent_id = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
+-- name: ReparentFolderChildren :exec
+UPDATE folders
+SET parent_id = sqlc.narg(new_parent_id), updated_at = CURRENT_TIMESTAMP
+WHERE parent_id = sqlc.arg(folder_id);
+
+-- name: DeleteFolder :exec
+DELETE FROM folders
+WHERE id = ?;
@@ -50,4 +50,15 @@ SELECT request_body FROM llm_requests WHERE id = ?;
 -- name: GetLLMResponseBody :one
 SELECT response_body FROM llm_requests WHERE id = ?;
 
-
+-- name: ListLLMRequestsForConversation :many
+SELECT
+    id,
+    model,
+    provider,
+    status_code,
+    error,
+    duration_ms,
+    created_at
+FROM llm_requests
+WHERE conversation_id = ?
+ORDER BY id ASC;
@@ -22,6 +22,22 @@ SELECT * FROM messages
 WHERE conversation_id = ? AND excluded_from_context = FALSE
 ORDER BY sequence_id ASC;
 
+-- name: ListMessagesPage :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+  AND sequence_id > sqlc.arg(after_sequence_id)
+  AND sequence_id < sqlc.arg(before_sequence_id)
+ORDER BY sequence_id ASC
+LIMIT sqlc.arg(limit);
+
+-- name: ListMessagesPageDesc :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+  AND sequence_id > sqlc.arg(after_sequence_id)
+  AND sequence_id < sqlc.arg(before_sequence_id)
+ORDER BY sequence_id DESC
+LIMIT sqlc.arg(limit);
+
 -- name: ListMessagesPaginated :many
 SELECT * FROM messages
 WHERE conversation_id = ?
@@ -39,6 +55,12 @@ WHERE conversation_id = ?
 ORDER BY sequence_id DESC
 LIMIT 1;
 
+-- name: GetLatestMessageWithUsage :one
+SELECT * FROM messages
+WHERE conversation_id = ? AND usage_data IS NOT NULL
+ORDER BY sequence_id DESC
+LIMIT 1;
+
 -- name: DeleteMessage :exec
 DELETE FROM messages
 WHERE message_id = ?;
@@ -47,6 +69,10 @@ WHERE message_id = ?;
 DELETE FROM messages
 WHERE conversation_id = ?;
 
+-- name: DeleteMessagesAfter :execrows
+DELETE FROM messages
+WHERE conversation_id = ? AND sequence_id > ?;
+
 -- name: CountMessagesInConversation :one
 SELECT COUNT(*) FROM messages
 WHERE conversation_id = ?;
@@ -0,0 +1,5 @@
+-- Add system_note column to conversations
+-- A user-set standing instruction that is prepended to the system prompt
+-- NULL means no note
+
+ALTER TABLE conversations ADD COLUMN system_note TEXT;
@@ -0,0 +1,5 @@
+-- Add slug_user_set column to conversations
+-- TRUE when the user renamed the conversation, so the slug is not
+-- regenerated automatically (e.g. when the first message is edited)
+
+ALTER TABLE conversations ADD COLUMN slug_user_set BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,13 @@
+-- Conversation tags
+-- User-chosen labels for grouping conversations; a conversation has each tag at most once
+
+CREATE TABLE conversation_tags (
+    conversation_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_id, tag),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(conversation_id) ON DELETE CASCADE
+);
+
+-- Index on tag for filtering conversations by tag
+CREATE INDEX idx_conversation_tags_tag ON conversation_tags(tag);
@@ -0,0 +1,5 @@
+-- Add forked_from column to conversations
+-- The conversation a fork copied its messages from
+-- NULL means the conversation is not a fork
+
+ALTER TABLE conversations ADD COLUMN forked_from TEXT;
@@ -0,0 +1,8 @@
+-- Add running token totals to conversations
+-- Summed from the usage of each message as it is recorded:
+-- input_tokens_total counts uncached input, including cache writes;
+-- cached_tokens_total counts input read from the prompt cache
+
+ALTER TABLE conversations ADD COLUMN input_tokens_total INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE conversations ADD COLUMN output_tokens_total INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE conversations ADD COLUMN cached_tokens_total INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Add pinned column to conversations
+-- Pinned conversations are listed before all others
+ALTER TABLE conversations ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,18 @@
+-- Conversation folders
+-- Folders nest through parent_id (NULL for a top-level folder); each
+-- conversation is in at most one folder
+
+CREATE TABLE folders (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    parent_id TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (parent_id) REFERENCES folders(id)
+);
+
+CREATE INDEX idx_folders_parent_id ON folders(parent_id);
+
+ALTER TABLE conversations ADD COLUMN folder_id TEXT REFERENCES folders(id);
+
+CREATE INDEX idx_conversations_folder_id ON conversations(folder_id);
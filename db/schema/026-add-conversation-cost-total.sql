@@ -0,0 +1,14 @@
+-- Add the running cost to conversations
+-- Summed from the cost_usd of each message's usage as it is recorded, which
+-- is priced at the model that produced the message. Messages recorded before
+-- this stored a cost only when the gateway reported one, so older
+-- conversations only count those.
+
+ALTER TABLE conversations ADD COLUMN cost_usd_total REAL NOT NULL DEFAULT 0;
+
+UPDATE conversations
+SET cost_usd_total = (
+    SELECT COALESCE(SUM(json_extract(m.usage_data, '$.cost_usd')), 0)
+    FROM messages m
+    WHERE m.conversation_id = conversations.conversation_id AND m.usage_data IS NOT NULL
+);
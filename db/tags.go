@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+
+	"shelley.exe.dev/db/generated"
+)
+
+// AddTag tags a conversation. Adding a tag the conversation already has is a
+// no-op.
+func (db *DB) AddTag(ctx context.Context, conversationID, tag string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.AddConversationTag(ctx, generated.AddConversationTagParams{
+			ConversationID: conversationID,
+			Tag:            tag,
+		})
+	})
+}
+
+// RemoveTag removes a tag from a conversation, if it has it.
+func (db *DB) RemoveTag(ctx context.Context, conversationID, tag string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.RemoveConversationTag(ctx, generated.RemoveConversationTagParams{
+			ConversationID: conversationID,
+			Tag:            tag,
+		})
+	})
+}
+
+// ListTags returns a conversation's tags in alphabetical order.
+func (db *DB) ListTags(ctx context.Context, conversationID string) ([]string, error) {
+	var tags []string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		tags, err = q.ListConversationTags(ctx, conversationID)
+		return err
+	})
+	return tags, err
+}
+
+// GetConversationTags returns the tags of every tagged conversation, keyed by
+// conversation ID.
+func (db *DB) GetConversationTags(ctx context.Context) (map[string][]string, error) {
+	var rows []generated.ListAllConversationTagsRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		rows, err = q.ListAllConversationTags(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string][]string)
+	for _, r := range rows {
+		tags[r.ConversationID] = append(tags[r.ConversationID], r.Tag)
+	}
+	return tags, nil
+}
+
+// ListConversationsByTag lists the unarchived top-level conversations with a
+// tag, most recently updated first.
+func (db *DB) ListConversationsByTag(ctx context.Context, tag string, limit, offset int64) ([]generated.Conversation, error) {
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListConversationsByTag(ctx, generated.ListConversationsByTagParams{
+			Tag:    tag,
+			Limit:  limit,
+			Offset: offset,
+		})
+		return err
+	})
+	return conversations, err
+}
+
+// CountConversationsByTag counts the conversations ListConversationsByTag
+// would list.
+func (db *DB) CountConversationsByTag(ctx context.Context, tag string) (int64, error) {
+	var count int64
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		count, err = q.CountConversationsByTag(ctx, tag)
+		return err
+	})
+	return count, err
+}
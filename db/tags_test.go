@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTags_AddIsIdempotent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conv, err := db.CreateConversation(ctx, stringPtr("tagged"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatalf("CreateConversation() error = %v", err)
+	}
+
+	for _, tag := range []string{"work", "urgent", "work"} {
+		if err := db.AddTag(ctx, conv.ConversationID, tag); err != nil {
+			t.Fatalf("AddTag(%q) error = %v", tag, err)
+		}
+	}
+
+	tags, err := db.ListTags(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if want := []string{"urgent", "work"}; !slices.Equal(tags, want) {
+		t.Errorf("ListTags() = %v, want %v", tags, want)
+	}
+
+	if err := db.RemoveTag(ctx, conv.ConversationID, "work"); err != nil {
+		t.Fatalf("RemoveTag() error = %v", err)
+	}
+	// Removing a tag the conversation doesn't have is not an error.
+	if err := db.RemoveTag(ctx, conv.ConversationID, "work"); err != nil {
+		t.Fatalf("RemoveTag() of a missing tag error = %v", err)
+	}
+	tags, err = db.ListTags(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if want := []string{"urgent"}; !slices.Equal(tags, want) {
+		t.Errorf("ListTags() after RemoveTag = %v, want %v", tags, want)
+	}
+}
+
+func TestTags_ListConversationsByTag(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ids := make(map[string]string)
+	for _, slug := range []string{"first", "second", "third", "archived"} {
+		conv, err := db.CreateConversation(ctx, stringPtr(slug), true, nil, nil, ConversationOptions{})
+		if err != nil {
+			t.Fatalf("CreateConversation(%q) error = %v", slug, err)
+		}
+		ids[slug] = conv.ConversationID
+	}
+	for _, slug := range []string{"first", "third", "archived"} {
+		if err := db.AddTag(ctx, ids[slug], "work"); err != nil {
+			t.Fatalf("AddTag() error = %v", err)
+		}
+	}
+	if err := db.AddTag(ctx, ids["second"], "home"); err != nil {
+		t.Fatalf("AddTag() error = %v", err)
+	}
+	if _, err := db.ArchiveConversation(ctx, ids["archived"]); err != nil {
+		t.Fatalf("ArchiveConversation() error = %v", err)
+	}
+
+	conversations, err := db.ListConversationsByTag(ctx, "work", 10, 0)
+	if err != nil {
+		t.Fatalf("ListConversationsByTag() error = %v", err)
+	}
+	var got []string
+	for _, c := range conversations {
+		got = append(got, *c.Slug)
+	}
+	slices.Sort(got)
+	if want := []string{"first", "third"}; !slices.Equal(got, want) {
+		t.Errorf("ListConversationsByTag() = %v, want %v", got, want)
+	}
+
+	count, err := db.CountConversationsByTag(ctx, "work")
+	if err != nil {
+		t.Fatalf("CountConversationsByTag() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountConversationsByTag() = %d, want 2", count)
+	}
+
+	all, err := db.GetConversationTags(ctx)
+	if err != nil {
+		t.Fatalf("GetConversationTags() error = %v", err)
+	}
+	if !slices.Equal(all[ids["second"]], []string{"home"}) {
+		t.Errorf("GetConversationTags()[second] = %v, want [home]", all[ids["second"]])
+	}
+
+	// Deleting a conversation drops its tags.
+	if err := db.DeleteConversation(ctx, ids["first"]); err != nil {
+		t.Fatalf("DeleteConversation() error = %v", err)
+	}
+	if count, err := db.CountConversationsByTag(ctx, "work"); err != nil || count != 1 {
+		t.Errorf("CountConversationsByTag() after delete = %d, %v; want 1", count, err)
+	}
+}
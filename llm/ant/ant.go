@@ -88,9 +88,9 @@ func (s *Service) TokenContextWindow() int {
 	}
 }
 
-// maxOutputTokens returns the maximum allowed output tokens for the configured model.
+// MaxOutputTokens returns the maximum allowed output tokens for the configured model.
 // Source: https://models.dev/api.json (Anthropic provider, limit.output)
-func (s *Service) maxOutputTokens() int {
+func (s *Service) MaxOutputTokens() int {
 	model := s.Model
 	if model == "" {
 		model = DefaultModel
@@ -106,6 +106,26 @@ func (s *Service) maxOutputTokens() int {
 	}
 }
 
+// ValidateParams rejects params the API refuses while thinking is enabled:
+// a temperature other than 1, top_p below 0.95, and max_tokens that leave no
+// room beyond the thinking budget.
+func (s *Service) ValidateParams(p llm.Params) error {
+	if s.ThinkingLevel == llm.ThinkingLevelOff {
+		return nil
+	}
+	if p.Temperature != nil && *p.Temperature != 1 {
+		return fmt.Errorf("temperature must be 1 while thinking is enabled, got %g", *p.Temperature)
+	}
+	if p.TopP != nil && *p.TopP < 0.95 {
+		return fmt.Errorf("top_p must be at least 0.95 while thinking is enabled, got %g", *p.TopP)
+	}
+	if budget := s.ThinkingLevel.ThinkingBudgetTokens(); p.MaxTokens > 0 && p.MaxTokens <= budget &&
+		!useAdaptiveThinking(cmp.Or(s.Model, DefaultModel)) {
+		return fmt.Errorf("max_tokens must exceed the thinking budget of %d, got %d", budget, p.MaxTokens)
+	}
+	return nil
+}
+
 // MaxImageDimension returns the maximum allowed image dimension for multi-image requests.
 // Anthropic enforces a 2000 pixel limit when multiple images are in a conversation.
 func (s *Service) MaxImageDimension() int {
@@ -282,9 +302,9 @@ type request struct {
 	ToolChoice    *toolChoice     `json:"tool_choice,omitempty"`
 	Thinking      *thinking       `json:"thinking,omitempty"`
 	OutputConfig  *outputConfig   `json:"output_config,omitempty"`
-	Temperature   float64         `json:"temperature,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
 	TopK          int             `json:"top_k,omitempty"`
-	TopP          float64         `json:"top_p,omitempty"`
+	TopP          *float64        `json:"top_p,omitempty"`
 	StopSequences []string        `json:"stop_sequences,omitempty"`
 	// Messages comes last since it grows with each request in a conversation
 	Messages []message `json:"messages"`
@@ -478,7 +498,7 @@ func fromLLMSystem(s llm.SystemContent) systemContent {
 
 func (s *Service) fromLLMRequest(r *llm.Request) *request {
 	model := cmp.Or(s.Model, DefaultModel)
-	maxTokens := cmp.Or(s.MaxTokens, maxOutputTokens(model))
+	maxTokens := cmp.Or(r.Params.MaxTokens, s.MaxTokens, maxOutputTokens(model))
 
 	// Find the last assistant message index so we can strip thinking blocks
 	// from all earlier assistant messages. The Anthropic API validates thinking
@@ -507,12 +527,15 @@ func (s *Service) fromLLMRequest(r *llm.Request) *request {
 		}
 	}
 	req := &request{
-		Model:      model,
-		Messages:   messages,
-		MaxTokens:  maxTokens,
-		ToolChoice: fromLLMToolChoice(r.ToolChoice),
-		Tools:      mapped(r.Tools, fromLLMTool),
-		System:     mapped(r.System, fromLLMSystem),
+		Model:         model,
+		Messages:      messages,
+		MaxTokens:     maxTokens,
+		ToolChoice:    fromLLMToolChoice(r.ToolChoice),
+		Tools:         mapped(r.Tools, fromLLMTool),
+		System:        mapped(r.System, fromLLMSystem),
+		Temperature:   r.Params.Temperature,
+		TopP:          r.Params.TopP,
+		StopSequences: r.Params.StopSequences,
 	}
 
 	// Enable thinking if a thinking level is set
@@ -533,7 +556,7 @@ func (s *Service) fromLLMRequest(r *llm.Request) *request {
 	}
 
 	// Cap max_tokens at the model's maximum allowed output tokens
-	if limit := s.maxOutputTokens(); req.MaxTokens > limit {
+	if limit := s.MaxOutputTokens(); req.MaxTokens > limit {
 		req.MaxTokens = limit
 		// Also cap the thinking budget if it exceeds the new max_tokens
 		if req.Thinking != nil && req.Thinking.BudgetTokens >= req.MaxTokens {
@@ -548,7 +571,7 @@ func (s *Service) fromLLMRequest(r *llm.Request) *request {
 // when the API rejects thinking signatures — e.g. after model version rotation.
 func (s *Service) fromLLMRequestStrippingAllThinking(r *llm.Request) *request {
 	model := cmp.Or(s.Model, DefaultModel)
-	maxTokens := cmp.Or(s.MaxTokens, maxOutputTokens(model))
+	maxTokens := cmp.Or(r.Params.MaxTokens, s.MaxTokens, maxOutputTokens(model))
 
 	var messages []message
 	for _, m := range r.Messages {
@@ -561,12 +584,15 @@ func (s *Service) fromLLMRequestStrippingAllThinking(r *llm.Request) *request {
 		}
 	}
 	req := &request{
-		Model:      model,
-		Messages:   messages,
-		MaxTokens:  maxTokens,
-		ToolChoice: fromLLMToolChoice(r.ToolChoice),
-		Tools:      mapped(r.Tools, fromLLMTool),
-		System:     mapped(r.System, fromLLMSystem),
+		Model:         model,
+		Messages:      messages,
+		MaxTokens:     maxTokens,
+		ToolChoice:    fromLLMToolChoice(r.ToolChoice),
+		Tools:         mapped(r.Tools, fromLLMTool),
+		System:        mapped(r.System, fromLLMSystem),
+		Temperature:   r.Params.Temperature,
+		TopP:          r.Params.TopP,
+		StopSequences: r.Params.StopSequences,
 	}
 
 	if s.ThinkingLevel != llm.ThinkingLevelOff {
@@ -582,7 +608,7 @@ func (s *Service) fromLLMRequestStrippingAllThinking(r *llm.Request) *request {
 		}
 	}
 
-	if limit := s.maxOutputTokens(); req.MaxTokens > limit {
+	if limit := s.MaxOutputTokens(); req.MaxTokens > limit {
 		req.MaxTokens = limit
 		if req.Thinking != nil && req.Thinking.BudgetTokens >= req.MaxTokens {
 			req.Thinking.BudgetTokens = req.MaxTokens - 1024
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -711,6 +712,68 @@ func TestFromLLMRequest(t *testing.T) {
 	}
 }
 
+func TestFromLLMRequestParams(t *testing.T) {
+	s := &Service{Model: Claude46Sonnet}
+	temp, topP := 0.0, 0.9
+	req := &llm.Request{
+		Messages: []llm.Message{{
+			Role:    llm.MessageRoleUser,
+			Content: []llm.Content{{Type: llm.ContentTypeText, Text: "Hello"}},
+		}},
+		Params: llm.Params{Temperature: &temp, TopP: &topP, MaxTokens: 2000, StopSequences: []string{"END"}},
+	}
+
+	got := s.fromLLMRequest(req)
+	if got.MaxTokens != 2000 {
+		t.Errorf("MaxTokens = %d, want 2000", got.MaxTokens)
+	}
+	if got.Temperature == nil || *got.Temperature != 0 {
+		t.Errorf("Temperature = %v, want 0", got.Temperature)
+	}
+	if got.TopP == nil || *got.TopP != 0.9 {
+		t.Errorf("TopP = %v, want 0.9", got.TopP)
+	}
+	if !slices.Equal(got.StopSequences, []string{"END"}) {
+		t.Errorf("StopSequences = %v, want [END]", got.StopSequences)
+	}
+
+	// Without params the model defaults apply and nothing is sent.
+	req.Params = llm.Params{}
+	got = s.fromLLMRequest(req)
+	if got.MaxTokens != maxOutputTokens(Claude46Sonnet) {
+		t.Errorf("MaxTokens = %d, want model default %d", got.MaxTokens, maxOutputTokens(Claude46Sonnet))
+	}
+	if got.Temperature != nil || got.TopP != nil || got.StopSequences != nil {
+		t.Errorf("expected no temperature/top_p/stop_sequences, got %v/%v/%v", got.Temperature, got.TopP, got.StopSequences)
+	}
+}
+
+func TestValidateParamsWithThinking(t *testing.T) {
+	one, low, high := 1.0, 0.5, 0.95
+	tests := []struct {
+		name    string
+		level   llm.ThinkingLevel
+		params  llm.Params
+		wantErr bool
+	}{
+		{name: "thinking off", level: llm.ThinkingLevelOff, params: llm.Params{Temperature: &low, TopP: &low, MaxTokens: 100}},
+		{name: "defaults", level: llm.ThinkingLevelMedium},
+		{name: "temperature 1", level: llm.ThinkingLevelMedium, params: llm.Params{Temperature: &one, TopP: &high}},
+		{name: "temperature", level: llm.ThinkingLevelMedium, params: llm.Params{Temperature: &low}, wantErr: true},
+		{name: "top_p", level: llm.ThinkingLevelMedium, params: llm.Params{TopP: &low}, wantErr: true},
+		{name: "max_tokens within budget", level: llm.ThinkingLevelMedium, params: llm.Params{MaxTokens: 8192}, wantErr: true},
+		{name: "max_tokens above budget", level: llm.ThinkingLevelMedium, params: llm.Params{MaxTokens: 8193}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{Model: Claude46Sonnet, ThinkingLevel: tt.level}
+			if err := llm.ValidateParams(s, tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestMaxOutputTokensCapping(t *testing.T) {
 	simpleReq := &llm.Request{
 		Messages: []llm.Message{{
@@ -751,7 +814,7 @@ func TestMaxOutputTokensCapping(t *testing.T) {
 	}
 }
 
-// TestMaxOutputTokensMatchModelsDevAPI validates our maxOutputTokens() values against
+// TestMaxOutputTokensMatchModelsDevAPI validates our MaxOutputTokens() values against
 // the live models.dev API (same pattern as llmpricing.TestPricingMatchesModelsDev).
 func TestMaxOutputTokensMatchModelsDevAPI(t *testing.T) {
 	resp, err := http.Get("https://models.dev/api.json")
@@ -794,9 +857,9 @@ func TestMaxOutputTokensMatchModelsDevAPI(t *testing.T) {
 			continue
 		}
 		svc := &Service{Model: model}
-		got := svc.maxOutputTokens()
+		got := svc.MaxOutputTokens()
 		if got != apiModel.Limit.Output {
-			t.Errorf("%s: maxOutputTokens() = %d, models.dev says %d", model, got, apiModel.Limit.Output)
+			t.Errorf("%s: MaxOutputTokens() = %d, models.dev says %d", model, got, apiModel.Limit.Output)
 		}
 	}
 }
@@ -308,6 +308,15 @@ func (s *Service) buildGeminiRequest(req *llm.Request) (*gemini.Request, error)
 		}
 	}
 
+	if p := req.Params; !p.IsZero() {
+		gemReq.GenerationConfig = &gemini.GenerationConfig{
+			Temperature:     p.Temperature,
+			TopP:            p.TopP,
+			MaxOutputTokens: p.MaxTokens,
+			StopSequences:   p.StopSequences,
+		}
+	}
+
 	return gemReq, nil
 }
 
@@ -3,11 +3,16 @@ package imageutil
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 // IsHEIC checks if data is a HEIC/HEIF image based on file magic.
 // HEIC files are ISO Base Media File Format containers with specific brand codes.
+// AVIF uses the same container but isn't matched: ConvertHEICToPNG is only
+// meant for HEIC.
 func IsHEIC(data []byte) bool {
 	if len(data) < 12 {
 		return false
@@ -19,22 +24,88 @@ func IsHEIC(data []byte) bool {
 	}
 	brand := string(data[8:12])
 	switch brand {
-	case "heic", "heix", "hevc", "hevx", "mif1", "msf1", "avif":
+	case "heic", "heix", "hevc", "hevx", "mif1", "msf1":
 		return true
 	}
 	return false
 }
 
-// ConvertHEICToPNG converts HEIC image data to PNG using ImageMagick's convert command.
-// Returns the PNG data or an error if conversion fails.
+// heicDecoder is one way of converting HEIC data to PNG.
+type heicDecoder struct {
+	name    string
+	convert func(data []byte) ([]byte, error)
+}
+
+// heicDecoders are tried in order by ConvertHEICToPNG. libheif comes first:
+// ImageMagick is often built without a HEIC delegate, and then fails with a
+// confusing "no decode delegate" error.
+var heicDecoders = []heicDecoder{
+	{"libheif", convertHEICWithLibheif},
+	{"ImageMagick", convertHEICWithImageMagick},
+}
+
+// ConvertHEICToPNG converts HEIC image data to PNG, using libheif's command
+// line decoder if it is installed and ImageMagick's convert otherwise. There
+// is no in-process decoder, so one of them must be installed (see README).
+// Returns the PNG data, or an error saying why each decoder failed.
 func ConvertHEICToPNG(data []byte) ([]byte, error) {
+	if !IsHEIC(data) {
+		return nil, fmt.Errorf("convert heic to png: not a HEIC image")
+	}
+	var failures []string
+	for _, d := range heicDecoders {
+		out, err := d.convert(data)
+		if err == nil {
+			return out, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", d.name, err))
+	}
+	return nil, fmt.Errorf("convert heic to png: install libheif (heif-dec) or ImageMagick with HEIC support; %s", strings.Join(failures, "; "))
+}
+
+// convertHEICWithLibheif runs libheif's heif-dec, or heif-convert as older
+// versions call it. They only work on files, so data goes through a
+// temporary directory.
+func convertHEICWithLibheif(data []byte) ([]byte, error) {
+	var bin string
+	for _, name := range []string{"heif-dec", "heif-convert"} {
+		if path, err := exec.LookPath(name); err == nil {
+			bin = path
+			break
+		}
+	}
+	if bin == "" {
+		return nil, fmt.Errorf("heif-dec not found in $PATH")
+	}
+
+	dir, err := os.MkdirTemp("", "shelley-heic-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in.heic"), filepath.Join(dir, "out.png")
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(bin, in, out)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return os.ReadFile(out)
+}
+
+// convertHEICWithImageMagick pipes data through ImageMagick's convert.
+func convertHEICWithImageMagick(data []byte) ([]byte, error) {
 	cmd := exec.Command("convert", "heic:-", "png:-")
 	cmd.Stdin = bytes.NewReader(data)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("convert heic to png: %w: %s", err, stderr.String())
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
 	}
 	return stdout.Bytes(), nil
 }
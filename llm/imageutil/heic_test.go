@@ -2,8 +2,13 @@ package imageutil
 
 import (
 	"bytes"
+	"fmt"
 	"image/png"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -18,7 +23,7 @@ func TestIsHEIC(t *testing.T) {
 		{"heic brand", []byte{0, 0, 0, 0, 'f', 't', 'y', 'p', 'h', 'e', 'i', 'c'}, true},
 		{"heix brand", []byte{0, 0, 0, 0, 'f', 't', 'y', 'p', 'h', 'e', 'i', 'x'}, true},
 		{"mif1 brand", []byte{0, 0, 0, 0, 'f', 't', 'y', 'p', 'm', 'i', 'f', '1'}, true},
-		{"avif brand", []byte{0, 0, 0, 0, 'f', 't', 'y', 'p', 'a', 'v', 'i', 'f'}, true},
+		{"avif brand", []byte{0, 0, 0, 0, 'f', 't', 'y', 'p', 'a', 'v', 'i', 'f'}, false},
 		{"not ftyp", []byte{0, 0, 0, 0, 'x', 'x', 'x', 'x', 'h', 'e', 'i', 'c'}, false},
 		{"unknown brand", []byte{0, 0, 0, 0, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm'}, false},
 		{"png", []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0, 0, 0, 0}, false},
@@ -55,3 +60,99 @@ func TestConvertHEICToPNG(t *testing.T) {
 		t.Fatalf("result is not valid PNG: %v", err)
 	}
 }
+
+func TestConvertHEICWithLibheif(t *testing.T) {
+	// heif-enc ships alongside heif-dec in libheif's examples
+	enc, err := exec.LookPath("heif-enc")
+	if err != nil {
+		t.Skip("heif-enc not installed")
+	}
+	_, errDec := exec.LookPath("heif-dec")
+	_, errConvert := exec.LookPath("heif-convert")
+	if errDec != nil && errConvert != nil {
+		t.Skip("heif-dec not installed")
+	}
+
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out.heic")
+	if err := os.WriteFile(in, createTestPNG(t, 64, 48), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := exec.Command(enc, "-o", out, in).CombinedOutput(); err != nil {
+		t.Skipf("heif-enc can't encode HEIC here: %v: %s", err, output)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHEIC(data) {
+		t.Fatal("heif-enc output should be detected as HEIC")
+	}
+
+	pngData, err := convertHEICWithLibheif(data)
+	if err != nil {
+		t.Fatalf("convertHEICWithLibheif failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatalf("result is not valid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 48 {
+		t.Errorf("decoded image is %dx%d, want 64x48", b.Dx(), b.Dy())
+	}
+}
+
+func TestConvertHEICToPNGFallback(t *testing.T) {
+	saved := heicDecoders
+	t.Cleanup(func() { heicDecoders = saved })
+
+	heic := []byte{0, 0, 0, 0, 'f', 't', 'y', 'p', 'h', 'e', 'i', 'c'}
+	var tried []string
+	fail := func(name string) heicDecoder {
+		return heicDecoder{name, func([]byte) ([]byte, error) {
+			tried = append(tried, name)
+			return nil, fmt.Errorf("%s is broken", name)
+		}}
+	}
+	succeed := func(name string) heicDecoder {
+		return heicDecoder{name, func([]byte) ([]byte, error) {
+			tried = append(tried, name)
+			return []byte(name), nil
+		}}
+	}
+
+	// The fallback is used when the primary decoder fails
+	heicDecoders = []heicDecoder{fail("primary"), succeed("fallback")}
+	out, err := ConvertHEICToPNG(heic)
+	if err != nil {
+		t.Fatalf("ConvertHEICToPNG failed: %v", err)
+	}
+	if string(out) != "fallback" || !slices.Equal(tried, []string{"primary", "fallback"}) {
+		t.Errorf("expected the fallback's output after trying both, got %q after %v", out, tried)
+	}
+
+	// The fallback isn't tried when the primary decoder works
+	tried = nil
+	heicDecoders = []heicDecoder{succeed("primary"), succeed("fallback")}
+	if out, err := ConvertHEICToPNG(heic); err != nil || string(out) != "primary" || len(tried) != 1 {
+		t.Errorf("expected only the primary to run, got %q, %v after %v", out, err, tried)
+	}
+
+	// One error reports both failures
+	heicDecoders = []heicDecoder{fail("primary"), fail("fallback")}
+	_, err = ConvertHEICToPNG(heic)
+	if err == nil {
+		t.Fatal("expected an error when every decoder fails")
+	}
+	for _, want := range []string{"primary is broken", "fallback is broken"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got %v", want, err)
+		}
+	}
+
+	// Data that isn't HEIC is rejected before any decoder runs
+	tried = nil
+	if _, err := ConvertHEICToPNG([]byte("\x89PNG\r\n\x1a\n0000")); err == nil || len(tried) != 0 {
+		t.Errorf("expected non-HEIC data to be rejected up front, got %v after %v", err, tried)
+	}
+}
@@ -0,0 +1,180 @@
+package imageutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// Orientation returns the EXIF orientation (1-8) of JPEG or PNG data, or 1,
+// meaning no change, if it has none. Phones store photos as the sensor saw
+// them and set this to say how to turn them upright; HEIC photos converted
+// to PNG can carry it in an eXIf chunk.
+func Orientation(data []byte) int {
+	var exif []byte
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		exif = jpegEXIF(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		exif = pngEXIF(data)
+	}
+	if o := tiffOrientation(exif); o >= 1 && o <= 8 {
+		return o
+	}
+	return 1
+}
+
+// FixOrientation turns JPEG or PNG data upright according to its EXIF
+// orientation, re-encoding it in the same format. Data that is already
+// upright, or has no orientation, is returned unchanged.
+func FixOrientation(data []byte) (fixed []byte, didFix bool, err error) {
+	orientation := Orientation(data)
+	if orientation == 1 {
+		return data, false, nil
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	upright := ApplyOrientation(img, orientation)
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, upright, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, upright)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode rotated image: %w", err)
+	}
+	return buf.Bytes(), true, nil
+}
+
+// ApplyOrientation returns img rotated and flipped as EXIF orientation
+// says it should be displayed. Orientation 1 and unknown values return img.
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	// from maps a pixel of the upright image to the stored one
+	var from func(x, y int) (int, int)
+	dw, dh := w, h
+	switch orientation {
+	case 2: // mirrored
+		from = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3: // upside down
+		from = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // upside down and mirrored
+		from = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5: // transposed
+		from = func(x, y int) (int, int) { return y, x }
+	case 6: // needs turning 90° clockwise
+		from = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7: // transversed
+		from = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8: // needs turning 90° counterclockwise
+		from = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := from(x, y)
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// jpegEXIF returns the TIFF structure in a JPEG's EXIF APP1 segment, or nil.
+func jpegEXIF(data []byte) []byte {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return nil
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xff: // fill byte
+			i++
+			continue
+		case marker == 0x01 || marker >= 0xd0 && marker <= 0xd7: // no payload
+			i += 2
+			continue
+		case marker == 0xd9 || marker == 0xda: // end of image, start of scan
+			return nil
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end < i+4 || end > len(data) {
+			return nil
+		}
+		if payload := data[i+4 : end]; marker == 0xe1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			return payload[6:]
+		}
+		i = end
+	}
+	return nil
+}
+
+// pngEXIF returns the contents of a PNG's eXIf chunk, or nil.
+func pngEXIF(data []byte) []byte {
+	for i := 8; i+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		typ := string(data[i+4 : i+8])
+		if n < 0 || i+12+n > len(data) || typ == "IEND" {
+			return nil
+		}
+		if typ == "eXIf" {
+			return data[i+8 : i+8+n]
+		}
+		i += 12 + n
+	}
+	return nil
+}
+
+// tiffOrientation returns the orientation tag from IFD0 of an EXIF TIFF
+// structure, or 0 if there isn't one.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		const tagOrientation, typeShort = 0x0112, 3
+		if order.Uint16(tiff[entry:]) == tagOrientation && order.Uint16(tiff[entry+2:]) == typeShort {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package imageutil
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// withEXIFOrientation inserts an EXIF APP1 segment with the given
+// orientation after a JPEG's SOI marker.
+func withEXIFOrientation(jpegData []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big-endian header, IFD0 at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xff, 0xe1, 0, byte(len(payload) + 2)}, payload...)
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestFixOrientation(t *testing.T) {
+	// 32x16: red on top, blue at the bottom
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for y := range 16 {
+		for x := range 32 {
+			c := color.NRGBA{255, 0, 0, 255}
+			if y >= 8 {
+				c = color.NRGBA{0, 0, 255, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("Failed to create test JPEG image: %v", err)
+	}
+
+	plain := buf.Bytes()
+	if got := Orientation(plain); got != 1 {
+		t.Errorf("Orientation() without EXIF = %d, want 1", got)
+	}
+	fixed, didFix, err := FixOrientation(plain)
+	if err != nil || didFix || !bytes.Equal(fixed, plain) {
+		t.Errorf("FixOrientation() without EXIF = %v, %v; want the data unchanged", didFix, err)
+	}
+
+	data := withEXIFOrientation(plain, 6)
+	if got := Orientation(data); got != 6 {
+		t.Fatalf("Orientation() = %d, want 6", got)
+	}
+	fixed, didFix, err = FixOrientation(data)
+	if err != nil {
+		t.Fatalf("FixOrientation() error = %v", err)
+	}
+	if !didFix {
+		t.Error("Expected FixOrientation() to rotate the image")
+	}
+	out, format, err := image.Decode(bytes.NewReader(fixed))
+	if err != nil {
+		t.Fatalf("Failed to decode rotated image: %v", err)
+	}
+	if format != "jpeg" {
+		t.Errorf("Rotated image format = %s, want jpeg", format)
+	}
+	if b := out.Bounds(); b.Dx() != 16 || b.Dy() != 32 {
+		t.Fatalf("Rotated image is %dx%d, want 16x32", b.Dx(), b.Dy())
+	}
+
+	// Turned 90° clockwise, the top (red) is on the right
+	isRed := func(c color.Color) bool {
+		r, _, b, _ := c.RGBA()
+		return r > 0xc000 && b < 0x4000
+	}
+	isBlue := func(c color.Color) bool {
+		r, _, b, _ := c.RGBA()
+		return b > 0xc000 && r < 0x4000
+	}
+	if c := out.At(12, 16); !isRed(c) {
+		t.Errorf("Expected red on the right, got %v", c)
+	}
+	if c := out.At(3, 16); !isBlue(c) {
+		t.Errorf("Expected blue on the left, got %v", c)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 3x2 image whose pixels are numbered in their red channel:
+	//   1 2 3
+	//   4 5 6
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := range 6 {
+		img.SetNRGBA(i%3, i/3, color.NRGBA{uint8(i + 1), 0, 0, 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{2, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{3, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{4, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{5, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{6, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{7, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		{8, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+	}
+	for _, tt := range tests {
+		out := ApplyOrientation(img, tt.orientation)
+		b := out.Bounds()
+		if b.Dx() != len(tt.want[0]) || b.Dy() != len(tt.want) {
+			t.Errorf("Orientation %d: got %dx%d", tt.orientation, b.Dx(), b.Dy())
+			continue
+		}
+		for y, row := range tt.want {
+			for x, want := range row {
+				if got := color.NRGBAModel.Convert(out.At(x, y)).(color.NRGBA).R; got != want {
+					t.Errorf("Orientation %d: pixel (%d, %d) = %d, want %d", tt.orientation, x, y, got, want)
+				}
+			}
+		}
+	}
+}
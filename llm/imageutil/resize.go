@@ -3,17 +3,23 @@ package imageutil
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
+	_ "image/gif"
 	"image/jpeg"
 	"image/png"
 	"strings"
 
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
 // ResizeImage resizes an image if any dimension exceeds maxDimension.
-// Returns the resized image bytes and the format ("png" or "jpeg").
+// Returns the resized image bytes and the format ("png", "jpeg" or "webp",
+// matching the source; other formats are re-encoded as PNG). Lossy WebP is
+// re-encoded as JPEG, since our lossless WebP encoder would make it several
+// times larger.
 // If no resize is needed, returns the original data unchanged.
 func ResizeImage(data []byte, maxDimension int) (resized []byte, format string, didResize bool, err error) {
 	img, detectedFormat, err := image.Decode(bytes.NewReader(data))
@@ -29,26 +35,28 @@ func ResizeImage(data []byte, maxDimension int) (resized []byte, format string,
 		return data, detectedFormat, false, nil
 	}
 
-	// Calculate new dimensions preserving aspect ratio
-	newWidth, newHeight := width, height
-	if width > height {
-		newWidth = maxDimension
-		newHeight = height * maxDimension / width
-	} else {
-		newHeight = maxDimension
-		newWidth = width * maxDimension / height
-	}
+	newWidth, newHeight := fitWithin(width, height, maxDimension)
+
+	detectedFormat = strings.ToLower(detectedFormat)
+	lossyWebP := detectedFormat == "webp" && isLossyWebP(data)
 
 	// Create resized image
 	resizedImg := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	if lossyWebP {
+		// Transparent areas become white, since JPEG has no alpha
+		draw.Draw(resizedImg, resizedImg.Bounds(), image.White, image.Point{}, draw.Src)
+	}
 	draw.BiLinear.Scale(resizedImg, resizedImg.Bounds(), img, bounds, draw.Over, nil)
 
 	// Encode to the same format
 	var buf bytes.Buffer
-	switch strings.ToLower(detectedFormat) {
-	case "jpeg", "jpg":
+	switch {
+	case detectedFormat == "jpeg" || detectedFormat == "jpg" || lossyWebP:
 		err = jpeg.Encode(&buf, resizedImg, &jpeg.Options{Quality: 85})
 		format = "jpeg"
+	case detectedFormat == "webp":
+		err = EncodeWebP(&buf, resizedImg)
+		format = "webp"
 	default:
 		err = png.Encode(&buf, resizedImg)
 		format = "png"
@@ -60,3 +68,55 @@ func ResizeImage(data []byte, maxDimension int) (resized []byte, format string,
 
 	return buf.Bytes(), format, true, nil
 }
+
+// ErrImageTooLarge is returned by Thumbnail for images with more than
+// maxThumbnailPixels pixels.
+var ErrImageTooLarge = errors.New("image too large")
+
+// maxThumbnailPixels bounds the images Thumbnail decodes, since a small file
+// can declare dimensions that take gigabytes to decode. It is a var so tests
+// can lower it.
+var maxThumbnailPixels = 64 << 20
+
+// Thumbnail scales an image down to fit within maxDimension and encodes it
+// as a JPEG. Images already that small are only re-encoded; transparent
+// areas become white, since JPEG has no alpha.
+func Thumbnail(data []byte, maxDimension int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if int64(config.Width)*int64(config.Height) > int64(maxThumbnailPixels) {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxDimension || height > maxDimension {
+		width, height = fitWithin(width, height, maxDimension)
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(thumb, thumb.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.BiLinear.Scale(thumb, thumb.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fitWithin scales width and height, preserving the aspect ratio, so the
+// larger one is maxDimension. Neither drops below 1.
+func fitWithin(width, height, maxDimension int) (int, int) {
+	if width > height {
+		return maxDimension, max(1, height*maxDimension/width)
+	}
+	return max(1, width*maxDimension/height), maxDimension
+}
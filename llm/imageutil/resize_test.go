@@ -2,6 +2,7 @@ package imageutil
 
 import (
 	"bytes"
+	"errors"
 	"image"
 	"image/jpeg"
 	"image/png"
@@ -150,3 +151,55 @@ func TestResizeImageNoResizeNeeded(t *testing.T) {
 		t.Error("Expected original data when no resize needed")
 	}
 }
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name       string
+		width      int
+		height     int
+		maxDim     int
+		wantWidth  int
+		wantHeight int
+	}{
+		{"landscape", 800, 400, 200, 200, 100},
+		{"portrait", 300, 900, 150, 50, 150},
+		{"already small", 80, 60, 200, 80, 60},
+		{"thin strip", 2000, 3, 100, 100, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := createTestPNG(t, tt.width, tt.height)
+			thumb, err := Thumbnail(data, tt.maxDim)
+			if err != nil {
+				t.Fatalf("Thumbnail() error = %v", err)
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatalf("Failed to decode thumbnail: %v", err)
+			}
+			if format != "jpeg" {
+				t.Errorf("Thumbnail() format = %v, want jpeg", format)
+			}
+			if config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+				t.Errorf("Thumbnail() is %dx%d, want %dx%d", config.Width, config.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+
+	if _, err := Thumbnail([]byte("not an image"), 100); err == nil {
+		t.Error("Thumbnail() expected an error for invalid data")
+	}
+}
+
+func TestThumbnailRejectsLargeImages(t *testing.T) {
+	defer func(old int) { maxThumbnailPixels = old }(maxThumbnailPixels)
+	maxThumbnailPixels = 100 * 100
+
+	if _, err := Thumbnail(createTestPNG(t, 100, 100), 50); err != nil {
+		t.Fatalf("Thumbnail() error = %v at the pixel bound", err)
+	}
+	if _, err := Thumbnail(createTestPNG(t, 101, 100), 50); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Thumbnail() error = %v, want ErrImageTooLarge", err)
+	}
+}
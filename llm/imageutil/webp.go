@@ -0,0 +1,501 @@
+package imageutil
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+)
+
+// isLossyWebP reports whether data is a WebP image with lossy (VP8) rather
+// than lossless (VP8L) image data. In the extended format the image data
+// follows a VP8X chunk and optional ones such as ALPH.
+func isLossyWebP(data []byte) bool {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return false
+	}
+	for p := 12; p+8 <= len(data); {
+		size := binary.LittleEndian.Uint32(data[p+4 : p+8])
+		switch string(data[p : p+4]) {
+		case "VP8 ":
+			return true
+		case "VP8L":
+			return false
+		}
+		if uint64(size) > uint64(len(data)) {
+			return false
+		}
+		p += 8 + int(size) + int(size&1)
+	}
+	return false
+}
+
+// EncodeWebP writes img to w as a lossless WebP (VP8L) image.
+// golang.org/x/image/webp only decodes, so this is a small encoder of our
+// own: it uses the subtract-green transform and LZ77 backward references,
+// but no predictors, color cache or per-tile prefix codes. Output is larger
+// than libwebp's, but much smaller than raw pixels for screenshots.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return fmt.Errorf("webp: cannot encode a %dx%d image", width, height)
+	}
+
+	// ARGB pixels with green subtracted from red and blue
+	argb := make([]uint32, 0, width*height)
+	hasAlpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff {
+				hasAlpha = true
+			}
+			argb = append(argb, uint32(c.A)<<24|uint32(c.R-c.G)<<16|uint32(c.G)<<8|uint32(c.B-c.G))
+		}
+	}
+
+	tokens := lz77(argb, width)
+
+	var hist [5][]int
+	for i, size := range webpAlphabetSizes {
+		hist[i] = make([]int, size)
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			hist[webpGreen][t.argb>>8&0xff]++
+			hist[webpRed][t.argb>>16&0xff]++
+			hist[webpBlue][t.argb&0xff]++
+			hist[webpAlpha][t.argb>>24]++
+			continue
+		}
+		lengthSym, _, _ := prefixEncode(t.length)
+		distSym, _, _ := prefixEncode(t.dist)
+		hist[webpGreen][256+lengthSym]++
+		hist[webpDistance][distSym]++
+	}
+
+	bw := &bitWriter{}
+	bw.write(8, 0x2f) // VP8L signature
+	bw.write(14, uint32(width-1))
+	bw.write(14, uint32(height-1))
+	bw.write(1, b2u(hasAlpha))
+	bw.write(3, 0) // version
+	bw.write(1, 1) // a transform follows:
+	bw.write(2, 2) // subtract green
+	bw.write(1, 0) // no more transforms
+	bw.write(1, 0) // no color cache
+	bw.write(1, 0) // one set of prefix codes for the whole image
+
+	var codes [5]*prefixCode
+	for i := range codes {
+		codes[i] = newPrefixCode(hist[i], 15)
+		codes[i].writeTo(bw)
+	}
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[webpGreen].writeSymbol(bw, int(t.argb>>8&0xff))
+			codes[webpRed].writeSymbol(bw, int(t.argb>>16&0xff))
+			codes[webpBlue].writeSymbol(bw, int(t.argb&0xff))
+			codes[webpAlpha].writeSymbol(bw, int(t.argb>>24))
+			continue
+		}
+		sym, n, extra := prefixEncode(t.length)
+		codes[webpGreen].writeSymbol(bw, 256+sym)
+		bw.write(n, extra)
+		sym, n, extra = prefixEncode(t.dist)
+		codes[webpDistance].writeSymbol(bw, sym)
+		bw.write(n, extra)
+	}
+	data := bw.flush()
+
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// Indexes of the VP8L prefix codes, in the order they are written.
+const (
+	webpGreen = iota // green, plus backward reference lengths
+	webpRed
+	webpBlue
+	webpAlpha
+	webpDistance
+)
+
+var webpAlphabetSizes = [5]int{256 + 24, 256, 256, 256, 40}
+
+const (
+	lz77MinLength = 3
+	lz77MaxLength = 4096 // the largest the 24 length prefix codes can express
+	lz77HashBits  = 16
+
+	// distances are sent as distance codes 120 higher, which the 40
+	// distance prefix codes limit to 1<<20
+	lz77MaxDistance = 1<<20 - 120
+)
+
+// webpToken is a literal pixel, or a backward reference when length > 0.
+type webpToken struct {
+	argb   uint32
+	length int
+	dist   int // distance code
+}
+
+// lz77 greedily replaces runs of pixels seen before with backward
+// references. It tries the pixel to the left, the one above, and the last
+// position with the same next three pixels.
+func lz77(argb []uint32, width int) []webpToken {
+	var table [1 << lz77HashBits]int32 // position+1 by hash
+	hash := func(i int) uint32 {
+		h := argb[i]*0x9e3779b1 ^ argb[i+1]*0x85ebca77 ^ argb[i+2]*0xc2b2ae3d
+		return h >> (32 - lz77HashBits)
+	}
+	matchLen := func(i, j int) int {
+		n := 0
+		for i+n < len(argb) && n < lz77MaxLength && argb[i+n] == argb[j+n] {
+			n++
+		}
+		return n
+	}
+
+	var tokens []webpToken
+	for i := 0; i < len(argb); {
+		bestLen, bestDist := 0, 0
+		candidates := [3]int{i - 1, i - width, -1}
+		if i+lz77MinLength <= len(argb) {
+			h := hash(i)
+			candidates[2] = int(table[h]) - 1
+			table[h] = int32(i + 1)
+		}
+		for _, j := range candidates {
+			if j < 0 || i-j > lz77MaxDistance {
+				continue
+			}
+			if n := matchLen(i, j); n > bestLen {
+				bestLen, bestDist = n, i-j
+			}
+		}
+		if bestLen < lz77MinLength {
+			tokens = append(tokens, webpToken{argb: argb[i]})
+			i++
+			continue
+		}
+		tokens = append(tokens, webpToken{length: bestLen, dist: distanceCode(bestDist, width)})
+		// Index the skipped positions so later matches can find them
+		for k := i + 1; k < i+bestLen && k+lz77MinLength <= len(argb); k++ {
+			table[hash(k)] = int32(k + 1)
+		}
+		i += bestLen
+	}
+	return tokens
+}
+
+// distanceCode maps a distance in pixels to a VP8L distance code, using the
+// short codes for the pixel to the left and the one above.
+func distanceCode(dist, width int) int {
+	switch dist {
+	case width:
+		return 1
+	case 1:
+		return 2
+	}
+	return dist + 120
+}
+
+// prefixEncode splits a length or distance code (at least 1) into its
+// prefix symbol and the extra bits that follow it.
+func prefixEncode(v int) (symbol int, nExtra uint, extra uint32) {
+	n := v - 1
+	if n < 4 {
+		return n, 0, 0
+	}
+	hb := bits.Len(uint(n)) - 1
+	second := n >> (hb - 1) & 1
+	nExtra = uint(hb - 1)
+	return 2*hb + second, nExtra, uint32(n) & (1<<nExtra - 1)
+}
+
+// prefixCode is a canonical Huffman code over one alphabet.
+type prefixCode struct {
+	lengths []uint8  // code lengths as written in the header
+	codes   []uint32 // bit-reversed codes, ready to write LSB first
+	emit    []uint8  // bits written per symbol; 0 if it's the only one
+}
+
+// newPrefixCode builds a Huffman code for hist with codes of at most maxLen
+// bits. An unused alphabet gets a code with the single symbol 0.
+func newPrefixCode(hist []int, maxLen int) *prefixCode {
+	pc := &prefixCode{
+		lengths: huffmanLengths(hist, maxLen),
+		codes:   make([]uint32, len(hist)),
+		emit:    make([]uint8, len(hist)),
+	}
+	used := 0
+	for _, l := range pc.lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	if used == 0 {
+		pc.lengths[0] = 1
+		used = 1
+	}
+	if used == 1 {
+		return pc
+	}
+
+	var count [16]uint32
+	for _, l := range pc.lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [16]uint32
+	code := uint32(0)
+	for l := 1; l < 16; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for sym, l := range pc.lengths {
+		if l == 0 {
+			continue
+		}
+		pc.codes[sym] = bits.Reverse32(next[l]) >> (32 - l)
+		pc.emit[sym] = l
+		next[l]++
+	}
+	return pc
+}
+
+func (pc *prefixCode) writeSymbol(bw *bitWriter, sym int) {
+	bw.write(uint(pc.emit[sym]), pc.codes[sym])
+}
+
+// codeLengthOrder is the order code length code lengths are written in.
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// writeTo writes the code's lengths, so the decoder can rebuild it.
+func (pc *prefixCode) writeTo(bw *bitWriter) {
+	var symbols []int
+	for sym, l := range pc.lengths {
+		if l > 0 {
+			symbols = append(symbols, sym)
+		}
+	}
+	if len(symbols) <= 2 && symbols[len(symbols)-1] < 256 {
+		// Simple code: the symbols are listed and their codes are implied
+		bw.write(1, 1)
+		bw.write(1, uint32(len(symbols)-1))
+		if symbols[0] < 2 {
+			bw.write(1, 0)
+			bw.write(1, uint32(symbols[0]))
+		} else {
+			bw.write(1, 1)
+			bw.write(8, uint32(symbols[0]))
+		}
+		if len(symbols) == 2 {
+			bw.write(8, uint32(symbols[1]))
+		}
+		return
+	}
+
+	// Normal code: the lengths are themselves Huffman coded, with 16
+	// repeating the previous length and 17 and 18 encoding runs of zeros.
+	type clToken struct {
+		sym    int
+		nExtra uint
+		extra  uint32
+	}
+	var clTokens []clToken
+	for i := 0; i < len(pc.lengths); {
+		l := pc.lengths[i]
+		run := 1
+		for i+run < len(pc.lengths) && pc.lengths[i+run] == l {
+			run++
+		}
+		i += run
+		if l == 0 {
+			for run > 0 {
+				switch {
+				case run >= 11:
+					n := min(run, 138)
+					clTokens = append(clTokens, clToken{18, 7, uint32(n - 11)})
+					run -= n
+				case run >= 3:
+					clTokens = append(clTokens, clToken{17, 3, uint32(run - 3)})
+					run = 0
+				default:
+					clTokens = append(clTokens, clToken{sym: 0})
+					run--
+				}
+			}
+			continue
+		}
+		clTokens = append(clTokens, clToken{sym: int(l)})
+		run--
+		for run >= 3 {
+			n := min(run, 6)
+			clTokens = append(clTokens, clToken{16, 2, uint32(n - 3)})
+			run -= n
+		}
+		for ; run > 0; run-- {
+			clTokens = append(clTokens, clToken{sym: int(l)})
+		}
+	}
+
+	clHist := make([]int, 19)
+	for _, t := range clTokens {
+		clHist[t.sym]++
+	}
+	cl := newPrefixCode(clHist, 7)
+	nCodes := 19
+	for nCodes > 4 && cl.lengths[codeLengthOrder[nCodes-1]] == 0 {
+		nCodes--
+	}
+
+	bw.write(1, 0)
+	bw.write(4, uint32(nCodes-4))
+	for _, sym := range codeLengthOrder[:nCodes] {
+		bw.write(3, uint32(cl.lengths[sym]))
+	}
+	bw.write(1, 0) // lengths are given for the whole alphabet
+	for _, t := range clTokens {
+		cl.writeSymbol(bw, t.sym)
+		bw.write(t.nExtra, t.extra)
+	}
+}
+
+// huffmanLengths returns Huffman code lengths for hist, none longer than
+// maxLen. Unused symbols get length 0; a lone used symbol gets length 1.
+func huffmanLengths(hist []int, maxLen int) []uint8 {
+	weights := make([]int, len(hist))
+	copy(weights, hist)
+	for {
+		lengths := huffmanTreeLengths(weights)
+		longest := uint8(0)
+		for _, l := range lengths {
+			longest = max(longest, l)
+		}
+		if int(longest) <= maxLen {
+			return lengths
+		}
+		// Flatten the distribution until the tree is shallow enough
+		for i, w := range weights {
+			if w > 0 {
+				weights[i] = (w + 1) / 2
+			}
+		}
+	}
+}
+
+func huffmanTreeLengths(weights []int) []uint8 {
+	lengths := make([]uint8, len(weights))
+	h := &nodeHeap{}
+	var leaves, parent []int
+	for sym, w := range weights {
+		if w > 0 {
+			h.nodes = append(h.nodes, huffNode{weight: w, id: len(leaves)})
+			leaves = append(leaves, sym)
+			parent = append(parent, -1)
+		}
+	}
+	switch len(leaves) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[leaves[0]] = 1
+		return lengths
+	}
+
+	heap.Init(h)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(huffNode)
+		b := heap.Pop(h).(huffNode)
+		id := len(parent)
+		parent = append(parent, -1)
+		parent[a.id], parent[b.id] = id, id
+		heap.Push(h, huffNode{weight: a.weight + b.weight, id: id})
+	}
+	for i, sym := range leaves {
+		depth := uint8(0)
+		for n := i; parent[n] >= 0; n = parent[n] {
+			depth++
+		}
+		lengths[sym] = depth
+	}
+	return lengths
+}
+
+// huffNode is a tree node while building a Huffman code; ids below the
+// number of leaves are leaves.
+type huffNode struct {
+	weight int
+	id     int
+}
+
+type nodeHeap struct{ nodes []huffNode }
+
+func (h *nodeHeap) Len() int { return len(h.nodes) }
+func (h *nodeHeap) Less(i, j int) bool {
+	if h.nodes[i].weight != h.nodes[j].weight {
+		return h.nodes[i].weight < h.nodes[j].weight
+	}
+	return h.nodes[i].id < h.nodes[j].id
+}
+func (h *nodeHeap) Swap(i, j int) { h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i] }
+func (h *nodeHeap) Push(x any)    { h.nodes = append(h.nodes, x.(huffNode)) }
+func (h *nodeHeap) Pop() any {
+	n := h.nodes[len(h.nodes)-1]
+	h.nodes = h.nodes[:len(h.nodes)-1]
+	return n
+}
+
+// bitWriter packs values least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint
+}
+
+func (bw *bitWriter) write(n uint, v uint32) {
+	bw.acc |= uint64(v) << bw.nBits
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nBits -= 8
+	}
+}
+
+func (bw *bitWriter) flush() []byte {
+	if bw.nBits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nBits = 0, 0
+	}
+	return bw.buf
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
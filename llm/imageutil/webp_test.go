@@ -0,0 +1,157 @@
+package imageutil
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name string
+		img  func() *image.NRGBA
+	}{
+		{"single pixel", func() *image.NRGBA {
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.Set(0, 0, color.NRGBA{10, 20, 30, 255})
+			return img
+		}},
+		{"solid", func() *image.NRGBA {
+			img := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+			for i := 0; i < len(img.Pix); i += 4 {
+				copy(img.Pix[i:], []byte{100, 150, 200, 255})
+			}
+			return img
+		}},
+		{"noise with alpha", func() *image.NRGBA {
+			img := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+			rng.Read(img.Pix)
+			return img
+		}},
+		{"repeating rows", func() *image.NRGBA {
+			// Exercises backward references to the left, above and far away
+			img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+			row := make([]byte, 4*50)
+			rng.Read(row)
+			for y := range 200 {
+				for x := range 300 {
+					c := row[4*((x+y/20)%50):]
+					img.SetNRGBA(x, y, color.NRGBA{c[0], c[1], c[2], 255})
+				}
+			}
+			return img
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := tt.img()
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, src); err != nil {
+				t.Fatalf("EncodeWebP() error = %v", err)
+			}
+			decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("Failed to decode encoded image: %v", err)
+			}
+			if decoded.Bounds() != src.Bounds() {
+				t.Fatalf("Decoded bounds %v, want %v", decoded.Bounds(), src.Bounds())
+			}
+			b := src.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					want := src.NRGBAAt(x, y)
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if got != want {
+						t.Fatalf("Pixel (%d, %d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResizeImageWebP(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	for y := range 300 {
+		for x := range 600 {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x / 10), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img); err != nil {
+		t.Fatalf("Failed to create test WebP image: %v", err)
+	}
+
+	resized, format, didResize, err := ResizeImage(buf.Bytes(), 200)
+	if err != nil {
+		t.Fatalf("ResizeImage() error = %v", err)
+	}
+	if !didResize {
+		t.Error("Expected resize for large WebP image")
+	}
+	if format != "webp" {
+		t.Errorf("ResizeImage() format = %v, want webp", format)
+	}
+
+	config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(resized))
+	if err != nil {
+		t.Fatalf("Failed to decode resized image: %v", err)
+	}
+	if decodedFormat != "webp" {
+		t.Errorf("Resized image decodes as %s, want webp", decodedFormat)
+	}
+	if config.Width != 200 || config.Height != 100 {
+		t.Errorf("Resized image is %dx%d, want 200x100", config.Width, config.Height)
+	}
+}
+
+func TestResizeImageLossyWebP(t *testing.T) {
+	for _, name := range []string{"yellow_rose.lossy.webp", "yellow_rose.lossy-with-alpha.webp"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatalf("Failed to read test image: %v", err)
+			}
+			if !isLossyWebP(data) {
+				t.Fatal("Expected test image to be detected as lossy")
+			}
+			config, _, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Failed to decode test image config: %v", err)
+			}
+
+			// Halving a lossy source must shrink it; a lossless re-encode
+			// would be several times larger
+			maxDimension := max(config.Width, config.Height) / 2
+			resized, format, didResize, err := ResizeImage(data, maxDimension)
+			if err != nil {
+				t.Fatalf("ResizeImage() error = %v", err)
+			}
+			if !didResize {
+				t.Fatal("Expected resize for lossy WebP image")
+			}
+			if format != "jpeg" {
+				t.Errorf("ResizeImage() format = %v, want jpeg", format)
+			}
+			if len(resized) > len(data) {
+				t.Errorf("Resized image is %d bytes, larger than the %d byte source", len(resized), len(data))
+			}
+		})
+	}
+
+	var lossless bytes.Buffer
+	if err := EncodeWebP(&lossless, image.NewNRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("Failed to create test WebP image: %v", err)
+	}
+	if isLossyWebP(lossless.Bytes()) {
+		t.Error("Expected lossless WebP not to be detected as lossy")
+	}
+}
@@ -25,6 +25,37 @@ type Service interface {
 	MaxImageDimension() int
 }
 
+// OutputTokenLimiter is implemented by services that know their model's
+// maximum output tokens.
+type OutputTokenLimiter interface {
+	MaxOutputTokens() int
+}
+
+// MaxOutputTokens returns the maximum output tokens for s, or 0 if unknown.
+func MaxOutputTokens(s Service) int {
+	if l, ok := s.(OutputTokenLimiter); ok {
+		return l.MaxOutputTokens()
+	}
+	return 0
+}
+
+// ParamsValidator is implemented by services that restrict generation
+// parameters beyond the ranges Params.Validate checks.
+type ParamsValidator interface {
+	ValidateParams(Params) error
+}
+
+// ValidateParams checks p against the common ranges and any restrictions s adds.
+func ValidateParams(s Service, p Params) error {
+	if err := p.Validate(MaxOutputTokens(s)); err != nil {
+		return err
+	}
+	if v, ok := s.(ParamsValidator); ok {
+		return v.ValidateParams(p)
+	}
+	return nil
+}
+
 // MustSchema validates that schema is a valid JSON schema and returns it as a json.RawMessage.
 // It panics if the schema is invalid.
 // The schema must have at least type="object" and a properties key.
@@ -52,9 +83,10 @@ func EmptySchema() json.RawMessage {
 type ErrorType string
 
 const (
-	ErrorTypeNone       ErrorType = ""            // Not an error
-	ErrorTypeTruncation ErrorType = "truncation"  // Response truncated due to max tokens
-	ErrorTypeLLMRequest ErrorType = "llm_request" // LLM request failed
+	ErrorTypeNone                ErrorType = ""                      // Not an error
+	ErrorTypeTruncation          ErrorType = "truncation"            // Response truncated due to max tokens
+	ErrorTypeLLMRequest          ErrorType = "llm_request"           // LLM request failed
+	ErrorTypeRepeatedToolFailure ErrorType = "repeated_tool_failure" // Turn halted after the same tool call kept failing
 )
 
 // StreamDelta represents a partial content update during streaming.
@@ -72,6 +104,8 @@ type Request struct {
 	ToolChoice *ToolChoice
 	Tools      []*Tool
 	System     []SystemContent
+	// Params overrides the model's default generation parameters.
+	Params Params
 	// OnStream is called with each streaming delta as the LLM generates content.
 	// If nil, no streaming callbacks are made. The full response is still returned from Do.
 	OnStream func(StreamDelta) `json:"-"`
@@ -99,6 +133,49 @@ type ToolUse struct {
 	Name string
 }
 
+// Params are optional generation parameters. Unset fields use the model's defaults.
+type Params struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	// StopSequences end generation when the model emits any of them.
+	StopSequences []string `json:"stop_sequences,omitempty"`
+}
+
+// maxStopSequences is the most stop sequences every provider accepts.
+const maxStopSequences = 4
+
+// IsZero reports whether p sets no parameters.
+func (p Params) IsZero() bool {
+	return p.Temperature == nil && p.TopP == nil && p.MaxTokens == 0 && len(p.StopSequences) == 0
+}
+
+// Validate checks p against the ranges providers accept. maxOutputTokens is the
+// model's output token limit; 0 means unknown.
+func (p Params) Validate(maxOutputTokens int) error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *p.Temperature)
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1, got %g", *p.TopP)
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be positive, got %d", p.MaxTokens)
+	}
+	if maxOutputTokens > 0 && p.MaxTokens > maxOutputTokens {
+		return fmt.Errorf("max_tokens %d exceeds the model limit of %d", p.MaxTokens, maxOutputTokens)
+	}
+	if len(p.StopSequences) > maxStopSequences {
+		return fmt.Errorf("at most %d stop_sequences are allowed, got %d", maxStopSequences, len(p.StopSequences))
+	}
+	for _, seq := range p.StopSequences {
+		if strings.TrimSpace(seq) == "" {
+			return fmt.Errorf("stop_sequences must not be empty or whitespace")
+		}
+	}
+	return nil
+}
+
 type ToolChoice struct {
 	Type ToolChoiceType
 	Name string
@@ -492,3 +492,33 @@ func TestDumpToFile(t *testing.T) {
 	// This might fail due to permissions, but it shouldn't panic
 	_ = DumpToFile("test", "http://example.com", content)
 }
+
+func TestParamsValidate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		params  Params
+		limit   int
+		wantErr bool
+	}{
+		{name: "empty", params: Params{}},
+		{name: "valid", params: Params{Temperature: f(0), TopP: f(1), MaxTokens: 1000}, limit: 64000},
+		{name: "temperature too high", params: Params{Temperature: f(2.5)}, wantErr: true},
+		{name: "negative temperature", params: Params{Temperature: f(-1)}, wantErr: true},
+		{name: "zero top_p", params: Params{TopP: f(0)}, wantErr: true},
+		{name: "negative max_tokens", params: Params{MaxTokens: -1}, wantErr: true},
+		{name: "max_tokens over model limit", params: Params{MaxTokens: 70000}, limit: 64000, wantErr: true},
+		{name: "unknown model limit", params: Params{MaxTokens: 70000}},
+		{name: "stop sequences", params: Params{StopSequences: []string{"END", "\n\nHuman:"}}},
+		{name: "blank stop sequence", params: Params{StopSequences: []string{" "}}, wantErr: true},
+		{name: "too many stop sequences", params: Params{StopSequences: []string{"a", "b", "c", "d", "e"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate(tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
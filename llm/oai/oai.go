@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"math/rand/v2"
 	"net/http"
 	"strings"
@@ -820,8 +821,16 @@ func (s *Service) Do(ctx context.Context, ir *llm.Request) (*llm.Response, error
 		Messages:            allMessages,
 		Tools:               tools,
 		ToolChoice:          fromLLMToolChoice(ir.ToolChoice), // TODO: make fromLLMToolChoice return an error when a perfect translation is not possible
-		MaxCompletionTokens: cmp.Or(s.MaxTokens, DefaultMaxTokens),
+		MaxCompletionTokens: cmp.Or(ir.Params.MaxTokens, s.MaxTokens, DefaultMaxTokens),
 	}
+	if t := ir.Params.Temperature; t != nil {
+		// go-openai omits a zero temperature; the smallest float32 stands in for 0.
+		req.Temperature = max(float32(*t), math.SmallestNonzeroFloat32)
+	}
+	if p := ir.Params.TopP; p != nil {
+		req.TopP = float32(*p)
+	}
+	req.Stop = ir.Params.StopSequences
 	// Construct the full URL for logging and debugging
 	fullURL := baseURL + "/chat/completions"
 
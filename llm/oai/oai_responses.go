@@ -41,6 +41,8 @@ type responsesRequest struct {
 	Tools           []responsesTool      `json:"tools,omitempty"`
 	ToolChoice      any                  `json:"tool_choice,omitempty"`
 	MaxOutputTokens int                  `json:"max_output_tokens,omitempty"`
+	Temperature     *float64             `json:"temperature,omitempty"`
+	TopP            *float64             `json:"top_p,omitempty"`
 	Reasoning       *responsesReasoning  `json:"reasoning,omitempty"`
 }
 
@@ -395,12 +397,15 @@ func (s *ResponsesService) Do(ctx context.Context, ir *llm.Request) (*llm.Respon
 		tools = append(tools, fromLLMToolResponses(t))
 	}
 
-	// Create the request
+	// Create the request. The Responses API has no stop sequences, so
+	// ir.Params.StopSequences is not sent.
 	req := responsesRequest{
 		Model:           model.ModelName,
 		Input:           allInput,
 		Tools:           tools,
-		MaxOutputTokens: cmp.Or(s.MaxTokens, DefaultMaxTokens),
+		MaxOutputTokens: cmp.Or(ir.Params.MaxTokens, s.MaxTokens, DefaultMaxTokens),
+		Temperature:     ir.Params.Temperature,
+		TopP:            ir.Params.TopP,
 	}
 
 	// Add reasoning if thinking is enabled
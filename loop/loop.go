@@ -47,8 +47,19 @@ type Config struct {
 	// This supports dynamic tool loading (e.g., deferred MCP tool groups).
 	// If nil, Config.Tools is used as a static list.
 	GetTools func() []*llm.Tool
+	// GetSystem, if set, is called each iteration to get the current system prompt.
+	// This supports system content that changes mid-conversation (e.g., a pinned note).
+	// If nil, Config.System is used as a static value.
+	GetSystem func() []llm.SystemContent
+	// MaxRepeatedToolErrors is how many times in a row an identical tool call
+	// may fail within a turn before the turn is halted.
+	// Zero uses DefaultMaxRepeatedToolErrors; a negative value disables the check.
+	MaxRepeatedToolErrors int
 }
 
+// DefaultMaxRepeatedToolErrors is the default for Config.MaxRepeatedToolErrors.
+const DefaultMaxRepeatedToolErrors = 3
+
 // Loop manages a conversation turn with an LLM including tool execution and message recording.
 // Notably, when the turn ends, the "Loop" is over. TODO: maybe rename to Turn?
 type Loop struct {
@@ -62,6 +73,7 @@ type Loop struct {
 	mu               sync.Mutex
 	logger           *slog.Logger
 	system           []llm.SystemContent
+	getSystem        func() []llm.SystemContent
 	workingDir       string
 	onGitStateChange GitStateChangeFunc
 	getWorkingDir    func() string
@@ -70,6 +82,14 @@ type Loop struct {
 	onStreamDelta    func(llm.StreamDelta)
 	onStreamDone     func()
 	notify           chan struct{} // signaled when a message is queued
+	params           llm.Params    // generation parameters for the next turn
+
+	// maxRepeatedToolErrors is the resolved Config.MaxRepeatedToolErrors (<= 0 disables).
+	maxRepeatedToolErrors int
+	// lastFailedCall and repeatedFailures track consecutive identical failing
+	// tool calls within the current turn.
+	lastFailedCall   string
+	repeatedFailures int
 }
 
 // NewLoop creates a new Loop instance with the provided configuration
@@ -86,6 +106,11 @@ func NewLoop(config Config) *Loop {
 	}
 	initialGitState := gitstate.GetGitState(workingDir)
 
+	maxRepeatedToolErrors := config.MaxRepeatedToolErrors
+	if maxRepeatedToolErrors == 0 {
+		maxRepeatedToolErrors = DefaultMaxRepeatedToolErrors
+	}
+
 	return &Loop{
 		llm:              config.LLM,
 		history:          config.History,
@@ -95,6 +120,7 @@ func NewLoop(config Config) *Loop {
 		messageQueue:     make([]llm.Message, 0),
 		logger:           logger,
 		system:           config.System,
+		getSystem:        config.GetSystem,
 		workingDir:       config.WorkingDir,
 		onGitStateChange: config.OnGitStateChange,
 		getWorkingDir:    config.GetWorkingDir,
@@ -103,6 +129,8 @@ func NewLoop(config Config) *Loop {
 		onStreamDelta:    config.OnStreamDelta,
 		onStreamDone:     config.OnStreamDone,
 		notify:           make(chan struct{}, 1),
+
+		maxRepeatedToolErrors: maxRepeatedToolErrors,
 	}
 }
 
@@ -119,6 +147,14 @@ func (l *Loop) QueueUserMessage(message llm.Message) {
 	}
 }
 
+// SetParams sets the generation parameters used from the start of the next
+// turn. A turn already in progress keeps the parameters it started with.
+func (l *Loop) SetParams(params llm.Params) {
+	l.mu.Lock()
+	l.params = params
+	l.mu.Unlock()
+}
+
 // GetUsage returns the total usage accumulated by this loop
 func (l *Loop) GetUsage() llm.Usage {
 	l.mu.Lock()
@@ -223,6 +259,10 @@ func (l *Loop) ProcessOneTurn(ctx context.Context) error {
 // mutual recursion (processLLMRequest ↔ executeToolCalls) caused, because
 // each iteration's locals are freed before the next iteration starts.
 func (l *Loop) processLLMRequest(ctx context.Context) error {
+	l.lastFailedCall, l.repeatedFailures = "", 0
+	l.mu.Lock()
+	params := l.params
+	l.mu.Unlock()
 	for {
 		l.mu.Lock()
 		messages := append([]llm.Message(nil), l.history...)
@@ -231,6 +271,9 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 			tools = l.getTools()
 		}
 		system := l.system
+		if l.getSystem != nil {
+			system = l.getSystem()
+		}
 		llmService := l.llm
 		l.mu.Unlock()
 
@@ -263,6 +306,7 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 			Messages: messages,
 			Tools:    tools,
 			System:   system,
+			Params:   params,
 			OnStream: l.onStreamDelta,
 		}
 
@@ -366,7 +410,56 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 		if err := l.executeToolCalls(ctx, resp.Content); err != nil {
 			return err
 		}
+		if l.maxRepeatedToolErrors > 0 && l.repeatedFailures >= l.maxRepeatedToolErrors {
+			return l.haltRepeatedToolFailure(ctx)
+		}
+	}
+}
+
+// trackToolFailure updates the count of consecutive identical failing tool calls.
+// A successful call, or a failure of a different call, resets the count.
+func (l *Loop) trackToolFailure(c llm.Content, failed bool) {
+	if !failed {
+		l.lastFailedCall, l.repeatedFailures = "", 0
+		return
+	}
+	key := c.ToolName + "\x00" + string(c.ToolInput)
+	if key != l.lastFailedCall {
+		l.lastFailedCall, l.repeatedFailures = key, 0
+	}
+	l.repeatedFailures++
+}
+
+// haltRepeatedToolFailure ends the turn after the same tool call has failed
+// too many times in a row, so the agent doesn't keep retrying a broken call.
+func (l *Loop) haltRepeatedToolFailure(ctx context.Context) error {
+	toolName, _, _ := strings.Cut(l.lastFailedCall, "\x00")
+	l.logger.Warn("halting turn after repeated identical tool failures", "name", toolName, "count", l.repeatedFailures)
+
+	errorMessage := llm.Message{
+		Role: llm.MessageRoleAssistant,
+		Content: []llm.Content{
+			{
+				Type: llm.ContentTypeText,
+				Text: fmt.Sprintf("[SYSTEM ERROR: The same %s tool call failed %d times in a row, so this turn was stopped. "+
+					"Do not retry that exact call; read the error, change the input or take a different approach. "+
+					"The user can ask you to continue if needed.]", toolName, l.repeatedFailures),
+			},
+		},
+		EndOfTurn: true,
+		ErrorType: llm.ErrorTypeRepeatedToolFailure,
+	}
+
+	l.mu.Lock()
+	l.history = append(l.history, errorMessage)
+	l.mu.Unlock()
+
+	if err := l.recordMessage(ctx, errorMessage, llm.Usage{}); err != nil {
+		l.logger.Error("failed to record repeated tool failure message", "error", err)
 	}
+
+	l.checkGitStateChange(ctx)
+	return nil
 }
 
 // checkGitStateChange checks if the git state has changed and calls the callback if so.
@@ -481,6 +574,7 @@ func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) erro
 
 		if tool == nil {
 			l.logger.Error("tool not found", "name", c.ToolName)
+			l.trackToolFailure(c, true)
 			toolResults = append(toolResults, llm.Content{
 				Type:      llm.ContentTypeToolResult,
 				ToolUseID: c.ID,
@@ -516,6 +610,8 @@ func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) erro
 			l.logger.Debug("tool executed successfully", "name", c.ToolName, "duration", endTime.Sub(startTime))
 		}
 
+		l.trackToolFailure(c, result.Error != nil)
+
 		toolResults = append(toolResults, llm.Content{
 			Type:             llm.ContentTypeToolResult,
 			ToolUseID:        c.ID,
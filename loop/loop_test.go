@@ -1955,6 +1955,127 @@ func TestExecuteToolCallsWithErrorTool(t *testing.T) {
 	}
 }
 
+// repeatingToolLLMService always responds with the same tool call.
+type repeatingToolLLMService struct {
+	mu        sync.Mutex
+	callCount int
+}
+
+func (r *repeatingToolLLMService) Do(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	r.mu.Lock()
+	r.callCount++
+	id := fmt.Sprintf("call_%d", r.callCount)
+	r.mu.Unlock()
+	return &llm.Response{
+		Role:       llm.MessageRoleAssistant,
+		StopReason: llm.StopReasonToolUse,
+		Content: []llm.Content{{
+			ID:        id,
+			Type:      llm.ContentTypeToolUse,
+			ToolName:  "error_tool",
+			ToolInput: json.RawMessage(`{"path": "/missing"}`),
+		}},
+	}, nil
+}
+
+func (r *repeatingToolLLMService) TokenContextWindow() int {
+	return 128000
+}
+
+func (r *repeatingToolLLMService) MaxImageDimension() int {
+	return 0
+}
+
+func TestRepeatedToolErrorsHaltTurn(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		wantCalls int
+	}{
+		{name: "default", limit: 0, wantCalls: DefaultMaxRepeatedToolErrors},
+		{name: "configured", limit: 2, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recordedMessages []llm.Message
+			recordFunc := func(ctx context.Context, message llm.Message, usage llm.Usage) error {
+				recordedMessages = append(recordedMessages, message)
+				return nil
+			}
+
+			errorTool := &llm.Tool{
+				Name:        "error_tool",
+				Description: "A tool that always errors",
+				InputSchema: llm.MustSchema(`{"type": "object", "properties": {"path": {"type": "string"}}}`),
+				Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+					return llm.ErrorToolOut(fmt.Errorf("file not found"))
+				},
+			}
+
+			service := &repeatingToolLLMService{}
+			loop := NewLoop(Config{
+				LLM:                   service,
+				History:               []llm.Message{},
+				Tools:                 []*llm.Tool{errorTool},
+				RecordMessage:         recordFunc,
+				MaxRepeatedToolErrors: tt.limit,
+			})
+			loop.QueueUserMessage(llm.Message{
+				Role:    llm.MessageRoleUser,
+				Content: []llm.Content{{Type: llm.ContentTypeText, Text: "read the file"}},
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := loop.ProcessOneTurn(ctx); err != nil {
+				t.Fatalf("ProcessOneTurn failed: %v", err)
+			}
+
+			if service.callCount != tt.wantCalls {
+				t.Errorf("expected %d LLM calls, got %d", tt.wantCalls, service.callCount)
+			}
+			if len(recordedMessages) == 0 {
+				t.Fatal("expected recorded messages")
+			}
+			last := recordedMessages[len(recordedMessages)-1]
+			if last.ErrorType != llm.ErrorTypeRepeatedToolFailure {
+				t.Errorf("expected ErrorType=%s, got %q", llm.ErrorTypeRepeatedToolFailure, last.ErrorType)
+			}
+			if !last.EndOfTurn {
+				t.Error("expected the halt message to end the turn")
+			}
+			if !strings.Contains(last.Content[0].Text, "error_tool") {
+				t.Errorf("expected halt message to name the tool, got %q", last.Content[0].Text)
+			}
+		})
+	}
+}
+
+func TestTrackToolFailure(t *testing.T) {
+	loop := NewLoop(Config{LLM: NewPredictableService()})
+	call := func(input string) llm.Content {
+		return llm.Content{ToolName: "bash", ToolInput: json.RawMessage(input)}
+	}
+
+	loop.trackToolFailure(call(`{"command": "false"}`), true)
+	loop.trackToolFailure(call(`{"command": "false"}`), true)
+	if loop.repeatedFailures != 2 {
+		t.Errorf("expected 2 repeated failures, got %d", loop.repeatedFailures)
+	}
+
+	// A different failing call starts a new run.
+	loop.trackToolFailure(call(`{"command": "exit 1"}`), true)
+	if loop.repeatedFailures != 1 {
+		t.Errorf("expected 1 repeated failure after a different call, got %d", loop.repeatedFailures)
+	}
+
+	// A success resets the count.
+	loop.trackToolFailure(call(`{"command": "exit 1"}`), false)
+	if loop.repeatedFailures != 0 {
+		t.Errorf("expected 0 repeated failures after success, got %d", loop.repeatedFailures)
+	}
+}
+
 func TestMaxTokensTruncation(t *testing.T) {
 	var mu sync.Mutex
 	var recordedMessages []llm.Message
@@ -2139,3 +2260,84 @@ func TestMaxTokensTruncation(t *testing.T) {
 //		t.Error("expected to find tool2 result in message 3")
 //	}
 //}
+
+// paramsRecordingService calls a tool once per turn and records the params of each request.
+type paramsRecordingService struct {
+	mu     sync.Mutex
+	params []llm.Params
+}
+
+func (p *paramsRecordingService) Do(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	p.mu.Lock()
+	p.params = append(p.params, req.Params)
+	p.mu.Unlock()
+	last := req.Messages[len(req.Messages)-1]
+	if last.Content[0].Type == llm.ContentTypeToolResult {
+		return &llm.Response{
+			Role:       llm.MessageRoleAssistant,
+			StopReason: llm.StopReasonEndTurn,
+			Content:    []llm.Content{{Type: llm.ContentTypeText, Text: "done"}},
+		}, nil
+	}
+	return &llm.Response{
+		Role:       llm.MessageRoleAssistant,
+		StopReason: llm.StopReasonToolUse,
+		Content: []llm.Content{{
+			ID:        fmt.Sprintf("call_%d", len(p.params)),
+			Type:      llm.ContentTypeToolUse,
+			ToolName:  "set_params",
+			ToolInput: json.RawMessage(`{}`),
+		}},
+	}, nil
+}
+
+func (p *paramsRecordingService) TokenContextWindow() int {
+	return 128000
+}
+
+func (p *paramsRecordingService) MaxImageDimension() int {
+	return 0
+}
+
+func TestSetParamsAppliesToNextTurn(t *testing.T) {
+	first, second := 0.2, 0.8
+	service := &paramsRecordingService{}
+	var loop *Loop
+	setParams := &llm.Tool{
+		Name:        "set_params",
+		Description: "Changes the params while the turn is running",
+		InputSchema: llm.MustSchema(`{"type": "object", "properties": {}}`),
+		Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+			loop.SetParams(llm.Params{Temperature: &second})
+			return llm.ToolOut{LLMContent: llm.TextContent("ok")}
+		},
+	}
+	loop = NewLoop(Config{
+		LLM:           service,
+		Tools:         []*llm.Tool{setParams},
+		RecordMessage: func(ctx context.Context, message llm.Message, usage llm.Usage) error { return nil },
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	loop.SetParams(llm.Params{Temperature: &first})
+	for range 2 {
+		loop.QueueUserMessage(llm.Message{
+			Role:    llm.MessageRoleUser,
+			Content: []llm.Content{{Type: llm.ContentTypeText, Text: "go"}},
+		})
+		if err := loop.ProcessOneTurn(ctx); err != nil {
+			t.Fatalf("ProcessOneTurn failed: %v", err)
+		}
+	}
+
+	want := []float64{first, first, second, second}
+	if len(service.params) != len(want) {
+		t.Fatalf("expected %d LLM calls, got %d", len(want), len(service.params))
+	}
+	for i, p := range service.params {
+		if p.Temperature == nil || *p.Temperature != want[i] {
+			t.Errorf("call %d: temperature = %v, want %g", i, p.Temperature, want[i])
+		}
+	}
+}
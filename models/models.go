@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"shelley.exe.dev/db"
@@ -58,10 +59,32 @@ type Model struct {
 	// GatewayEnabled indicates whether this model is available when using a gateway
 	GatewayEnabled bool
 
+	// Pricing is the model's list price, if known, for estimating what a conversation cost
+	Pricing *Pricing
+
 	// Factory creates an llm.Service instance for this model
 	Factory func(config *Config, httpc *http.Client) (llm.Service, error)
 }
 
+// Pricing is a model's price in USD per million tokens.
+type Pricing struct {
+	Input       float64 // uncached input, including cache writes
+	Output      float64
+	CachedInput float64 // input read from the prompt cache
+}
+
+// Cost returns the price of the given numbers of tokens.
+func (p Pricing) Cost(input, output, cachedInput int64) float64 {
+	return (float64(input)*p.Input + float64(output)*p.Output + float64(cachedInput)*p.CachedInput) / 1e6
+}
+
+// List prices of the Anthropic model families
+var (
+	opusPricing   = &Pricing{Input: 5, Output: 25, CachedInput: 0.5}
+	sonnetPricing = &Pricing{Input: 3, Output: 15, CachedInput: 0.3}
+	haikuPricing  = &Pricing{Input: 1, Output: 5, CachedInput: 0.1}
+)
+
 // Source returns a human-readable description of where this model's configuration comes from.
 // For example: "exe.dev gateway", "$ANTHROPIC_API_KEY", etc.
 func (m Model) Source(cfg *Config) string {
@@ -120,6 +143,10 @@ type Config struct {
 
 	// Database for recording LLM requests (optional)
 	DB *db.DB
+
+	// Aliases maps stable names (e.g. "default", "fast", "smart") to
+	// concrete model IDs (optional)
+	Aliases map[string]string
 }
 
 // getAnthropicURL returns the Anthropic API URL, with gateway suffix if gateway is set
@@ -163,6 +190,7 @@ func All() []Model {
 			Description:     "Claude Opus 4.7 (default)",
 			RequiredEnvVars: []string{"ANTHROPIC_API_KEY"},
 			GatewayEnabled:  true,
+			Pricing:         opusPricing,
 			Factory: func(config *Config, httpc *http.Client) (llm.Service, error) {
 				if config.AnthropicAPIKey == "" {
 					return nil, fmt.Errorf("claude-opus-4.7 requires ANTHROPIC_API_KEY")
@@ -180,6 +208,7 @@ func All() []Model {
 			Description:     "Claude Opus 4.6",
 			RequiredEnvVars: []string{"ANTHROPIC_API_KEY"},
 			GatewayEnabled:  true,
+			Pricing:         opusPricing,
 			Factory: func(config *Config, httpc *http.Client) (llm.Service, error) {
 				if config.AnthropicAPIKey == "" {
 					return nil, fmt.Errorf("claude-opus-4.6 requires ANTHROPIC_API_KEY")
@@ -197,6 +226,7 @@ func All() []Model {
 			Description:     "Claude Opus 4.5",
 			RequiredEnvVars: []string{"ANTHROPIC_API_KEY"},
 			GatewayEnabled:  true,
+			Pricing:         opusPricing,
 			Factory: func(config *Config, httpc *http.Client) (llm.Service, error) {
 				if config.AnthropicAPIKey == "" {
 					return nil, fmt.Errorf("claude-opus-4.5 requires ANTHROPIC_API_KEY")
@@ -214,6 +244,7 @@ func All() []Model {
 			Description:     "Claude Sonnet 4.6",
 			RequiredEnvVars: []string{"ANTHROPIC_API_KEY"},
 			GatewayEnabled:  true,
+			Pricing:         sonnetPricing,
 			Factory: func(config *Config, httpc *http.Client) (llm.Service, error) {
 				if config.AnthropicAPIKey == "" {
 					return nil, fmt.Errorf("claude-sonnet-4.6 requires ANTHROPIC_API_KEY")
@@ -232,6 +263,7 @@ func All() []Model {
 			Tags:            "slug-backup",
 			RequiredEnvVars: []string{"ANTHROPIC_API_KEY"},
 			GatewayEnabled:  true,
+			Pricing:         haikuPricing,
 			Factory: func(config *Config, httpc *http.Client) (llm.Service, error) {
 				if config.AnthropicAPIKey == "" {
 					return nil, fmt.Errorf("claude-haiku-4.5 requires ANTHROPIC_API_KEY")
@@ -451,6 +483,9 @@ func Default() Model {
 	return All()[0] // claude-opus-4.7
 }
 
+// DefaultAlias is the alias clients use to request the configured default model
+const DefaultAlias = "default"
+
 // Manager manages LLM services for all configured models
 type Manager struct {
 	mu         sync.RWMutex
@@ -460,6 +495,9 @@ type Manager struct {
 	db         *db.DB       // for custom models and LLM request recording
 	httpc      *http.Client // HTTP client with recording middleware
 	cfg        *Config      // retained for refreshing custom models
+	aliases    map[string]string
+	requests   atomic.Int64 // LLM requests made through services from GetService
+	failures   atomic.Int64 // those of requests that returned an error
 }
 
 type serviceEntry struct {
@@ -469,6 +507,7 @@ type serviceEntry struct {
 	source      string // Human-readable source (e.g., "exe.dev gateway", "$ANTHROPIC_API_KEY")
 	displayName string // For custom models, the user-provided display name
 	tags        string // For custom models, user-provided tags
+	pricing     *Pricing
 }
 
 // ConfigInfo is an optional interface that services can implement to provide configuration details for logging
@@ -484,11 +523,13 @@ type loggingService struct {
 	modelID  string
 	provider Provider
 	db       *db.DB
+	manager  *Manager
 }
 
 // Do wraps the underlying service's Do method with logging and database recording
 func (l *loggingService) Do(ctx context.Context, request *llm.Request) (*llm.Response, error) {
 	start := time.Now()
+	l.manager.requests.Add(1)
 
 	// Add model ID and provider to context for the HTTP transport
 	ctx = llmhttp.WithModelID(ctx, l.modelID)
@@ -502,6 +543,7 @@ func (l *loggingService) Do(ctx context.Context, request *llm.Request) (*llm.Res
 
 	// Log the completion with usage information
 	if err != nil {
+		l.manager.failures.Add(1)
 		logAttrs := []any{
 			"model", l.modelID,
 			"duration_seconds", durationSeconds,
@@ -554,12 +596,26 @@ func (l *loggingService) MaxImageDimension() int {
 	return l.service.MaxImageDimension()
 }
 
+// MaxOutputTokens returns the wrapped service's output token limit, or 0 if unknown
+func (l *loggingService) MaxOutputTokens() int {
+	return llm.MaxOutputTokens(l.service)
+}
+
+// ValidateParams checks params against the wrapped service's restrictions
+func (l *loggingService) ValidateParams(params llm.Params) error {
+	if v, ok := l.service.(llm.ParamsValidator); ok {
+		return v.ValidateParams(params)
+	}
+	return nil
+}
+
 // NewManager creates a new Manager with all models configured
 func NewManager(cfg *Config) (*Manager, error) {
 	manager := &Manager{
 		services: make(map[string]serviceEntry),
 		logger:   cfg.Logger,
 		db:       cfg.DB,
+		aliases:  cfg.Aliases,
 	}
 
 	// Create HTTP client with recording if database is available
@@ -648,6 +704,7 @@ func NewManager(cfg *Config) (*Manager, error) {
 			source:      model.Source(cfg),
 			displayName: model.ID, // built-in models use ID as display name
 			tags:        model.Tags,
+			pricing:     model.Pricing,
 		}
 		manager.modelOrder = append(manager.modelOrder, model.ID)
 	}
@@ -724,8 +781,18 @@ func (m *Manager) RefreshCustomModels() error {
 	return m.loadCustomModels()
 }
 
-// GetService returns the LLM service for the given model ID, wrapped with logging
+// ResolveModel returns the model ID an alias points to, or modelID itself
+// if it is not an alias.
+func (m *Manager) ResolveModel(modelID string) string {
+	if target, ok := m.aliases[modelID]; ok {
+		return target
+	}
+	return modelID
+}
+
+// GetService returns the LLM service for the given model ID or alias, wrapped with logging
 func (m *Manager) GetService(modelID string) (llm.Service, error) {
+	modelID = m.ResolveModel(modelID)
 	m.mu.RLock()
 	entry, ok := m.services[modelID]
 	m.mu.RUnlock()
@@ -743,11 +810,19 @@ func (m *Manager) GetService(modelID string) (llm.Service, error) {
 			modelID:  entry.modelID,
 			provider: entry.provider,
 			db:       m.db,
+			manager:  m,
 		}, nil
 	}
 	return entry.service, nil
 }
 
+// RequestStats returns how many LLM requests have been made through services
+// returned by GetService, and how many of them failed. Requests are only
+// counted when the manager has a logger.
+func (m *Manager) RequestStats() (requests, failures int64) {
+	return m.requests.Load(), m.failures.Load()
+}
+
 // GetAvailableModels returns a list of available model IDs.
 // Returns union of built-in models (in order) followed by custom models.
 func (m *Manager) GetAvailableModels() []string {
@@ -759,8 +834,9 @@ func (m *Manager) GetAvailableModels() []string {
 	return result
 }
 
-// HasModel reports whether the manager has a service for the given model ID
+// HasModel reports whether the manager has a service for the given model ID or alias
 func (m *Manager) HasModel(modelID string) bool {
+	modelID = m.ResolveModel(modelID)
 	m.mu.RLock()
 	_, ok := m.services[modelID]
 	m.mu.RUnlock()
@@ -771,11 +847,13 @@ func (m *Manager) HasModel(modelID string) bool {
 type ModelInfo struct {
 	DisplayName string
 	Tags        string
-	Source      string // Human-readable source (e.g., "exe.dev gateway", "$ANTHROPIC_API_KEY", "custom")
+	Source      string   // Human-readable source (e.g., "exe.dev gateway", "$ANTHROPIC_API_KEY", "custom")
+	Pricing     *Pricing // nil if the price isn't known
 }
 
-// GetModelInfo returns the display name, tags, and source for a model
+// GetModelInfo returns the display name, tags, source and pricing for a model ID or alias
 func (m *Manager) GetModelInfo(modelID string) *ModelInfo {
+	modelID = m.ResolveModel(modelID)
 	m.mu.RLock()
 	entry, ok := m.services[modelID]
 	m.mu.RUnlock()
@@ -786,6 +864,7 @@ func (m *Manager) GetModelInfo(modelID string) *ModelInfo {
 		DisplayName: entry.displayName,
 		Tags:        entry.tags,
 		Source:      entry.source,
+		Pricing:     entry.pricing,
 	}
 }
 
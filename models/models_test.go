@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"log/slog"
+	"math"
 	"net/http"
 	"sync"
 	"testing"
@@ -68,6 +69,19 @@ func TestByID(t *testing.T) {
 	}
 }
 
+func TestPricingCost(t *testing.T) {
+	p := Pricing{Input: 3, Output: 15, CachedInput: 0.3}
+	got := p.Cost(1_000_000, 100_000, 2_000_000)
+	if want := 3 + 1.5 + 0.6; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Cost() = %v, want %v", got, want)
+	}
+
+	m := ByID("claude-sonnet-4.6")
+	if m == nil || m.Pricing == nil {
+		t.Fatal("claude-sonnet-4.6 should have pricing")
+	}
+}
+
 func TestDefault(t *testing.T) {
 	d := Default()
 	if d.ID != "claude-opus-4.7" {
@@ -186,12 +200,14 @@ func TestLoggingService(t *testing.T) {
 	// Create a mock service for testing
 	mockService := &mockLLMService{}
 	logger := slog.Default()
+	manager := &Manager{}
 
 	loggingSvc := &loggingService{
 		service:  mockService,
 		logger:   logger,
 		modelID:  "test-model",
 		provider: ProviderBuiltIn,
+		manager:  manager,
 	}
 
 	// Test Do method
@@ -210,6 +226,9 @@ func TestLoggingService(t *testing.T) {
 	if response == nil {
 		t.Error("Do returned nil response")
 	}
+	if requests, failures := manager.RequestStats(); requests != 1 || failures != 0 {
+		t.Errorf("RequestStats() = %d, %d; want 1, 0", requests, failures)
+	}
 
 	// Test TokenContextWindow
 	window := loggingSvc.TokenContextWindow()
@@ -305,6 +324,39 @@ func TestManagerHasModel(t *testing.T) {
 	}
 }
 
+func TestManagerAliases(t *testing.T) {
+	cfg := &Config{Aliases: map[string]string{
+		DefaultAlias: "predictable",
+		"smart":      "claude-opus-4.7",
+	}}
+
+	manager, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	if got := manager.ResolveModel(DefaultAlias); got != "predictable" {
+		t.Errorf("ResolveModel(%q) = %q, want predictable", DefaultAlias, got)
+	}
+	if got := manager.ResolveModel("predictable"); got != "predictable" {
+		t.Errorf("ResolveModel('predictable') = %q, want predictable", got)
+	}
+	if !manager.HasModel(DefaultAlias) {
+		t.Error("HasModel should resolve the default alias")
+	}
+	if _, err := manager.GetService(DefaultAlias); err != nil {
+		t.Errorf("GetService(%q) failed: %v", DefaultAlias, err)
+	}
+
+	// An alias to an unavailable model is not available either
+	if manager.HasModel("smart") {
+		t.Error("HasModel('smart') should return false without API key")
+	}
+	if _, err := manager.GetService("smart"); err == nil {
+		t.Error("GetService('smart') should have failed but didn't")
+	}
+}
+
 func TestConfigGetURLMethods(t *testing.T) {
 	// Test getGeminiURL with no gateway
 	cfg := &Config{}
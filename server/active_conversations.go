@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ActiveConversation describes a conversation manager held in memory.
+type ActiveConversation struct {
+	ConversationID string     `json:"conversation_id"`
+	LastActivity   time.Time  `json:"last_activity"`
+	Working        bool       `json:"working"`
+	WorkingSince   *time.Time `json:"working_since,omitempty"`
+	Model          string     `json:"model,omitempty"`
+	Subscribers    int        `json:"subscribers"`
+}
+
+// handleActiveConversations lists the conversation managers currently held
+// in memory, sorted by conversation ID. Requires an authenticated request.
+func (s *Server) handleActiveConversations(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	managers := make([]*ConversationManager, 0, len(s.activeConversations))
+	for _, manager := range s.activeConversations {
+		managers = append(managers, manager)
+	}
+	s.mu.Unlock()
+
+	active := make([]ActiveConversation, 0, len(managers))
+	for _, manager := range managers {
+		manager.mu.Lock()
+		lastActivity := manager.lastActivity
+		state := manager.stateLocked()
+		manager.mu.Unlock()
+		active = append(active, ActiveConversation{
+			ConversationID: state.ConversationID,
+			LastActivity:   lastActivity,
+			Working:        state.Working,
+			WorkingSince:   state.WorkingSince,
+			Model:          state.Model,
+			Subscribers:    manager.subpub.SubscriberCount(),
+		})
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].ConversationID < active[j].ConversationID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(active)
+}
+
+// handleEvictConversation stops an active conversation's loop, disconnects
+// its stream subscribers and drops its manager. The next request for the
+// conversation hydrates a fresh manager from the database.
+func (s *Server) handleEvictConversation(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	conversationID := r.PathValue("id")
+	s.mu.Lock()
+	manager, ok := s.activeConversations[conversationID]
+	if ok {
+		delete(s.activeConversations, conversationID)
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "Conversation not active", http.StatusNotFound)
+		return
+	}
+
+	// Stop outside s.mu, as in Cleanup: stopLoop can block on browser shutdown.
+	manager.stopLoop()
+	manager.subpub.Close()
+	s.logger.InfoContext(r.Context(), "Evicted active conversation", "conversationID", conversationID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "evicted"})
+}
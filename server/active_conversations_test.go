@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestActiveConversationsAdmin(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("echo: hi", "/tmp")
+	h.WaitResponse()
+
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+	do := func(method, path string, local bool) *httptest.ResponseRecorder {
+		var handler http.Handler = mux
+		if local {
+			handler = LocalSocketMiddleware(handler)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := do("GET", "/api/admin/active-conversations", false); w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for unauthenticated list, got %d", w.Code)
+	}
+	if w := do("POST", "/api/admin/active-conversations/"+h.convID+"/evict", false); w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for unauthenticated evict, got %d", w.Code)
+	}
+
+	h.server.mu.Lock()
+	manager := h.server.activeConversations[h.convID]
+	h.server.mu.Unlock()
+	next := manager.subpub.Subscribe(context.Background(), 1<<40)
+
+	w := do("GET", "/api/admin/active-conversations", true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var active []ActiveConversation
+	if err := json.Unmarshal(w.Body.Bytes(), &active); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(active) != 1 || active[0].ConversationID != h.convID {
+		t.Fatalf("expected only %s to be active, got %+v", h.convID, active)
+	}
+	if active[0].Subscribers != 1 || active[0].LastActivity.IsZero() {
+		t.Errorf("unexpected active conversation: %+v", active[0])
+	}
+
+	if w := do("POST", "/api/admin/active-conversations/"+h.convID+"/evict", true); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := next(); ok {
+		t.Error("expected subscriber to be disconnected after evict")
+	}
+	h.server.mu.Lock()
+	_, stillActive := h.server.activeConversations[h.convID]
+	h.server.mu.Unlock()
+	if stillActive {
+		t.Error("expected conversation to be removed from active conversations")
+	}
+
+	if w := do("POST", "/api/admin/active-conversations/"+h.convID+"/evict", true); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for evicting an inactive conversation, got %d", w.Code)
+	}
+}
+
+func TestCleanupKeepsWatchedConversations(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("echo: hi", "/tmp")
+	h.WaitResponse()
+
+	h.server.mu.Lock()
+	manager := h.server.activeConversations[h.convID]
+	h.server.mu.Unlock()
+	manager.mu.Lock()
+	manager.lastActivity = time.Now().Add(-2 * DefaultConversationIdleTimeout)
+	manager.mu.Unlock()
+
+	isActive := func() bool {
+		h.server.mu.Lock()
+		defer h.server.mu.Unlock()
+		_, ok := h.server.activeConversations[h.convID]
+		return ok
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	manager.subpub.Subscribe(ctx, 1<<40)
+	h.server.Cleanup()
+	if !isActive() {
+		t.Fatal("expected a watched conversation to survive cleanup")
+	}
+
+	cancel()
+	h.server.Cleanup()
+	if isActive() {
+		t.Error("expected an idle, unwatched conversation to be evicted")
+	}
+}
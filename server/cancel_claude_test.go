@@ -545,6 +545,13 @@ func (m *claudeLLMManager) HasModel(modelID string) bool {
 	return modelID == "claude" || modelID == "claude-haiku-4.5"
 }
 
+func (m *claudeLLMManager) ResolveModel(modelID string) string {
+	if modelID == models.DefaultAlias {
+		return "claude"
+	}
+	return modelID
+}
+
 func (m *claudeLLMManager) GetModelInfo(modelID string) *models.ModelInfo {
 	if modelID == "claude-haiku-4.5" {
 		return &models.ModelInfo{DisplayName: "Claude Haiku", Tags: "slug"}
@@ -400,6 +400,13 @@ func (m *testLLMManager) HasModel(modelID string) bool {
 	return modelID == "predictable"
 }
 
+func (m *testLLMManager) ResolveModel(modelID string) string {
+	if modelID == models.DefaultAlias {
+		return "predictable"
+	}
+	return modelID
+}
+
 func (m *testLLMManager) GetModelInfo(modelID string) *models.ModelInfo {
 	return nil
 }
@@ -407,3 +414,65 @@ func (m *testLLMManager) GetModelInfo(modelID string) *models.ModelInfo {
 func (m *testLLMManager) RefreshCustomModels() error {
 	return nil
 }
+
+// TestCancelRecordsReason tests that the message ending a cancelled turn
+// records who cancelled it and why.
+func TestCancelRecordsReason(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+
+	conversation, err := database.CreateConversation(context.Background(), nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	conversationID := conversation.ConversationID
+
+	chatBody, _ := json.Marshal(ChatRequest{Message: "delay: 2", Model: "predictable"})
+	req := httptest.NewRequest("POST", "/api/conversation/"+conversationID+"/chat", strings.NewReader(string(chatBody)))
+	w := httptest.NewRecorder()
+	server.handleChatConversation(w, req, conversationID)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return server.IsAgentWorking(conversationID)
+	})
+
+	cancelReq := httptest.NewRequest("POST", "/api/conversation/"+conversationID+"/cancel", strings.NewReader(`{"reason": "wrong directory"}`))
+	cancelW := httptest.NewRecorder()
+	server.handleCancelConversation(cancelW, cancelReq, conversationID)
+	if cancelW.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", cancelW.Code, cancelW.Body.String())
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return !server.IsAgentWorking(conversationID)
+	})
+
+	var messages []generated.Message
+	err = database.Queries(context.Background(), func(q *generated.Queries) error {
+		var qerr error
+		messages, qerr = q.ListMessages(context.Background(), conversationID)
+		return qerr
+	})
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	last := messages[len(messages)-1]
+	if last.Type != string(db.MessageTypeAgent) || last.UserData == nil {
+		t.Fatalf("expected the last message to be an agent message with user_data, got %s %v", last.Type, last.UserData)
+	}
+	var cancellation CancelUserData
+	if err := json.Unmarshal([]byte(*last.UserData), &cancellation); err != nil {
+		t.Fatalf("failed to parse user_data: %v", err)
+	}
+	if cancellation.CancelledBy != CancelledByUser || cancellation.Reason != "wrong directory" {
+		t.Errorf("unexpected cancellation %+v", cancellation)
+	}
+	var llmMsg llm.Message
+	if err := json.Unmarshal([]byte(*last.LlmData), &llmMsg); err != nil {
+		t.Fatalf("failed to parse llm_data: %v", err)
+	}
+	if !llmMsg.EndOfTurn || len(llmMsg.Content) == 0 || llmMsg.Content[0].Text != "[Operation cancelled: wrong directory]" {
+		t.Errorf("unexpected end of turn message %+v", llmMsg)
+	}
+}
@@ -1,11 +1,17 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
 	"testing"
 
+	"shelley.exe.dev/claudetool"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/llm"
+	"shelley.exe.dev/models"
 )
 
 // TestContextWindowSizeCalculation tests that the context window size is correctly
@@ -204,3 +210,108 @@ func TestContextWindowGrowsWithConversation(t *testing.T) {
 
 	t.Logf("Context window sizes: first=%d, second=%d, third=%d", firstSize, secondSize, thirdSize)
 }
+
+// pricedLLMManager is a testLLMManager whose models have prices.
+type pricedLLMManager struct {
+	testLLMManager
+	pricing map[string]*models.Pricing
+}
+
+func (m *pricedLLMManager) GetModelInfo(modelID string) *models.ModelInfo {
+	if p, ok := m.pricing[modelID]; ok {
+		return &models.ModelInfo{DisplayName: modelID, Pricing: p}
+	}
+	return nil
+}
+
+// TestRecordMessagePricesAtRecordingModel tests that each message is priced
+// at the conversation's model when it is recorded, so changing the model
+// doesn't re-price earlier messages.
+func TestRecordMessagePricesAtRecordingModel(t *testing.T) {
+	t.Parallel()
+	database, cleanup := setupTestDB(t)
+	t.Cleanup(cleanup)
+	server := NewServer(database, &pricedLLMManager{pricing: map[string]*models.Pricing{
+		"cheap":  {Input: 1, Output: 2},
+		"pricey": {Input: 10, Output: 20},
+	}}, claudetool.ToolSetConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)), true, "", "cheap", "", nil)
+	ctx := context.Background()
+
+	model := "cheap"
+	conv, err := database.CreateConversation(ctx, nil, true, nil, &model, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	record := func(usage llm.Usage) {
+		t.Helper()
+		msg := llm.Message{
+			Role:    llm.MessageRoleAssistant,
+			Content: []llm.Content{{Type: llm.ContentTypeText, Text: "ok"}},
+		}
+		if err := server.recordMessage(ctx, conv.ConversationID, msg, usage); err != nil {
+			t.Fatalf("recordMessage: %v", err)
+		}
+	}
+
+	record(llm.Usage{InputTokens: 1_000_000, OutputTokens: 500_000}) // $2 at the cheap price
+	if _, err := database.UpdateConversationModel(ctx, conv.ConversationID, "pricey"); err != nil {
+		t.Fatalf("failed to change model: %v", err)
+	}
+	record(llm.Usage{InputTokens: 100_000})               // $1 at the pricey price
+	record(llm.Usage{InputTokens: 100_000, CostUSD: 0.5}) // a reported cost is kept
+
+	got, err := database.GetConversationByID(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("failed to get conversation: %v", err)
+	}
+	cost := conversationCost(*got)
+	if cost == nil || math.Abs(*cost-3.5) > 1e-9 {
+		t.Errorf("conversationCost() = %v, want 3.5", cost)
+	}
+}
+
+// TestRecordMessageAccumulatesUsage tests that each recorded message's usage
+// is added to the conversation's token totals.
+func TestRecordMessageAccumulatesUsage(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	ctx := context.Background()
+
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+
+	usages := []llm.Usage{
+		{InputTokens: 100, CacheCreationInputTokens: 20, CacheReadInputTokens: 0, OutputTokens: 30},
+		{InputTokens: 10, CacheCreationInputTokens: 5, CacheReadInputTokens: 120, OutputTokens: 40},
+	}
+	for _, usage := range usages {
+		msg := llm.Message{
+			Role:    llm.MessageRoleAssistant,
+			Content: []llm.Content{{Type: llm.ContentTypeText, Text: "ok"}},
+		}
+		if err := server.recordMessage(ctx, conv.ConversationID, msg, usage); err != nil {
+			t.Fatalf("recordMessage: %v", err)
+		}
+	}
+	// A message without usage leaves the totals alone
+	userMsg := llm.UserStringMessage("hello")
+	if err := server.recordMessage(ctx, conv.ConversationID, userMsg, llm.Usage{}); err != nil {
+		t.Fatalf("recordMessage: %v", err)
+	}
+
+	got, err := database.GetConversationByID(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("failed to get conversation: %v", err)
+	}
+	if got.InputTokensTotal != 135 {
+		t.Errorf("InputTokensTotal = %d, want 135", got.InputTokensTotal)
+	}
+	if got.OutputTokensTotal != 70 {
+		t.Errorf("OutputTokensTotal = %d, want 70", got.OutputTokensTotal)
+	}
+	if got.CachedTokensTotal != 120 {
+		t.Errorf("CachedTokensTotal = %d, want 120", got.CachedTokensTotal)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/models"
+)
+
+// multiModelManager serves the predictable model under several IDs.
+type multiModelManager struct {
+	*testLLMManager
+	models []string
+}
+
+func (m *multiModelManager) GetAvailableModels() []string {
+	return m.models
+}
+
+func (m *multiModelManager) HasModel(modelID string) bool {
+	return slices.Contains(m.models, modelID)
+}
+
+func TestSetConversationModel(t *testing.T) {
+	h := NewTestHarness(t)
+	h.server.llmManager = &multiModelManager{
+		testLLMManager: h.server.llmManager.(*testLLMManager),
+		models:         []string{"predictable", "other"},
+	}
+
+	h.NewConversation("echo: hello", "/tmp")
+	h.WaitResponse()
+
+	// The turn has been recorded; wait for the manager to notice it's done.
+	h.server.mu.Lock()
+	manager := h.server.activeConversations[h.convID]
+	h.server.mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for manager.IsAgentWorking() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	setModel := func(model string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ConversationModelRequest{Model: model})
+		req := httptest.NewRequest("POST", "/api/conversation/"+h.convID+"/model", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		h.server.handleSetConversationModel(w, req, h.convID)
+		return w
+	}
+
+	w := setModel("other")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var conv generated.Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &conv); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if conv.Model == nil || *conv.Model != "other" {
+		t.Fatalf("expected model %q in response, got %v", "other", conv.Model)
+	}
+
+	stored, err := h.db.GetConversationByID(context.Background(), h.convID)
+	if err != nil {
+		t.Fatalf("failed to reload conversation: %v", err)
+	}
+	if stored.Model == nil || *stored.Model != "other" {
+		t.Fatalf("expected stored model %q, got %v", "other", stored.Model)
+	}
+	if got := manager.GetModel(); got != "other" {
+		t.Errorf("expected active conversation to use %q, got %q", "other", got)
+	}
+
+	// The next message goes to the new model rather than failing the model check.
+	body, _ := json.Marshal(ChatRequest{Message: "echo: again", Model: "other"})
+	req := httptest.NewRequest("POST", "/api/conversation/"+h.convID+"/chat", strings.NewReader(string(body)))
+	w = httptest.NewRecorder()
+	h.server.handleChatConversation(w, req, h.convID)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected chat with the new model to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+	h.WaitResponse()
+
+	if w := setModel("no-such-model"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown model, got %d: %s", w.Code, w.Body.String())
+	}
+	stored, err = h.db.GetConversationByID(context.Background(), h.convID)
+	if err != nil {
+		t.Fatalf("failed to reload conversation: %v", err)
+	}
+	if stored.Model == nil || *stored.Model != "other" {
+		t.Errorf("expected an unknown model to leave %q stored, got %v", "other", stored.Model)
+	}
+}
+
+func TestDefaultModelAlias(t *testing.T) {
+	h := NewTestHarness(t)
+	h.server.defaultModel = models.DefaultAlias
+
+	if got := h.server.resolveModel(""); got != "predictable" {
+		t.Errorf("expected the default alias to resolve to %q, got %q", "predictable", got)
+	}
+	if got := h.server.resolveModel("other"); got != "other" {
+		t.Errorf("expected an explicit model to be kept, got %q", got)
+	}
+
+	h.NewConversation("echo: hello", "/tmp")
+	h.WaitResponse()
+	h.server.mu.Lock()
+	manager := h.server.activeConversations[h.convID]
+	h.server.mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for manager.IsAgentWorking() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A message without a model uses the resolved default, which matches
+	// the conversation's stored model.
+	body, _ := json.Marshal(ChatRequest{Message: "echo: again"})
+	req := httptest.NewRequest("POST", "/api/conversation/"+h.convID+"/chat", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	h.server.handleChatConversation(w, req, h.convID)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected chat with the default model to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+	h.WaitResponse()
+}
+
+func TestHandleModels(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+	server.predictableOnly = false
+	server.defaultModel = "second"
+	server.llmManager = &multiModelManager{
+		testLLMManager: server.llmManager.(*testLLMManager),
+		models:         []string{"predictable", "first", "second"},
+	}
+
+	w := httptest.NewRecorder()
+	server.handleModels(w, httptest.NewRequest(http.MethodGet, "/api/models", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", ct)
+	}
+
+	var list []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	// The predictable test model is only listed in predictable-only mode.
+	want := []map[string]any{
+		{"id": "first", "ready": true, "max_context_tokens": float64(200000)},
+		{"id": "second", "ready": true, "max_context_tokens": float64(200000), "default": true},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("expected %d models, got %v", len(want), list)
+	}
+	for i := range want {
+		if len(list[i]) != len(want[i]) {
+			t.Errorf("model %d: expected fields %v, got %v", i, want[i], list[i])
+			continue
+		}
+		for k, v := range want[i] {
+			if list[i][k] != v {
+				t.Errorf("model %d: %s = %v, want %v", i, k, list[i][k], v)
+			}
+		}
+	}
+
+	w = httptest.NewRecorder()
+	server.handleModels(w, httptest.NewRequest(http.MethodPost, "/api/models", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", w.Code)
+	}
+}
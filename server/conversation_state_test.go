@@ -184,6 +184,9 @@ func TestConversationStateAfterServerRestart(t *testing.T) {
 	if response.ConversationState.Working {
 		t.Error("Expected Working=false after server restart (no active loop)")
 	}
+	if response.ConversationState.WorkingSince != nil {
+		t.Errorf("Expected no WorkingSince when not working, got %v", response.ConversationState.WorkingSince)
+	}
 
 	// Verify messages were loaded
 	if len(response.Messages) != 2 {
@@ -191,6 +194,45 @@ func TestConversationStateAfterServerRestart(t *testing.T) {
 	}
 }
 
+// TestConversationStateWorkingSince verifies that working_since is set when a
+// turn starts, broadcast with the state change, and cleared when it ends.
+func TestConversationStateWorkingSince(t *testing.T) {
+	t.Parallel()
+	var broadcast []ConversationState
+	cm := NewConversationManager("conv-1", nil, slog.Default(), claudetool.ToolSetConfig{}, nil, func(state ConversationState) {
+		broadcast = append(broadcast, state)
+	})
+
+	before := time.Now()
+	cm.SetAgentWorking(true)
+	state := cm.State()
+	if !state.Working || state.WorkingSince == nil || state.WorkingSince.Before(before) {
+		t.Fatalf("Expected working state with WorkingSince after %v, got %+v", before, state)
+	}
+	since := *state.WorkingSince
+
+	// Re-marking as working keeps the original start time.
+	cm.SetAgentWorking(true)
+	if got := cm.WorkingSince(); !got.Equal(since) {
+		t.Errorf("Expected WorkingSince to stay %v, got %v", since, got)
+	}
+
+	cm.SetAgentWorking(false)
+	if state := cm.State(); state.Working || state.WorkingSince != nil {
+		t.Errorf("Expected idle state without WorkingSince, got %+v", state)
+	}
+
+	if len(broadcast) != 2 {
+		t.Fatalf("Expected 2 state broadcasts, got %d", len(broadcast))
+	}
+	if broadcast[0].WorkingSince == nil || !broadcast[0].WorkingSince.Equal(since) {
+		t.Errorf("Expected first broadcast WorkingSince %v, got %v", since, broadcast[0].WorkingSince)
+	}
+	if broadcast[1].WorkingSince != nil {
+		t.Errorf("Expected end-of-turn broadcast without WorkingSince, got %v", broadcast[1].WorkingSince)
+	}
+}
+
 // TestModelRestorationAfterServerRestart verifies that when a conversation is
 // resumed after a server restart, the model is correctly loaded from the database
 // and reported in the ConversationState.
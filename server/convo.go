@@ -23,6 +23,8 @@ import (
 
 var errConversationModelMismatch = errors.New("conversation model mismatch")
 
+var errConversationBusy = errors.New("conversation is busy")
+
 // pendingMessage holds a user message that is queued to be sent after the
 // current agent turn (or distillation) completes.
 type pendingMessage struct {
@@ -51,12 +53,17 @@ type ConversationManager struct {
 
 	hydrated              bool
 	hasConversationEvents bool
-	cwd                   string // working directory for tools
-	alwaysOnSkills        []string // skill names pre-activated in system prompt
+	cwd                   string                // working directory for tools
+	alwaysOnSkills        []string              // skill names pre-activated in system prompt
+	systemNote            string                // user-pinned standing instruction, prepended to the system prompt
+	systemOverride        *SystemPromptOverride // system prompt override for the current turn
+	maxRepeatedToolErrs   int                   // passed to loop.Config.MaxRepeatedToolErrors
 
 	// agentWorking tracks whether the agent is currently working.
 	// This is explicitly managed and broadcast to subscribers when it changes.
 	agentWorking bool
+	// workingSince is when agentWorking last became true; zero when not working.
+	workingSince time.Time
 
 	// distilling is true while a distillation goroutine is inserting content
 	// into this conversation. When true, queued messages should NOT be drained
@@ -71,6 +78,10 @@ type ConversationManager struct {
 	onStateChange func(state ConversationState)
 }
 
+// streamReplaySize is how many recent messages a conversation's subpub keeps
+// for streams that subscribe just after one was published.
+const streamReplaySize = 32
+
 // NewConversationManager constructs a manager with dependencies but defers hydration until needed.
 func NewConversationManager(conversationID string, database *db.DB, baseLogger *slog.Logger, toolSetConfig claudetool.ToolSetConfig, recordMessage loop.MessageRecordFunc, onStateChange func(ConversationState)) *ConversationManager {
 	logger := baseLogger
@@ -86,7 +97,7 @@ func NewConversationManager(conversationID string, database *db.DB, baseLogger *
 		recordMessage:  recordMessage,
 		logger:         logger,
 		toolSetConfig:  toolSetConfig,
-		subpub:         subpub.New[StreamResponse](),
+		subpub:         subpub.NewWithReplay[StreamResponse](streamReplaySize),
 		onStateChange:  onStateChange,
 	}
 }
@@ -99,18 +110,18 @@ func (cm *ConversationManager) SetAgentWorking(working bool) {
 		return
 	}
 	cm.agentWorking = working
+	if working {
+		cm.workingSince = time.Now()
+	} else {
+		cm.workingSince = time.Time{}
+	}
 	onStateChange := cm.onStateChange
-	convID := cm.conversationID
-	modelID := cm.modelID
+	state := cm.stateLocked()
 	cm.mu.Unlock()
 
 	cm.logger.Debug("agent working state changed", "working", working)
 	if onStateChange != nil {
-		onStateChange(ConversationState{
-			ConversationID: convID,
-			Working:        working,
-			Model:          modelID,
-		})
+		onStateChange(state)
 	}
 }
 
@@ -121,6 +132,35 @@ func (cm *ConversationManager) IsAgentWorking() bool {
 	return cm.agentWorking
 }
 
+// WorkingSince returns when the agent's current turn started, or the zero
+// time if the agent is not working.
+func (cm *ConversationManager) WorkingSince() time.Time {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.workingSince
+}
+
+// State returns the conversation's current state for broadcasting to clients.
+func (cm *ConversationManager) State() ConversationState {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.stateLocked()
+}
+
+// stateLocked is State with cm.mu held.
+func (cm *ConversationManager) stateLocked() ConversationState {
+	state := ConversationState{
+		ConversationID: cm.conversationID,
+		Working:        cm.agentWorking,
+		Model:          cm.modelID,
+	}
+	if cm.agentWorking {
+		since := cm.workingSince
+		state.WorkingSince = &since
+	}
+	return state
+}
+
 // SetDistilling marks the conversation as distilling. While true, queued
 // messages will not be drained immediately — they wait for distillation to
 // complete and the caller to invoke drainPendingMessages.
@@ -137,6 +177,48 @@ func (cm *ConversationManager) GetModel() string {
 	return cm.modelID
 }
 
+// SetModel switches the conversation to modelID. A running loop is stopped so
+// that the next message starts one on the new model. It returns
+// errConversationBusy while the agent is working or messages are queued.
+func (cm *ConversationManager) SetModel(modelID string) error {
+	cm.mu.Lock()
+	if cm.agentWorking || cm.distilling || len(cm.pendingMessages) > 0 {
+		cm.mu.Unlock()
+		return errConversationBusy
+	}
+	restart := cm.loop != nil && cm.modelID != modelID
+	cm.mu.Unlock()
+
+	if restart {
+		cm.stopLoop()
+	}
+
+	cm.mu.Lock()
+	cm.modelID = modelID
+	cm.mu.Unlock()
+	return nil
+}
+
+// ResetHistory stops the conversation's loop and forgets what Hydrate loaded,
+// so that the next message rebuilds both from the database. It is used after
+// the stored history changes underneath the loop. It returns
+// errConversationBusy while the agent is working or messages are queued.
+func (cm *ConversationManager) ResetHistory() error {
+	cm.mu.Lock()
+	if cm.agentWorking || cm.distilling || len(cm.pendingMessages) > 0 {
+		cm.mu.Unlock()
+		return errConversationBusy
+	}
+	cm.mu.Unlock()
+
+	cm.stopLoop()
+
+	cm.mu.Lock()
+	cm.hydrated = false
+	cm.mu.Unlock()
+	return nil
+}
+
 // Hydrate loads conversation metadata from the database and generates a system
 // prompt if one doesn't exist yet. It does NOT cache the message history;
 // ensureLoop reads messages fresh from the DB when creating a loop so that
@@ -172,6 +254,13 @@ func (cm *ConversationManager) Hydrate(ctx context.Context) error {
 	// Load conversation options
 	cm.conversationOptions = db.ParseConversationOptions(conversation.ConversationOptions)
 
+	cm.mu.Lock()
+	cm.systemNote = ""
+	if conversation.SystemNote != nil {
+		cm.systemNote = *conversation.SystemNote
+	}
+	cm.mu.Unlock()
+
 	// Set ParentConversationID on toolSetConfig so that subagent tool is included
 	// in the display_data tools list when generating system prompt.
 	// This is also set in ensureLoop, but must be set here for Hydrate's system prompt creation.
@@ -235,10 +324,40 @@ func (cm *ConversationManager) Hydrate(ctx context.Context) error {
 	return nil
 }
 
+// SetSystemNote updates the pinned system note. An active loop picks it up
+// on its next LLM request.
+func (cm *ConversationManager) SetSystemNote(note string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.systemNote = note
+}
+
+// systemWithNote returns the system prompt with the current turn's override,
+// if any, applied and the pinned note, if any, prepended.
+func (cm *ConversationManager) systemWithNote(system []llm.SystemContent) []llm.SystemContent {
+	cm.mu.Lock()
+	note := cm.systemNote
+	override := cm.systemOverride
+	cm.mu.Unlock()
+	if override != nil {
+		system = override.apply(system)
+	}
+	if note == "" {
+		return system
+	}
+	pinned := llm.SystemContent{
+		Type: "text",
+		Text: "The user has pinned the following standing instruction for this conversation:\n\n" + note,
+	}
+	return append([]llm.SystemContent{pinned}, system...)
+}
+
 // AcceptUserMessage enqueues a user message, ensuring the loop is ready first.
+// params override the model's generation defaults for the turn it starts, and
+// a non-nil system overrides its system prompt.
 // The message is recorded to the database immediately so it appears in the UI,
 // even if the loop is busy processing a previous request.
-func (cm *ConversationManager) AcceptUserMessage(ctx context.Context, service llm.Service, modelID string, message llm.Message) (bool, error) {
+func (cm *ConversationManager) AcceptUserMessage(ctx context.Context, service llm.Service, modelID string, message llm.Message, params llm.Params, system *SystemPromptOverride) (bool, error) {
 	if service == nil {
 		return false, fmt.Errorf("llm service is required")
 	}
@@ -272,6 +391,10 @@ func (cm *ConversationManager) AcceptUserMessage(ctx context.Context, service ll
 		}
 	}
 
+	cm.mu.Lock()
+	cm.systemOverride = system
+	cm.mu.Unlock()
+	loopInstance.SetParams(params)
 	loopInstance.QueueUserMessage(message)
 
 	// Mark agent as working - we just queued work for the loop
@@ -601,14 +724,20 @@ func (cm *ConversationManager) createOrchestratorSystemPrompt(ctx context.Contex
 	// Build orchestrator-specific display data with the orchestrator's tool set.
 	// Pass SubagentRunner/SubagentDB/EnableBrowser so the tool list matches what ensureLoop creates.
 	ts := claudetool.NewOrchestratorToolSet(ctx, claudetool.OrchestratorToolSetConfig{
-		ContextDir:           contextDir,
-		WorkingDir:           cwd,
-		LLMProvider:          cm.toolSetConfig.LLMProvider,
-		SubagentRunner:       cm.toolSetConfig.SubagentRunner,
-		SubagentDB:           cm.toolSetConfig.SubagentDB,
-		ParentConversationID: cm.conversationID,
-		EnableBrowser:        cm.toolSetConfig.EnableBrowser,
-		CLIAgent:             cm.conversationOptions.SubagentBackend,
+		ContextDir:            contextDir,
+		WorkingDir:            cwd,
+		LLMProvider:           cm.toolSetConfig.LLMProvider,
+		SubagentRunner:        cm.toolSetConfig.SubagentRunner,
+		SubagentDB:            cm.toolSetConfig.SubagentDB,
+		ParentConversationID:  cm.conversationID,
+		EnableBrowser:         cm.toolSetConfig.EnableBrowser,
+		BrowserPool:           cm.toolSetConfig.BrowserPool,
+		BrowserViewportWidth:  cm.toolSetConfig.BrowserViewportWidth,
+		BrowserViewportHeight: cm.toolSetConfig.BrowserViewportHeight,
+		MaxDirImages:          cm.toolSetConfig.MaxDirImages,
+		MaxDirImageBytes:      cm.toolSetConfig.MaxDirImageBytes,
+		RedactPatterns:        cm.toolSetConfig.RedactPatterns,
+		CLIAgent:              cm.conversationOptions.SubagentBackend,
 	})
 	defer ts.Cleanup()
 
@@ -785,21 +914,39 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 	if conversationOpts.IsOrchestrator() {
 		contextDir := cm.orchestratorContextDir(cwd)
 		toolSet = claudetool.NewOrchestratorToolSet(processCtx, claudetool.OrchestratorToolSetConfig{
-			ContextDir:           contextDir,
-			SubagentRunner:       toolSetConfig.SubagentRunner,
-			SubagentDB:           toolSetConfig.SubagentDB,
-			ParentConversationID: conversationID,
-			ModelID:              modelID,
-			LLMProvider:          toolSetConfig.LLMProvider,
-			AvailableModels:      toolSetConfig.AvailableModels,
-			WorkingDir:           cwd,
-			OnWorkingDirChange:   toolSetConfig.OnWorkingDirChange,
-			EnableBrowser:        toolSetConfig.EnableBrowser,
-			CLIAgent:             conversationOpts.SubagentBackend,
+			ContextDir:            contextDir,
+			SubagentRunner:        toolSetConfig.SubagentRunner,
+			SubagentDB:            toolSetConfig.SubagentDB,
+			ParentConversationID:  conversationID,
+			ModelID:               modelID,
+			LLMProvider:           toolSetConfig.LLMProvider,
+			AvailableModels:       toolSetConfig.AvailableModels,
+			WorkingDir:            cwd,
+			OnWorkingDirChange:    toolSetConfig.OnWorkingDirChange,
+			EnableBrowser:         toolSetConfig.EnableBrowser,
+			BrowserPool:           toolSetConfig.BrowserPool,
+			BrowserViewportWidth:  toolSetConfig.BrowserViewportWidth,
+			BrowserViewportHeight: toolSetConfig.BrowserViewportHeight,
+			MaxDirImages:          toolSetConfig.MaxDirImages,
+			MaxDirImageBytes:      toolSetConfig.MaxDirImageBytes,
+			RedactPatterns:        toolSetConfig.RedactPatterns,
+			CLIAgent:              conversationOpts.SubagentBackend,
 		})
 	} else {
 		toolSet = claudetool.NewToolSet(processCtx, toolSetConfig)
 	}
+	getTools := toolSet.Tools
+	if len(conversationOpts.Tools) > 0 {
+		getTools = func() []*llm.Tool {
+			var allowed []*llm.Tool
+			for _, tool := range toolSet.Tools() {
+				if conversationOpts.AllowsTool(tool.Name) {
+					allowed = append(allowed, tool)
+				}
+			}
+			return allowed
+		}
+	}
 
 	// streamFlusher batches LLM stream deltas and flushes them periodically
 	// to avoid overwhelming the subpub channel (buffer=10) with hundreds
@@ -809,11 +956,14 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 	loopInstance := loop.NewLoop(loop.Config{
 		LLM:           service,
 		History:       history,
-		Tools:         toolSet.Tools(),
-		GetTools:      toolSet.Tools,
+		Tools:         getTools(),
+		GetTools:      getTools,
 		RecordMessage: recordMessage,
 		Logger:        logger,
 		System:        system,
+		GetSystem: func() []llm.SystemContent {
+			return cm.systemWithNote(system)
+		},
 		WorkingDir:    cwd,
 		GetWorkingDir: toolSet.WorkingDir().Get,
 		OnGitStateChange: func(ctx context.Context, state *gitstate.GitState) {
@@ -824,8 +974,9 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 				ToolProgress: &progress,
 			})
 		},
-		OnStreamDelta: sf.Push,
-		OnStreamDone:  sf.Flush,
+		OnStreamDelta:         sf.Push,
+		OnStreamDone:          sf.Flush,
+		MaxRepeatedToolErrors: cm.maxRepeatedToolErrs,
 	})
 
 	cm.mu.Lock()
@@ -850,7 +1001,7 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 
 	// Persist model for legacy conversations
 	if needsPersist {
-		if err := database.UpdateConversationModel(context.Background(), conversationID, modelID); err != nil {
+		if err := database.SetConversationModelIfUnset(context.Background(), conversationID, modelID); err != nil {
 			logger.Error("failed to persist model for legacy conversation", "error", err)
 		}
 	}
@@ -887,12 +1038,28 @@ func (cm *ConversationManager) stopLoop() {
 	}
 }
 
-// CancelConversation cancels the current conversation loop and records a cancelled tool result if a tool was in progress
-func (cm *ConversationManager) CancelConversation(ctx context.Context) error {
+// Who can cancel a turn, recorded as CancelUserData.CancelledBy.
+const (
+	CancelledByUser   = "user"
+	CancelledByParent = "parent" // the parent conversation re-sent a subagent's task
+)
+
+// CancelUserData is the structured data stored in user_data for the message
+// that ends a cancelled turn, so the transcript shows who stopped the turn
+// and why rather than it looking like the model ended it.
+type CancelUserData struct {
+	CancelledBy string `json:"cancelled_by"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// CancelConversation cancels the current conversation loop and records a cancelled tool result if a tool was in progress.
+// The message ending the turn records cancellation as its user_data, and its reason, if any, in its text.
+func (cm *ConversationManager) CancelConversation(ctx context.Context, cancellation CancelUserData) error {
 	cm.mu.Lock()
 	loopInstance := cm.loop
 	loopCtx := cm.loopCtx
 	cancel := cm.loopCancel
+	toolSet := cm.toolSet
 	cm.mu.Unlock()
 
 	if loopInstance == nil {
@@ -959,7 +1126,11 @@ func (cm *ConversationManager) CancelConversation(ctx context.Context) error {
 		}
 	}
 
-	// Cancel the context
+	// Abort in-flight tool actions (e.g., a stuck browser navigation) rather
+	// than waiting out their timeouts, then cancel the context
+	if toolSet != nil {
+		toolSet.CancelActions()
+	}
 	if cancel != nil {
 		cancel()
 	}
@@ -1019,13 +1190,17 @@ func (cm *ConversationManager) CancelConversation(ctx context.Context) error {
 
 	// Always record an assistant message with EndOfTurn to properly end the turn
 	// This ensures agentWorking() returns false, even if no tool was executing
+	endTurnText := "[Operation cancelled]"
+	if cancellation.Reason != "" {
+		endTurnText = fmt.Sprintf("[Operation cancelled: %s]", cancellation.Reason)
+	}
 	endTurnMessage := llm.Message{
 		Role:      llm.MessageRoleAssistant,
-		Content:   []llm.Content{{Type: llm.ContentTypeText, Text: "[Operation cancelled]"}},
+		Content:   []llm.Content{{Type: llm.ContentTypeText, Text: endTurnText}},
 		EndOfTurn: true,
 	}
 
-	if err := cm.recordMessage(ctx, endTurnMessage, llm.Usage{}); err != nil {
+	if err := cm.recordMessage(withMessageUserData(ctx, cancellation), endTurnMessage, llm.Usage{}); err != nil {
 		cm.logger.Error("Failed to record end turn message", "error", err)
 		return fmt.Errorf("failed to record end turn message: %w", err)
 	}
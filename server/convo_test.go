@@ -3,7 +3,9 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
@@ -69,3 +71,34 @@ func TestHydrateGeneratesSystemPromptWithSubagentTool(t *testing.T) {
 		t.Logf("Found tools: %v", displayData.Tools)
 	}
 }
+
+func TestHydrationConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.server.SetMaxConcurrentHydrations(1)
+
+	conv, err := h.db.CreateConversation(context.Background(), nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	// With the only slot taken, hydration waits until the caller gives up.
+	h.server.hydrationSem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := h.server.getOrCreateConversationManager(ctx, conv.ConversationID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected hydration to wait for a free slot, got %v", err)
+	}
+	<-h.server.hydrationSem
+
+	manager, err := h.server.getOrCreateConversationManager(context.Background(), conv.ConversationID)
+	if err != nil {
+		t.Fatalf("Failed to hydrate once a slot was free: %v", err)
+	}
+	if manager == nil {
+		t.Fatal("Expected a conversation manager")
+	}
+	if n := len(h.server.hydrationSem); n != 0 {
+		t.Errorf("Expected the hydration slot to be released, %d still held", n)
+	}
+}
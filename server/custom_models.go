@@ -143,7 +143,7 @@ func (s *Server) handleCreateModel(w http.ResponseWriter, r *http.Request) {
 
 	// Refresh the model manager's cache
 	if err := s.llmManager.RefreshCustomModels(); err != nil {
-		s.logger.Warn("Failed to refresh custom models cache", "error", err)
+		s.logger.WarnContext(r.Context(), "Failed to refresh custom models cache", "error", err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -241,7 +241,7 @@ func (s *Server) handleUpdateModel(w http.ResponseWriter, r *http.Request, model
 
 	// Refresh the model manager's cache
 	if err := s.llmManager.RefreshCustomModels(); err != nil {
-		s.logger.Warn("Failed to refresh custom models cache", "error", err)
+		s.logger.WarnContext(r.Context(), "Failed to refresh custom models cache", "error", err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -257,7 +257,7 @@ func (s *Server) handleDeleteModel(w http.ResponseWriter, r *http.Request, model
 
 	// Refresh the model manager's cache
 	if err := s.llmManager.RefreshCustomModels(); err != nil {
-		s.logger.Warn("Failed to refresh custom models cache", "error", err)
+		s.logger.WarnContext(r.Context(), "Failed to refresh custom models cache", "error", err)
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -309,7 +309,7 @@ func (s *Server) handleDuplicateModel(w http.ResponseWriter, r *http.Request, mo
 
 	// Refresh the model manager's cache
 	if err := s.llmManager.RefreshCustomModels(); err != nil {
-		s.logger.Warn("Failed to refresh custom models cache", "error", err)
+		s.logger.WarnContext(r.Context(), "Failed to refresh custom models cache", "error", err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"shelley.exe.dev/db/generated"
 	"shelley.exe.dev/llm"
@@ -538,6 +539,91 @@ func TestListDirectory(t *testing.T) {
 	})
 }
 
+// TestListDirectoryIncludeFiles tests listing files as well as directories,
+// and the sort orders.
+func TestListDirectoryIncludeFiles(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"zeta", "alpha"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, name), 0o755); err != nil {
+			t.Fatalf("failed to create dir %s: %v", name, err)
+		}
+	}
+	// Files get increasing mtimes in the order they are listed here.
+	now := time.Now()
+	for i, name := range []string{"b.txt", "a.txt", ".hidden", "c.txt"} {
+		file := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(file, []byte(strings.Repeat("x", i+1)), 0o644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+		mtime := now.Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatalf("failed to set mtime of %s: %v", name, err)
+		}
+	}
+
+	list := func(params string) []DirectoryEntry {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/list-directory?path="+tmpDir+params, nil)
+		w := httptest.NewRecorder()
+		h.server.handleListDirectory(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp ListDirectoryResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return resp.Entries
+	}
+	names := func(entries []DirectoryEntry) string {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	t.Run("sorted_by_name", func(t *testing.T) {
+		entries := list("&include_files=1&sort=name")
+		if got, want := names(entries), "alpha,zeta,a.txt,b.txt,c.txt"; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+		for _, e := range entries {
+			if e.IsDir != (e.Name == "alpha" || e.Name == "zeta") {
+				t.Errorf("%s: unexpected is_dir %v", e.Name, e.IsDir)
+			}
+		}
+		if entries[2].Size != 2 {
+			t.Errorf("expected a.txt to be 2 bytes, got %d", entries[2].Size)
+		}
+	})
+
+	t.Run("sorted_by_mtime", func(t *testing.T) {
+		entries := list("&include_files=1&sort=mtime")
+		if got := names(entries[2:]); got != "c.txt,a.txt,b.txt" {
+			t.Errorf("expected files newest first, got %s", got)
+		}
+	})
+
+	t.Run("show_hidden", func(t *testing.T) {
+		if got, want := names(list("&include_files=1&show_hidden=1")), "alpha,zeta,a.txt,b.txt,c.txt,.hidden"; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+
+	t.Run("invalid_sort", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/list-directory?path="+tmpDir+"&sort=size", nil)
+		w := httptest.NewRecorder()
+		h.server.handleListDirectory(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
 // TestConversationCwdReturnedInList tests that CWD is returned in the conversations list.
 func TestConversationCwdReturnedInList(t *testing.T) {
 	t.Parallel()
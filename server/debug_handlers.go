@@ -43,7 +43,7 @@ func (s *Server) handleDebugLLMRequestsAPI(w http.ResponseWriter, r *http.Reques
 
 	requests, err := s.db.ListRecentLLMRequests(ctx, limit)
 	if err != nil {
-		s.logger.Error("Failed to list LLM requests", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to list LLM requests", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -65,19 +65,17 @@ func (s *Server) handleDebugLLMRequestBody(w http.ResponseWriter, r *http.Reques
 
 	body, err := s.db.GetLLMRequestBody(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to get LLM request body", "error", err, "id", id)
+		s.logger.ErrorContext(ctx, "Failed to get LLM request body", "error", err, "id", id)
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
 	if body == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte("null"))
+		writeDebugBody(w, []byte("null"))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(*body))
+	writeDebugBody(w, []byte(*body))
 }
 
 // handleDebugLLMResponseBody returns the response body for a specific LLM request
@@ -93,19 +91,17 @@ func (s *Server) handleDebugLLMResponseBody(w http.ResponseWriter, r *http.Reque
 
 	body, err := s.db.GetLLMResponseBody(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to get LLM response body", "error", err, "id", id)
+		s.logger.ErrorContext(ctx, "Failed to get LLM response body", "error", err, "id", id)
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
 	if body == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte("null"))
+		writeDebugBody(w, []byte("null"))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(*body))
+	writeDebugBody(w, []byte(*body))
 }
 
 // handleDebugLLMRequestBodyFull returns the full reconstructed request body,
@@ -123,19 +119,30 @@ func (s *Server) handleDebugLLMRequestBodyFull(w http.ResponseWriter, r *http.Re
 	// Use the existing DB method to reconstruct the full body
 	fullBody, err := s.db.GetFullLLMRequestBody(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to get full LLM request body", "error", err, "id", id)
+		s.logger.ErrorContext(ctx, "Failed to get full LLM request body", "error", err, "id", id)
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
 	if fullBody == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte("null"))
+		writeDebugBody(w, []byte("null"))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(fullBody))
+	writeDebugBody(w, []byte(fullBody))
+}
+
+// writeDebugBody writes a stored LLM request/response body. Bodies are usually
+// JSON, but streaming (SSE) or provider error pages are stored verbatim, so
+// anything that isn't valid JSON is served as plain text rather than mislabeled.
+func writeDebugBody(w http.ResponseWriter, body []byte) {
+	if json.Valid(body) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(body)
 }
 
 const debugLLMRequestsHTML = `<!DOCTYPE html>
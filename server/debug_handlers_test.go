@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteDebugBodyContentType(t *testing.T) {
+	tests := []struct {
+		body        string
+		contentType string
+	}{
+		{`{"model":"x"}`, "application/json; charset=utf-8"},
+		{"null", "application/json; charset=utf-8"},
+		{"event: message_start\ndata: {\"type\":\"message_start\"}\n\n", "text/plain; charset=utf-8"},
+		{"<html><body>502 Bad Gateway</body></html>", "text/plain; charset=utf-8"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		writeDebugBody(w, []byte(test.body))
+		if got := w.Header().Get("Content-Type"); got != test.contentType {
+			t.Errorf("body %q: got Content-Type %q, expected %q", test.body, got, test.contentType)
+		}
+		if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("body %q: expected nosniff header", test.body)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("body %q: written body changed to %q", test.body, w.Body.String())
+		}
+	}
+}
@@ -88,7 +88,7 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 	// Get source conversation
 	sourceConv, err := s.db.GetConversationByID(ctx, req.SourceConversationID)
 	if err != nil {
-		s.logger.Error("Failed to get source conversation", "conversationID", req.SourceConversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get source conversation", "conversationID", req.SourceConversationID, "error", err)
 		http.Error(w, "Source conversation not found", http.StatusNotFound)
 		return
 	}
@@ -96,7 +96,7 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 	// Get messages from source conversation
 	messages, err := s.db.ListMessages(ctx, req.SourceConversationID)
 	if err != nil {
-		s.logger.Error("Failed to get messages", "conversationID", req.SourceConversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get messages", "conversationID", req.SourceConversationID, "error", err)
 		http.Error(w, "Failed to get messages", http.StatusInternalServerError)
 		return
 	}
@@ -106,9 +106,7 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 	if modelID == "" && sourceConv.Model != nil {
 		modelID = *sourceConv.Model
 	}
-	if modelID == "" {
-		modelID = s.defaultModel
-	}
+	modelID = s.resolveModel(modelID)
 
 	// Create new conversation
 	var cwdPtr *string
@@ -119,7 +117,7 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 	}
 	conversation, err := s.db.CreateConversation(ctx, nil, true, cwdPtr, &modelID, db.ConversationOptions{})
 	if err != nil {
-		s.logger.Error("Failed to create conversation", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to create conversation", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -147,7 +145,7 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 		ExcludedFromContext: true,
 	})
 	if err != nil {
-		s.logger.Error("Failed to create status message", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to create status message", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -159,7 +157,7 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 	// distillation to complete before being drained.
 	manager, err := s.getOrCreateConversationManager(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to create conversation manager for distill", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to create conversation manager for distill", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -276,7 +274,7 @@ func (s *Server) runDistillation(ctx context.Context, conversationID, sourceSlug
 	// Generate slug for the new conversation
 	slugCtx, slugCancel := context.WithTimeout(ctx, 15*time.Second)
 	defer slugCancel()
-	_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, distilledText, modelID)
+	_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, distilledText, modelID, s.slugPrompt, !s.noHeuristicSlugs)
 	if err != nil {
 		s.logger.Warn("Failed to generate slug", "conversationID", conversationID, "error", err)
 	} else {
@@ -378,7 +376,7 @@ func (s *Server) handleDistillReplace(w http.ResponseWriter, r *http.Request) {
 	// Get source conversation
 	sourceConv, err := s.db.GetConversationByID(ctx, req.SourceConversationID)
 	if err != nil {
-		s.logger.Error("Failed to get source conversation", "conversationID", req.SourceConversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get source conversation", "conversationID", req.SourceConversationID, "error", err)
 		http.Error(w, "Source conversation not found", http.StatusNotFound)
 		return
 	}
@@ -386,7 +384,7 @@ func (s *Server) handleDistillReplace(w http.ResponseWriter, r *http.Request) {
 	// Get messages from source conversation
 	messages, err := s.db.ListMessages(ctx, req.SourceConversationID)
 	if err != nil {
-		s.logger.Error("Failed to get messages", "conversationID", req.SourceConversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get messages", "conversationID", req.SourceConversationID, "error", err)
 		http.Error(w, "Failed to get messages", http.StatusInternalServerError)
 		return
 	}
@@ -396,9 +394,7 @@ func (s *Server) handleDistillReplace(w http.ResponseWriter, r *http.Request) {
 	if modelID == "" && sourceConv.Model != nil {
 		modelID = *sourceConv.Model
 	}
-	if modelID == "" {
-		modelID = s.defaultModel
-	}
+	modelID = s.resolveModel(modelID)
 
 	// Create new conversation (slug=nil, will be set after distillation)
 	var cwdPtr *string
@@ -409,7 +405,7 @@ func (s *Server) handleDistillReplace(w http.ResponseWriter, r *http.Request) {
 	}
 	conversation, err := s.db.CreateConversation(ctx, nil, true, cwdPtr, &modelID, db.ConversationOptions{})
 	if err != nil {
-		s.logger.Error("Failed to create conversation", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to create conversation", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -438,7 +434,7 @@ func (s *Server) handleDistillReplace(w http.ResponseWriter, r *http.Request) {
 		ExcludedFromContext: true,
 	})
 	if err != nil {
-		s.logger.Error("Failed to create status message", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to create status message", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -449,7 +445,7 @@ func (s *Server) handleDistillReplace(w http.ResponseWriter, r *http.Request) {
 	// Mark the conversation as distilling so queued messages wait.
 	manager, err := s.getOrCreateConversationManager(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to create conversation manager for distill-replace", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to create conversation manager for distill-replace", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -508,7 +504,7 @@ func (s *Server) runDistillReplace(ctx context.Context, newConvID, sourceConvID,
 	if sourceConv.Slug == nil {
 		slugCtx, slugCancel := context.WithTimeout(ctx, 15*time.Second)
 		defer slugCancel()
-		_, err = slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, newConvID, distilledText, modelID)
+		_, err = slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, newConvID, distilledText, modelID, s.slugPrompt, !s.noHeuristicSlugs)
 		if err != nil {
 			logger.Warn("Failed to generate slug for distill-replace", "error", err)
 		}
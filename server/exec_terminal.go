@@ -51,7 +51,7 @@ func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 		CompressionMode: websocket.CompressionDisabled,
 	})
 	if err != nil {
-		s.logger.Error("Failed to upgrade websocket", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to upgrade websocket", "error", err)
 		return
 	}
 	defer conn.Close(websocket.StatusInternalError, "internal error")
@@ -59,7 +59,7 @@ func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 	// Wait for init message with terminal size
 	var initMsg ExecMessage
 	if err := wsjson.Read(ctx, conn, &initMsg); err != nil {
-		s.logger.Error("Failed to read init message", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to read init message", "error", err)
 		conn.Close(websocket.StatusPolicyViolation, "no init message")
 		return
 	}
@@ -89,7 +89,7 @@ func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 		Rows: rows,
 	})
 	if err != nil {
-		s.logger.Error("Failed to start command with pty", "error", err, "cmd", cmd)
+		s.logger.ErrorContext(ctx, "Failed to start command with pty", "error", err, "cmd", cmd)
 		errMsg := ExecMessage{
 			Type: "error",
 			Data: err.Error(),
@@ -120,7 +120,7 @@ func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 			}
 			if err != nil {
 				if !errors.Is(err, io.EOF) {
-					s.logger.Debug("PTY read error", "error", err)
+					s.logger.DebugContext(ctx, "PTY read error", "error", err)
 				}
 				break
 			}
@@ -166,16 +166,16 @@ func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 				Type: "exit",
 				Data: fmt.Sprintf("%d", exitCode),
 			}
-			s.logger.Info("Sending exit message", "exitCode", exitCode)
+			s.logger.InfoContext(ctx, "Sending exit message", "exitCode", exitCode)
 			if err := wsjson.Write(ctx, conn, exitMsg); err != nil {
-				s.logger.Error("Failed to write exit message", "error", err)
+				s.logger.ErrorContext(ctx, "Failed to write exit message", "error", err)
 			}
-			s.logger.Info("Closing websocket normally")
+			s.logger.InfoContext(ctx, "Closing websocket normally")
 			conn.Close(websocket.StatusNormalClosure, "process exited")
 			return
 		case err := <-errChan:
 			if websocket.CloseStatus(err) != websocket.StatusNormalClosure {
-				s.logger.Debug("Websocket read error", "error", err)
+				s.logger.DebugContext(ctx, "Websocket read error", "error", err)
 			}
 			if shellCmd.Process != nil {
 				shellCmd.Process.Kill()
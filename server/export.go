@@ -0,0 +1,375 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+// Timeline event types
+const (
+	TimelineEventMessage    = "message"
+	TimelineEventToolCall   = "tool_call"
+	TimelineEventLLMRequest = "llm_request"
+)
+
+// TimelineEvent is one entry in a conversation's exported timeline.
+// Fields are populated according to Type.
+type TimelineEvent struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// DurationMs is set for tool calls and LLM requests.
+	DurationMs *int64 `json:"duration_ms,omitempty"`
+
+	// message and tool_call
+	MessageID   string `json:"message_id,omitempty"`
+	SequenceID  int64  `json:"sequence_id,omitempty"`
+	MessageType string `json:"message_type,omitempty"`
+	// Usage is the token/cost usage recorded for an agent message.
+	Usage *llm.Usage `json:"usage,omitempty"`
+
+	// tool_call
+	ToolName  string `json:"tool_name,omitempty"`
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	ToolError bool   `json:"tool_error,omitempty"`
+
+	// llm_request
+	LLMRequestID int64  `json:"llm_request_id,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Provider     string `json:"provider,omitempty"`
+	StatusCode   *int64 `json:"status_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// TimelineExport is the machine-readable event timeline of a conversation.
+type TimelineExport struct {
+	Conversation generated.Conversation `json:"conversation"`
+	Events       []TimelineEvent        `json:"events"`
+}
+
+// messageBatchSize is how many messages exports and full conversation fetches
+// read from the database at a time, so a huge conversation is never held in
+// memory all at once.
+var messageBatchSize int64 = 100
+
+// How exported messages carry image content (the images query parameter).
+const (
+	// ExportImagesInline keeps base64 image data in the messages.
+	ExportImagesInline = "inline"
+	// ExportImagesLink replaces image data with /api/message/{id}/image URLs.
+	ExportImagesLink = "link"
+	// ExportImagesOmit drops image data entirely.
+	ExportImagesOmit = "omit"
+)
+
+// handleExportConversation handles GET /api/conversation/<id>/export?format=timeline|jsonl|json|md
+//
+// timeline is a single JSON document of events. jsonl streams the conversation
+// on the first line followed by one message per line, flushing as it goes.
+// json is the conversation as GET /api/conversation/<id> returns it, also
+// written in batches. For both, images=inline|link|omit (default inline)
+// controls how images are included. md is a readable Markdown transcript.
+func (s *Server) handleExportConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "timeline", "jsonl", "json", "md":
+	default:
+		http.Error(w, "Unsupported export format (supported: timeline, jsonl, json, md)", http.StatusBadRequest)
+		return
+	}
+	images := r.URL.Query().Get("images")
+	switch images {
+	case "":
+		images = ExportImagesInline
+	case ExportImagesInline, ExportImagesLink, ExportImagesOmit:
+	default:
+		http.Error(w, "Unsupported images option (supported: inline, link, omit)", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var conversation generated.Conversation
+	err := s.db.Queries(ctx, func(q *generated.Queries) error {
+		var err error
+		conversation, err = q.GetConversation(ctx, conversationID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to export conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch format {
+	case "jsonl":
+		s.streamJSONLExport(w, r, conversation, images)
+		return
+	case "json":
+		s.streamJSONExport(w, r, conversation, images)
+		return
+	case "md":
+		s.streamMarkdownExport(w, r, conversation)
+		return
+	}
+
+	tb := newTimelineBuilder()
+	err = s.forEachMessageBatch(ctx, conversationID, messageBatchSize, func(messages []generated.Message) error {
+		for _, msg := range messages {
+			tb.addMessage(msg)
+		}
+		return nil
+	})
+	var requests []generated.ListLLMRequestsForConversationRow
+	if err == nil {
+		err = s.db.Queries(ctx, func(q *generated.Queries) error {
+			var err error
+			requests, err = q.ListLLMRequestsForConversation(ctx, &conversationID)
+			return err
+		})
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to export conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	tb.addLLMRequests(requests)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TimelineExport{
+		Conversation: conversation,
+		Events:       tb.timeline(),
+	})
+}
+
+// streamJSONLExport writes the conversation and then its messages as JSON
+// lines, one batch at a time, flushing after each batch. images is one of
+// the ExportImages options.
+func (s *Server) streamJSONLExport(w http.ResponseWriter, r *http.Request, conversation generated.Conversation, images string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	setExportDisposition(w, conversation, "jsonl")
+	flusher, _ := w.(http.Flusher)
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(conversation); err != nil {
+		return
+	}
+	err := s.forEachMessageBatch(r.Context(), conversation.ConversationID, messageBatchSize, func(messages []generated.Message) error {
+		apiMessages := toAPIMessages(messages)
+		s.pairWithPrevious(r.Context(), messages, apiMessages)
+		applyExportImages(messages, apiMessages, images)
+		for _, msg := range apiMessages {
+			if err := enc.Encode(msg); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// The response has started, so all we can do is stop writing.
+		s.logger.WarnContext(r.Context(), "JSONL export ended early", "conversationID", conversation.ConversationID, "error", err)
+	}
+}
+
+// applyExportImages puts image data back into, or drops it from, the API
+// messages of messages, which toAPIMessages made link to their images.
+// images is one of the ExportImages options.
+func applyExportImages(messages []generated.Message, apiMessages []APIMessage, images string) {
+	for i := range apiMessages {
+		switch images {
+		case ExportImagesInline:
+			apiMessages[i].LlmData = messages[i].LlmData
+		case ExportImagesOmit:
+			apiMessages[i].LlmData = omitImageDataFromLLMData(messages[i].LlmData)
+		}
+	}
+}
+
+// forEachMessageBatch calls fn with a conversation's messages in sequence
+// order, batchSize at a time. Each batch is read in its own read
+// transaction, so a slow fn doesn't hold a database connection.
+func (s *Server) forEachMessageBatch(ctx context.Context, conversationID string, batchSize int64, fn func([]generated.Message) error) error {
+	var after int64
+	for {
+		var batch []generated.Message
+		err := s.db.Queries(ctx, func(q *generated.Queries) error {
+			var err error
+			batch, err = db.ListMessagesPage(ctx, q, conversationID, db.MessagePageOptions{AfterSequenceID: after, Limit: batchSize})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if int64(len(batch)) < batchSize {
+			return nil
+		}
+		after = batch[len(batch)-1].SequenceID
+	}
+}
+
+// writeMessagesJSON writes a conversation's messages to w as a JSON array of
+// APIMessage, reading batchSize messages at a time and flushing w after each
+// batch if it is an http.Flusher. transform, if non-nil, adjusts each batch
+// before it is written. It returns the context window size reported by the
+// last message that has one.
+func (s *Server) writeMessagesJSON(ctx context.Context, w io.Writer, conversationID string, batchSize int64, transform func([]generated.Message, []APIMessage)) (uint64, error) {
+	flusher, _ := w.(http.Flusher)
+	bw := bufio.NewWriter(w)
+	bw.WriteByte('[')
+	first := true
+	var ctxSize uint64
+	err := s.forEachMessageBatch(ctx, conversationID, batchSize, func(messages []generated.Message) error {
+		apiMessages := toAPIMessages(messages)
+		s.pairWithPrevious(ctx, messages, apiMessages)
+		if transform != nil {
+			transform(messages, apiMessages)
+		}
+		if size := calculateContextWindowSize(apiMessages); size != 0 {
+			ctxSize = size
+		}
+		for _, msg := range apiMessages {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if !first {
+				bw.WriteByte(',')
+			}
+			first = false
+			bw.Write(data)
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	bw.WriteByte(']')
+	return ctxSize, bw.Flush()
+}
+
+// timelineBuilder merges messages, tool calls (from tool_result timing) and
+// recorded LLM requests into a single list ordered by time. It is fed one
+// message at a time and keeps only the events, not the messages.
+type timelineBuilder struct {
+	events    []TimelineEvent
+	toolNames map[string]string // tool_use ID -> tool name
+}
+
+func newTimelineBuilder() *timelineBuilder {
+	return &timelineBuilder{
+		events:    []TimelineEvent{},
+		toolNames: make(map[string]string),
+	}
+}
+
+// addMessage adds a message event, plus a tool_call event for each timed
+// tool result in it. Messages must be added in sequence order.
+func (tb *timelineBuilder) addMessage(msg generated.Message) {
+	ev := TimelineEvent{
+		Type:        TimelineEventMessage,
+		Time:        msg.CreatedAt,
+		MessageID:   msg.MessageID,
+		SequenceID:  msg.SequenceID,
+		MessageType: msg.Type,
+	}
+	if msg.UsageData != nil {
+		var usage llm.Usage
+		if err := json.Unmarshal([]byte(*msg.UsageData), &usage); err == nil && !usage.IsZero() {
+			ev.Usage = &usage
+		}
+	}
+	tb.events = append(tb.events, ev)
+
+	if msg.LlmData == nil {
+		return
+	}
+	var llmMsg llm.Message
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return
+	}
+	for _, c := range llmMsg.Content {
+		switch c.Type {
+		case llm.ContentTypeToolUse:
+			tb.toolNames[c.ID] = c.ToolName
+		case llm.ContentTypeToolResult:
+			if c.ToolUseStartTime == nil {
+				continue
+			}
+			tc := TimelineEvent{
+				Type:        TimelineEventToolCall,
+				Time:        *c.ToolUseStartTime,
+				MessageID:   msg.MessageID,
+				SequenceID:  msg.SequenceID,
+				MessageType: msg.Type,
+				ToolName:    tb.toolNames[c.ToolUseID],
+				ToolUseID:   c.ToolUseID,
+				ToolError:   c.ToolError,
+			}
+			if c.ToolUseEndTime != nil {
+				d := c.ToolUseEndTime.Sub(*c.ToolUseStartTime).Milliseconds()
+				tc.DurationMs = &d
+			}
+			tb.events = append(tb.events, tc)
+		}
+	}
+}
+
+// addLLMRequests adds an llm_request event for each recorded request.
+func (tb *timelineBuilder) addLLMRequests(requests []generated.ListLLMRequestsForConversationRow) {
+	for _, req := range requests {
+		// Requests are recorded when they finish; place them at their start.
+		start := req.CreatedAt
+		if req.DurationMs != nil {
+			start = start.Add(-time.Duration(*req.DurationMs) * time.Millisecond)
+		}
+		ev := TimelineEvent{
+			Type:         TimelineEventLLMRequest,
+			Time:         start,
+			DurationMs:   req.DurationMs,
+			LLMRequestID: req.ID,
+			Model:        req.Model,
+			Provider:     req.Provider,
+			StatusCode:   req.StatusCode,
+		}
+		if req.Error != nil {
+			ev.Error = *req.Error
+		}
+		tb.events = append(tb.events, ev)
+	}
+}
+
+// timeline returns the accumulated events ordered by time.
+func (tb *timelineBuilder) timeline() []TimelineEvent {
+	sort.SliceStable(tb.events, func(i, j int) bool {
+		return tb.events[i].Time.Before(tb.events[j].Time)
+	})
+	return tb.events
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+// exportName names an exported conversation: its slug if it has one,
+// otherwise its ID.
+func exportName(conversation generated.Conversation) string {
+	if conversation.Slug != nil && *conversation.Slug != "" {
+		return *conversation.Slug
+	}
+	return conversation.ConversationID
+}
+
+// setExportDisposition marks the response as a file download named after the
+// conversation.
+func setExportDisposition(w http.ResponseWriter, conversation generated.Conversation, ext string) {
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportName(conversation)+"."+ext))
+}
+
+// streamJSONExport writes the whole conversation as a single StreamResponse,
+// the same shape GET /api/conversation/<id> returns, except that images
+// follow the images option (inline by default) so the export can be imported
+// elsewhere. Messages are written one batch at a time, flushing after each,
+// and the conversation follows them.
+func (s *Server) streamJSONExport(w http.ResponseWriter, r *http.Request, conversation generated.Conversation, images string) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	setExportDisposition(w, conversation, "json")
+
+	io.WriteString(w, `{"messages":`)
+	ctxSize, err := s.writeMessagesJSON(ctx, w, conversation.ConversationID, messageBatchSize, func(messages []generated.Message, apiMessages []APIMessage) {
+		applyExportImages(messages, apiMessages, images)
+	})
+	if err != nil {
+		// The response has started, so all we can do is stop writing.
+		s.logger.WarnContext(ctx, "JSON export ended early", "conversationID", conversation.ConversationID, "error", err)
+		return
+	}
+
+	convData, err := json.Marshal(conversation)
+	if err != nil {
+		s.logger.WarnContext(ctx, "JSON export ended early", "conversationID", conversation.ConversationID, "error", err)
+		return
+	}
+	fmt.Fprintf(w, `,"conversation":%s`, convData)
+	if ctxSize != 0 {
+		fmt.Fprintf(w, `,"context_window_size":%d`, ctxSize)
+	}
+	io.WriteString(w, "}\n")
+}
+
+// streamMarkdownExport renders the conversation as a readable Markdown
+// document, one batch of messages at a time. System and git info messages
+// are left out; tool output goes in fenced code blocks and images are
+// linked rather than embedded.
+func (s *Server) streamMarkdownExport(w http.ResponseWriter, r *http.Request, conversation generated.Conversation) {
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	setExportDisposition(w, conversation, "md")
+	flusher, _ := w.(http.Flusher)
+
+	bw := bufio.NewWriter(w)
+	mw := &markdownWriter{w: bw, toolNames: make(map[string]string)}
+	fmt.Fprintf(bw, "# %s\n", exportName(conversation))
+
+	err := s.forEachMessageBatch(r.Context(), conversation.ConversationID, messageBatchSize, func(messages []generated.Message) error {
+		for _, msg := range messages {
+			mw.writeMessage(msg)
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// The response has started, so all we can do is stop writing.
+		s.logger.WarnContext(r.Context(), "Markdown export ended early", "conversationID", conversation.ConversationID, "error", err)
+	}
+}
+
+// markdownWriter renders messages as Markdown. Messages must be written in
+// sequence order so tool results can be labelled with their tool's name.
+type markdownWriter struct {
+	w         *bufio.Writer
+	toolNames map[string]string // tool_use ID -> tool name
+}
+
+func (mw *markdownWriter) writeMessage(msg generated.Message) {
+	var heading string
+	switch db.MessageType(msg.Type) {
+	case db.MessageTypeUser:
+		heading = "User"
+	case db.MessageTypeAgent:
+		heading = "Agent"
+	case db.MessageTypeTool:
+		heading = "Tool"
+	case db.MessageTypeError:
+		heading = "Error"
+	default:
+		return
+	}
+	llmMsg, ok := parseLLMData(msg)
+	if !ok {
+		return
+	}
+
+	fmt.Fprintf(mw.w, "\n## %s\n", heading)
+	for i, c := range llmMsg.Content {
+		switch c.Type {
+		case llm.ContentTypeText:
+			if c.MediaType != "" {
+				mw.writeImage(imageURL(msg.MessageID, i, -1))
+			} else if text := strings.TrimSpace(c.Text); text != "" {
+				fmt.Fprintf(mw.w, "\n%s\n", text)
+			}
+		case llm.ContentTypeToolUse:
+			mw.toolNames[c.ID] = c.ToolName
+			fmt.Fprintf(mw.w, "\n### Tool call: %s\n", c.ToolName)
+			mw.writeFenced("json", string(c.ToolInput))
+		case llm.ContentTypeToolResult:
+			mw.writeToolResult(msg.MessageID, i, c)
+		}
+	}
+}
+
+func (mw *markdownWriter) writeToolResult(messageID string, contentIdx int, c llm.Content) {
+	name := mw.toolNames[c.ToolUseID]
+	if name == "" {
+		name = c.ToolUseID
+	}
+	if c.ToolError {
+		fmt.Fprintf(mw.w, "\n### Tool error: %s\n", name)
+	} else {
+		fmt.Fprintf(mw.w, "\n### Tool result: %s\n", name)
+	}
+	if text := strings.TrimSpace(toolResultText(c.ToolResult)); text != "" {
+		mw.writeFenced("", text)
+	}
+
+	// Tools that save files (screenshots, recordings) say where in Display;
+	// prefer that over the copy of the image sent to the LLM.
+	if display, ok := c.Display.(map[string]any); ok {
+		if u, _ := display["url"].(string); u != "" {
+			if display["type"] == "screenshot" {
+				mw.writeImage(u)
+			} else {
+				fmt.Fprintf(mw.w, "\n[%s](%s)\n", u, u)
+			}
+			return
+		}
+	}
+	for j, tr := range c.ToolResult {
+		if tr.MediaType != "" {
+			mw.writeImage(imageURL(messageID, contentIdx, j))
+		}
+	}
+}
+
+func (mw *markdownWriter) writeImage(url string) {
+	fmt.Fprintf(mw.w, "\n![](%s)\n", url)
+}
+
+// writeFenced writes text as a fenced code block, using a fence longer than
+// any run of backticks in the text.
+func (mw *markdownWriter) writeFenced(lang, text string) {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	fmt.Fprintf(mw.w, "\n%s%s\n%s\n%s\n", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
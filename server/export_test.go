@@ -0,0 +1,351 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+func TestExportTimeline(t *testing.T) {
+	h := NewTestHarness(t)
+
+	h.NewConversation("change_dir: /tmp", "/tmp")
+	h.WaitToolResult()
+	h.WaitResponse()
+
+	req := httptest.NewRequest("GET", "/api/conversation/"+h.convID+"/export?format=timeline", nil)
+	w := httptest.NewRecorder()
+	h.server.handleExportConversation(w, req, h.convID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var export TimelineExport
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("failed to parse export: %v", err)
+	}
+	if export.Conversation.ConversationID != h.convID {
+		t.Errorf("expected conversation %s, got %s", h.convID, export.Conversation.ConversationID)
+	}
+
+	var messages int
+	var toolCall *TimelineEvent
+	for i, ev := range export.Events {
+		if i > 0 && ev.Time.Before(export.Events[i-1].Time) {
+			t.Errorf("events out of order at %d", i)
+		}
+		switch ev.Type {
+		case TimelineEventMessage:
+			messages++
+		case TimelineEventToolCall:
+			toolCall = &export.Events[i]
+		}
+	}
+	if messages < 3 {
+		t.Errorf("expected at least 3 message events, got %d", messages)
+	}
+	if toolCall == nil {
+		t.Fatal("expected a tool_call event")
+	}
+	if toolCall.ToolName != "change_dir" || toolCall.DurationMs == nil {
+		t.Errorf("expected timed change_dir tool call, got %+v", toolCall)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	h := NewTestHarness(t)
+
+	w := httptest.NewRecorder()
+	h.server.handleExportConversation(w, httptest.NewRequest("GET", "/api/conversation/nope/export?format=timeline", nil), "nope")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.server.handleExportConversation(w, httptest.NewRequest("GET", "/api/conversation/nope/export?format=pdf", nil), "nope")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	h := NewTestHarness(t)
+
+	oldBatchSize := messageBatchSize
+	messageBatchSize = 2
+	t.Cleanup(func() { messageBatchSize = oldBatchSize })
+
+	h.NewConversation("bash: echo hi", "/tmp")
+	h.WaitToolResult()
+	h.WaitResponse()
+
+	var want int64
+	if err := h.db.Queries(context.Background(), func(q *generated.Queries) error {
+		var err error
+		want, err = q.CountMessagesInConversation(context.Background(), h.convID)
+		return err
+	}); err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	if want <= messageBatchSize {
+		t.Fatalf("expected more than %d messages to span batches, got %d", messageBatchSize, want)
+	}
+
+	req := httptest.NewRequest("GET", "/api/conversation/"+h.convID+"/export?format=jsonl", nil)
+	w := httptest.NewRecorder()
+	h.server.handleExportConversation(w, req, h.convID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected Content-Type application/x-ndjson, got %s", ct)
+	}
+	if !w.Flushed {
+		t.Error("expected the export to be flushed while streaming")
+	}
+
+	scanner := bufio.NewScanner(w.Body)
+	scanner.Buffer(nil, 1<<20)
+	if !scanner.Scan() {
+		t.Fatal("expected a conversation line")
+	}
+	var conv generated.Conversation
+	if err := json.Unmarshal(scanner.Bytes(), &conv); err != nil || conv.ConversationID != h.convID {
+		t.Fatalf("unexpected conversation line %s: %v", scanner.Text(), err)
+	}
+	var got int64
+	var lastSeq int64
+	for scanner.Scan() {
+		var msg APIMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("failed to parse message line %s: %v", scanner.Text(), err)
+		}
+		if msg.SequenceID <= lastSeq {
+			t.Errorf("messages out of order: %d after %d", msg.SequenceID, lastSeq)
+		}
+		lastSeq = msg.SequenceID
+		got++
+	}
+	if got != want {
+		t.Errorf("expected %d message lines, got %d", want, got)
+	}
+}
+
+func TestExportJSONLImages(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	conv, err := h.db.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	imageData := strings.Repeat("QUJD", 1000)
+	msg, err := h.db.CreateMessage(ctx, db.CreateMessageParams{
+		ConversationID: conv.ConversationID,
+		Type:           db.MessageTypeUser,
+		LLMData: llm.Message{
+			Role: llm.MessageRoleUser,
+			Content: []llm.Content{{
+				Type:      llm.ContentTypeToolResult,
+				ToolUseID: "shot",
+				ToolResult: []llm.Content{
+					{Type: llm.ContentTypeText, Text: "Screenshot taken"},
+					{Type: llm.ContentTypeText, MediaType: "image/png", Data: imageData},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+
+	export := func(images string) llm.Content {
+		t.Helper()
+		url := "/api/conversation/" + conv.ConversationID + "/export?format=jsonl&images=" + images
+		w := httptest.NewRecorder()
+		h.server.handleExportConversation(w, httptest.NewRequest("GET", url, nil), conv.ConversationID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("images=%s: expected status 200, got %d: %s", images, w.Code, w.Body.String())
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("images=%s: expected 2 lines, got %d", images, len(lines))
+		}
+		var apiMsg APIMessage
+		if err := json.Unmarshal([]byte(lines[1]), &apiMsg); err != nil {
+			t.Fatalf("images=%s: failed to parse message: %v", images, err)
+		}
+		var llmMsg llm.Message
+		if err := json.Unmarshal([]byte(*apiMsg.LlmData), &llmMsg); err != nil {
+			t.Fatalf("images=%s: failed to parse llm_data: %v", images, err)
+		}
+		return llmMsg.Content[0].ToolResult[1]
+	}
+
+	if img := export(""); img.Data != imageData {
+		t.Errorf("expected inline image data by default, got %d bytes", len(img.Data))
+	}
+	if img := export("link"); img.Data != "" || img.DisplayImageURL != imageURL(msg.MessageID, 0, 1) {
+		t.Errorf("expected a linked image, got url %q and %d bytes", img.DisplayImageURL, len(img.Data))
+	}
+	if img := export("omit"); img.Data != "" || img.DisplayImageURL != "" || img.MediaType != "image/png" {
+		t.Errorf("expected an omitted image, got %+v", img)
+	}
+
+	w := httptest.NewRecorder()
+	h.server.handleExportConversation(w, httptest.NewRequest("GET", "/api/conversation/x/export?format=jsonl&images=base64", nil), conv.ConversationID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown images option, got %d", w.Code)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	oldBatchSize := messageBatchSize
+	messageBatchSize = 2
+	t.Cleanup(func() { messageBatchSize = oldBatchSize })
+
+	conv, err := h.db.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	imageData := strings.Repeat("QUJD", 1000)
+	for i := range 5 {
+		content := []llm.Content{{Type: llm.ContentTypeText, Text: fmt.Sprintf("message %d", i)}}
+		if i == 3 {
+			content = append(content, llm.Content{Type: llm.ContentTypeText, MediaType: "image/png", Data: imageData})
+		}
+		if _, err := h.db.CreateMessage(ctx, db.CreateMessageParams{
+			ConversationID: conv.ConversationID,
+			Type:           db.MessageTypeUser,
+			LLMData:        llm.Message{Role: llm.MessageRoleUser, Content: content},
+		}); err != nil {
+			t.Fatalf("failed to create message: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/conversation/"+conv.ConversationID+"/export?format=json", nil)
+	w := httptest.NewRecorder()
+	h.server.handleExportConversation(w, req, conv.ConversationID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !w.Flushed {
+		t.Error("expected the export to be flushed while streaming")
+	}
+	var resp StreamResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse JSON export: %v", err)
+	}
+	if resp.Conversation.ConversationID != conv.ConversationID {
+		t.Errorf("expected conversation %s, got %s", conv.ConversationID, resp.Conversation.ConversationID)
+	}
+	if len(resp.Messages) != 5 {
+		t.Fatalf("expected 5 messages across batches, got %d", len(resp.Messages))
+	}
+	for i, msg := range resp.Messages {
+		var llmMsg llm.Message
+		if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+			t.Fatalf("failed to parse llm_data of message %d: %v", i, err)
+		}
+		if got := llmMsg.Content[0].Text; got != fmt.Sprintf("message %d", i) {
+			t.Errorf("message %d is %q", i, got)
+		}
+		if i == 3 && llmMsg.Content[1].Data != imageData {
+			t.Errorf("expected the image data inline, got %d bytes", len(llmMsg.Content[1].Data))
+		}
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	slug := "markdown-export"
+	conv, err := h.db.CreateConversation(ctx, &slug, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	messages := []db.CreateMessageParams{
+		{Type: db.MessageTypeUser, LLMData: llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{
+			{Type: llm.ContentTypeText, Text: "Take a screenshot"},
+		}}},
+		{Type: db.MessageTypeAgent, LLMData: llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{
+			{Type: llm.ContentTypeText, Text: "Sure."},
+			{Type: llm.ContentTypeToolUse, ID: "shot", ToolName: "browser", ToolInput: json.RawMessage(`{"action":"screenshot"}`)},
+		}}},
+		{Type: db.MessageTypeTool, LLMData: llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{{
+			Type:      llm.ContentTypeToolResult,
+			ToolUseID: "shot",
+			ToolResult: []llm.Content{
+				{Type: llm.ContentTypeText, Text: "Screenshot taken (saved as /tmp/shot.png)"},
+				{Type: llm.ContentTypeText, MediaType: "image/png", Data: "QUJD"},
+			},
+			Display: map[string]any{"type": "screenshot", "url": "/api/read?path=%2Ftmp%2Fshot.png"},
+		}}}},
+		{Type: db.MessageTypeAgent, LLMData: llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{
+			{Type: llm.ContentTypeText, Text: "Here it is."},
+		}}},
+	}
+	for _, m := range messages {
+		m.ConversationID = conv.ConversationID
+		if _, err := h.db.CreateMessage(ctx, m); err != nil {
+			t.Fatalf("failed to create message: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/conversation/"+conv.ConversationID+"/export?format=md", nil)
+	w := httptest.NewRecorder()
+	h.server.handleExportConversation(w, req, conv.ConversationID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="markdown-export.md"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# markdown-export\n",
+		"## User\n\nTake a screenshot\n",
+		"## Agent\n\nSure.\n",
+		"### Tool call: browser\n\n```json\n{\"action\":\"screenshot\"}\n```\n",
+		"## Tool\n\n### Tool result: browser\n\n```\nScreenshot taken (saved as /tmp/shot.png)\n```\n",
+		"![](/api/read?path=%2Ftmp%2Fshot.png)",
+		"Here it is.",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Markdown export missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/api/message/") {
+		t.Errorf("expected the screenshot to link to the saved file only:\n%s", body)
+	}
+
+	req = httptest.NewRequest("GET", "/api/conversation/"+conv.ConversationID+"/export?format=json", nil)
+	w = httptest.NewRecorder()
+	h.server.handleExportConversation(w, req, conv.ConversationID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="markdown-export.json"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	var resp StreamResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse JSON export: %v", err)
+	}
+	if len(resp.Messages) != len(messages) || resp.Conversation.ConversationID != conv.ConversationID {
+		t.Errorf("expected %d messages of %s, got %d of %s", len(messages), conv.ConversationID, len(resp.Messages), resp.Conversation.ConversationID)
+	}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"shelley.exe.dev/db"
+)
+
+// FolderRequest is the body of POST /api/folders and PUT /api/folders/<id>
+type FolderRequest struct {
+	Name string `json:"name"`
+	// ParentID is the folder to put this one in; null for the top level
+	ParentID *string `json:"parent_id"`
+}
+
+// MoveConversationRequest is the body of POST /api/conversation/<id>/move
+type MoveConversationRequest struct {
+	// FolderID is the folder to move the conversation to; null takes it out
+	// of its folder
+	FolderID *string `json:"folder_id"`
+}
+
+// handleFolders handles /api/folders: GET lists every folder, POST creates one.
+func (s *Server) handleFolders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleListFolders(w, r)
+	case http.MethodPost:
+		s.handleCreateFolder(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleListFolders(w http.ResponseWriter, r *http.Request) {
+	folders, err := s.db.ListFolders(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Failed to list folders", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folders)
+}
+
+func (s *Server) handleCreateFolder(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeFolderRequest(w, r)
+	if !ok {
+		return
+	}
+	folder, err := s.db.CreateFolder(r.Context(), req.Name, req.ParentID)
+	if errors.Is(err, db.ErrFolderNotFound) {
+		http.Error(w, "Parent folder not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Failed to create folder", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(folder)
+}
+
+// handleFolder handles /api/folders/<id>: GET, PUT (rename and move) and
+// DELETE. Deleting a folder moves its contents up to its parent.
+func (s *Server) handleFolder(w http.ResponseWriter, r *http.Request) {
+	folderID := strings.TrimPrefix(r.URL.Path, "/api/folders/")
+	if folderID == "" || strings.Contains(folderID, "/") {
+		http.Error(w, "Invalid folder ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGetFolder(w, r, folderID)
+	case http.MethodPut:
+		s.handleUpdateFolder(w, r, folderID)
+	case http.MethodDelete:
+		s.handleDeleteFolder(w, r, folderID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleGetFolder(w http.ResponseWriter, r *http.Request, folderID string) {
+	folder, err := s.db.GetFolder(r.Context(), folderID)
+	if errors.Is(err, db.ErrFolderNotFound) {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Failed to get folder", "folderID", folderID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folder)
+}
+
+func (s *Server) handleUpdateFolder(w http.ResponseWriter, r *http.Request, folderID string) {
+	req, ok := decodeFolderRequest(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if _, err := s.db.GetFolder(ctx, folderID); errors.Is(err, db.ErrFolderNotFound) {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+	folder, err := s.db.UpdateFolder(ctx, folderID, req.Name, req.ParentID)
+	switch {
+	case errors.Is(err, db.ErrFolderCycle):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, db.ErrFolderNotFound):
+		http.Error(w, "Parent folder not found", http.StatusBadRequest)
+		return
+	case err != nil:
+		s.logger.ErrorContext(ctx, "Failed to update folder", "folderID", folderID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folder)
+}
+
+func (s *Server) handleDeleteFolder(w http.ResponseWriter, r *http.Request, folderID string) {
+	err := s.db.DeleteFolder(r.Context(), folderID)
+	if errors.Is(err, db.ErrFolderNotFound) {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Failed to delete folder", "folderID", folderID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeFolderRequest reads and validates a FolderRequest, writing an error
+// response if it is invalid.
+func decodeFolderRequest(w http.ResponseWriter, r *http.Request) (FolderRequest, bool) {
+	var req FolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// handleMoveConversation handles POST /conversation/<id>/move
+func (s *Server) handleMoveConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+
+	var req MoveConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.db.GetConversationByID(ctx, conversationID); err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	conversation, err := s.db.MoveConversation(ctx, conversationID, req.FolderID)
+	if errors.Is(err, db.ErrFolderNotFound) {
+		http.Error(w, "Folder not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to move conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Notify conversation list subscribers
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conversation,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversation)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+func TestFolderEndpoints(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	slug := "foldered"
+	conv, err := h.db.CreateConversation(ctx, &slug, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	do := func(handler http.HandlerFunc, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do(h.server.handleFolders, http.MethodPost, "/api/folders", `{"name": " work "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create folder: expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var folder generated.Folder
+	if err := json.Unmarshal(w.Body.Bytes(), &folder); err != nil {
+		t.Fatalf("failed to unmarshal folder: %v", err)
+	}
+	if folder.Name != "work" {
+		t.Errorf("folder name = %q, want %q", folder.Name, "work")
+	}
+
+	if w := do(h.server.handleFolders, http.MethodPost, "/api/folders", `{"name": ""}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty name: expected status 400, got %d", w.Code)
+	}
+	if w := do(h.server.handleFolder, http.MethodPut, "/api/folders/"+folder.ID, `{"name": "work", "parent_id": "`+folder.ID+`"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("folder inside itself: expected status 400, got %d", w.Code)
+	}
+
+	mux := h.server.conversationMux()
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/"+conv.ConversationID+"/move", strings.NewReader(`{"folder_id": "`+folder.ID+`"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("move conversation: expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = do(h.server.handleConversations, http.MethodGet, "/api/conversations?folder="+folder.ID, "")
+	var conversations []ConversationWithState
+	if err := json.Unmarshal(w.Body.Bytes(), &conversations); err != nil {
+		t.Fatalf("failed to unmarshal list: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].ConversationID != conv.ConversationID {
+		t.Errorf("unexpected conversations in folder: %+v", conversations)
+	}
+
+	if w := do(h.server.handleFolder, http.MethodDelete, "/api/folders/"+folder.ID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete folder: expected status 204, got %d", w.Code)
+	}
+	if w := do(h.server.handleFolder, http.MethodGet, "/api/folders/"+folder.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted folder: expected status 404, got %d", w.Code)
+	}
+	got, err := h.db.GetConversationByID(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if got.FolderID != nil {
+		t.Errorf("conversation still in deleted folder %s", *got.FolderID)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+// ForkConversationRequest is the body of POST /conversation/<id>/fork
+type ForkConversationRequest struct {
+	// SequenceID is the last message copied into the fork
+	SequenceID int64 `json:"sequence_id"`
+}
+
+// handleForkConversation handles POST /conversation/<id>/fork. It copies the
+// conversation's messages up to and including the given sequence ID into a
+// new conversation, leaving the original untouched, and returns the fork.
+func (s *Server) handleForkConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+
+	var req ForkConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.SequenceID <= 0 {
+		http.Error(w, "sequence_id must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	source, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	fork, err := s.db.ForkConversation(ctx, conversationID, req.SequenceID)
+	if errors.Is(err, db.ErrForkPointNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to fork conversation", "conversationID", conversationID, "sequenceID", req.SequenceID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.InfoContext(ctx, "Forked conversation", "conversationID", conversationID, "forkID", fork.ConversationID, "sequenceID", req.SequenceID)
+
+	if source.Slug != nil {
+		if named, err := s.nameFork(ctx, fork.ConversationID, *source.Slug); err != nil {
+			s.logger.WarnContext(ctx, "Failed to name forked conversation", "forkID", fork.ConversationID, "error", err)
+		} else {
+			fork = named
+		}
+	}
+
+	// Load the copied history now, so the first message sent to the fork continues from it
+	if _, err := s.getOrCreateConversationManager(ctx, fork.ConversationID); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to hydrate forked conversation", "forkID", fork.ConversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: fork,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(fork)
+}
+
+// nameFork gives a fork the slug "<sourceSlug>-fork", numbered if that is taken.
+func (s *Server) nameFork(ctx context.Context, forkID, sourceSlug string) (*generated.Conversation, error) {
+	return s.claimSlug(ctx, forkID, func(attempt int) string {
+		if attempt == 0 {
+			return sourceSlug + "-fork"
+		}
+		return fmt.Sprintf("%s-fork-%d", sourceSlug, attempt+1)
+	})
+}
+
+// claimSlug sets the first of candidate(0), candidate(1), ... that no other
+// conversation has as the conversation's slug.
+func (s *Server) claimSlug(ctx context.Context, conversationID string, candidate func(attempt int) string) (*generated.Conversation, error) {
+	var err error
+	for attempt := 0; attempt < 100; attempt++ {
+		var conv *generated.Conversation
+		conv, err = s.db.UpdateConversationAutoSlug(ctx, conversationID, candidate(attempt))
+		if err == nil {
+			return conv, nil
+		}
+		if !strings.Contains(strings.ToLower(err.Error()), "unique") {
+			return nil, err
+		}
+	}
+	return nil, err
+}
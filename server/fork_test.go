@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+func TestForkConversationEndpoint(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	h.NewConversation("hello", "")
+	h.WaitResponse()
+	h.Chat("hello again")
+	h.WaitResponse()
+	sourceID := h.ConversationID()
+	if _, err := h.db.RenameConversation(ctx, sourceID, "greetings"); err != nil {
+		t.Fatalf("failed to rename conversation: %v", err)
+	}
+
+	messages, err := h.db.ListMessages(ctx, sourceID)
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	// Fork just after the first reply
+	var forkSeq int64
+	for _, m := range messages {
+		if m.Type == string(db.MessageTypeAgent) {
+			forkSeq = m.SequenceID
+			break
+		}
+	}
+
+	mux := h.server.conversationMux()
+	req := httptest.NewRequest("POST", "/"+sourceID+"/fork", strings.NewReader(fmt.Sprintf(`{"sequence_id":%d}`, forkSeq)))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var fork generated.Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &fork); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if fork.ForkedFrom == nil || *fork.ForkedFrom != sourceID {
+		t.Errorf("expected forked_from %q, got %v", sourceID, fork.ForkedFrom)
+	}
+	if fork.Slug == nil || *fork.Slug != "greetings-fork" {
+		t.Errorf("expected slug %q, got %v", "greetings-fork", fork.Slug)
+	}
+
+	forkMessages, err := h.db.ListMessages(ctx, fork.ConversationID)
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	if len(forkMessages) == 0 || forkMessages[len(forkMessages)-1].SequenceID != forkSeq {
+		t.Errorf("expected the fork to end at sequence %d, got %d messages", forkSeq, len(forkMessages))
+	}
+
+	h.server.mu.Lock()
+	manager, ok := h.server.activeConversations[fork.ConversationID]
+	h.server.mu.Unlock()
+	if !ok {
+		t.Fatal("expected an active conversation manager for the fork")
+	}
+	manager.mu.Lock()
+	hydrated, hasEvents := manager.hydrated, manager.hasConversationEvents
+	manager.mu.Unlock()
+	if !hydrated || !hasEvents {
+		t.Errorf("expected the fork's manager to be hydrated from the copied messages, got hydrated=%v events=%v", hydrated, hasEvents)
+	}
+
+	for _, tc := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/" + sourceID + "/fork", `{"sequence_id":0}`, http.StatusBadRequest},
+		{"/" + sourceID + "/fork", `{"sequence_id":9999}`, http.StatusBadRequest},
+		{"/missing/fork", `{"sequence_id":1}`, http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body)))
+		if w.Code != tc.want {
+			t.Errorf("POST %s %s: expected status %d, got %d", tc.path, tc.body, tc.want, w.Code)
+		}
+	}
+}
@@ -5,18 +5,21 @@ import (
 	"context"
 	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -46,6 +49,7 @@ func detectCLIAgents() []string {
 }
 
 // handleRead serves files from limited allowed locations via /api/read?path=
+// Only file types in the server's read extension allowlist are served.
 func (s *Server) handleRead(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -56,20 +60,52 @@ func (s *Server) handleRead(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "path required", http.StatusBadRequest)
 		return
 	}
-	// Clean and enforce prefix restriction
-	clean := filepath.Clean(p)
-	if !(strings.HasPrefix(clean, browse.ScreenshotDir+"/") || strings.HasPrefix(clean, browse.ConsoleLogsDir+"/") || strings.HasPrefix(clean, browse.ScreencastDir+"/")) {
+	clean, ok := assetPath(p)
+	if !ok {
+		http.Error(w, "path not allowed", http.StatusForbidden)
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(clean))
+	if !s.readAllowed(clean) {
+		http.Error(w, "file type not allowed", http.StatusForbidden)
+		return
+	}
+	thumb := 0
+	if v := r.URL.Query().Get("thumb"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < minThumbnailDim || n > maxThumbnailDim {
+			http.Error(w, fmt.Sprintf("thumb must be between %d and %d", minThumbnailDim, maxThumbnailDim), http.StatusBadRequest)
+			return
+		}
+		if !thumbnailExtensions[ext] {
+			http.Error(w, "thumbnails are only available for raster images", http.StatusBadRequest)
+			return
+		}
+		thumb = n
+	}
+	// The prefix check alone would let a symlink inside an asset directory
+	// serve any file it points at, so check where it actually leads.
+	resolved, ok, err := resolveAssetPath(clean)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if !ok || !s.readAllowed(resolved) {
 		http.Error(w, "path not allowed", http.StatusForbidden)
 		return
 	}
-	f, err := os.Open(clean)
+	f, err := os.Open(resolved)
 	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
 	defer f.Close()
-	// Determine content type by extension first, then fallback to sniffing
-	ext := strings.ToLower(filepath.Ext(clean))
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	// Set the content type by extension; otherwise ServeContent sniffs it
 	switch ext {
 	case ".png":
 		w.Header().Set("Content-Type", "image/png")
@@ -85,19 +121,116 @@ func (s *Server) handleRead(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "video/mp4")
 	case ".json":
 		w.Header().Set("Content-Type", "application/json")
-	default:
-		buf := make([]byte, 512)
-		n, _ := f.Read(buf)
-		contentType := http.DetectContentType(buf[:n])
-		if _, err := f.Seek(0, 0); err != nil {
-			http.Error(w, "seek failed", http.StatusInternalServerError)
-			return
+	}
+	// Size and modification time change whenever a screenshot is re-captured,
+	// so they make a cheap validator without hashing large videos.
+	etag := fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+	if thumb > 0 {
+		etag = fmt.Sprintf(`"%x-%x-t%d"`, info.Size(), info.ModTime().UnixNano(), thumb)
+	}
+	w.Header().Set("ETag", etag)
+	// Use must-revalidate so an overwritten file is picked up immediately,
+	// while unchanged files are answered with 304.
+	w.Header().Set("Cache-Control", "public, max-age=0, must-revalidate")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if thumb > 0 {
+		s.serveThumbnail(w, r, f, info, thumb)
+		return
+	}
+	// ServeContent handles Range requests, so videos can be seeked
+	http.ServeContent(w, r, clean, info.ModTime(), f)
+}
+
+// assetPath cleans p and reports whether it is inside one of the browser
+// tool's output directories, the only files /api/read serves or deletes.
+func assetPath(p string) (string, bool) {
+	clean := filepath.Clean(p)
+	for _, dir := range assetDirs {
+		if strings.HasPrefix(clean, dir+"/") {
+			return clean, true
 		}
-		w.Header().Set("Content-Type", contentType)
 	}
-	// Reasonable short-term caching for assets, allow quick refresh during sessions
-	w.Header().Set("Cache-Control", "public, max-age=300")
-	io.Copy(w, f)
+	return clean, false
+}
+
+// readAllowed reports whether /api/read serves the file at path, by its
+// extension. CPU profiles in the console logs directory are always served,
+// since the UI links them for opening in speedscope.
+func (s *Server) readAllowed(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if s.readExtensions[ext] {
+		return true
+	}
+	if ext != ".json" {
+		return false
+	}
+	if strings.HasPrefix(path, browse.ConsoleLogsDir+"/") {
+		return true
+	}
+	// path may have been resolved through a symlinked /tmp (macOS)
+	real, err := filepath.EvalSymlinks(browse.ConsoleLogsDir)
+	return err == nil && strings.HasPrefix(path, real+"/")
+}
+
+// assetDirs are the browser tool's output directories.
+var assetDirs = []string{browse.ScreenshotDir, browse.ConsoleLogsDir, browse.ScreencastDir}
+
+// resolveAssetPath follows any symlinks in clean, a path accepted by
+// assetPath, and reports whether the file it leads to is still inside one of
+// the asset directories. An error means the path doesn't resolve.
+func resolveAssetPath(clean string) (string, bool, error) {
+	resolved, err := filepath.EvalSymlinks(clean)
+	if err != nil {
+		return "", false, err
+	}
+	for _, dir := range assetDirs {
+		// The directories may themselves be behind a symlink (/tmp on macOS).
+		real, err := filepath.EvalSymlinks(dir)
+		if err == nil && strings.HasPrefix(resolved, real+"/") {
+			return resolved, true, nil
+		}
+	}
+	return resolved, false, nil
+}
+
+// handleDeleteAsset removes a screenshot, upload or other browser tool output
+// file, subject to the same path restriction as handleRead.
+func (s *Server) handleDeleteAsset(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		http.Error(w, "path required", http.StatusBadRequest)
+		return
+	}
+	clean, ok := assetPath(p)
+	if !ok {
+		http.Error(w, "path not allowed", http.StatusForbidden)
+		return
+	}
+	// As in handleRead, a symlink inside an asset directory mustn't reach
+	// files outside it.
+	resolved, ok, err := resolveAssetPath(clean)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		http.Error(w, "path not allowed", http.StatusForbidden)
+		return
+	}
+	if info, err := os.Stat(resolved); err != nil || info.IsDir() {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err := os.Remove(resolved); err != nil {
+		s.logger.ErrorContext(r.Context(), "Failed to delete asset", "path", clean, "error", err)
+		http.Error(w, "failed to delete file", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "path": clean})
 }
 
 // handleUserAgentsMd returns the current content of the user's AGENTS.md.
@@ -173,43 +306,74 @@ func userAgentsMdPath() (string, error) {
 	return filepath.Join(home, ".config", "shelley", "AGENTS.md"), nil
 }
 
+// maxUploadSize limits the total size of the files in one /api/upload request.
+const maxUploadSize = 10 * 1024 * 1024
+
+// uploadExtensions are the extensions uploads are saved with, by sniffed
+// media type. Types missing here fall back to the mime package's table.
+var uploadExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// UploadResponse is the response to POST /api/upload.
+type UploadResponse struct {
+	Path  string   `json:"path"` // the first file, for clients that upload one at a time
+	Paths []string `json:"paths"`
+}
+
 // handleUpload handles file uploads via POST /api/upload
-// Files are saved to the ScreenshotDir with a random filename
+// Each "file" field is saved to the ScreenshotDir with a random filename.
+// The file's type is sniffed from its content and must be one of the
+// configured upload types; the saved extension comes from that type, never
+// from the client's filename.
 func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Limit to 10MB file size
-	r.Body = http.MaxBytesReader(w, r.Body, 10*1024*1024)
+	// The limit covers all files together, not each one
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
 	// Parse the multipart form
-	if err := r.ParseMultipartForm(10 * 1024 * 1024); err != nil {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
 		http.Error(w, "failed to parse form: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Get the file from the multipart form
-	file, handler, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "failed to get uploaded file: "+err.Error(), http.StatusBadRequest)
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		http.Error(w, "failed to get uploaded file: no file field", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
-
-	// Generate a unique ID (8 random bytes converted to 16 hex chars)
-	randBytes := make([]byte, 8)
-	if _, err := rand.Read(randBytes); err != nil {
-		http.Error(w, "failed to generate random filename: "+err.Error(), http.StatusInternalServerError)
+	var total int64
+	for _, fh := range files {
+		total += fh.Size
+	}
+	if total > maxUploadSize {
+		http.Error(w, fmt.Sprintf("uploaded files total %d bytes, over the %d byte limit", total, maxUploadSize), http.StatusRequestEntityTooLarge)
 		return
 	}
 
-	// Get file extension from the original filename
-	ext := filepath.Ext(handler.Filename)
-
-	// Create a unique filename in the ScreenshotDir
-	filename := filepath.Join(browse.ScreenshotDir, fmt.Sprintf("upload_%s%s", hex.EncodeToString(randBytes), ext))
+	// Check every file before saving any, so a rejected upload leaves nothing behind
+	exts := make([]string, len(files))
+	for i, fh := range files {
+		mediaType, err := sniffUpload(fh)
+		if err != nil {
+			http.Error(w, "failed to get uploaded file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.uploadTypes[mediaType] {
+			http.Error(w, fmt.Sprintf("%s: file type %s is not allowed", fh.Filename, mediaType), http.StatusUnsupportedMediaType)
+			return
+		}
+		exts[i] = uploadExtension(mediaType)
+	}
 
 	// Ensure the directory exists
 	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
@@ -217,23 +381,84 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the destination file
+	paths := make([]string, 0, len(files))
+	for i, fh := range files {
+		filename, err := saveUpload(fh, exts[i])
+		if err != nil {
+			// Don't leave the files saved so far behind when the request fails
+			for _, p := range paths {
+				os.Remove(p)
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		paths = append(paths, filename)
+	}
+
+	// Return the paths to the saved files
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UploadResponse{Path: paths[0], Paths: paths})
+}
+
+// sniffUpload returns the media type of an uploaded file, without
+// parameters, as detected from its first 512 bytes.
+func sniffUpload(fh *multipart.FileHeader) (string, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	mediaType, _, _ := strings.Cut(http.DetectContentType(buf[:n]), ";")
+	return mediaType, nil
+}
+
+// uploadExtension returns the extension to save a file of mediaType with,
+// or "" if there is no known one.
+func uploadExtension(mediaType string) string {
+	if ext, ok := uploadExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// saveUpload copies an uploaded file into the ScreenshotDir under a random
+// name with extension ext, and returns its path.
+func saveUpload(fh *multipart.FileHeader, ext string) (string, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to get uploaded file: %w", err)
+	}
+	defer file.Close()
+
+	// Generate a unique ID (8 random bytes converted to 16 hex chars)
+	randBytes := make([]byte, 8)
+	if _, err := rand.Read(randBytes); err != nil {
+		return "", fmt.Errorf("failed to generate random filename: %w", err)
+	}
+
+	// Create a unique filename in the ScreenshotDir
+	filename := filepath.Join(browse.ScreenshotDir, fmt.Sprintf("upload_%s%s", hex.EncodeToString(randBytes), ext))
+
 	destFile, err := os.Create(filename)
 	if err != nil {
-		http.Error(w, "failed to create destination file: "+err.Error(), http.StatusInternalServerError)
-		return
+		return "", fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer destFile.Close()
 
-	// Copy the file contents to the destination file
 	if _, err := io.Copy(destFile, file); err != nil {
-		http.Error(w, "failed to save file: "+err.Error(), http.StatusInternalServerError)
-		return
+		os.Remove(filename)
+		return "", fmt.Errorf("failed to save file: %w", err)
 	}
-
-	// Return the path to the saved file
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"path": filename})
+	return filename, nil
 }
 
 // handleUploadToCwd handles file uploads to the working directory via POST /api/upload-to-cwd.
@@ -390,8 +615,8 @@ func (s *Server) staticHandler(fsys http.FileSystem) http.Handler {
 			return
 		}
 
-		// For JS and CSS files, serve from .gz files (only .gz versions are embedded)
-		if strings.HasSuffix(r.URL.Path, ".js") || strings.HasSuffix(r.URL.Path, ".css") {
+		// For JS, CSS and source map files, serve from .gz files (only .gz versions are embedded)
+		if ext := filepath.Ext(r.URL.Path); ext == ".js" || ext == ".css" || ext == ".map" {
 			gzPath := r.URL.Path + ".gz"
 			gzFile, err := fsys.Open(gzPath)
 			if err != nil {
@@ -422,7 +647,12 @@ func (s *Server) staticHandler(fsys http.FileSystem) http.Handler {
 				}
 			}
 
-			w.Header().Set("Content-Type", mime.TypeByExtension(filepath.Ext(r.URL.Path)))
+			contentType := mime.TypeByExtension(ext)
+			if ext == ".map" {
+				// Source maps are JSON; the system MIME table rarely lists .map.
+				contentType = "application/json"
+			}
+			w.Header().Set("Content-Type", contentType)
 			w.Header().Set("Vary", "Accept-Encoding")
 			// Use must-revalidate so browsers check ETag on each request.
 			// We can't use immutable since we don't have content-hashed filenames.
@@ -493,7 +723,9 @@ func generateFaviconSVG(hostname string) string {
 	)
 }
 
-// serveIndexWithInit serves index.html with injected initialization data
+// serveIndexWithInit serves index.html with injected initialization data.
+// With a static index only its asset links are rewritten for the base path,
+// and the client fetches the init data from api/config instead.
 func (s *Server) serveIndexWithInit(w http.ResponseWriter, r *http.Request, fs http.FileSystem) {
 	// Read index.html from the filesystem
 	file, err := fs.Open("/index.html")
@@ -508,43 +740,65 @@ func (s *Server) serveIndexWithInit(w http.ResponseWriter, r *http.Request, fs h
 		http.Error(w, "Failed to read index.html", http.StatusInternalServerError)
 		return
 	}
+	if s.staticIndex {
+		w.Write([]byte(s.withBasePathLinks(string(indexHTML))))
+		return
+	}
 
-	// Build initialization data
-	modelList := s.getModelList()
+	initJSON, err := json.Marshal(s.initData())
+	if err != nil {
+		http.Error(w, "Failed to marshal init data", http.StatusInternalServerError)
+		return
+	}
 
-	// Select default model - use configured default if available, otherwise first ready model
-	// If no models are available, default_model should be empty
-	defaultModel := ""
-	if len(modelList) > 0 {
-		defaultModel = s.defaultModel
-		if defaultModel == "" {
-			defaultModel = models.Default().ID
-		}
-		defaultModelAvailable := false
-		for _, m := range modelList {
-			if m.ID == defaultModel && m.Ready {
-				defaultModelAvailable = true
-				break
-			}
-		}
-		if !defaultModelAvailable {
-			// Fall back to first ready model
-			for _, m := range modelList {
-				if m.Ready {
-					defaultModel = m.ID
-					break
-				}
-			}
-		}
+	// Generate favicon as data URI
+	// Include the listening port in the hash so demo servers on different ports
+	// get visually distinct favicons.
+	hostname := publicHostname()
+	faviconKey := hostname
+	if s.listenPort != 0 {
+		faviconKey = fmt.Sprintf("%s:%d", hostname, s.listenPort)
+	}
+	faviconSVG := generateFaviconSVG(faviconKey)
+	faviconDataURI := "data:image/svg+xml," + url.PathEscape(faviconSVG)
+	faviconLink := fmt.Sprintf(`<link rel="icon" type="image/svg+xml" href="%s"/>`, faviconDataURI)
+
+	// Inject the script tag and favicon before </head>
+	initScript := fmt.Sprintf(`<script>window.__SHELLEY_INIT__=%s;</script>`, initJSON)
+	injection := faviconLink + initScript
+	modifiedHTML := strings.Replace(string(indexHTML), "</head>", injection+"</head>", 1)
+
+	w.Write([]byte(s.withBasePathLinks(modifiedHTML)))
+}
+
+// withBasePathLinks points the root-relative asset links in index.html under
+// the base path.
+func (s *Server) withBasePathLinks(html string) string {
+	if s.basePath == "" {
+		return html
 	}
+	return strings.NewReplacer(
+		`href="/`, `href="`+s.basePath+`/`,
+		`src="/`, `src="`+s.basePath+`/`,
+	).Replace(html)
+}
+
+// initData returns the client's initialization data, which is injected into
+// index.html as window.__SHELLEY_INIT__ and served by /api/config.
+func (s *Server) initData() map[string]interface{} {
+	modelList := s.getModelList()
+	defaultModel := s.defaultModelID(modelList)
 
 	// Get hostname
 	hostname := publicHostname()
 
 	// Get default working directory
-	defaultCwd, err := os.Getwd()
-	if err != nil {
-		defaultCwd = "/"
+	defaultCwd := s.newConvTemplate.Cwd
+	if defaultCwd == "" {
+		var err error
+		if defaultCwd, err = os.Getwd(); err != nil {
+			defaultCwd = "/"
+		}
 	}
 
 	// Get home directory for tilde display
@@ -569,38 +823,86 @@ func (s *Server) serveIndexWithInit(w http.ResponseWriter, r *http.Request, fs h
 	if len(s.links) > 0 {
 		initData["links"] = s.links
 	}
+	if s.basePath != "" {
+		initData["base_path"] = s.basePath
+	}
 
 	// Inject notification channel type metadata for the settings modal
 	initData["notification_channel_types"] = s.getNotificationChannelTypes()
 	initData["cli_agents"] = detectCLIAgents()
+	return initData
+}
 
-	initJSON, err := json.Marshal(initData)
-	if err != nil {
-		http.Error(w, "Failed to marshal init data", http.StatusInternalServerError)
-		return
+// ListPage is a page of a list with paging metadata, returned by the
+// conversation list endpoints when ?meta=1 is set.
+type ListPage[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+	// HasMore reports whether there are items after this page.
+	HasMore bool `json:"has_more"`
+}
+
+// CursorPage is a page of conversations returned with ?cursor=. Pass
+// NextCursor back as cursor to get the next page; it is empty on the last.
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// encodeConversationCursor returns an opaque cursor for the page after conv.
+func encodeConversationCursor(conv generated.Conversation) string {
+	raw := strconv.FormatInt(conv.UpdatedAt.Unix(), 10) + ":" + conv.ConversationID
+	if conv.Pinned {
+		raw = "pinned:" + raw
 	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
 
-	// Generate favicon as data URI
-	// Include the listening port in the hash so demo servers on different ports
-	// get visually distinct favicons.
-	faviconKey := hostname
-	if s.listenPort != 0 {
-		faviconKey = fmt.Sprintf("%s:%d", hostname, s.listenPort)
+// decodeConversationCursor parses a cursor from encodeConversationCursor,
+// returning whether the cursor conversation is pinned, its updated_at and ID.
+func decodeConversationCursor(cursor string) (bool, time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return false, time.Time{}, "", errors.New("invalid cursor")
 	}
-	faviconSVG := generateFaviconSVG(faviconKey)
-	faviconDataURI := "data:image/svg+xml," + url.PathEscape(faviconSVG)
-	faviconLink := fmt.Sprintf(`<link rel="icon" type="image/svg+xml" href="%s"/>`, faviconDataURI)
+	rest, pinned := strings.CutPrefix(string(raw), "pinned:")
+	secs, id, ok := strings.Cut(rest, ":")
+	unix, err := strconv.ParseInt(secs, 10, 64)
+	if !ok || err != nil || id == "" {
+		return false, time.Time{}, "", errors.New("invalid cursor")
+	}
+	return pinned, time.Unix(unix, 0).UTC(), id, nil
+}
 
-	// Inject the script tag and favicon before </head>
-	initScript := fmt.Sprintf(`<script>window.__SHELLEY_INIT__=%s;</script>`, initJSON)
-	injection := faviconLink + initScript
-	modifiedHTML := strings.Replace(string(indexHTML), "</head>", injection+"</head>", 1)
+// wantsListMeta reports whether a list request asked for a ListPage rather
+// than a bare array.
+func wantsListMeta(r *http.Request) bool {
+	meta, _ := strconv.ParseBool(r.URL.Query().Get("meta"))
+	return meta
+}
 
-	w.Write([]byte(modifiedHTML))
+// handleConfig handles GET /api/config, returning the init data that is
+// otherwise injected into index.html.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(s.initData())
 }
 
-// handleConfig returns server configuration
 // handleConversations handles GET /conversations
+//
+// It pages with limit and offset, or with ?cursor= (empty for the first page)
+// and a CursorPage response, which doesn't skip or repeat conversations when
+// new ones are created while paging. Cursors can't be combined with q.
+// ?tag= and ?folder= list only the conversations with that tag or directly in
+// that folder.
 func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -624,12 +926,44 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 	}
 	query = r.URL.Query().Get("q")
 	searchContent := r.URL.Query().Get("search_content") == "true"
+	useCursor := r.URL.Query().Has("cursor")
+	cursor := r.URL.Query().Get("cursor")
+	if useCursor && query != "" {
+		http.Error(w, "cursor is not supported with q", http.StatusBadRequest)
+		return
+	}
+	tag := r.URL.Query().Get("tag")
+	if tag != "" && (useCursor || query != "") {
+		http.Error(w, "tag is not supported with q or cursor", http.StatusBadRequest)
+		return
+	}
+	folder := r.URL.Query().Get("folder")
+	if folder != "" && (useCursor || query != "" || tag != "") {
+		http.Error(w, "folder is not supported with q, tag or cursor", http.StatusBadRequest)
+		return
+	}
 
 	// Get conversations from database
 	var conversations []generated.Conversation
 	var err error
 
-	if query != "" {
+	if useCursor {
+		// Fetch one extra row to tell whether there is a next page.
+		if cursor == "" {
+			conversations, err = s.db.ListConversations(ctx, int64(limit)+1, 0)
+		} else {
+			pinned, updatedAt, id, cerr := decodeConversationCursor(cursor)
+			if cerr != nil {
+				http.Error(w, cerr.Error(), http.StatusBadRequest)
+				return
+			}
+			conversations, err = s.db.ListConversationsAfter(ctx, pinned, updatedAt, id, int64(limit)+1)
+		}
+	} else if tag != "" {
+		conversations, err = s.db.ListConversationsByTag(ctx, tag, int64(limit), int64(offset))
+	} else if folder != "" {
+		conversations, err = s.db.ListConversationsInFolder(ctx, folder, int64(limit), int64(offset))
+	} else if query != "" {
 		if searchContent {
 			// Search in both slug and message content
 			conversations, err = s.db.SearchConversationsWithMessages(ctx, query, int64(limit), int64(offset))
@@ -642,10 +976,15 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		s.logger.Error("Failed to get conversations", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get conversations", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	var nextCursor string
+	if useCursor && len(conversations) > limit {
+		conversations = conversations[:limit]
+		nextCursor = encodeConversationCursor(conversations[limit-1])
+	}
 
 	// Get working states for all active conversations
 	workingStates := s.getWorkingConversations()
@@ -653,11 +992,18 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 	// Get subagent counts
 	subagentCounts, err := s.db.GetSubagentCounts(ctx)
 	if err != nil {
-		s.logger.Error("Failed to get subagent counts", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get subagent counts", "error", err)
 		// Non-fatal, continue with zero counts
 		subagentCounts = make(map[string]int64)
 	}
 
+	tags, err := s.db.GetConversationTags(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get conversation tags", "error", err)
+		// Non-fatal, continue without tags
+		tags = make(map[string][]string)
+	}
+
 	// Build response with working state included
 	// Cache git info by cwd to avoid redundant git subprocess calls
 	gitStates := make(map[string]*gitstate.GitState)
@@ -668,8 +1014,12 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 	for i, conv := range conversations {
 		cws := ConversationWithState{
 			Conversation:  conv,
-			Working:       workingStates[conv.ConversationID],
 			SubagentCount: subagentCounts[conv.ConversationID],
+			Tags:          tags[conv.ConversationID],
+		}
+		if since, ok := workingStates[conv.ConversationID]; ok {
+			cws.Working = true
+			cws.WorkingSince = &since
 		}
 		if conv.Cwd != nil {
 			gs, ok := gitStates[*conv.Cwd]
@@ -705,6 +1055,37 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if useCursor {
+		json.NewEncoder(w).Encode(CursorPage[ConversationWithState]{
+			Items:      result,
+			Limit:      limit,
+			NextCursor: nextCursor,
+		})
+		return
+	}
+	if wantsListMeta(r) {
+		var total int64
+		if tag != "" {
+			total, err = s.db.CountConversationsByTag(ctx, tag)
+		} else if folder != "" {
+			total, err = s.db.CountConversationsInFolder(ctx, folder)
+		} else {
+			total, err = s.db.CountConversations(ctx, query, searchContent)
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Failed to count conversations", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(ListPage[ConversationWithState]{
+			Items:   result,
+			Total:   total,
+			Limit:   limit,
+			Offset:  offset,
+			HasMore: int64(offset+len(result)) < total,
+		})
+		return
+	}
 	json.NewEncoder(w).Encode(result)
 }
 
@@ -715,9 +1096,8 @@ func (s *Server) conversationMux() *http.ServeMux {
 	mux.Handle("GET /{id}", gzipHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s.handleGetConversation(w, r, r.PathValue("id"))
 	})))
-	// GET /api/conversation/<id>/stream - SSE stream (do NOT compress)
-	// TODO: Consider gzip for SSE in the future. Would reduce bandwidth
-	// for large tool outputs, but needs flush after each event.
+	// GET /api/conversation/<id>/stream - SSE stream (compresses itself,
+	// flushing after each event)
 	mux.HandleFunc("GET /{id}/stream", func(w http.ResponseWriter, r *http.Request) {
 		s.handleStreamConversation(w, r, r.PathValue("id"))
 	})
@@ -734,12 +1114,48 @@ func (s *Server) conversationMux() *http.ServeMux {
 	mux.HandleFunc("POST /{id}/unarchive", func(w http.ResponseWriter, r *http.Request) {
 		s.handleUnarchiveConversation(w, r, r.PathValue("id"))
 	})
+	mux.HandleFunc("POST /{id}/pin", func(w http.ResponseWriter, r *http.Request) {
+		s.handlePinConversation(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/unpin", func(w http.ResponseWriter, r *http.Request) {
+		s.handleUnpinConversation(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/move", func(w http.ResponseWriter, r *http.Request) {
+		s.handleMoveConversation(w, r, r.PathValue("id"))
+	})
 	mux.HandleFunc("POST /{id}/delete", func(w http.ResponseWriter, r *http.Request) {
 		s.handleDeleteConversation(w, r, r.PathValue("id"))
 	})
 	mux.HandleFunc("POST /{id}/rename", func(w http.ResponseWriter, r *http.Request) {
 		s.handleRenameConversation(w, r, r.PathValue("id"))
 	})
+	mux.HandleFunc("POST /{id}/fork", func(w http.ResponseWriter, r *http.Request) {
+		s.handleForkConversation(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/regenerate-slug", func(w http.ResponseWriter, r *http.Request) {
+		s.handleRegenerateSlug(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/truncate", func(w http.ResponseWriter, r *http.Request) {
+		s.handleTruncateConversation(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/system-note", func(w http.ResponseWriter, r *http.Request) {
+		s.handleSetSystemNote(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/model", func(w http.ResponseWriter, r *http.Request) {
+		s.handleSetConversationModel(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/tags", func(w http.ResponseWriter, r *http.Request) {
+		s.handleAddTag(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("DELETE /{id}/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		s.handleRemoveTag(w, r, r.PathValue("id"), r.PathValue("tag"))
+	})
+	mux.HandleFunc("GET /{id}/message/{seq}", func(w http.ResponseWriter, r *http.Request) {
+		s.handleGetMessageInContext(w, r, r.PathValue("id"), r.PathValue("seq"))
+	})
+	mux.HandleFunc("GET /{id}/export", func(w http.ResponseWriter, r *http.Request) {
+		s.handleExportConversation(w, r, r.PathValue("id"))
+	})
 	mux.HandleFunc("GET /{id}/subagents", func(w http.ResponseWriter, r *http.Request) {
 		s.handleGetSubagents(w, r, r.PathValue("id"))
 	})
@@ -749,21 +1165,72 @@ func (s *Server) conversationMux() *http.ServeMux {
 	return mux
 }
 
+// parseMessagePageOptions parses the limit, after, before and order query
+// parameters that page through a conversation's messages.
+func parseMessagePageOptions(query url.Values) (db.MessagePageOptions, error) {
+	var opts db.MessagePageOptions
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{
+		{"limit", &opts.Limit},
+		{"after", &opts.AfterSequenceID},
+		{"before", &opts.BeforeSequenceID},
+	} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("%s must be a positive integer", p.name)
+		}
+		*p.dst = n
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, fmt.Errorf("order must be asc or desc")
+	}
+	return opts, nil
+}
+
 // handleGetConversation handles GET /conversation/<id>
+// Optional limit, after, before and order query parameters return a page of
+// the conversation's messages instead of all of them.
 func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	ctx := r.Context()
-	var (
-		messages     []generated.Message
-		conversation generated.Conversation
-	)
-	err := s.db.Queries(ctx, func(q *generated.Queries) error {
+	pageOpts, err := parseMessagePageOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	paged := pageOpts != (db.MessagePageOptions{})
+	// Stream the full history. Pages are bounded by the client, and profiling
+	// needs every message before it can set the Server-Timing header.
+	if !paged && !wantsProfile(r) {
+		s.streamConversation(w, r, conversationID)
+		return
+	}
+
+	ctx := r.Context()
+	var (
+		messages     []generated.Message
+		conversation generated.Conversation
+	)
+	err = s.db.Queries(ctx, func(q *generated.Queries) error {
 		var err error
-		messages, err = q.ListMessages(ctx, conversationID)
+		if paged {
+			messages, err = db.ListMessagesPage(ctx, q, conversationID, pageOpts)
+		} else {
+			messages, err = q.ListMessages(ctx, conversationID)
+		}
 		if err != nil {
 			return err
 		}
@@ -775,18 +1242,176 @@ func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request, c
 		return
 	}
 	if err != nil {
-		s.logger.Error("Failed to get conversation messages", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get conversation messages", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	apiMessages := toAPIMessages(messages)
+	if paged {
+		s.pairWithPrevious(ctx, messages, apiMessages)
+	}
+	if wantsProfile(r) {
+		var prev time.Time
+		apiMessages = withTimings(apiMessages, &prev)
+		w.Header().Set("Server-Timing", serverTimingHeader(apiMessages))
+	}
+	// The context window size can only be calculated from the full history.
+	var ctxSize uint64
+	if !paged {
+		ctxSize = calculateContextWindowSize(apiMessages)
+	}
 	json.NewEncoder(w).Encode(StreamResponse{
 		Messages:     apiMessages,
 		Conversation: conversation,
 		// ConversationState is sent via the streaming endpoint, not on initial load
-		ContextWindowSize: calculateContextWindowSize(apiMessages),
+		ContextWindowSize: ctxSize,
+		CostUSD:           conversationCost(conversation),
+	})
+}
+
+// streamConversation writes a conversation's full history as a StreamResponse
+// without holding it all in memory: messages are read messageBatchSize at a
+// time and encoded straight into the response, which is flushed after each
+// batch.
+func (s *Server) streamConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+	var conversation generated.Conversation
+	err := s.db.Queries(ctx, func(q *generated.Queries) error {
+		var err error
+		conversation, err = q.GetConversation(ctx, conversationID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, `{"messages":`)
+	ctxSize, err := s.writeMessagesJSON(ctx, w, conversationID, messageBatchSize, nil)
+	if err != nil {
+		// The response has started, so all we can do is stop writing.
+		s.logger.WarnContext(ctx, "Conversation response ended early", "conversationID", conversationID, "error", err)
+		return
+	}
+
+	// The rest of the StreamResponse; ConversationState is sent via the
+	// streaming endpoint, not on initial load.
+	enc := json.NewEncoder(w)
+	io.WriteString(w, `,"conversation":`)
+	enc.Encode(conversation)
+	if ctxSize != 0 {
+		fmt.Fprintf(w, `,"context_window_size":%d`, ctxSize)
+	}
+	if cost := conversationCost(conversation); cost != nil {
+		io.WriteString(w, `,"cost_usd":`)
+		enc.Encode(*cost)
+	}
+	io.WriteString(w, "}\n")
+}
+
+// defaultMessageContext and maxMessageContext bound how many surrounding
+// messages handleGetMessageInContext returns on each side.
+const (
+	defaultMessageContext = 5
+	maxMessageContext     = 100
+)
+
+// MessageInContext is a single message with the messages around it.
+type MessageInContext struct {
+	Conversation generated.Conversation `json:"conversation"`
+	Message      APIMessage             `json:"message"`
+	// Before and After are in ascending sequence order.
+	Before []APIMessage `json:"before"`
+	After  []APIMessage `json:"after"`
+}
+
+// handleGetMessageInContext handles GET /conversation/<id>/message/<seq>
+// It returns the message with that sequence ID plus up to "before" earlier
+// and "after" later messages (default 5 each), so a deep link to a message
+// can render without loading the whole conversation.
+func (s *Server) handleGetMessageInContext(w http.ResponseWriter, r *http.Request, conversationID, seqStr string) {
+	seq, err := strconv.ParseInt(seqStr, 10, 64)
+	if err != nil || seq <= 0 {
+		http.Error(w, "Invalid sequence ID", http.StatusBadRequest)
+		return
+	}
+	counts := map[string]int64{"before": defaultMessageContext, "after": defaultMessageContext}
+	for name := range counts {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 || n > maxMessageContext {
+			http.Error(w, fmt.Sprintf("%s must be between 0 and %d", name, maxMessageContext), http.StatusBadRequest)
+			return
+		}
+		counts[name] = n
+	}
+
+	ctx := r.Context()
+	var (
+		conversation           generated.Conversation
+		message, before, after []generated.Message
+	)
+	err = s.db.Queries(ctx, func(q *generated.Queries) error {
+		var err error
+		conversation, err = q.GetConversation(ctx, conversationID)
+		if err != nil {
+			return err
+		}
+		message, err = db.ListMessagesPage(ctx, q, conversationID, db.MessagePageOptions{
+			AfterSequenceID:  seq - 1,
+			BeforeSequenceID: seq + 1,
+		})
+		if err != nil || len(message) == 0 {
+			return err
+		}
+		if n := counts["before"]; n > 0 {
+			before, err = db.ListMessagesPage(ctx, q, conversationID, db.MessagePageOptions{BeforeSequenceID: seq, Limit: n, Descending: true})
+			if err != nil {
+				return err
+			}
+			slices.Reverse(before)
+		}
+		if n := counts["after"]; n > 0 {
+			after, err = db.ListMessagesPage(ctx, q, conversationID, db.MessagePageOptions{AfterSequenceID: seq, Limit: n})
+		}
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get message in context", "conversationID", conversationID, "sequenceID", seq, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(message) == 0 {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	// Convert as one batch so tool results are paired across the window.
+	window := slices.Concat(before, message, after)
+	apiMessages := toAPIMessages(window)
+	s.pairWithPrevious(ctx, window, apiMessages)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MessageInContext{
+		Conversation: conversation,
+		Message:      apiMessages[len(before)],
+		Before:       apiMessages[:len(before)],
+		After:        apiMessages[len(before)+1:],
 	})
 }
 
@@ -797,6 +1422,58 @@ type ChatRequest struct {
 	Cwd                 string                  `json:"cwd,omitempty"`
 	ConversationOptions *db.ConversationOptions `json:"conversation_options,omitempty"`
 	Queue               bool                    `json:"queue,omitempty"`
+	// Params override the model's generation defaults for this turn.
+	Params llm.Params `json:"params,omitzero"`
+	// SystemPrompt overrides the generated system prompt for this turn.
+	// Only authenticated requests may set it.
+	SystemPrompt *SystemPromptOverride `json:"system_prompt,omitempty"`
+	// SystemNote pins a standing instruction on a new conversation; see
+	// handleSetSystemNote. An empty note opts out of the template's.
+	SystemNote *string `json:"system_note,omitempty"`
+}
+
+// SystemPromptOverride replaces or extends the generated system prompt.
+type SystemPromptOverride struct {
+	Text string `json:"text"`
+	// Mode is "append" (the default) or "replace".
+	Mode string `json:"mode,omitempty"`
+}
+
+// Validate checks that o has text and a known mode.
+func (o *SystemPromptOverride) Validate() error {
+	if strings.TrimSpace(o.Text) == "" {
+		return fmt.Errorf("text is required")
+	}
+	if o.Mode != "" && o.Mode != "append" && o.Mode != "replace" {
+		return fmt.Errorf("mode must be append or replace, got %q", o.Mode)
+	}
+	return nil
+}
+
+// apply returns system with o applied.
+func (o *SystemPromptOverride) apply(system []llm.SystemContent) []llm.SystemContent {
+	override := llm.SystemContent{Type: "text", Text: o.Text}
+	if o.Mode == "replace" {
+		return []llm.SystemContent{override}
+	}
+	return append(slices.Clone(system), override)
+}
+
+// validateSystemPrompt writes an error response and returns false if req
+// carries a system prompt override that r may not set or that is invalid.
+func (s *Server) validateSystemPrompt(w http.ResponseWriter, r *http.Request, req *ChatRequest) bool {
+	if req.SystemPrompt == nil {
+		return true
+	}
+	if !s.isAuthenticated(r) {
+		http.Error(w, "system_prompt override requires an authenticated request", http.StatusForbidden)
+		return false
+	}
+	if err := req.SystemPrompt.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid system_prompt: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
 }
 
 // handleChatConversation handles POST /conversation/<id>/chat
@@ -821,17 +1498,21 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 	}
 
 	// Get LLM service for the requested model
-	modelID := req.Model
-	if modelID == "" {
-		modelID = s.defaultModel
-	}
+	modelID := s.resolveModel(req.Model)
 
 	llmService, err := s.llmManager.GetService(modelID)
 	if err != nil {
-		s.logger.Error("Unsupported model requested", "model", modelID, "error", err)
+		s.logger.ErrorContext(ctx, "Unsupported model requested", "model", modelID, "error", err)
 		http.Error(w, fmt.Sprintf("Unsupported model: %s", modelID), http.StatusBadRequest)
 		return
 	}
+	if err := llm.ValidateParams(llmService, req.Params); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !s.validateSystemPrompt(w, r, &req) {
+		return
+	}
 
 	// Get or create conversation manager
 	manager, err := s.getOrCreateConversationManager(ctx, conversationID)
@@ -840,7 +1521,7 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 		return
 	}
 	if err != nil {
-		s.logger.Error("Failed to get conversation manager", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get conversation manager", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -857,7 +1538,7 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 	// The message will be sent when the agent finishes its current turn.
 	if req.Queue {
 		if err := manager.QueueMessage(ctx, s, modelID, userMessage); err != nil {
-			s.logger.Error("Failed to queue user message", "conversationID", conversationID, "error", err)
+			s.logger.ErrorContext(ctx, "Failed to queue user message", "conversationID", conversationID, "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -866,13 +1547,13 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage)
+	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage, req.Params, req.SystemPrompt)
 	if errors.Is(err, errConversationModelMismatch) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	if err != nil {
-		s.logger.Error("Failed to accept user message", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to accept user message", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -882,9 +1563,9 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 		go func() {
 			slugCtx, cancel := context.WithTimeout(ctxNoCancel, 15*time.Second)
 			defer cancel()
-			_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, req.Message, modelID)
+			_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, req.Message, modelID, s.slugPrompt, !s.noHeuristicSlugs)
 			if err != nil {
-				s.logger.Warn("Failed to generate slug for conversation", "conversationID", conversationID, "error", err)
+				s.logger.WarnContext(ctx, "Failed to generate slug for conversation", "conversationID", conversationID, "error", err)
 			} else {
 				go s.notifySubscribers(ctxNoCancel, conversationID)
 			}
@@ -915,19 +1596,24 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Message is required", http.StatusBadRequest)
 		return
 	}
+	s.newConvTemplate.apply(&req)
 
-	// Get LLM service for the requested model
-	modelID := req.Model
-	if modelID == "" {
-		modelID = s.defaultModel
-	}
+	// Get LLM service for the requested model, storing the concrete ID behind any alias
+	modelID := s.resolveModel(req.Model)
 
 	llmService, err := s.llmManager.GetService(modelID)
 	if err != nil {
-		s.logger.Error("Unsupported model requested", "model", modelID, "error", err)
+		s.logger.ErrorContext(ctx, "Unsupported model requested", "model", modelID, "error", err)
 		http.Error(w, fmt.Sprintf("Unsupported model: %s", modelID), http.StatusBadRequest)
 		return
 	}
+	if err := llm.ValidateParams(llmService, req.Params); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !s.validateSystemPrompt(w, r, &req) {
+		return
+	}
 
 	// Create new conversation with optional cwd
 	var cwdPtr *string
@@ -949,11 +1635,21 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 
 	conversation, err := s.db.CreateConversation(ctx, nil, true, cwdPtr, &modelID, convOpts)
 	if err != nil {
-		s.logger.Error("Failed to create conversation", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to create conversation", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 	conversationID := conversation.ConversationID
+	if req.SystemNote != nil {
+		if note := strings.TrimSpace(*req.SystemNote); note != "" {
+			conversation, err = s.db.UpdateConversationSystemNote(ctx, conversationID, note)
+			if err != nil {
+				s.logger.ErrorContext(ctx, "Failed to set system note", "conversationID", conversationID, "error", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
 
 	// Notify conversation list subscribers about the new conversation
 	go s.publishConversationListUpdate(ConversationListUpdate{
@@ -968,7 +1664,7 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		s.logger.Error("Failed to get conversation manager", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get conversation manager", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -981,13 +1677,13 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage)
+	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage, req.Params, req.SystemPrompt)
 	if errors.Is(err, errConversationModelMismatch) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	if err != nil {
-		s.logger.Error("Failed to accept user message", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to accept user message", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -997,9 +1693,9 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 		go func() {
 			slugCtx, cancel := context.WithTimeout(ctxNoCancel, 15*time.Second)
 			defer cancel()
-			_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, req.Message, modelID)
+			_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, req.Message, modelID, s.slugPrompt, !s.noHeuristicSlugs)
 			if err != nil {
-				s.logger.Warn("Failed to generate slug for conversation", "conversationID", conversationID, "error", err)
+				s.logger.WarnContext(ctx, "Failed to generate slug for conversation", "conversationID", conversationID, "error", err)
 			} else {
 				go s.notifySubscribers(ctxNoCancel, conversationID)
 			}
@@ -1014,6 +1710,13 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CancelRequest is the optional body of POST /conversation/<id>/cancel
+type CancelRequest struct {
+	// Reason says why the turn was stopped. It is shown in the transcript
+	// and passed to the model with the rest of the conversation.
+	Reason string `json:"reason,omitempty"`
+}
+
 // handleCancelConversation handles POST /conversation/<id>/cancel
 func (s *Server) handleCancelConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
 	if r.Method != http.MethodPost {
@@ -1023,6 +1726,13 @@ func (s *Server) handleCancelConversation(w http.ResponseWriter, r *http.Request
 
 	ctx := r.Context()
 
+	// The body is optional; without one the turn is cancelled with no reason.
+	var req CancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
 	// Get the conversation manager if it exists
 	s.mu.Lock()
 	manager, exists := s.activeConversations[conversationID]
@@ -1036,20 +1746,24 @@ func (s *Server) handleCancelConversation(w http.ResponseWriter, r *http.Request
 	}
 
 	// Cancel the conversation
-	if err := manager.CancelConversation(ctx); err != nil {
-		s.logger.Error("Failed to cancel conversation", "conversationID", conversationID, "error", err)
+	cancellation := CancelUserData{CancelledBy: CancelledByUser, Reason: strings.TrimSpace(req.Reason)}
+	if err := manager.CancelConversation(ctx, cancellation); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to cancel conversation", "conversationID", conversationID, "error", err)
 		http.Error(w, "Failed to cancel conversation", http.StatusInternalServerError)
 		return
 	}
 
-	s.logger.Info("Conversation cancelled", "conversationID", conversationID)
+	s.logger.InfoContext(ctx, "Conversation cancelled", "conversationID", conversationID, "reason", cancellation.Reason)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
 }
 
-// handleStreamConversation handles GET /conversation/<id>/stream
+// handleStreamConversation handles GET /conversation/<id>/stream, gzipped
+// when the client sends Accept-Encoding: gzip.
 // Query parameters:
 //   - last_sequence_id: Resume from this sequence ID (skip messages up to and including this ID)
+//   - events: "meta" streams only conversation metadata (slug, working state,
+//     context window size) without message bodies; the default is "all"
 func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -1066,19 +1780,62 @@ func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	var metaOnly bool
+	switch r.URL.Query().Get("events") {
+	case "", "all":
+	case "meta":
+		metaOnly = true
+	default:
+		http.Error(w, "events must be all or meta", http.StatusBadRequest)
+		return
+	}
+
+	// In profiling mode each streamed message is annotated with its timing.
+	// prevMessageAt carries the previous message's time across events.
+	profile := wantsProfile(r)
+	var prevMessageAt time.Time
+
 	// Set up SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	// CORSMiddleware sets the allowed origin when origins are configured.
+	if len(s.corsOrigins) == 0 {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
 
 	// For fresh connections, get messages BEFORE calling getOrCreateConversationManager.
 	// This is important because getOrCreateConversationManager may create a system prompt
 	// message during hydration, and we want to return the messages as they were before.
 	var messages []generated.Message
 	var conversation generated.Conversation
+	var metaCtxSize uint64
 	resuming := lastSeqID >= 0
-	if lastSeqID < 0 {
+	if metaOnly {
+		// No messages are sent, so read just the one the context window size
+		// comes from.
+		err := s.db.Queries(ctx, func(q *generated.Queries) error {
+			var err error
+			conversation, err = q.GetConversation(ctx, conversationID)
+			if err != nil {
+				return err
+			}
+			latest, err := q.GetLatestMessageWithUsage(ctx, conversationID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			metaCtxSize = calculateContextWindowSizeFromMsg(&latest)
+			return nil
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Failed to get conversation data", "conversationID", conversationID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	} else if lastSeqID < 0 {
 		err := s.db.Queries(ctx, func(q *generated.Queries) error {
 			var err error
 			messages, err = q.ListMessages(ctx, conversationID)
@@ -1089,7 +1846,7 @@ func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request
 			return err
 		})
 		if err != nil {
-			s.logger.Error("Failed to get conversation data", "conversationID", conversationID, "error", err)
+			s.logger.ErrorContext(ctx, "Failed to get conversation data", "conversationID", conversationID, "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -1112,7 +1869,7 @@ func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request
 			return err
 		})
 		if err != nil {
-			s.logger.Error("Failed to get conversation data", "conversationID", conversationID, "error", err)
+			s.logger.ErrorContext(ctx, "Failed to get conversation data", "conversationID", conversationID, "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -1125,14 +1882,48 @@ func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request
 	// Get or create conversation manager to access working state
 	manager, err := s.getOrCreateConversationManager(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to get conversation manager", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get conversation manager", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	// Subscribe to new messages after the last one we send below, before
+	// anything is written so a full conversation can still get a 503.
+	// Messages published since we read them are replayed from the subpub.
+	// The subscription ends early when the max stream duration elapses or
+	// the server shuts down.
+	subCtx, cancelSub := context.WithCancel(ctx)
+	defer cancelSub()
+	defer context.AfterFunc(s.streamsCtx, cancelSub)()
+	if s.maxStreamDuration > 0 {
+		var cancel context.CancelFunc
+		subCtx, cancel = context.WithTimeout(subCtx, s.maxStreamDuration)
+		defer cancel()
+	}
+	next, err := manager.subpub.TrySubscribe(subCtx, lastSeqID, s.maxSubscribers)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Conversation stream subscriber limit reached", "conversationID", conversationID, "max", s.maxSubscribers)
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Too many clients are streaming this conversation", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Nothing below responds with an error, so compress the stream if the
+	// client accepts it. Every event is flushed through the compressor, so
+	// compression doesn't delay events.
+	if acceptsGzip(r) {
+		gzw, finish := startGzip(w)
+		defer finish()
+		w = gzw
+	}
+
 	// Send initial response (all messages for fresh connections, missed messages for resumes)
 	if len(messages) > 0 {
 		apiMessages := toAPIMessages(messages)
+		s.pairWithPrevious(ctx, messages, apiMessages)
+		if profile {
+			apiMessages = withTimings(apiMessages, &prevMessageAt)
+		}
 		// Only send context_window_size for fresh connections where we have all messages.
 		// On resume we only have the missed messages, so the calculation would be wrong.
 		// The client keeps its previous value and gets updates from subsequent stream events.
@@ -1140,86 +1931,108 @@ func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request
 		if !resuming {
 			ctxSize = calculateContextWindowSize(apiMessages)
 		}
+		state := manager.State()
 		streamData := StreamResponse{
-			Messages:     apiMessages,
-			Conversation: conversation,
-			ConversationState: &ConversationState{
-				ConversationID: conversationID,
-				Working:        manager.IsAgentWorking(),
-				Model:          manager.GetModel(),
-			},
+			Messages:          apiMessages,
+			Conversation:      conversation,
+			ConversationState: &state,
 			ContextWindowSize: ctxSize,
 		}
 		data, _ := json.Marshal(streamData)
 		fmt.Fprintf(w, "data: %s\n\n", data)
 		w.(http.Flusher).Flush()
 	} else {
-		// Either resuming or no messages yet - send current state as heartbeat
+		// Either resuming, metadata only or no messages yet - send current state as heartbeat
+		state := manager.State()
 		streamData := StreamResponse{
-			Conversation: conversation,
-			ConversationState: &ConversationState{
-				ConversationID: conversationID,
-				Working:        manager.IsAgentWorking(),
-				Model:          manager.GetModel(),
-			},
-			Heartbeat: true,
+			Conversation:      conversation,
+			ConversationState: &state,
+			ContextWindowSize: metaCtxSize,
+			Heartbeat:         true,
 		}
 		data, _ := json.Marshal(streamData)
 		fmt.Fprintf(w, "data: %s\n\n", data)
 		w.(http.Flusher).Flush()
 	}
 
-	// Subscribe to new messages after the last one we sent
-	next := manager.subpub.Subscribe(ctx, lastSeqID)
-
-	// Start heartbeat goroutine - sends state every 30 seconds if no other messages
-	heartbeatDone := make(chan struct{})
+	// Hand updates to the loop below over a channel so it can also send
+	// heartbeats. Only the loop writes to the response, so a heartbeat never
+	// lands in the middle of an event.
+	updates := make(chan StreamResponse)
 	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
+		defer close(updates)
 		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-heartbeatDone:
+			streamData, cont := next()
+			if !cont {
 				return
-			case <-ticker.C:
-				// Get current conversation state for heartbeat
-				var conv generated.Conversation
-				err := s.db.Queries(ctx, func(q *generated.Queries) error {
-					var err error
-					conv, err = q.GetConversation(ctx, conversationID)
-					return err
-				})
-				if err != nil {
-					continue // Skip heartbeat on error
-				}
-
-				heartbeat := StreamResponse{
-					Conversation: conv,
-					ConversationState: &ConversationState{
-						ConversationID: conversationID,
-						Working:        manager.IsAgentWorking(),
-						Model:          manager.GetModel(),
-					},
-					Heartbeat: true,
-				}
-				manager.subpub.Broadcast(heartbeat)
 			}
+			updates <- streamData
 		}
 	}()
-	defer close(heartbeatDone)
 
+	// Send a heartbeat whenever the stream has been idle for heartbeatInterval,
+	// so proxies don't drop the connection and the client sees current state.
+	heartbeat := time.NewTicker(s.heartbeatInterval)
+	defer heartbeat.Stop()
+
+stream:
 	for {
-		streamData, cont := next()
-		if !cont {
-			break
+		var streamData StreamResponse
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				break stream
+			}
+			streamData = update
+			heartbeat.Reset(s.heartbeatInterval)
+			if metaOnly {
+				// Streaming deltas and tool output only make sense alongside the
+				// messages; message events still carry the conversation and
+				// context window size.
+				if streamData.StreamDelta != nil || streamData.ToolProgress != nil {
+					continue
+				}
+				streamData.Messages = nil
+			}
+			if profile && len(streamData.Messages) > 0 {
+				streamData.Messages = withTimings(streamData.Messages, &prevMessageAt)
+			}
+		case <-heartbeat.C:
+			// Get current conversation state for heartbeat
+			var conv generated.Conversation
+			err := s.db.Queries(ctx, func(q *generated.Queries) error {
+				var err error
+				conv, err = q.GetConversation(ctx, conversationID)
+				return err
+			})
+			if err != nil {
+				continue // Skip heartbeat on error
+			}
+			state := manager.State()
+			streamData = StreamResponse{
+				Conversation:      conv,
+				ConversationState: &state,
+				Heartbeat:         true,
+			}
 		}
 		// Always forward updates, even if only the conversation changed (e.g., slug added)
 		data, _ := json.Marshal(streamData)
 		fmt.Fprintf(w, "data: %s\n\n", data)
 		w.(http.Flusher).Flush()
 	}
+
+	// If the server is shutting down or the stream hit its max duration
+	// (rather than the client going away), say so; after a max duration
+	// stream the client reconnects and resumes from its last sequence ID.
+	if ctx.Err() == nil && subCtx.Err() != nil {
+		final := StreamResponse{Conversation: conversation, Reconnect: true}
+		if s.streamsCtx.Err() != nil {
+			final = StreamResponse{Type: "shutdown"}
+		}
+		data, _ := json.Marshal(final)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		w.(http.Flusher).Flush()
+	}
 }
 
 // handleVersion returns version information as JSON
@@ -1240,6 +2053,7 @@ type ModelInfo struct {
 	Source           string `json:"source,omitempty"` // Human-readable source (e.g., "exe.dev gateway", "$ANTHROPIC_API_KEY")
 	Ready            bool   `json:"ready"`
 	MaxContextTokens int    `json:"max_context_tokens,omitempty"`
+	Default          bool   `json:"default,omitempty"` // set by /api/models on the model new conversations use
 }
 
 // getModelList returns the list of available models
@@ -1271,14 +2085,54 @@ func (s *Server) getModelList() []ModelInfo {
 	return modelList
 }
 
-// handleModels returns the list of available models
+// resolveModel returns the concrete model ID behind modelID, which may be an
+// alias. An empty modelID means the configured default model.
+func (s *Server) resolveModel(modelID string) string {
+	if modelID == "" {
+		modelID = s.defaultModel
+	}
+	return s.llmManager.ResolveModel(modelID)
+}
+
+// defaultModelID picks the model the UI preselects from modelList: the
+// configured default if it is ready, otherwise the first ready model. It is
+// empty if there are no models.
+func (s *Server) defaultModelID(modelList []ModelInfo) string {
+	if len(modelList) == 0 {
+		return ""
+	}
+	defaultModel := s.resolveModel(s.newConvTemplate.Model)
+	if defaultModel == "" {
+		defaultModel = models.Default().ID
+	}
+	for _, m := range modelList {
+		if m.ID == defaultModel && m.Ready {
+			return defaultModel
+		}
+	}
+	// Fall back to first ready model
+	for _, m := range modelList {
+		if m.Ready {
+			return m.ID
+		}
+	}
+	return defaultModel
+}
+
+// handleModels returns the list of available models, with the default
+// model flagged
 func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	modelList := s.getModelList()
+	defaultModel := s.defaultModelID(modelList)
+	for i := range modelList {
+		modelList[i].Default = modelList[i].ID == defaultModel
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s.getModelList())
+	json.NewEncoder(w).Encode(modelList)
 }
 
 // handleConversationPreviews handles GET /api/conversations/previews
@@ -1297,7 +2151,7 @@ func (s *Server) handleConversationPreviews(w http.ResponseWriter, r *http.Reque
 		return err
 	})
 	if err != nil {
-		s.logger.Error("Failed to get conversation previews", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get conversation previews", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1371,12 +2225,28 @@ func (s *Server) handleArchivedConversations(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err != nil {
-		s.logger.Error("Failed to get archived conversations", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get archived conversations", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if wantsListMeta(r) {
+		total, err := s.db.CountArchivedConversations(ctx, query)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Failed to count archived conversations", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(ListPage[generated.Conversation]{
+			Items:   conversations,
+			Total:   total,
+			Limit:   limit,
+			Offset:  offset,
+			HasMore: int64(offset+len(conversations)) < total,
+		})
+		return
+	}
 	json.NewEncoder(w).Encode(conversations)
 }
 
@@ -1390,7 +2260,7 @@ func (s *Server) handleArchiveConversation(w http.ResponseWriter, r *http.Reques
 	ctx := r.Context()
 	conversation, err := s.db.ArchiveConversation(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to archive conversation", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to archive conversation", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1415,7 +2285,46 @@ func (s *Server) handleUnarchiveConversation(w http.ResponseWriter, r *http.Requ
 	ctx := r.Context()
 	conversation, err := s.db.UnarchiveConversation(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to unarchive conversation", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to unarchive conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Notify conversation list subscribers
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conversation,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversation)
+}
+
+// handlePinConversation handles POST /conversation/<id>/pin
+func (s *Server) handlePinConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
+	s.setConversationPinned(w, r, conversationID, true)
+}
+
+// handleUnpinConversation handles POST /conversation/<id>/unpin
+func (s *Server) handleUnpinConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
+	s.setConversationPinned(w, r, conversationID, false)
+}
+
+func (s *Server) setConversationPinned(w http.ResponseWriter, r *http.Request, conversationID string, pinned bool) {
+	ctx := r.Context()
+	var conversation *generated.Conversation
+	var err error
+	if pinned {
+		conversation, err = s.db.PinConversation(ctx, conversationID)
+	} else {
+		conversation, err = s.db.UnpinConversation(ctx, conversationID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to set conversation pinned", "conversationID", conversationID, "pinned", pinned, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1439,7 +2348,7 @@ func (s *Server) handleDeleteConversation(w http.ResponseWriter, r *http.Request
 
 	ctx := r.Context()
 	if err := s.db.DeleteConversation(ctx, conversationID); err != nil {
-		s.logger.Error("Failed to delete conversation", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to delete conversation", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1454,6 +2363,111 @@ func (s *Server) handleDeleteConversation(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
 }
 
+// maxBulkConversations bounds how many conversations one bulk request may act on.
+const maxBulkConversations = 500
+
+// BulkConversationRequest is the body of POST /api/conversations/bulk.
+type BulkConversationRequest struct {
+	IDs    []string `json:"ids"`
+	Action string   `json:"action"` // "archive", "unarchive" or "delete"
+}
+
+// BulkConversationResult is the outcome of a bulk action on one conversation.
+type BulkConversationResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkConversationResponse lists the outcome for each requested conversation.
+type BulkConversationResponse struct {
+	Results []BulkConversationResult `json:"results"`
+}
+
+// handleBulkConversationAction handles POST /api/conversations/bulk
+// It archives, unarchives or deletes several conversations in one
+// transaction. Conversations that don't exist are reported as failures
+// without affecting the rest.
+func (s *Server) handleBulkConversationAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BulkConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	switch req.Action {
+	case "archive", "unarchive", "delete":
+	default:
+		http.Error(w, "action must be archive, unarchive or delete", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 {
+		http.Error(w, "ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) > maxBulkConversations {
+		http.Error(w, fmt.Sprintf("at most %d ids are allowed", maxBulkConversations), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	results := make([]BulkConversationResult, len(req.IDs))
+	var updates []ConversationListUpdate
+	err := s.db.QueriesTx(ctx, func(q *generated.Queries) error {
+		for i, id := range req.IDs {
+			results[i] = BulkConversationResult{ID: id}
+			if _, err := q.GetConversation(ctx, id); errors.Is(err, sql.ErrNoRows) {
+				results[i].Error = "conversation not found"
+				continue
+			} else if err != nil {
+				return err
+			}
+			switch req.Action {
+			case "archive", "unarchive":
+				var conversation generated.Conversation
+				var err error
+				if req.Action == "archive" {
+					conversation, err = q.ArchiveConversation(ctx, id)
+				} else {
+					conversation, err = q.UnarchiveConversation(ctx, id)
+				}
+				if err != nil {
+					return err
+				}
+				updates = append(updates, ConversationListUpdate{Type: "update", Conversation: &conversation})
+			case "delete":
+				// Delete messages first (foreign key constraint)
+				if err := q.DeleteConversationMessages(ctx, id); err != nil {
+					return fmt.Errorf("failed to delete messages: %w", err)
+				}
+				if err := q.DeleteConversation(ctx, id); err != nil {
+					return err
+				}
+				updates = append(updates, ConversationListUpdate{Type: "delete", ConversationID: id})
+			}
+			results[i].Success = true
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed bulk conversation action", "action", req.Action, "count", len(req.IDs), "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Notify conversation list subscribers
+	for _, update := range updates {
+		go s.publishConversationListUpdate(update)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BulkConversationResponse{Results: results})
+}
+
 // handleConversationBySlug handles GET /api/conversation-by-slug/<slug>
 func (s *Server) handleConversationBySlug(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -1474,7 +2488,7 @@ func (s *Server) handleConversationBySlug(w http.ResponseWriter, r *http.Request
 			http.Error(w, "Conversation not found", http.StatusNotFound)
 			return
 		}
-		s.logger.Error("Failed to get conversation by slug", "slug", slug, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get conversation by slug", "slug", slug, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1510,13 +2524,128 @@ func (s *Server) handleRenameConversation(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	conversation, err := s.db.UpdateConversationSlug(ctx, conversationID, sanitized)
+	conversation, err := s.db.RenameConversation(ctx, conversationID, sanitized)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to rename conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Notify conversation list subscribers
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conversation,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversation)
+}
+
+// maxTagLength is the longest tag, in bytes, a conversation may be given.
+const maxTagLength = 64
+
+// TagRequest represents a request to tag a conversation
+type TagRequest struct {
+	Tag string `json:"tag"`
+}
+
+// TagsResponse lists a conversation's tags
+type TagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+// handleAddTag handles POST /conversation/<id>/tags. Adding a tag the
+// conversation already has succeeds without changing anything.
+func (s *Server) handleAddTag(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+
+	var req TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	tag := strings.TrimSpace(req.Tag)
+	if tag == "" || len(tag) > maxTagLength {
+		http.Error(w, fmt.Sprintf("Tag must be 1-%d characters", maxTagLength), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.GetConversationByID(ctx, conversationID); err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err := s.db.AddTag(ctx, conversationID, tag); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to add tag", "conversationID", conversationID, "tag", tag, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.writeTags(w, r, conversationID)
+}
+
+// handleRemoveTag handles DELETE /conversation/<id>/tags/<tag>
+func (s *Server) handleRemoveTag(w http.ResponseWriter, r *http.Request, conversationID, tag string) {
+	ctx := r.Context()
+
+	if _, err := s.db.GetConversationByID(ctx, conversationID); err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err := s.db.RemoveTag(ctx, conversationID, tag); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to remove tag", "conversationID", conversationID, "tag", tag, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.writeTags(w, r, conversationID)
+}
+
+// writeTags responds with the conversation's current tags.
+func (s *Server) writeTags(w http.ResponseWriter, r *http.Request, conversationID string) {
+	tags, err := s.db.ListTags(r.Context(), conversationID)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Failed to list tags", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TagsResponse{Tags: tags})
+}
+
+// SystemNoteRequest represents a request to set a conversation's pinned system note
+type SystemNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// handleSetSystemNote handles POST /conversation/<id>/system-note.
+// An empty note clears it.
+func (s *Server) handleSetSystemNote(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+
+	var req SystemNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+
+	conversation, err := s.db.UpdateConversationSystemNote(ctx, conversationID, note)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		s.logger.Error("Failed to rename conversation", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to set system note", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	s.mu.Lock()
+	manager, exists := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	if exists {
+		manager.SetSystemNote(note)
+		manager.subpub.Broadcast(StreamResponse{Conversation: *conversation})
+	}
+
 	// Notify conversation list subscribers
 	go s.publishConversationListUpdate(ConversationListUpdate{
 		Type:         "update",
@@ -1527,13 +2656,152 @@ func (s *Server) handleRenameConversation(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(conversation)
 }
 
+// ConversationModelRequest is the body of POST /conversation/<id>/model.
+type ConversationModelRequest struct {
+	Model string `json:"model"`
+}
+
+// handleSetConversationModel handles POST /conversation/<id>/model, switching
+// the model later messages in the conversation are sent to.
+func (s *Server) handleSetConversationModel(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+
+	var req ConversationModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Model == "" || !s.llmManager.HasModel(req.Model) {
+		http.Error(w, fmt.Sprintf("Unsupported model: %s", req.Model), http.StatusBadRequest)
+		return
+	}
+	modelID := s.llmManager.ResolveModel(req.Model)
+
+	if _, err := s.db.GetConversationByID(ctx, conversationID); err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	manager, exists := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	if exists {
+		if err := manager.SetModel(modelID); err != nil {
+			http.Error(w, "Cannot change the model while the agent is working", http.StatusConflict)
+			return
+		}
+	}
+
+	conversation, err := s.db.UpdateConversationModel(ctx, conversationID, modelID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to set conversation model", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if exists {
+		manager.subpub.Broadcast(StreamResponse{Conversation: *conversation})
+	}
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conversation,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversation)
+}
+
+// TruncateRequest is the body of POST /conversation/<id>/truncate
+type TruncateRequest struct {
+	// SequenceID is the last message kept
+	SequenceID int64 `json:"sequence_id"`
+}
+
+// handleTruncateConversation handles POST /conversation/<id>/truncate. It
+// deletes every message after the given sequence ID, so the conversation
+// continues from that point, and responds with the remaining history.
+func (s *Server) handleTruncateConversation(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+
+	var req TruncateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.SequenceID <= 0 {
+		http.Error(w, "sequence_id must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.GetConversationByID(ctx, conversationID); err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	manager, exists := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	if exists {
+		if err := manager.ResetHistory(); err != nil {
+			http.Error(w, "Cannot truncate the conversation while the agent is working", http.StatusConflict)
+			return
+		}
+	}
+
+	deleted, err := s.db.TruncateConversationAfter(ctx, conversationID, req.SequenceID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to truncate conversation", "conversationID", conversationID, "sequenceID", req.SequenceID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.InfoContext(ctx, "Truncated conversation", "conversationID", conversationID, "sequenceID", req.SequenceID, "deleted", deleted)
+
+	var (
+		messages     []generated.Message
+		conversation generated.Conversation
+	)
+	err = s.db.Queries(ctx, func(q *generated.Queries) error {
+		var err error
+		if messages, err = q.ListMessages(ctx, conversationID); err != nil {
+			return err
+		}
+		conversation, err = q.GetConversation(ctx, conversationID)
+		return err
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get truncated conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	apiMessages := toAPIMessages(messages)
+	resp := StreamResponse{
+		Messages:          apiMessages,
+		Conversation:      conversation,
+		ContextWindowSize: calculateContextWindowSize(apiMessages),
+		TruncatedAfter:    req.SequenceID,
+	}
+
+	if exists {
+		// The deleted sequence IDs are reused by the next messages
+		manager.subpub.Rewind(req.SequenceID)
+		manager.subpub.Broadcast(resp)
+	}
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: &conversation,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // handleVersionCheck returns version check information including update availability
 func (s *Server) handleVersionCheck(w http.ResponseWriter, r *http.Request) {
 	forceRefresh := r.URL.Query().Get("refresh") == "true"
 
 	info, err := s.versionChecker.Check(r.Context(), forceRefresh)
 	if err != nil {
-		s.logger.Error("Version check failed", "error", err)
+		s.logger.ErrorContext(r.Context(), "Version check failed", "error", err)
 		http.Error(w, "Version check failed", http.StatusInternalServerError)
 		return
 	}
@@ -1554,7 +2822,7 @@ func (s *Server) handleVersionChangelog(w http.ResponseWriter, r *http.Request)
 
 	commits, err := s.versionChecker.FetchChangelog(r.Context(), currentTag, latestTag)
 	if err != nil {
-		s.logger.Error("Failed to fetch changelog", "error", err, "current", currentTag, "latest", latestTag)
+		s.logger.ErrorContext(r.Context(), "Failed to fetch changelog", "error", err, "current", currentTag, "latest", latestTag)
 		http.Error(w, "Failed to fetch changelog", http.StatusInternalServerError)
 		return
 	}
@@ -1568,7 +2836,7 @@ func (s *Server) handleVersionChangelog(w http.ResponseWriter, r *http.Request)
 func (s *Server) handleUpgrade(w http.ResponseWriter, r *http.Request) {
 	err := s.versionChecker.DoUpgrade(r.Context())
 	if err != nil {
-		s.logger.Error("Upgrade failed", "error", err)
+		s.logger.ErrorContext(r.Context(), "Upgrade failed", "error", err)
 		http.Error(w, fmt.Sprintf("Upgrade failed: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -1588,7 +2856,7 @@ func (s *Server) handleUpgrade(w http.ResponseWriter, r *http.Request) {
 		// Exit after a short delay to allow response to be sent
 		go func() {
 			time.Sleep(100 * time.Millisecond)
-			s.logger.Info("Exiting Shelley after upgrade")
+			s.logger.InfoContext(r.Context(), "Exiting Shelley after upgrade")
 			os.Exit(0)
 		}()
 	} else {
@@ -1631,7 +2899,7 @@ func (s *Server) handleUpgradeHeadlessShell(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	s.logger.Info("Upgrading headless-shell", "url", downloadURL, "from", info.HeadlessShellCurrent, "to", info.HeadlessShellLatest)
+	s.logger.InfoContext(ctx, "Upgrading headless-shell", "url", downloadURL, "from", info.HeadlessShellCurrent, "to", info.HeadlessShellLatest)
 
 	// Download the tarball
 	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
@@ -1676,7 +2944,7 @@ func (s *Server) handleUpgradeHeadlessShell(w http.ResponseWriter, r *http.Reque
 
 	cmd := exec.CommandContext(ctx, "tar", "xzf", tmpTarPath, "-C", tmpDir)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		s.logger.Error("Failed to extract tarball", "error", err, "output", string(output))
+		s.logger.ErrorContext(ctx, "Failed to extract tarball", "error", err, "output", string(output))
 		http.Error(w, fmt.Sprintf("Failed to extract: %v\n%s", err, output), http.StatusInternalServerError)
 		return
 	}
@@ -1686,12 +2954,12 @@ func (s *Server) handleUpgradeHeadlessShell(w http.ResponseWriter, r *http.Reque
 	cmd = exec.CommandContext(ctx, extractedBin, "--version")
 	versionOutput, err := cmd.Output()
 	if err != nil {
-		s.logger.Error("Extracted headless-shell --version failed", "error", err)
+		s.logger.ErrorContext(ctx, "Extracted headless-shell --version failed", "error", err)
 		http.Error(w, fmt.Sprintf("Extracted binary failed --version: %v", err), http.StatusInternalServerError)
 		return
 	}
 	newVersion := strings.TrimSpace(string(versionOutput))
-	s.logger.Info("New headless-shell version verified", "version", newVersion)
+	s.logger.InfoContext(ctx, "New headless-shell version verified", "version", newVersion)
 
 	// Replace the install dir using sudo: backup -> move new -> cleanup
 	installDir := filepath.Dir(headlessShellPath)
@@ -1701,7 +2969,7 @@ func (s *Server) handleUpgradeHeadlessShell(w http.ResponseWriter, r *http.Reque
 
 	cmd = exec.CommandContext(ctx, "sudo", "mv", installDir, backupDir)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		s.logger.Error("Failed to backup old headless-shell", "error", err, "output", string(output))
+		s.logger.ErrorContext(ctx, "Failed to backup old headless-shell", "error", err, "output", string(output))
 		http.Error(w, fmt.Sprintf("Failed to backup old installation: %v\n%s", err, output), http.StatusInternalServerError)
 		return
 	}
@@ -1710,7 +2978,7 @@ func (s *Server) handleUpgradeHeadlessShell(w http.ResponseWriter, r *http.Reque
 	if output, err := cmd.CombinedOutput(); err != nil {
 		// Rollback: restore backup (use background context since request ctx may be cancelled)
 		exec.CommandContext(context.Background(), "sudo", "mv", backupDir, installDir).Run()
-		s.logger.Error("Failed to install new headless-shell", "error", err, "output", string(output))
+		s.logger.ErrorContext(ctx, "Failed to install new headless-shell", "error", err, "output", string(output))
 		http.Error(w, fmt.Sprintf("Failed to install: %v\n%s", err, output), http.StatusInternalServerError)
 		return
 	}
@@ -1724,7 +2992,7 @@ func (s *Server) handleUpgradeHeadlessShell(w http.ResponseWriter, r *http.Reque
 	vc.cachedInfo = nil
 	vc.mu.Unlock()
 
-	s.logger.Info("headless-shell upgraded successfully", "version", newVersion)
+	s.logger.InfoContext(ctx, "headless-shell upgraded successfully", "version", newVersion)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -1748,7 +3016,7 @@ func (s *Server) handleExit(w http.ResponseWriter, r *http.Request) {
 	// Exit after a short delay to allow response to be sent
 	go func() {
 		time.Sleep(100 * time.Millisecond)
-		s.logger.Info("Exiting Shelley via /exit endpoint")
+		s.logger.InfoContext(r.Context(), "Exiting Shelley via /exit endpoint")
 		os.Exit(0)
 	}()
 }
@@ -1757,7 +3025,7 @@ func (s *Server) handleExit(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 	settings, err := s.db.GetAllSettings(r.Context())
 	if err != nil {
-		s.logger.Error("Failed to get settings", "error", err)
+		s.logger.ErrorContext(r.Context(), "Failed to get settings", "error", err)
 		http.Error(w, fmt.Sprintf("Failed to get settings: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -1793,7 +3061,7 @@ func (s *Server) handleSetSetting(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.db.SetSetting(r.Context(), req.Key, req.Value); err != nil {
-		s.logger.Error("Failed to set setting", "error", err, "key", req.Key)
+		s.logger.ErrorContext(r.Context(), "Failed to set setting", "error", err, "key", req.Key)
 		http.Error(w, fmt.Sprintf("Failed to set setting: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,7 +11,10 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
@@ -101,6 +105,197 @@ func TestHandleArchivedConversations(t *testing.T) {
 	}
 }
 
+func TestHandleConversationsListMeta(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	for _, slug := range []string{"meta-a", "meta-b", "meta-c", "old-a", "old-b", "old-c"} {
+		conv, err := h.db.CreateConversation(ctx, &slug, true, nil, nil, db.ConversationOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if strings.HasPrefix(slug, "old-") {
+			if _, err := h.db.ArchiveConversation(ctx, conv.ConversationID); err != nil {
+				t.Fatalf("Failed to archive conversation: %v", err)
+			}
+		}
+	}
+
+	list := func(handler http.HandlerFunc, url string) ListPage[generated.Conversation] {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", url, w.Code, w.Body.String())
+		}
+		var page ListPage[generated.Conversation]
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("%s: failed to unmarshal response: %v", url, err)
+		}
+		return page
+	}
+
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		url     string
+	}{
+		{"active", h.server.handleConversations, "/api/conversations"},
+		{"archived", h.server.handleArchivedConversations, "/api/conversations/archived"},
+	} {
+		first := list(tc.handler, tc.url+"?meta=1&limit=2")
+		if len(first.Items) != 2 || first.Total != 3 || first.Limit != 2 || first.Offset != 0 || !first.HasMore {
+			t.Errorf("%s: unexpected first page %+v", tc.name, first)
+		}
+		last := list(tc.handler, tc.url+"?meta=1&limit=2&offset=2")
+		if len(last.Items) != 1 || last.Total != 3 || last.HasMore {
+			t.Errorf("%s: unexpected last page %+v", tc.name, last)
+		}
+	}
+
+	for _, url := range []string{"/api/conversations?meta=1&q=meta-b", "/api/conversations?meta=1&q=meta-b&search_content=true"} {
+		if search := list(h.server.handleConversations, url); search.Total != 1 || len(search.Items) != 1 || search.HasMore {
+			t.Errorf("%s: unexpected search page %+v", url, search)
+		}
+	}
+	search := list(h.server.handleArchivedConversations, "/api/conversations/archived?meta=1&q=old&limit=1")
+	if search.Total != 3 || len(search.Items) != 1 || !search.HasMore {
+		t.Errorf("unexpected archived search page %+v", search)
+	}
+
+	// Without meta the response is still a bare array.
+	w := httptest.NewRecorder()
+	h.server.handleConversations(w, httptest.NewRequest(http.MethodGet, "/api/conversations?limit=2", nil))
+	var conversations []ConversationWithState
+	if err := json.Unmarshal(w.Body.Bytes(), &conversations); err != nil || len(conversations) != 2 {
+		t.Errorf("expected a bare array of 2 conversations, got %s (%v)", w.Body.String(), err)
+	}
+}
+
+func TestHandleConversationsCursor(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	for i, slug := range []string{"cursor-a", "cursor-b", "cursor-c"} {
+		conv, err := h.db.CreateConversation(ctx, &slug, true, nil, nil, db.ConversationOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		// Older than anything created during the test, so the new
+		// conversation below sorts first.
+		updatedAt := fmt.Sprintf("2024-01-01 10:00:0%d", i)
+		if err := h.db.Pool().Exec(ctx, "UPDATE conversations SET updated_at = ? WHERE conversation_id = ?", updatedAt, conv.ConversationID); err != nil {
+			t.Fatalf("Failed to set updated_at: %v", err)
+		}
+	}
+
+	get := func(url string) (*httptest.ResponseRecorder, CursorPage[ConversationWithState]) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.server.handleConversations(w, httptest.NewRequest(http.MethodGet, url, nil))
+		var page CursorPage[ConversationWithState]
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+				t.Fatalf("%s: failed to unmarshal response: %v", url, err)
+			}
+		}
+		return w, page
+	}
+
+	_, first := get("/api/conversations?cursor=&limit=2")
+	if len(first.Items) != 2 || first.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+
+	// A conversation created while paging doesn't shift the next page.
+	newSlug := "cursor-new"
+	if _, err := h.db.CreateConversation(ctx, &newSlug, true, nil, nil, db.ConversationOptions{}); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	_, second := get("/api/conversations?limit=2&cursor=" + first.NextCursor)
+	if len(second.Items) != 1 || second.NextCursor != "" {
+		t.Fatalf("unexpected last page %+v", second)
+	}
+	seen := make(map[string]bool)
+	for _, conv := range append(first.Items, second.Items...) {
+		if seen[conv.ConversationID] || *conv.Slug == newSlug {
+			t.Errorf("unexpected or repeated conversation %s", *conv.Slug)
+		}
+		seen[conv.ConversationID] = true
+	}
+
+	for _, url := range []string{"/api/conversations?cursor=not-a-cursor", "/api/conversations?cursor=&q=cursor"} {
+		if w, _ := get(url); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleConversationTags(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	ids := make(map[string]string)
+	for _, slug := range []string{"tags-a", "tags-b"} {
+		conv, err := h.db.CreateConversation(ctx, &slug, true, nil, nil, db.ConversationOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		ids[slug] = conv.ConversationID
+	}
+
+	mux := h.server.conversationMux()
+	do := func(method, path, body string) (int, TagsResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var resp TagsResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("%s %s: failed to unmarshal response: %v", method, path, err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	if code, resp := do(http.MethodPost, "/"+ids["tags-a"]+"/tags", `{"tag": " work "}`); code != http.StatusOK || !slices.Equal(resp.Tags, []string{"work"}) {
+		t.Fatalf("add tag: got %d %v", code, resp.Tags)
+	}
+	if code, resp := do(http.MethodPost, "/"+ids["tags-a"]+"/tags", `{"tag": "needs review"}`); code != http.StatusOK || !slices.Equal(resp.Tags, []string{"needs review", "work"}) {
+		t.Fatalf("add second tag: got %d %v", code, resp.Tags)
+	}
+	if code, _ := do(http.MethodPost, "/"+ids["tags-a"]+"/tags", `{"tag": "  "}`); code != http.StatusBadRequest {
+		t.Errorf("empty tag: expected status 400, got %d", code)
+	}
+	if code, _ := do(http.MethodPost, "/nope/tags", `{"tag": "work"}`); code != http.StatusNotFound {
+		t.Errorf("unknown conversation: expected status 404, got %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	h.server.handleConversations(w, httptest.NewRequest(http.MethodGet, "/api/conversations?tag=work", nil))
+	var conversations []ConversationWithState
+	if err := json.Unmarshal(w.Body.Bytes(), &conversations); err != nil {
+		t.Fatalf("failed to unmarshal list: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].ConversationID != ids["tags-a"] || !slices.Equal(conversations[0].Tags, []string{"needs review", "work"}) {
+		t.Errorf("unexpected conversations tagged work: %+v", conversations)
+	}
+
+	if code, resp := do(http.MethodDelete, "/"+ids["tags-a"]+"/tags/needs%20review", ""); code != http.StatusOK || !slices.Equal(resp.Tags, []string{"work"}) {
+		t.Errorf("remove tag: got %d %v", code, resp.Tags)
+	}
+
+	w = httptest.NewRecorder()
+	h.server.handleConversations(w, httptest.NewRequest(http.MethodGet, "/api/conversations?tag=work&q=tags", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("tag with q: expected status 400, got %d", w.Code)
+	}
+}
+
 func TestHandleArchiveConversation(t *testing.T) {
 	t.Parallel()
 	h := NewTestHarness(t)
@@ -519,3 +714,594 @@ func TestHandleUploadToCwd(t *testing.T) {
 		t.Fatalf("expected 400 for mismatch, got %d", rec.Code)
 	}
 }
+
+func TestHandleNewConversationParams(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+
+	newConversation := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/conversations/new", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.server.handleNewConversation(w, req)
+		return w
+	}
+
+	w := newConversation(`{"message":"echo: hi","model":"predictable","params":{"temperature":3}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for invalid params, got %d: %s", w.Code, w.Body.String())
+	}
+
+	h.llm.ClearRequests()
+	w = newConversation(`{"message":"echo: hi","model":"predictable","params":{"temperature":0.2,"max_tokens":500}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	h.convID = resp.ConversationID
+	h.WaitResponse()
+
+	last := h.llm.GetLastRequest()
+	if last == nil {
+		t.Fatal("expected an LLM request")
+	}
+	if last.Params.Temperature == nil || *last.Params.Temperature != 0.2 || last.Params.MaxTokens != 500 {
+		t.Errorf("params not passed to LLM request: %+v", last.Params)
+	}
+}
+
+func TestHandleNewConversationSystemPromptOverride(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+
+	newConversation := func(body string, local bool) *httptest.ResponseRecorder {
+		var handler http.Handler = http.HandlerFunc(h.server.handleNewConversation)
+		if local {
+			handler = LocalSocketMiddleware(handler)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/conversations/new", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"message":"echo: hi","model":"predictable","system_prompt":{"text":"Answer tersely.","mode":"replace"},"params":{"stop_sequences":["STOP"]}}`
+	if w := newConversation(body, false); w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for unauthenticated override, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := newConversation(`{"message":"echo: hi","model":"predictable","system_prompt":{"text":"x","mode":"prepend"}}`, true); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for invalid mode, got %d: %s", w.Code, w.Body.String())
+	}
+
+	h.llm.ClearRequests()
+	w := newConversation(body, true)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	h.convID = resp.ConversationID
+	h.WaitResponse()
+
+	last := h.llm.GetLastRequest()
+	if last == nil {
+		t.Fatal("expected an LLM request")
+	}
+	if len(last.System) != 1 || last.System[0].Text != "Answer tersely." {
+		t.Errorf("system prompt not replaced: %+v", last.System)
+	}
+	if !slices.Equal(last.Params.StopSequences, []string{"STOP"}) {
+		t.Errorf("stop sequences not passed to LLM request: %+v", last.Params)
+	}
+}
+
+func TestHandleNewConversationTemplate(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	templateCwd := t.TempDir()
+	h.server.SetConversationTemplate(ConversationTemplate{
+		Model:      "predictable",
+		Cwd:        templateCwd,
+		Tools:      []string{"bash", " "},
+		SystemNote: "  Keep answers short.  ",
+	})
+
+	newConversation := func(body string) generated.Conversation {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/conversations/new", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.server.handleNewConversation(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			ConversationID string `json:"conversation_id"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		h.convID = resp.ConversationID
+		h.responsesCount = 0
+		h.WaitResponse()
+		conv, err := h.db.GetConversationByID(context.Background(), resp.ConversationID)
+		if err != nil {
+			t.Fatalf("failed to load conversation: %v", err)
+		}
+		return *conv
+	}
+
+	// Without overrides, the conversation takes everything from the template.
+	h.llm.ClearRequests()
+	conv := newConversation(`{"message":"echo: hi"}`)
+	if conv.Model == nil || *conv.Model != "predictable" {
+		t.Errorf("expected template model, got %v", conv.Model)
+	}
+	if conv.Cwd == nil || *conv.Cwd != templateCwd {
+		t.Errorf("expected template cwd %s, got %v", templateCwd, conv.Cwd)
+	}
+	if conv.SystemNote == nil || *conv.SystemNote != "Keep answers short." {
+		t.Errorf("expected template system note, got %v", conv.SystemNote)
+	}
+	if opts := db.ParseConversationOptions(conv.ConversationOptions); !slices.Equal(opts.Tools, []string{"bash"}) {
+		t.Errorf("expected template tools [bash], got %v", opts.Tools)
+	}
+	last := h.llm.GetLastRequest()
+	if last == nil {
+		t.Fatal("expected an LLM request")
+	}
+	var tools []string
+	for _, tool := range last.Tools {
+		tools = append(tools, tool.Name)
+	}
+	if !slices.Equal(tools, []string{"bash"}) {
+		t.Errorf("expected only the allowed tools to be offered, got %v", tools)
+	}
+
+	// The request's own settings win.
+	otherCwd := t.TempDir()
+	conv = newConversation(fmt.Sprintf(`{"message":"echo: hi","cwd":%q,"system_note":"","conversation_options":{"tools":["bash","think"]}}`, otherCwd))
+	if conv.Cwd == nil || *conv.Cwd != otherCwd {
+		t.Errorf("expected requested cwd %s, got %v", otherCwd, conv.Cwd)
+	}
+	if conv.SystemNote != nil {
+		t.Errorf("expected an empty system_note to opt out of the template's, got %q", *conv.SystemNote)
+	}
+	if opts := db.ParseConversationOptions(conv.ConversationOptions); !slices.Equal(opts.Tools, []string{"bash", "think"}) {
+		t.Errorf("expected requested tools, got %v", opts.Tools)
+	}
+}
+
+func TestHandleGetConversationPagination(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("echo: first", "/tmp")
+	h.WaitResponse()
+
+	getConversation := func(query string) (*httptest.ResponseRecorder, StreamResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/conversation/"+h.convID+query, nil)
+		w := httptest.NewRecorder()
+		h.server.handleGetConversation(w, req, h.convID)
+		var resp StreamResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return w, resp
+	}
+	sequenceIDs := func(resp StreamResponse) []int64 {
+		var ids []int64
+		for _, m := range resp.Messages {
+			ids = append(ids, m.SequenceID)
+		}
+		return ids
+	}
+
+	_, all := getConversation("")
+	if len(all.Messages) < 3 {
+		t.Fatalf("expected at least 3 messages, got %d", len(all.Messages))
+	}
+	if all.ContextWindowSize == 0 {
+		t.Error("expected context_window_size for the full history")
+	}
+	ids := sequenceIDs(all)
+	last := ids[len(ids)-1]
+
+	w, page := getConversation("?limit=2&order=desc")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := sequenceIDs(page); !slices.Equal(got, []int64{last, last - 1}) {
+		t.Errorf("latest page sequence IDs = %v, want [%d %d]", got, last, last-1)
+	}
+	if page.ContextWindowSize != 0 {
+		t.Errorf("expected no context_window_size for a page, got %d", page.ContextWindowSize)
+	}
+
+	_, page = getConversation(fmt.Sprintf("?after=%d", ids[0]))
+	if got := sequenceIDs(page); !slices.Equal(got, ids[1:]) {
+		t.Errorf("after page sequence IDs = %v, want %v", got, ids[1:])
+	}
+
+	for _, query := range []string{"?limit=0", "?before=abc", "?order=sideways"} {
+		if w, _ := getConversation(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestHandleGetConversationStreamsGzip(t *testing.T) {
+	h := NewTestHarness(t)
+
+	oldBatchSize := messageBatchSize
+	messageBatchSize = 2
+	t.Cleanup(func() { messageBatchSize = oldBatchSize })
+
+	h.NewConversation("bash: echo hi", "/tmp")
+	h.WaitToolResult()
+	h.WaitResponse()
+
+	// The profiled response is built in memory; the streamed one must match it.
+	req := httptest.NewRequest(http.MethodGet, "/api/conversation/"+h.convID+"?profile=1", nil)
+	w := httptest.NewRecorder()
+	h.server.handleGetConversation(w, req, h.convID)
+	var want StreamResponse
+	if err := json.NewDecoder(w.Body).Decode(&want); err != nil {
+		t.Fatalf("failed to decode profiled response: %v", err)
+	}
+	if int64(len(want.Messages)) <= messageBatchSize {
+		t.Fatalf("expected more than %d messages to span batches, got %d", messageBatchSize, len(want.Messages))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/"+h.convID, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	h.server.conversationMux().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip response, got Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+	if !w.Flushed {
+		t.Error("expected the response to be flushed while streaming")
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("failed to create gzip reader: %v", err)
+	}
+	var got StreamResponse
+	if err := json.NewDecoder(gr).Decode(&got); err != nil {
+		t.Fatalf("failed to decode streamed response: %v", err)
+	}
+
+	if got.Conversation.ConversationID != h.convID {
+		t.Errorf("expected conversation %s, got %s", h.convID, got.Conversation.ConversationID)
+	}
+	if got.ContextWindowSize == 0 || got.ContextWindowSize != want.ContextWindowSize {
+		t.Errorf("context_window_size = %d, want %d", got.ContextWindowSize, want.ContextWindowSize)
+	}
+	if len(got.Messages) != len(want.Messages) {
+		t.Fatalf("expected %d messages, got %d", len(want.Messages), len(got.Messages))
+	}
+	for i, msg := range got.Messages {
+		if msg.MessageID != want.Messages[i].MessageID {
+			t.Errorf("message %d: expected %s, got %s", i, want.Messages[i].MessageID, msg.MessageID)
+		}
+		if len(msg.ToolCalls) != len(want.Messages[i].ToolCalls) || hasUnpairedToolCalls(msg) {
+			t.Errorf("message %d: expected paired tool calls %+v, got %+v", i, want.Messages[i].ToolCalls, msg.ToolCalls)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.server.handleGetConversation(w, httptest.NewRequest(http.MethodGet, "/api/conversation/nope", nil), "nope")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing conversation, got %d", w.Code)
+	}
+}
+
+func TestHandleGetMessageInContext(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("echo: first", "/tmp")
+	h.WaitResponse()
+	h.Chat("echo: second")
+	h.WaitResponse()
+
+	mux := h.server.conversationMux()
+	getMessage := func(path string) (*httptest.ResponseRecorder, MessageInContext) {
+		req := httptest.NewRequest(http.MethodGet, "/"+h.convID+path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp MessageInContext
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return w, resp
+	}
+	sequenceIDs := func(messages []APIMessage) []int64 {
+		ids := []int64{}
+		for _, m := range messages {
+			ids = append(ids, m.SequenceID)
+		}
+		return ids
+	}
+
+	w, resp := getMessage("/message/3?before=1&after=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Message.SequenceID != 3 || resp.Conversation.ConversationID != h.convID {
+		t.Errorf("got message %d of %s, want 3 of %s", resp.Message.SequenceID, resp.Conversation.ConversationID, h.convID)
+	}
+	if got := sequenceIDs(resp.Before); !slices.Equal(got, []int64{2}) {
+		t.Errorf("before = %v, want [2]", got)
+	}
+	if got := sequenceIDs(resp.After); !slices.Equal(got, []int64{4}) {
+		t.Errorf("after = %v, want [4]", got)
+	}
+
+	// The default context includes everything around the first message.
+	_, resp = getMessage("/message/1")
+	if len(resp.Before) != 0 || len(resp.After) < 3 {
+		t.Errorf("expected no messages before and at least 3 after, got %v and %v", sequenceIDs(resp.Before), sequenceIDs(resp.After))
+	}
+
+	for path, want := range map[string]int{
+		"/message/999":         http.StatusNotFound,
+		"/message/abc":         http.StatusBadRequest,
+		"/message/1?before=-1": http.StatusBadRequest,
+		"/message/1?after=101": http.StatusBadRequest,
+	} {
+		if w, _ := getMessage(path); w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestStaticHandlerServesSourceMaps(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+
+	const sourceMap = `{"version":3,"sources":["src/main.tsx"],"mappings":""}`
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write([]byte(sourceMap))
+	gw.Close()
+	handler := h.server.staticHandler(http.FS(fstest.MapFS{
+		"main.js.map.gz": {Data: buf.Bytes()},
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/main.js.map", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", got)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("expected Content-Encoding gzip, got %q", got)
+	}
+	if !bytes.Equal(w.Body.Bytes(), buf.Bytes()) {
+		t.Error("expected the compressed source map to be served as is")
+	}
+
+	// Clients that don't accept gzip get it decompressed.
+	req = httptest.NewRequest(http.MethodGet, "/main.js.map", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no Content-Encoding, got %q", got)
+	}
+	if got := w.Body.String(); got != sourceMap {
+		t.Errorf("expected decompressed source map %q, got %q", sourceMap, got)
+	}
+}
+
+func TestStaticIndexServesConfigSeparately(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+
+	const indexHTML = `<html><head><script src="/main.js"></script></head><body></body></html>`
+	handler := h.server.staticHandler(http.FS(fstest.MapFS{
+		"index.html": {Data: []byte(indexHTML)},
+	}))
+	getIndex := func() string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		return w.Body.String()
+	}
+
+	if body := getIndex(); !strings.Contains(body, "window.__SHELLEY_INIT__=") {
+		t.Fatalf("expected init data to be injected by default, got %s", body)
+	}
+
+	h.server.SetStaticIndex(true)
+	if body := getIndex(); body != indexHTML {
+		t.Errorf("expected index.html to be served as is, got %s", body)
+	}
+
+	// Asset links still go under the base path
+	h.server.SetBasePath("/shelley")
+	if body, want := getIndex(), strings.Replace(indexHTML, `src="/`, `src="/shelley/`, 1); body != want {
+		t.Errorf("expected %s with a base path, got %s", want, body)
+	}
+
+	w := httptest.NewRecorder()
+	h.server.handleConfig(w, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var config map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	for _, key := range []string{"models", "default_model", "hostname", "default_cwd"} {
+		if _, ok := config[key]; !ok {
+			t.Errorf("expected %q in config, got %v", key, config)
+		}
+	}
+}
+
+func TestHandleBulkConversationAction(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	var ids []string
+	for _, slug := range []string{"bulk-a", "bulk-b", "bulk-c"} {
+		conv, err := h.db.CreateConversation(ctx, &slug, true, nil, nil, db.ConversationOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		ids = append(ids, conv.ConversationID)
+	}
+
+	bulk := func(action string, ids ...string) (int, BulkConversationResponse) {
+		t.Helper()
+		body, _ := json.Marshal(BulkConversationRequest{IDs: ids, Action: action})
+		w := httptest.NewRecorder()
+		h.server.handleBulkConversationAction(w, httptest.NewRequest(http.MethodPost, "/api/conversations/bulk", bytes.NewReader(body)))
+		var resp BulkConversationResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	code, resp := bulk("archive", append(ids, "missing")...)
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if len(resp.Results) != 4 {
+		t.Fatalf("expected 4 results, got %+v", resp.Results)
+	}
+	for i, result := range resp.Results[:3] {
+		if result.ID != ids[i] || !result.Success {
+			t.Errorf("expected %s to be archived, got %+v", ids[i], result)
+		}
+	}
+	if last := resp.Results[3]; last.Success || last.Error == "" {
+		t.Errorf("expected the missing conversation to fail, got %+v", last)
+	}
+
+	archived, err := h.db.ListArchivedConversations(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListArchivedConversations() error = %v", err)
+	}
+	var archivedIDs []string
+	for _, c := range archived {
+		archivedIDs = append(archivedIDs, c.ConversationID)
+	}
+	for _, id := range ids {
+		if !slices.Contains(archivedIDs, id) {
+			t.Errorf("expected %s to be archived, archived = %v", id, archivedIDs)
+		}
+	}
+
+	if code, resp := bulk("delete", ids[0]); code != http.StatusOK || !resp.Results[0].Success {
+		t.Fatalf("delete: got %d %+v", code, resp.Results)
+	}
+	if _, err := h.db.GetConversationByID(ctx, ids[0]); err == nil {
+		t.Error("expected the deleted conversation to be gone")
+	}
+
+	if code, _ := bulk("explode", ids[1]); code != http.StatusBadRequest {
+		t.Errorf("unknown action: expected status 400, got %d", code)
+	}
+	if code, _ := bulk("archive"); code != http.StatusBadRequest {
+		t.Errorf("no ids: expected status 400, got %d", code)
+	}
+}
+
+func TestTruncateConversationEndpoint(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	h.NewConversation("echo: first", "")
+	h.WaitResponse()
+	h.Chat("echo: second")
+	h.WaitResponse()
+	convID := h.ConversationID()
+
+	messages, err := h.db.ListMessages(ctx, convID)
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	// Keep everything up to the first reply
+	var keepSeq int64
+	for _, m := range messages {
+		if m.Type == string(db.MessageTypeAgent) {
+			keepSeq = m.SequenceID
+			break
+		}
+	}
+
+	mux := h.server.conversationMux()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/"+convID+"/truncate", strings.NewReader(fmt.Sprintf(`{"sequence_id":%d}`, keepSeq))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp StreamResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.TruncatedAfter != keepSeq {
+		t.Errorf("expected truncated_after %d, got %d", keepSeq, resp.TruncatedAfter)
+	}
+	if len(resp.Messages) == 0 || resp.Messages[len(resp.Messages)-1].SequenceID != keepSeq {
+		t.Errorf("expected the remaining messages to end at sequence %d, got %d messages", keepSeq, len(resp.Messages))
+	}
+	remaining, err := h.db.ListMessages(ctx, convID)
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	if len(remaining) != len(resp.Messages) {
+		t.Errorf("expected %d messages in the database, got %d", len(resp.Messages), len(remaining))
+	}
+
+	// The next turn is built from the truncated history
+	h.responsesCount = 1
+	h.llm.ClearRequests()
+	h.Chat("echo: third")
+	h.WaitResponse()
+	last := h.llm.GetLastRequest()
+	if last == nil {
+		t.Fatal("expected an LLM request")
+	}
+	for _, m := range last.Messages {
+		for _, c := range m.Content {
+			if strings.Contains(c.Text, "second") {
+				t.Errorf("expected the truncated turn to be gone from the LLM request, found %q", c.Text)
+			}
+		}
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/missing/truncate", strings.NewReader(`{"sequence_id":1}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing conversation, got %d", w.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readyCheckTimeout bounds how long /readyz waits for the database.
+const readyCheckTimeout = 2 * time.Second
+
+// HealthResponse is the body of /healthz and /readyz
+type HealthResponse struct {
+	Status string `json:"status"` // "ok" or "unavailable"
+	// Reason says why the server isn't ready
+	Reason string `json:"reason,omitempty"`
+}
+
+// handleHealthz handles GET /healthz, a liveness probe: it succeeds whenever
+// the process is serving requests.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// handleReadyz handles GET /readyz, a readiness probe: it answers 503 unless
+// the database responds and at least one model is available.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if reason := s.notReadyReason(r.Context()); reason != "" {
+		s.logger.WarnContext(r.Context(), "Readiness check failed", "reason", reason)
+		writeHealth(w, http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Reason: reason})
+		return
+	}
+	writeHealth(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// notReadyReason returns why the server can't serve conversations, or "" if it can.
+func (s *Server) notReadyReason(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+	if err := s.db.Ping(ctx); err != nil {
+		return "database unavailable: " + err.Error()
+	}
+	if len(s.llmManager.GetAvailableModels()) == 0 {
+		return "no models available"
+	}
+	return ""
+}
+
+func writeHealth(w http.ResponseWriter, status int, resp HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
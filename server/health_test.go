@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	get := func(path string) (int, HealthResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp HealthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("GET %s: failed to parse response %q: %v", path, w.Body.String(), err)
+		}
+		return w.Code, resp
+	}
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code, resp := get(path); code != http.StatusOK || resp.Status != "ok" {
+			t.Errorf("GET %s = %d %+v, want 200 ok", path, code, resp)
+		}
+	}
+
+	database.Close()
+
+	code, resp := get("/readyz")
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" {
+		t.Errorf("GET /readyz with closed database = %d %+v, want 503 unavailable", code, resp)
+	}
+	if !strings.Contains(resp.Reason, "database") {
+		t.Errorf("reason = %q, want it to mention the database", resp.Reason)
+	}
+	// Liveness doesn't depend on the database
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("GET /healthz with closed database = %d, want 200", code)
+	}
+}
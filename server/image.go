@@ -100,6 +100,21 @@ func (s *Server) handleMessageImage(w http.ResponseWriter, r *http.Request) {
 // replaces it with a URL pointing to the /api/message/{id}/image endpoint.
 // This dramatically reduces response sizes for conversations with screenshots.
 func stripImageDataFromLLMData(llmData *string, messageID string) *string {
+	return replaceImageData(llmData, func(contentIdx, trIdx int) string {
+		return imageURL(messageID, contentIdx, trIdx)
+	})
+}
+
+// omitImageDataFromLLMData removes base64 image data from llm_data JSON
+// without leaving a URL in its place. The MediaType is kept so readers can
+// tell an image was there.
+func omitImageDataFromLLMData(llmData *string) *string {
+	return replaceImageData(llmData, func(int, int) string { return "" })
+}
+
+// replaceImageData rewrites the image content in llm_data JSON with
+// stripImageDataFromContents, returning llmData unchanged if it has no images.
+func replaceImageData(llmData *string, urlFor func(contentIdx, trIdx int) string) *string {
 	if llmData == nil {
 		return nil
 	}
@@ -107,7 +122,7 @@ func stripImageDataFromLLMData(llmData *string, messageID string) *string {
 	if err := json.Unmarshal([]byte(*llmData), &msg); err != nil {
 		return llmData
 	}
-	if !stripImageDataFromContents(msg.Content, messageID) {
+	if !stripImageDataFromContents(msg.Content, urlFor) {
 		return llmData
 	}
 	stripped, err := json.Marshal(msg)
@@ -124,15 +139,15 @@ func imageURL(messageID string, contentIdx, trIdx int) string {
 }
 
 // stripImageDataFromContents removes Data from content items that have a MediaType
-// (i.e., image content) and replaces it with a placeholder URL in the Text field.
+// (i.e., image content) and sets DisplayImageURL to urlFor(contentIdx, trIdx).
 // Returns true if any data was stripped.
-func stripImageDataFromContents(contents []llm.Content, messageID string) bool {
+func stripImageDataFromContents(contents []llm.Content, urlFor func(contentIdx, trIdx int) string) bool {
 	changed := false
 	for i := range contents {
 		if contents[i].MediaType != "" && contents[i].Data != "" {
 			// Replace inline data with a URL to the image endpoint.
 			// Use trIdx=-1 for top-level content (not inside ToolResult).
-			contents[i].DisplayImageURL = imageURL(messageID, i, -1)
+			contents[i].DisplayImageURL = urlFor(i, -1)
 			contents[i].Data = ""
 			changed = true
 		}
@@ -140,7 +155,7 @@ func stripImageDataFromContents(contents []llm.Content, messageID string) bool {
 		for j := range contents[i].ToolResult {
 			tr := &contents[i].ToolResult[j]
 			if tr.MediaType != "" && tr.Data != "" {
-				tr.DisplayImageURL = imageURL(messageID, i, j)
+				tr.DisplayImageURL = urlFor(i, j)
 				tr.Data = ""
 				changed = true
 			}
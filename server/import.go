@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+// maxImportSize bounds the body of POST /api/conversations/import.
+const maxImportSize = 100 * 1024 * 1024
+
+// importableMessageTypes are the message types an import may contain.
+var importableMessageTypes = map[string]db.MessageType{
+	string(db.MessageTypeUser):    db.MessageTypeUser,
+	string(db.MessageTypeAgent):   db.MessageTypeAgent,
+	string(db.MessageTypeTool):    db.MessageTypeTool,
+	string(db.MessageTypeSystem):  db.MessageTypeSystem,
+	string(db.MessageTypeError):   db.MessageTypeError,
+	string(db.MessageTypeGitInfo): db.MessageTypeGitInfo,
+}
+
+// handleImportConversation handles POST /api/conversations/import. It takes a
+// conversation in the format GET /api/conversation/<id>/export?format=json
+// produces and recreates it as a new conversation with a fresh ID, keeping
+// its working directory, model, options and slug (numbered if another
+// conversation has it). Messages keep their order, types and data. Images
+// must be inline: an export made with images=link or images=omit is rejected,
+// since the imported conversation would send empty images to the LLM.
+func (s *Server) handleImportConversation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var export StreamResponse
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	opts, err := validateImport(export)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source := export.Conversation
+	conv, err := s.db.CreateConversation(ctx, nil, true, source.Cwd, source.Model, opts)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to create imported conversation", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	err = s.importMessages(ctx, conv.ConversationID, export.Messages)
+	if err == nil {
+		// The totals count the imported messages, as in a fork
+		err = s.db.QueriesTx(ctx, func(q *generated.Queries) error {
+			return q.RecomputeConversationUsage(ctx, conv.ConversationID)
+		})
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to import messages", "conversationID", conv.ConversationID, "error", err)
+		if err := s.db.DeleteConversation(context.WithoutCancel(ctx), conv.ConversationID); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to remove partially imported conversation", "conversationID", conv.ConversationID, "error", err)
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.InfoContext(ctx, "Imported conversation", "conversationID", conv.ConversationID, "sourceID", source.ConversationID, "messages", len(export.Messages))
+
+	if source.Slug != nil && *source.Slug != "" {
+		sourceSlug := *source.Slug
+		named, err := s.claimSlug(ctx, conv.ConversationID, func(attempt int) string {
+			if attempt == 0 {
+				return sourceSlug
+			}
+			return fmt.Sprintf("%s-%d", sourceSlug, attempt+1)
+		})
+		if err != nil {
+			s.logger.WarnContext(ctx, "Failed to name imported conversation", "conversationID", conv.ConversationID, "error", err)
+		} else {
+			conv = named
+		}
+	}
+
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conv,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conv)
+}
+
+// validateImport checks that an import is well formed, returning the
+// conversation options to create it with.
+func validateImport(export StreamResponse) (db.ConversationOptions, error) {
+	var opts db.ConversationOptions
+	if raw := export.Conversation.ConversationOptions; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+			return opts, errors.New("invalid conversation_options")
+		}
+	}
+	if len(export.Messages) == 0 {
+		return opts, errors.New("import has no messages")
+	}
+	var lastSeq int64
+	for i, msg := range export.Messages {
+		if _, ok := importableMessageTypes[msg.Type]; !ok {
+			return opts, fmt.Errorf("message %d has unknown type %q", i, msg.Type)
+		}
+		if msg.SequenceID <= lastSeq {
+			return opts, fmt.Errorf("message %d is out of sequence order", i)
+		}
+		lastSeq = msg.SequenceID
+		for _, field := range []struct {
+			name string
+			data *string
+		}{
+			{"llm_data", msg.LlmData},
+			{"user_data", msg.UserData},
+			{"usage_data", msg.UsageData},
+			{"display_data", msg.DisplayData},
+		} {
+			if field.data != nil && !json.Valid([]byte(*field.data)) {
+				return opts, fmt.Errorf("message %d has invalid %s", i, field.name)
+			}
+		}
+		if msg.LlmData != nil {
+			var llmMsg llm.Message
+			if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+				return opts, fmt.Errorf("message %d has invalid llm_data", i)
+			}
+			if hasImageWithoutData(llmMsg.Content) {
+				return opts, fmt.Errorf("message %d has an image without its data; export with images=inline", i)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// hasImageWithoutData reports whether any image in contents, or in their
+// tool results, was linked or omitted rather than inlined.
+func hasImageWithoutData(contents []llm.Content) bool {
+	for _, c := range contents {
+		if c.MediaType != "" && c.Data == "" {
+			return true
+		}
+		if hasImageWithoutData(c.ToolResult) {
+			return true
+		}
+	}
+	return false
+}
+
+// importMessages adds validated messages to a conversation in order.
+func (s *Server) importMessages(ctx context.Context, conversationID string, messages []APIMessage) error {
+	for _, msg := range messages {
+		params := db.CreateMessageParams{
+			ConversationID: conversationID,
+			Type:           importableMessageTypes[msg.Type],
+		}
+		// Only set present fields: a nil json.RawMessage would be stored as "null".
+		if msg.LlmData != nil {
+			params.LLMData = json.RawMessage(*msg.LlmData)
+		}
+		if msg.UserData != nil {
+			params.UserData = json.RawMessage(*msg.UserData)
+		}
+		if msg.UsageData != nil {
+			params.UsageData = json.RawMessage(*msg.UsageData)
+		}
+		if msg.DisplayData != nil {
+			params.DisplayData = json.RawMessage(*msg.DisplayData)
+		}
+		if _, err := s.db.CreateMessage(ctx, params); err != nil {
+			return fmt.Errorf("message %d: %w", msg.SequenceID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+func TestImportConversationRoundTrip(t *testing.T) {
+	h := NewTestHarness(t)
+	h.NewConversation("bash: echo hi", "/tmp")
+	h.WaitToolResult()
+	h.WaitResponse()
+
+	req := httptest.NewRequest("GET", "/api/conversation/"+h.convID+"/export?format=json", nil)
+	w := httptest.NewRecorder()
+	h.server.handleExportConversation(w, req, h.convID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	exported := w.Body.String()
+
+	req = httptest.NewRequest("POST", "/api/conversations/import", strings.NewReader(exported))
+	w = httptest.NewRecorder()
+	h.server.handleImportConversation(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("import: expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var imported generated.Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &imported); err != nil {
+		t.Fatalf("failed to parse import response: %v", err)
+	}
+	if imported.ConversationID == h.convID {
+		t.Fatal("expected the import to get a fresh conversation ID")
+	}
+
+	ctx := context.Background()
+	original, err := h.db.GetConversationByID(ctx, h.convID)
+	if err != nil {
+		t.Fatalf("failed to get original conversation: %v", err)
+	}
+	if original.Slug == nil || imported.Slug == nil {
+		t.Fatalf("expected both conversations to have slugs, got %v and %v", original.Slug, imported.Slug)
+	}
+	if *imported.Slug != *original.Slug+"-2" {
+		t.Errorf("imported slug = %q, want %q since the original's is taken", *imported.Slug, *original.Slug+"-2")
+	}
+
+	want, err := h.db.ListMessages(ctx, h.convID)
+	if err != nil {
+		t.Fatalf("failed to list original messages: %v", err)
+	}
+	got, err := h.db.ListMessages(ctx, imported.ConversationID)
+	if err != nil {
+		t.Fatalf("failed to list imported messages: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("imported %d messages, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type {
+			t.Errorf("message %d: type %q, want %q", i, got[i].Type, want[i].Type)
+		}
+		for _, field := range []struct {
+			name      string
+			got, want *string
+		}{
+			{"llm_data", got[i].LlmData, want[i].LlmData},
+			{"user_data", got[i].UserData, want[i].UserData},
+			{"usage_data", got[i].UsageData, want[i].UsageData},
+			{"display_data", got[i].DisplayData, want[i].DisplayData},
+		} {
+			if (field.got == nil) != (field.want == nil) || (field.got != nil && !jsonEqual(t, *field.got, *field.want)) {
+				t.Errorf("message %d: %s differs after import", i, field.name)
+			}
+		}
+	}
+}
+
+func TestImportConversationImages(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	conv, err := h.db.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	imageData := strings.Repeat("QUJD", 1000)
+	if _, err := h.db.CreateMessage(ctx, db.CreateMessageParams{
+		ConversationID: conv.ConversationID,
+		Type:           db.MessageTypeUser,
+		LLMData: llm.Message{
+			Role: llm.MessageRoleUser,
+			Content: []llm.Content{{
+				Type:      llm.ContentTypeToolResult,
+				ToolUseID: "shot",
+				ToolResult: []llm.Content{
+					{Type: llm.ContentTypeText, Text: "Screenshot taken"},
+					{Type: llm.ContentTypeText, MediaType: "image/png", Data: imageData},
+				},
+			}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+
+	export := func(images string) string {
+		t.Helper()
+		url := "/api/conversation/" + conv.ConversationID + "/export?format=json&images=" + images
+		w := httptest.NewRecorder()
+		h.server.handleExportConversation(w, httptest.NewRequest("GET", url, nil), conv.ConversationID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("images=%s: expected status 200, got %d: %s", images, w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+	importBody := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.server.handleImportConversation(w, httptest.NewRequest("POST", "/api/conversations/import", strings.NewReader(body)))
+		return w
+	}
+
+	w := importBody(export("inline"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var imported generated.Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &imported); err != nil {
+		t.Fatalf("failed to parse import response: %v", err)
+	}
+	messages, err := h.db.ListMessages(ctx, imported.ConversationID)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("expected 1 imported message, got %d: %v", len(messages), err)
+	}
+	var llmMsg llm.Message
+	if err := json.Unmarshal([]byte(*messages[0].LlmData), &llmMsg); err != nil {
+		t.Fatalf("failed to parse imported llm_data: %v", err)
+	}
+	if img := llmMsg.Content[0].ToolResult[1]; img.Data != imageData || img.DisplayImageURL != "" {
+		t.Errorf("expected the image data restored, got url %q and %d bytes", img.DisplayImageURL, len(img.Data))
+	}
+
+	for _, images := range []string{"link", "omit"} {
+		if w := importBody(export(images)); w.Code != http.StatusBadRequest {
+			t.Errorf("images=%s: expected status 400, got %d: %s", images, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestImportConversationRejectsMalformed(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not JSON", "{"},
+		{"no messages", `{"conversation": {}, "messages": []}`},
+		{"unknown type", `{"messages": [{"sequence_id": 1, "type": "bogus"}]}`},
+		{"out of order", `{"messages": [{"sequence_id": 2, "type": "user"}, {"sequence_id": 1, "type": "agent"}]}`},
+		{"invalid data", `{"messages": [{"sequence_id": 1, "type": "user", "llm_data": "{not json"}]}`},
+		{"invalid options", `{"conversation": {"conversation_options": "nope"}, "messages": [{"sequence_id": 1, "type": "user"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/conversations/import", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.handleImportConversation(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// jsonEqual reports whether two JSON documents are equivalent.
+func jsonEqual(t *testing.T, a, b string) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		t.Fatalf("invalid JSON %q: %v", a, err)
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		t.Fatalf("invalid JSON %q: %v", b, err)
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return string(ja) == string(jb)
+}
@@ -31,6 +31,10 @@ type LLMConfig struct {
 	// DefaultModel is the default model to use (optional, defaults to models.Default())
 	DefaultModel string
 
+	// ModelAliases maps stable names (e.g. "fast", "smart") to concrete
+	// model IDs (optional). The "default" alias falls back to DefaultModel.
+	ModelAliases map[string]string
+
 	// Links are custom links to be displayed in the UI (optional)
 	Links []Link
 
@@ -49,6 +53,19 @@ type LLMConfig struct {
 	// AlwaysOnSkills is a list of skill names whose bodies are always
 	// included in the system prompt (pre-activated).
 	AlwaysOnSkills []string
+
+	// SlugPrompt is a custom text/template prompt for slug generation
+	// (optional). It must include {{.UserMessage}}; see slug.PromptData.
+	SlugPrompt string
+
+	// MaxRepeatedToolErrors is how many times an identical tool call may fail
+	// in a row within a turn before the turn is halted (optional).
+	// Zero uses the default; a negative value disables the check.
+	MaxRepeatedToolErrors int
+
+	// NewConversation holds the defaults new conversations start with
+	// (optional).
+	NewConversation ConversationTemplate
 	// DB is the database for recording LLM requests (optional)
 	DB *db.DB
 
@@ -275,3 +275,106 @@ done:
 		}
 	}
 }
+
+// TestStreamMetaEvents verifies that events=meta streams conversation updates
+// without message bodies.
+func TestStreamMetaEvents(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+
+	conversation, err := database.CreateConversation(context.Background(), nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	conversationID := conversation.ConversationID
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/api/conversation/" + conversationID + "/stream?events=bogus")
+	if err != nil {
+		t.Fatalf("failed to request stream: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an unknown events value, got %d", resp.StatusCode)
+	}
+
+	readEvents := func(body *bufio.Scanner, events chan<- StreamResponse) {
+		for body.Scan() {
+			line, ok := strings.CutPrefix(body.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var streamResp StreamResponse
+			if err := json.Unmarshal([]byte(line), &streamResp); err != nil {
+				continue
+			}
+			events <- streamResp
+		}
+	}
+
+	sseResp, err := http.Get(httpServer.URL + "/api/conversation/" + conversationID + "/stream?events=meta")
+	if err != nil {
+		t.Fatalf("failed to connect to SSE stream: %v", err)
+	}
+	defer sseResp.Body.Close()
+	events := make(chan StreamResponse, 100)
+	go readEvents(bufio.NewScanner(sseResp.Body), events)
+
+	select {
+	case ev := <-events:
+		if ev.ConversationState == nil || ev.Conversation.ConversationID != conversationID {
+			t.Fatalf("expected initial event with conversation state, got %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for initial SSE event")
+	}
+
+	chatBody, _ := json.Marshal(ChatRequest{Message: "hello", Model: "predictable"})
+	resp, err = http.Post(httpServer.URL+"/api/conversation/"+conversationID+"/chat", "application/json", strings.NewReader(string(chatBody)))
+	if err != nil {
+		t.Fatalf("failed to send chat message: %v", err)
+	}
+	resp.Body.Close()
+
+	// The agent's reply carries usage, so its event updates the context window size.
+	var ctxSize uint64
+	deadline := time.After(5 * time.Second)
+	for ctxSize == 0 {
+		select {
+		case ev := <-events:
+			if len(ev.Messages) > 0 {
+				t.Fatalf("metadata stream sent %d messages", len(ev.Messages))
+			}
+			if ev.StreamDelta != nil || ev.ToolProgress != nil {
+				t.Fatalf("metadata stream sent a streaming update: %+v", ev)
+			}
+			ctxSize = ev.ContextWindowSize
+		case <-deadline:
+			t.Fatal("timed out waiting for a context window size update")
+		}
+	}
+
+	// A new metadata subscriber starts with the current context window size.
+	sseResp2, err := http.Get(httpServer.URL + "/api/conversation/" + conversationID + "/stream?events=meta")
+	if err != nil {
+		t.Fatalf("failed to connect to SSE stream: %v", err)
+	}
+	defer sseResp2.Body.Close()
+	events2 := make(chan StreamResponse, 100)
+	go readEvents(bufio.NewScanner(sseResp2.Body), events2)
+	select {
+	case ev := <-events2:
+		if len(ev.Messages) > 0 {
+			t.Errorf("initial metadata event sent %d messages", len(ev.Messages))
+		}
+		if ev.ContextWindowSize != ctxSize {
+			t.Errorf("expected initial context window size %d, got %d", ctxSize, ev.ContextWindowSize)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for initial SSE event")
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// requestDurationBuckets are the upper bounds, in seconds, of the HTTP
+// request latency histogram.
+var requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestMetrics records HTTP request latencies for the /metrics endpoint.
+type requestMetrics struct {
+	buckets []atomic.Int64 // per-bucket counts; the last is +Inf
+	sumNs   atomic.Int64
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{buckets: make([]atomic.Int64, len(requestDurationBuckets)+1)}
+}
+
+func (m *requestMetrics) observe(d time.Duration) {
+	i := 0
+	for i < len(requestDurationBuckets) && d.Seconds() > requestDurationBuckets[i] {
+		i++
+	}
+	m.buckets[i].Add(1)
+	m.sumNs.Add(int64(d))
+}
+
+// Middleware times each request. Event streams and websockets stay open for
+// as long as a client watches, so they are left out.
+func (m *requestMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		m.observe(time.Since(start))
+	})
+}
+
+// llmRequestCounter is implemented by LLM providers that count their requests.
+type llmRequestCounter interface {
+	RequestStats() (requests, failures int64)
+}
+
+// handleMetrics serves GET /metrics in the Prometheus text exposition format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	active := len(s.activeConversations)
+	managers := make([]*ConversationManager, 0, active)
+	for _, manager := range s.activeConversations {
+		managers = append(managers, manager)
+	}
+	s.mu.Unlock()
+	subscribers := 0
+	for _, manager := range managers {
+		subscribers += manager.subpub.SubscriberCount()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	writeMetric(bw, "shelley_active_conversations", "gauge", "Conversations loaded in memory.", active)
+	writeMetric(bw, "shelley_stream_subscribers", "gauge", "Clients streaming conversation updates.", subscribers)
+	if counter, ok := s.llmManager.(llmRequestCounter); ok {
+		requests, failures := counter.RequestStats()
+		writeMetric(bw, "shelley_llm_requests_total", "counter", "LLM requests made.", requests)
+		writeMetric(bw, "shelley_llm_request_errors_total", "counter", "LLM requests that failed.", failures)
+	}
+
+	if m := s.metrics; m != nil {
+		const name = "shelley_http_request_duration_seconds"
+		fmt.Fprintf(bw, "# HELP %s HTTP request latency, excluding event streams and websockets.\n", name)
+		fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+		var cumulative int64
+		for i, le := range requestDurationBuckets {
+			cumulative += m.buckets[i].Load()
+			fmt.Fprintf(bw, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		cumulative += m.buckets[len(requestDurationBuckets)].Load()
+		fmt.Fprintf(bw, "%s_bucket{le=\"+Inf\"} %d\n", name, cumulative)
+		fmt.Fprintf(bw, "%s_sum %g\n", name, time.Duration(m.sumNs.Load()).Seconds())
+		fmt.Fprintf(bw, "%s_count %d\n", name, cumulative)
+	}
+}
+
+func writeMetric[T int | int64](w *bufio.Writer, name, typ, help string, value T) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, typ, name, value)
+}
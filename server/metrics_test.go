@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.server.SetMetricsEnabled(true)
+	h.NewConversation("echo: hello", "/tmp")
+	h.WaitResponse()
+
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+	handler := h.server.metrics.Middleware(mux)
+
+	// Time one request before reading the metrics.
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE shelley_active_conversations gauge\nshelley_active_conversations 1\n",
+		"# TYPE shelley_stream_subscribers gauge\n",
+		"# TYPE shelley_http_request_duration_seconds histogram\n",
+		"shelley_http_request_duration_seconds_bucket{le=\"+Inf\"} 1\n",
+		"shelley_http_request_duration_seconds_count 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -2,11 +2,16 @@ package server
 
 import (
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 
+	"github.com/google/uuid"
 	sloghttp "github.com/samber/slog-http"
 )
 
@@ -21,6 +26,80 @@ func LoggerMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 	return sloghttp.NewWithConfig(logger, config)
 }
 
+// requestIDHeader carries a request's correlation ID, both inbound and in the
+// response.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds inbound request IDs, which end up in every log line.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestIDMiddleware gives every request an ID, reusing a valid inbound
+// X-Request-ID or generating one. The ID is stored in the request context,
+// where the server's logger picks it up, and echoed in the response header.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+			r.Header.Set(requestIDHeader, id)
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// validRequestID reports whether an inbound request ID is safe to log: short
+// and printable ASCII without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDFromContext returns the ID RequestIDMiddleware gave the request, if any.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDLogHandler adds a "request_id" attribute to records logged with a
+// request's context, so log lines can be tied to the request that caused them.
+type requestIDLogHandler struct {
+	slog.Handler
+}
+
+// withRequestIDs returns a logger that tags records with the request ID found
+// in the context passed to the *Context logging methods.
+func withRequestIDs(logger *slog.Logger) *slog.Logger {
+	if _, ok := logger.Handler().(requestIDLogHandler); ok {
+		return logger
+	}
+	return slog.New(requestIDLogHandler{logger.Handler()})
+}
+
+func (h requestIDLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDLogHandler) WithGroup(name string) slog.Handler {
+	return requestIDLogHandler{h.Handler.WithGroup(name)}
+}
+
 // RequireHeaderMiddleware requires a specific header to be present on all API requests.
 // This is used to ensure requests come through an authenticated proxy.
 func RequireHeaderMiddleware(headerName string) func(http.Handler) http.Handler {
@@ -38,6 +117,146 @@ func RequireHeaderMiddleware(headerName string) func(http.Handler) http.Handler
 	}
 }
 
+// bearerAuthPrefixes are the routes BearerAuthMiddleware guards: the API and
+// the endpoints that control the server or expose its internals. The UI
+// assets and health probes stay open.
+var bearerAuthPrefixes = []string{"/api/", "/debug/", "/metrics", "/exit", "/upgrade", "/settings"}
+
+// BearerAuthMiddleware requires API, debug and control requests to carry
+// "Authorization: Bearer <token>" with one of tokens, answering 401 otherwise.
+func BearerAuthMiddleware(tokens []string) func(http.Handler) http.Handler {
+	// Compare hashes so the comparison takes the same time whatever the
+	// length of the presented token.
+	hashes := make([][sha256.Size]byte, len(tokens))
+	for i, token := range tokens {
+		hashes[i] = sha256.Sum256([]byte(token))
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.ContainsFunc(bearerAuthPrefixes, func(prefix string) bool {
+				return strings.HasPrefix(r.URL.Path, prefix)
+			}) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="shelley"`)
+				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				return
+			}
+			presented := sha256.Sum256([]byte(token))
+			valid := 0
+			for _, h := range hashes {
+				valid |= subtle.ConstantTimeCompare(presented[:], h[:])
+			}
+			if valid != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="shelley", error="invalid_token"`)
+				http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BasePathMiddleware serves the app under basePath (e.g. "/shelley") for
+// hosting behind a reverse proxy. Requests under basePath have it stripped;
+// other requests pass through unchanged, so a proxy that strips the prefix
+// itself works too. A request for basePath alone is redirected to basePath+"/".
+func BasePathMiddleware(basePath string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if basePath == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == basePath {
+				target := basePath + "/"
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, http.StatusMovedPermanently)
+				return
+			}
+			rest, ok := strings.CutPrefix(r.URL.Path, basePath)
+			if !ok || !strings.HasPrefix(rest, "/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = rest
+			r2.URL.RawPath = ""
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// corsAllowMethods are the methods a CORS preflight may be granted.
+const corsAllowMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+
+// CORSMiddleware lets browser clients on the allowed origins call the server
+// with credentials. A request whose Origin is in allowedOrigins gets that
+// origin echoed back, and its OPTIONS preflight is answered here. Requests
+// from other origins pass through without CORS headers, so the browser blocks
+// them. With no allowed origins the middleware does nothing.
+func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			if !allowed[origin] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+				if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+				}
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type localRequestKey struct{}
+
+// LocalSocketMiddleware marks requests as arriving over the local Unix socket,
+// which is only accessible to the current user.
+func LocalSocketMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localRequestKey{}, true)))
+	})
+}
+
+// isLocalRequest reports whether r arrived over the local Unix socket.
+func isLocalRequest(r *http.Request) bool {
+	local, _ := r.Context().Value(localRequestKey{}).(bool)
+	return local
+}
+
+// isAuthenticated reports whether r is known to come from an authenticated
+// client: either over the local Unix socket, or over TCP with the required
+// header enforced by RequireHeaderMiddleware or a bearer token checked by
+// BearerAuthMiddleware.
+func (s *Server) isAuthenticated(r *http.Request) bool {
+	return isLocalRequest(r) ||
+		(s.requireHeader != "" && r.Header.Get(s.requireHeader) != "") ||
+		(len(s.authTokens) > 0 && r.Header.Get("Authorization") != "")
+}
+
 // gzipResponseWriter wraps http.ResponseWriter to compress responses
 type gzipResponseWriter struct {
 	http.ResponseWriter
@@ -48,6 +267,15 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.gw.Write(b)
 }
 
+// Flush writes out the data compressed so far and flushes the underlying
+// writer, if it can be.
+func (w *gzipResponseWriter) Flush() {
+	w.gw.Flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 var gzipWriterPool = sync.Pool{
 	New: func() interface{} {
 		gw, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
@@ -57,25 +285,34 @@ var gzipWriterPool = sync.Pool{
 
 // gzipHandler wraps a handler to compress responses when the client accepts gzip.
 // Use this to wrap specific handlers that benefit from compression.
-// Do NOT use for SSE or streaming responses.
+// Do NOT use for SSE: stream handlers compress with startGzip themselves, once
+// they can no longer fail with a plain error. Handlers that stream a single
+// large response can Flush to send what they have compressed so far.
 func gzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		if !acceptsGzip(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
+		gzw, finish := startGzip(w)
+		defer finish()
+		next.ServeHTTP(gzw, r)
+	})
+}
 
-		gw := gzipWriterPool.Get().(*gzip.Writer)
-		gw.Reset(w)
-		defer func() {
-			gw.Close()
-			gzipWriterPool.Put(gw)
-		}()
+// startGzip marks the response as gzip-compressed and returns a writer that
+// compresses into w, and a function to call once the response is complete.
+// Flushing the writer sends everything written so far.
+func startGzip(w http.ResponseWriter) (*gzipResponseWriter, func()) {
+	gw := gzipWriterPool.Get().(*gzip.Writer)
+	gw.Reset(w)
 
-		w.Header().Set("Content-Encoding", "gzip")
-		w.Header().Add("Vary", "Accept-Encoding")
-		w.Header().Del("Content-Length") // Compression changes size
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Del("Content-Length") // Compression changes size
 
-		next.ServeHTTP(&gzipResponseWriter{ResponseWriter: w, gw: gw}, r)
-	})
+	return &gzipResponseWriter{ResponseWriter: w, gw: gw}, func() {
+		gw.Close()
+		gzipWriterPool.Put(gw)
+	}
 }
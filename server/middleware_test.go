@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -129,3 +131,206 @@ func TestGzipHandler_SkipsWhenNoAcceptEncoding(t *testing.T) {
 		t.Errorf("body doesn't contain expected content: %s", w.Body.String())
 	}
 }
+
+func TestBasePathMiddleware(t *testing.T) {
+	t.Parallel()
+	handler := BasePathMiddleware("/shelley")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+
+	tests := []struct {
+		path     string
+		wantCode int
+		want     string
+	}{
+		{"/shelley/api/conversations", http.StatusOK, "/api/conversations"},
+		{"/shelley/", http.StatusOK, "/"},
+		{"/api/conversations", http.StatusOK, "/api/conversations"},
+		{"/shelleyx/api", http.StatusOK, "/shelleyx/api"},
+		{"/shelley?x=1", http.StatusMovedPermanently, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.wantCode, w.Code)
+			continue
+		}
+		if tt.wantCode == http.StatusMovedPermanently {
+			if loc := w.Header().Get("Location"); loc != "/shelley/?x=1" {
+				t.Errorf("%s: expected redirect to /shelley/?x=1, got %q", tt.path, loc)
+			}
+			continue
+		}
+		if w.Body.String() != tt.want {
+			t.Errorf("%s: expected path %q, got %q", tt.path, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestCORSMiddleware_EchoesAllowedOrigin(t *testing.T) {
+	t.Parallel()
+	handler := CORSMiddleware([]string{"https://app.example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/conversations", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected the origin to be echoed, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("expected credentials to be allowed, got %q", got)
+	}
+}
+
+func TestCORSMiddleware_IgnoresOtherOrigins(t *testing.T) {
+	t.Parallel()
+	handler := CORSMiddleware([]string{"https://app.example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/conversations", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no Access-Control-Allow-Origin for a disallowed origin, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("expected no Access-Control-Allow-Credentials for a disallowed origin, got %q", got)
+	}
+}
+
+func TestCORSMiddleware_AnswersPreflight(t *testing.T) {
+	t.Parallel()
+	called := false
+	handler := CORSMiddleware([]string{"https://app.example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("OPTIONS", "/api/conversations/new", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if called {
+		t.Error("expected the preflight to be answered by the middleware")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected the origin to be echoed, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != corsAllowMethods {
+		t.Errorf("expected Access-Control-Allow-Methods %q, got %q", corsAllowMethods, got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "content-type" {
+		t.Errorf("expected the requested headers to be allowed, got %q", got)
+	}
+}
+
+func TestBearerAuthMiddleware(t *testing.T) {
+	t.Parallel()
+	handler := BearerAuthMiddleware([]string{"first-token", "second-token"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"valid token", "/api/conversations", "Bearer second-token", http.StatusOK},
+		{"missing header", "/api/conversations", "", http.StatusUnauthorized},
+		{"wrong token", "/api/conversations", "Bearer wrong-token", http.StatusUnauthorized},
+		{"wrong scheme", "/api/conversations", "Basic Zmlyc3QtdG9rZW4=", http.StatusUnauthorized},
+		{"debug route", "/debug/pprof/", "", http.StatusUnauthorized},
+		{"control route", "/exit", "", http.StatusUnauthorized},
+		{"settings route", "/settings", "Bearer first-token", http.StatusOK},
+		{"metrics route", "/metrics", "", http.StatusUnauthorized},
+		{"non-API route", "/", "", http.StatusOK},
+		{"health probe", "/healthz", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	t.Parallel()
+	var logs bytes.Buffer
+	logger := withRequestIDs(slog.New(slog.NewTextHandler(&logs, nil)))
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFromContext(r.Context())
+		logger.InfoContext(r.Context(), "handled")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Without an inbound ID one is generated
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/conversations", nil))
+	generated := w.Header().Get("X-Request-ID")
+	if generated == "" {
+		t.Fatal("expected a generated X-Request-ID response header")
+	}
+	if seen != generated {
+		t.Errorf("context request ID = %q, want %q", seen, generated)
+	}
+	if !strings.Contains(logs.String(), "request_id="+generated) {
+		t.Errorf("expected the log line to carry the request ID, got %q", logs.String())
+	}
+
+	// An inbound ID is preserved
+	req := httptest.NewRequest("GET", "/api/conversations", nil)
+	req.Header.Set("X-Request-ID", "upstream-1234")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "upstream-1234" {
+		t.Errorf("X-Request-ID = %q, want the inbound upstream-1234", got)
+	}
+	if seen != "upstream-1234" {
+		t.Errorf("context request ID = %q, want upstream-1234", seen)
+	}
+
+	// An unusable inbound ID is replaced
+	req = httptest.NewRequest("GET", "/api/conversations", nil)
+	req.Header.Set("X-Request-ID", "has spaces\tand tabs")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got == "" || got == "has spaces\tand tabs" {
+		t.Errorf("X-Request-ID = %q, want a generated ID", got)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db/generated"
+)
+
+func TestNotifyNewMessageWithoutConversationData(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("echo: hi", "/tmp")
+	h.WaitResponse()
+
+	h.server.mu.Lock()
+	manager := h.server.activeConversations[h.convID]
+	h.server.mu.Unlock()
+
+	ctx := context.Background()
+	var latest generated.Message
+	if err := h.db.Queries(ctx, func(q *generated.Queries) error {
+		var err error
+		latest, err = q.GetLatestMessage(ctx, h.convID)
+		return err
+	}); err != nil {
+		t.Fatalf("failed to get latest message: %v", err)
+	}
+	next := manager.subpub.Subscribe(ctx, latest.SequenceID)
+
+	// A cancelled context makes the conversation read fail; the message must
+	// still be published.
+	msg := latest
+	msg.SequenceID++
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	h.server.notifySubscribersNewMessage(cancelled, h.convID, &msg)
+
+	resp, ok := next()
+	if !ok {
+		t.Fatal("expected the message to be published")
+	}
+	if len(resp.Messages) != 1 || resp.Messages[0].SequenceID != msg.SequenceID {
+		t.Fatalf("unexpected published messages: %+v", resp.Messages)
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"conversation":`) {
+		t.Errorf("expected the unread conversation to be omitted, got %s", data)
+	}
+}
+
+func TestGetConversationForNotify(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("echo: hi", "/tmp")
+	h.WaitResponse()
+
+	conv, err := h.server.getConversationForNotify(context.Background(), h.convID)
+	if err != nil || conv.ConversationID != h.convID {
+		t.Fatalf("expected conversation %s, got %+v: %v", h.convID, conv, err)
+	}
+	if _, err := h.server.getConversationForNotify(context.Background(), "missing"); err == nil {
+		t.Error("expected an error for a missing conversation")
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/llm"
+)
+
+// MessageTiming is the latency breakdown for a single message. It is only
+// included when a conversation is fetched or streamed with ?profile=1.
+type MessageTiming struct {
+	// SincePreviousMs is the time between the previous message and this one.
+	SincePreviousMs *int64 `json:"since_previous_ms,omitempty"`
+	// LLMMs is how long the LLM request that produced this message took.
+	LLMMs *int64 `json:"llm_ms,omitempty"`
+	// ToolMs is the combined run time of the tool results in this message.
+	ToolMs *int64 `json:"tool_ms,omitempty"`
+	// Tools is the run time of each tool result, keyed by tool_use_id.
+	Tools map[string]int64 `json:"tools,omitempty"`
+}
+
+// wantsProfile reports whether the request asked for per-message timings.
+func wantsProfile(r *http.Request) bool {
+	profile, _ := strconv.ParseBool(r.URL.Query().Get("profile"))
+	return profile
+}
+
+// withTimings returns a copy of messages annotated with their timings.
+// prev is the creation time of the message before messages[0], or zero if
+// unknown; it is advanced to the last message so a stream can keep calling
+// withTimings as new messages arrive. The input slice is not modified,
+// since stream responses are shared between subscribers.
+func withTimings(messages []APIMessage, prev *time.Time) []APIMessage {
+	annotated := make([]APIMessage, len(messages))
+	for i, msg := range messages {
+		timing := &MessageTiming{}
+		if !prev.IsZero() {
+			timing.SincePreviousMs = durationMs(msg.CreatedAt.Sub(*prev))
+		}
+		*prev = msg.CreatedAt
+
+		if msg.UsageData != nil {
+			var usage llm.Usage
+			if err := json.Unmarshal([]byte(*msg.UsageData), &usage); err == nil && usage.StartTime != nil && usage.EndTime != nil {
+				timing.LLMMs = durationMs(usage.EndTime.Sub(*usage.StartTime))
+			}
+		}
+
+		if msg.LlmData != nil {
+			var llmMsg llm.Message
+			if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err == nil {
+				var total int64
+				for _, c := range llmMsg.Content {
+					if c.Type != llm.ContentTypeToolResult || c.ToolUseStartTime == nil || c.ToolUseEndTime == nil {
+						continue
+					}
+					if timing.Tools == nil {
+						timing.Tools = make(map[string]int64)
+					}
+					ms := *durationMs(c.ToolUseEndTime.Sub(*c.ToolUseStartTime))
+					timing.Tools[c.ToolUseID] = ms
+					total += ms
+				}
+				if timing.Tools != nil {
+					timing.ToolMs = &total
+				}
+			}
+		}
+
+		msg.Timing = timing
+		annotated[i] = msg
+	}
+	return annotated
+}
+
+// serverTimingHeader summarizes annotated messages as a Server-Timing header
+// value, so browser devtools show where the conversation spent its time.
+func serverTimingHeader(messages []APIMessage) string {
+	var llmMs, toolMs int64
+	for _, msg := range messages {
+		if msg.Timing == nil {
+			continue
+		}
+		if msg.Timing.LLMMs != nil {
+			llmMs += *msg.Timing.LLMMs
+		}
+		if msg.Timing.ToolMs != nil {
+			toolMs += *msg.Timing.ToolMs
+		}
+	}
+	metrics := []string{
+		fmt.Sprintf(`llm;desc="LLM requests";dur=%d`, llmMs),
+		fmt.Sprintf(`tool;desc="Tool calls";dur=%d`, toolMs),
+	}
+	if len(messages) > 0 {
+		wall := messages[len(messages)-1].CreatedAt.Sub(messages[0].CreatedAt)
+		metrics = append(metrics, fmt.Sprintf(`wall;desc="First to last message";dur=%d`, wall.Milliseconds()))
+	}
+	return strings.Join(metrics, ", ")
+}
+
+func durationMs(d time.Duration) *int64 {
+	ms := d.Milliseconds()
+	return &ms
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/llm"
+)
+
+func TestWithTimings(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ptr := func(tm time.Time) *time.Time { return &tm }
+	str := func(v any) *string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := string(data)
+		return &s
+	}
+
+	messages := []APIMessage{
+		{SequenceID: 1, CreatedAt: base},
+		{
+			SequenceID: 2,
+			CreatedAt:  base.Add(3 * time.Second),
+			UsageData:  str(llm.Usage{StartTime: ptr(base.Add(500 * time.Millisecond)), EndTime: ptr(base.Add(2500 * time.Millisecond))}),
+		},
+		{
+			SequenceID: 3,
+			CreatedAt:  base.Add(5 * time.Second),
+			LlmData: str(llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{
+				{Type: llm.ContentTypeToolResult, ToolUseID: "a", ToolUseStartTime: ptr(base.Add(3 * time.Second)), ToolUseEndTime: ptr(base.Add(4 * time.Second))},
+				{Type: llm.ContentTypeToolResult, ToolUseID: "b", ToolUseStartTime: ptr(base.Add(4 * time.Second)), ToolUseEndTime: ptr(base.Add(4250 * time.Millisecond))},
+			}}),
+		},
+	}
+
+	var prev time.Time
+	annotated := withTimings(messages, &prev)
+
+	if messages[0].Timing != nil {
+		t.Error("withTimings modified its input")
+	}
+	if !prev.Equal(base.Add(5 * time.Second)) {
+		t.Errorf("prev = %v, want the last message's time", prev)
+	}
+	if annotated[0].Timing.SincePreviousMs != nil {
+		t.Errorf("first message should have no since_previous_ms, got %d", *annotated[0].Timing.SincePreviousMs)
+	}
+	if got := annotated[1].Timing; got.SincePreviousMs == nil || *got.SincePreviousMs != 3000 || got.LLMMs == nil || *got.LLMMs != 2000 {
+		t.Errorf("unexpected agent message timing: %+v", got)
+	}
+	if got := annotated[2].Timing; got.ToolMs == nil || *got.ToolMs != 1250 || got.Tools["a"] != 1000 || got.Tools["b"] != 250 {
+		t.Errorf("unexpected tool message timing: %+v", got)
+	}
+
+	header := serverTimingHeader(annotated)
+	for _, want := range []string{"llm;", "dur=2000", "tool;", "dur=1250", "wall;", "dur=5000"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("Server-Timing %q missing %q", header, want)
+		}
+	}
+
+	// Continuing a stream measures from the previous batch.
+	next := withTimings([]APIMessage{{SequenceID: 4, CreatedAt: base.Add(6 * time.Second)}}, &prev)
+	if got := next[0].Timing.SincePreviousMs; got == nil || *got != 1000 {
+		t.Errorf("expected since_previous_ms 1000 across batches, got %v", got)
+	}
+}
+
+func TestHandleGetConversationProfile(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("bash: echo hi", "/tmp")
+	h.WaitToolResult()
+	h.WaitResponse()
+
+	get := func(query string) (*httptest.ResponseRecorder, StreamResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/conversation/"+h.convID+query, nil)
+		w := httptest.NewRecorder()
+		h.server.handleGetConversation(w, req, h.convID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp StreamResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return w, resp
+	}
+
+	w, resp := get("")
+	if w.Header().Get("Server-Timing") != "" {
+		t.Error("expected no Server-Timing header without profile")
+	}
+	for _, msg := range resp.Messages {
+		if msg.Timing != nil {
+			t.Fatalf("expected no timing without profile, got one on message %d", msg.SequenceID)
+		}
+	}
+
+	w, resp = get("?profile=1")
+	if !strings.Contains(w.Header().Get("Server-Timing"), "tool;") {
+		t.Errorf("expected Server-Timing header, got %q", w.Header().Get("Server-Timing"))
+	}
+	var sawToolTiming bool
+	for i, msg := range resp.Messages {
+		if msg.Timing == nil {
+			t.Fatalf("message %d has no timing", msg.SequenceID)
+		}
+		if i > 0 && msg.Timing.SincePreviousMs == nil {
+			t.Errorf("message %d has no since_previous_ms", msg.SequenceID)
+		}
+		if len(msg.Timing.Tools) > 0 {
+			sawToolTiming = true
+		}
+	}
+	if !sawToolTiming {
+		t.Error("expected a message with tool timings")
+	}
+}
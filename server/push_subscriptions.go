@@ -87,12 +87,12 @@ func (s *Server) handlePushSubscribe(w http.ResponseWriter, r *http.Request) {
 
 	id := "push-" + uuid.NewString()
 	if err := s.db.CreatePushSubscription(r.Context(), id, req.Endpoint, req.P256DH, req.Auth, req.UserAgent); err != nil {
-		s.logger.Error("Failed to create push subscription", "error", err)
+		s.logger.ErrorContext(r.Context(), "Failed to create push subscription", "error", err)
 		http.Error(w, "Failed to store subscription", http.StatusInternalServerError)
 		return
 	}
 
-	s.logger.Info("Push subscription registered", "id", id, "endpoint", truncateURL(req.Endpoint, 60))
+	s.logger.InfoContext(r.Context(), "Push subscription registered", "id", id, "endpoint", truncateURL(req.Endpoint, 60))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"id": id})
@@ -112,7 +112,7 @@ func (s *Server) handlePushUnsubscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := s.db.DeletePushSubscriptionByEndpoint(r.Context(), req.Endpoint); err != nil {
-		s.logger.Error("Failed to delete push subscription", "error", err)
+		s.logger.ErrorContext(r.Context(), "Failed to delete push subscription", "error", err)
 		http.Error(w, "Failed to delete subscription", http.StatusInternalServerError)
 		return
 	}
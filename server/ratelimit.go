@@ -0,0 +1,156 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: a client may make Rate requests per second on
+// average, in bursts of up to Burst. A zero Rate means no limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig configures RateLimitMiddleware.
+type RateLimitConfig struct {
+	// Write limits requests that can change state or start work, such as
+	// creating conversations and sending messages.
+	Write RateLimit
+	// Read limits GET, HEAD and OPTIONS requests, including SSE streams.
+	// It is kept separate so reads never use up the stricter write budget.
+	Read RateLimit
+	// KeyHeader identifies the client when set and present on the request
+	// (e.g. the header enforced by RequireHeaderMiddleware); otherwise
+	// clients are told apart by remote IP.
+	KeyHeader string
+}
+
+// RateLimitMiddleware limits API requests per client, answering 429 with a
+// Retry-After header once a client has used up its bucket.
+func RateLimitMiddleware(cfg RateLimitConfig) func(http.Handler) http.Handler {
+	return newRateLimitMiddleware(cfg, time.Now)
+}
+
+func newRateLimitMiddleware(cfg RateLimitConfig, now func() time.Time) func(http.Handler) http.Handler {
+	write := newRateLimiter(cfg.Write, now)
+	read := newRateLimiter(cfg.Read, now)
+	return func(next http.Handler) http.Handler {
+		if write == nil && read == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Only limit API routes
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limiter := write
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				limiter = read
+			}
+			if limiter != nil {
+				if wait := limiter.take(rateLimitKey(r, cfg.KeyHeader)); wait > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+					http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies the client r comes from.
+func rateLimitKey(r *http.Request, keyHeader string) string {
+	if keyHeader != "" {
+		if v := r.Header.Get(keyHeader); v != "" {
+			return "header:" + v
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimiterSweepInterval is how often buckets that have refilled completely,
+// and so are no different from new ones, are dropped.
+const rateLimiterSweepInterval = time.Minute
+
+// rateLimiter keeps a token bucket per client key.
+type rateLimiter struct {
+	limit RateLimit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter returns a limiter for limit, or nil if limit has no rate.
+func newRateLimiter(limit RateLimit, now func() time.Time) *rateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = max(1, int(math.Ceil(limit.Rate)))
+	}
+	return &rateLimiter{
+		limit:     limit,
+		now:       now,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: now(),
+	}
+}
+
+// take spends one of key's tokens. If none is left it returns how long until
+// one will be, and spends nothing.
+func (l *rateLimiter) take(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= rateLimiterSweepInterval {
+		for k, b := range l.buckets {
+			if l.refill(b, now) >= float64(l.limit.Burst) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// refill returns how many tokens b holds at now.
+func (l *rateLimiter) refill(b *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed <= 0 {
+		return b.tokens
+	}
+	return math.Min(float64(l.limit.Burst), b.tokens+elapsed*l.limit.Rate)
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for rate limiter tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newRateLimitTestHandler(cfg RateLimitConfig, clock *fakeClock) http.Handler {
+	return newRateLimitMiddleware(cfg, clock.Now)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func doRateLimited(handler http.Handler, method, path, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitMiddleware_ExhaustsBucket(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	handler := newRateLimitTestHandler(RateLimitConfig{Write: RateLimit{Rate: 0.5, Burst: 3}}, clock)
+
+	for i := range 3 {
+		w := doRateLimited(handler, "POST", "/api/conversations/new", "10.0.0.1:1234", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, w.Code)
+		}
+	}
+
+	w := doRateLimited(handler, "POST", "/api/conversations/new", "10.0.0.1:5678", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 once the bucket is empty, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2 (one token at 0.5/s)", got)
+	}
+
+	// Other clients have their own buckets
+	if w := doRateLimited(handler, "POST", "/api/conversations/new", "10.0.0.2:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("expected another IP to be allowed, got %d", w.Code)
+	}
+
+	// Reads and non-API routes aren't subject to the write limit
+	if w := doRateLimited(handler, "GET", "/api/conversations", "10.0.0.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("expected a GET to be allowed, got %d", w.Code)
+	}
+	if w := doRateLimited(handler, "POST", "/login", "10.0.0.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("expected a non-API route to be allowed, got %d", w.Code)
+	}
+}
+
+func TestRateLimitMiddleware_Refills(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	handler := newRateLimitTestHandler(RateLimitConfig{
+		Write:     RateLimit{Rate: 1, Burst: 2},
+		Read:      RateLimit{Rate: 10, Burst: 1},
+		KeyHeader: "X-Exedev-Userid",
+	}, clock)
+	alice := http.Header{"X-Exedev-Userid": {"alice"}}
+
+	// Requests with the key header share a bucket whatever their address
+	doRateLimited(handler, "POST", "/api/conversation/c1/chat", "10.0.0.1:1", alice)
+	doRateLimited(handler, "POST", "/api/conversation/c1/chat", "10.0.0.2:1", alice)
+	if w := doRateLimited(handler, "POST", "/api/conversation/c1/chat", "10.0.0.3:1", alice); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if w := doRateLimited(handler, "POST", "/api/conversation/c1/chat", "10.0.0.1:1", alice); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 before a token has refilled, got %d", w.Code)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if w := doRateLimited(handler, "POST", "/api/conversation/c1/chat", "10.0.0.1:1", alice); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 after a token refilled, got %d", w.Code)
+	}
+
+	// Reads have a separate bucket
+	if w := doRateLimited(handler, "GET", "/api/conversation/c1/stream", "10.0.0.1:1", alice); w.Code != http.StatusOK {
+		t.Fatalf("expected the first read to be allowed, got %d", w.Code)
+	}
+	if w := doRateLimited(handler, "GET", "/api/conversation/c1/stream", "10.0.0.1:1", alice); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 for the second read, got %d", w.Code)
+	}
+	clock.Advance(100 * time.Millisecond)
+	if w := doRateLimited(handler, "GET", "/api/conversation/c1/stream", "10.0.0.1:1", alice); w.Code != http.StatusOK {
+		t.Fatalf("expected a read after the refill to be allowed, got %d", w.Code)
+	}
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -12,6 +14,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -43,6 +46,10 @@ type APIMessage struct {
 	CreatedAt      time.Time `json:"created_at"`
 	DisplayData    *string   `json:"display_data,omitempty"`
 	EndOfTurn      *bool     `json:"end_of_turn,omitempty"`
+	// Timing is only set in profiling mode (?profile=1).
+	Timing *MessageTiming `json:"timing,omitempty"`
+	// ToolCalls pairs each tool result in this message with its call.
+	ToolCalls []APIToolCall `json:"tool_calls,omitempty"`
 }
 
 // ConversationState represents the current state of a conversation.
@@ -51,24 +58,29 @@ type ConversationState struct {
 	ConversationID string `json:"conversation_id"`
 	Working        bool   `json:"working"`
 	Model          string `json:"model,omitempty"`
+	// WorkingSince is when the current turn started; nil when not working.
+	WorkingSince *time.Time `json:"working_since,omitempty"`
 }
 
 // ConversationWithState combines a conversation with its working state.
 type ConversationWithState struct {
 	generated.Conversation
 	Working         bool             `json:"working"`
+	WorkingSince    *time.Time       `json:"working_since,omitempty"`
 	GitRepoRoot     string           `json:"git_repo_root,omitempty"`
 	GitWorktreeRoot string           `json:"git_worktree_root,omitempty"`
 	GitCommit       string           `json:"git_commit,omitempty"`
 	GitSubject      string           `json:"git_subject,omitempty"`
 	SubagentCount   int64            `json:"subagent_count"`
 	PRInfo          *gitstate.PRInfo `json:"pr_info,omitempty"`
+	Tags            []string         `json:"tags,omitempty"`
 }
 
 // StreamResponse represents the response format for conversation streaming
 type StreamResponse struct {
 	Messages          []APIMessage           `json:"messages"`
-	Conversation      generated.Conversation `json:"conversation"`
+	// Conversation is omitted only if it couldn't be read when publishing a message.
+	Conversation      generated.Conversation `json:"conversation,omitzero"`
 	ConversationState *ConversationState     `json:"conversation_state,omitempty"`
 	ContextWindowSize uint64                 `json:"context_window_size,omitempty"`
 	// ConversationListUpdate is set when another conversation in the list changed
@@ -81,6 +93,19 @@ type StreamResponse struct {
 	ToolProgress *llm.ToolProgress `json:"tool_progress,omitempty"`
 	// StreamDelta is set when the LLM streams partial text content.
 	StreamDelta *llm.StreamDelta `json:"stream_delta,omitempty"`
+	// Reconnect is set on the final event of a stream the server is closing;
+	// the client should reconnect with its last sequence ID.
+	Reconnect bool `json:"reconnect,omitempty"`
+	// Type is "shutdown" on the final event of a stream closed because the
+	// server is shutting down.
+	Type string `json:"type,omitempty"`
+	// TruncatedAfter is set when the messages after this sequence ID were
+	// deleted; Messages then holds the whole remaining history.
+	TruncatedAfter int64 `json:"truncated_after,omitempty"`
+	// CostUSD is what the conversation has cost so far, with each message
+	// priced at the model that produced it; it is unset when no message had
+	// a known price.
+	CostUSD *float64 `json:"cost_usd,omitempty"`
 }
 
 // LLMProvider is an interface for getting LLM services
@@ -88,12 +113,22 @@ type LLMProvider interface {
 	GetService(modelID string) (llm.Service, error)
 	GetAvailableModels() []string
 	HasModel(modelID string) bool
+	ResolveModel(modelID string) string
 	GetModelInfo(modelID string) *models.ModelInfo
 	RefreshCustomModels() error
 }
 
 // NewLLMServiceManager creates a new LLM service manager from config
 func NewLLMServiceManager(cfg *LLMConfig) LLMProvider {
+	// The "default" alias follows DefaultModel unless configured explicitly
+	aliases := make(map[string]string, len(cfg.ModelAliases)+1)
+	for alias, target := range cfg.ModelAliases {
+		aliases[alias] = target
+	}
+	if _, ok := aliases[models.DefaultAlias]; !ok && cfg.DefaultModel != "" {
+		aliases[models.DefaultAlias] = cfg.DefaultModel
+	}
+
 	// Convert LLMConfig to models.Config
 	modelConfig := &models.Config{
 		AnthropicAPIKey: cfg.AnthropicAPIKey,
@@ -103,6 +138,7 @@ func NewLLMServiceManager(cfg *LLMConfig) LLMProvider {
 		Gateway:         cfg.Gateway,
 		Logger:          cfg.Logger,
 		DB:              cfg.DB,
+		Aliases:         aliases,
 	}
 
 	manager, err := models.NewManager(modelConfig)
@@ -117,7 +153,8 @@ func NewLLMServiceManager(cfg *LLMConfig) LLMProvider {
 // toAPIMessages converts database messages to API messages.
 // Image data is stripped from llm_data and replaced with URLs to
 // /api/message/{id}/image endpoints to avoid sending large base64
-// blobs to clients.
+// blobs to clients. Tool results are paired with calls in the same batch;
+// see Server.pairWithPrevious for results whose call precedes it.
 func toAPIMessages(messages []generated.Message) []APIMessage {
 	apiMessages := make([]APIMessage, len(messages))
 	for i, msg := range messages {
@@ -143,6 +180,7 @@ func toAPIMessages(messages []generated.Message) []APIMessage {
 		}
 		apiMessages[i] = apiMsg
 	}
+	pairToolCalls(messages, apiMessages, make(map[string]toolUseRef))
 	return apiMessages
 }
 
@@ -201,6 +239,30 @@ func isAgentEndOfTurn(msg *generated.Message) bool {
 	return endOfTurn
 }
 
+// conversationCost returns what a conversation has cost so far, or nil if
+// none of its messages had a known price.
+func conversationCost(conv generated.Conversation) *float64 {
+	if conv.CostUsdTotal == 0 {
+		return nil
+	}
+	cost := conv.CostUsdTotal
+	return &cost
+}
+
+// estimateCost prices usage at the conversation's current model, which made
+// the call being recorded, or returns 0 if its price isn't known.
+func (s *Server) estimateCost(ctx context.Context, conversationID string, usage llm.Usage) float64 {
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil || conv.Model == nil {
+		return 0
+	}
+	info := s.llmManager.GetModelInfo(*conv.Model)
+	if info == nil || info.Pricing == nil {
+		return 0
+	}
+	return info.Pricing.Cost(int64(usage.InputTokens+usage.CacheCreationInputTokens), int64(usage.OutputTokens), int64(usage.CacheReadInputTokens))
+}
+
 // calculateContextWindowSizeFromMsg calculates context window usage from a single message.
 // Returns 0 if the message has no usage data (e.g., user messages), in which case
 // the client should keep its previous context window value.
@@ -242,11 +304,60 @@ type Server struct {
 	versionChecker      *VersionChecker
 	notifDispatcher     *notifications.Dispatcher
 	shutdownCh          chan struct{} // Signals background routines to stop
+	streamsCtx          context.Context    // cancelled on shutdown to end open conversation streams
+	closeStreams        context.CancelFunc // cancels streamsCtx
 	listenPort          int           // TCP port the server is listening on
 	onAgentDone         func(conversationID string) // optional callback when agent finishes a turn
 	alwaysOnSkills      []string                    // skill names pre-activated in system prompt
+	slugPrompt          string                      // custom slug prompt template (empty uses the default)
+	noHeuristicSlugs    bool                        // leave conversations untitled rather than derive slugs from messages
+	slugBackfill        slugBackfill                // bulk slug regeneration progress
+	maxRepeatedToolErrs int                         // loop breaker threshold (0 uses the loop default)
+	maxStreamDuration   time.Duration               // max conversation stream lifetime (0 = unlimited)
+	heartbeatInterval   time.Duration               // idle time before a conversation stream sends a heartbeat
+	maxSubscribers      int                         // streams allowed per conversation (0 = unlimited)
+	idleTimeout         time.Duration               // unwatched managers idle this long are evicted by Cleanup
+	basePath            string                      // URL prefix when hosted under a sub-path; "" for the root
+	corsOrigins         []string                    // origins allowed to make cross-origin requests; nil disables CORS
+	authTokens          []string                    // bearer tokens accepted on TCP API requests; nil disables the check
+	writeRateLimit      RateLimit                   // per-client limit on TCP API writes (zero Rate = unlimited)
+	readRateLimit       RateLimit                   // per-client limit on TCP API reads (zero Rate = unlimited)
+	staticIndex         bool                        // serve index.html unmodified; the client fetches /api/config
+	readExtensions      map[string]bool             // lowercase file extensions /api/read serves
+	uploadTypes         map[string]bool             // sniffed media types /api/upload accepts
+	thumbnailCacheDir   string                      // where /api/read keeps generated thumbnails; "" doesn't keep them
+	newConvTemplate     ConversationTemplate        // defaults for new conversations
+	keepSlugOnEdit      bool                        // don't regenerate slugs when the first message is edited
+	hydrationSem        chan struct{}               // bounds concurrent conversation manager hydrations
+	metrics             *requestMetrics             // request latencies for /metrics; nil when metrics are off
 }
 
+// DefaultConversationIdleTimeout is how long an unwatched conversation
+// manager may be idle before Cleanup evicts it.
+const DefaultConversationIdleTimeout = 30 * time.Minute
+
+// DefaultMaxConcurrentHydrations is how many conversation managers may load
+// their state from the database at once.
+const DefaultMaxConcurrentHydrations = 8
+
+// DefaultMaxStreamSubscribers is how many clients may stream one
+// conversation at once.
+const DefaultMaxStreamSubscribers = 1000
+
+// DefaultStreamHeartbeatInterval is how long a conversation stream may go
+// without an event before it sends a heartbeat. It is kept under the ~60s
+// after which proxies commonly drop idle connections.
+const DefaultStreamHeartbeatInterval = 25 * time.Second
+
+// DefaultReadExtensions are the file types /api/read serves unless
+// configured otherwise: the screenshot, upload and screencast formats. Saved
+// CPU profiles (.json in the console logs directory) are served regardless.
+var DefaultReadExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg", ".mp4"}
+
+// DefaultUploadTypes are the media types /api/upload accepts unless
+// configured otherwise, as sniffed by http.DetectContentType.
+var DefaultUploadTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
 // NewServer creates a new server instance
 func NewServer(database *db.DB, llmManager LLMProvider, toolSetConfig claudetool.ToolSetConfig, logger *slog.Logger, predictableOnly bool, terminalURL, defaultModel, requireHeader string, links []Link) *Server {
 	s := &Server{
@@ -254,7 +365,7 @@ func NewServer(database *db.DB, llmManager LLMProvider, toolSetConfig claudetool
 		llmManager:          llmManager,
 		toolSetConfig:       toolSetConfig,
 		activeConversations: make(map[string]*ConversationManager),
-		logger:              logger,
+		logger:              withRequestIDs(logger),
 		predictableOnly:     predictableOnly,
 		terminalURL:         terminalURL,
 		defaultModel:        defaultModel,
@@ -263,7 +374,14 @@ func NewServer(database *db.DB, llmManager LLMProvider, toolSetConfig claudetool
 		versionChecker:      NewVersionChecker(),
 		notifDispatcher:     notifications.NewDispatcher(logger),
 		shutdownCh:          make(chan struct{}),
+		idleTimeout:         DefaultConversationIdleTimeout,
+		heartbeatInterval:   DefaultStreamHeartbeatInterval,
+		maxSubscribers:      DefaultMaxStreamSubscribers,
 	}
+	s.streamsCtx, s.closeStreams = context.WithCancel(context.Background())
+	s.SetReadExtensions(nil)
+	s.SetUploadTypes(nil)
+	s.SetMaxConcurrentHydrations(0)
 
 	// Set up subagent support
 	s.toolSetConfig.SubagentRunner = NewSubagentRunner(s)
@@ -285,6 +403,216 @@ func (s *Server) SetAlwaysOnSkills(names []string) {
 	s.alwaysOnSkills = names
 }
 
+// SetMaxRepeatedToolErrors configures how many times an identical tool call
+// may fail in a row within a turn before the turn is halted.
+// Zero uses the loop default; a negative value disables the check.
+func (s *Server) SetMaxRepeatedToolErrors(n int) {
+	s.maxRepeatedToolErrs = n
+}
+
+// SetMaxStreamDuration bounds how long a conversation stream stays open.
+// When it elapses the server sends a reconnect event and closes the stream,
+// and the client resumes from its last sequence ID. Zero means no limit.
+func (s *Server) SetMaxStreamDuration(d time.Duration) {
+	s.maxStreamDuration = d
+}
+
+// SetConversationIdleTimeout configures how long a conversation manager may
+// be idle before Cleanup evicts it. Zero or negative uses the default.
+func (s *Server) SetConversationIdleTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultConversationIdleTimeout
+	}
+	s.idleTimeout = d
+}
+
+// SetMaxConcurrentHydrations bounds how many conversation managers may be
+// hydrated from the database at once, so that the burst of reconnects after a
+// restart queues up instead of overwhelming the database. Zero or negative
+// uses the default.
+func (s *Server) SetMaxConcurrentHydrations(n int) {
+	if n <= 0 {
+		n = DefaultMaxConcurrentHydrations
+	}
+	s.hydrationSem = make(chan struct{}, n)
+}
+
+// SetMetricsEnabled serves Prometheus metrics at /metrics and starts timing
+// requests for them. It must be called before the server starts.
+func (s *Server) SetMetricsEnabled(enabled bool) {
+	if enabled {
+		s.metrics = newRequestMetrics()
+	} else {
+		s.metrics = nil
+	}
+}
+
+// SetBasePath serves the app under a URL prefix such as "/shelley", for
+// hosting behind a reverse proxy. The prefix is injected into the page so
+// the client builds URLs under it. An empty path or "/" serves at the root.
+func (s *Server) SetBasePath(p string) {
+	p = strings.TrimRight(p, "/")
+	if p != "" && !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	s.basePath = p
+}
+
+// SetMaxStreamSubscribers caps how many clients may stream one conversation
+// at once; more get a 503. Zero means no limit.
+func (s *Server) SetMaxStreamSubscribers(n int) {
+	s.maxSubscribers = n
+}
+
+// SetStaticIndex serves index.html as-is, without the injected init data and
+// favicon, so it can be hosted by a CDN. The client then fetches its init
+// data from /api/config.
+func (s *Server) SetStaticIndex(enabled bool) {
+	s.staticIndex = enabled
+}
+
+// SetCORSOrigins allows browser clients on these origins (e.g.
+// "https://app.example.com") to call the server over TCP with credentials.
+// Empty entries are ignored.
+func (s *Server) SetCORSOrigins(origins []string) {
+	s.corsOrigins = nil
+	for _, origin := range origins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			s.corsOrigins = append(s.corsOrigins, origin)
+		}
+	}
+}
+
+// SetAuthTokens requires TCP API requests to carry one of these tokens as
+// "Authorization: Bearer <token>". Empty entries are ignored; with no tokens
+// no bearer token is required. The local Unix socket is never checked.
+func (s *Server) SetAuthTokens(tokens []string) {
+	s.authTokens = nil
+	for _, token := range tokens {
+		if token = strings.TrimSpace(token); token != "" {
+			s.authTokens = append(s.authTokens, token)
+		}
+	}
+}
+
+// SetRateLimits limits how fast each client may make API requests over TCP,
+// keyed by the required header if one is configured and by remote IP
+// otherwise. Writes and reads (GET, including streams) have separate buckets.
+// A zero Rate leaves that kind of request unlimited.
+func (s *Server) SetRateLimits(write, read RateLimit) {
+	s.writeRateLimit = write
+	s.readRateLimit = read
+}
+
+// SetReadExtensions configures which file extensions (e.g. ".png") /api/read
+// serves; other files in its directories get a 403. An empty list uses
+// DefaultReadExtensions.
+func (s *Server) SetReadExtensions(exts []string) {
+	if len(exts) == 0 {
+		exts = DefaultReadExtensions
+	}
+	s.readExtensions = make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		s.readExtensions[ext] = true
+	}
+}
+
+// SetThumbnailCacheDir keeps the thumbnails /api/read?thumb=N generates in
+// dir, so each is only made once per file version. An empty dir makes them
+// on every request.
+func (s *Server) SetThumbnailCacheDir(dir string) {
+	s.thumbnailCacheDir = dir
+}
+
+// ConversationTemplate holds the settings a new conversation starts with
+// when its first request doesn't choose them.
+type ConversationTemplate struct {
+	Model      string   `json:"model,omitempty"`       // model ID or alias
+	Cwd        string   `json:"cwd,omitempty"`         // working directory
+	Tools      []string `json:"tools,omitempty"`       // tool allowlist; empty allows every tool
+	SystemNote string   `json:"system_note,omitempty"` // pinned standing instruction
+}
+
+// apply fills in the settings req leaves unset from the template.
+func (t ConversationTemplate) apply(req *ChatRequest) {
+	if req.Model == "" {
+		req.Model = t.Model
+	}
+	if req.Cwd == "" {
+		req.Cwd = t.Cwd
+	}
+	if len(t.Tools) > 0 {
+		if req.ConversationOptions == nil {
+			req.ConversationOptions = &db.ConversationOptions{}
+		}
+		if req.ConversationOptions.Tools == nil {
+			req.ConversationOptions.Tools = slices.Clone(t.Tools)
+		}
+	}
+	if req.SystemNote == nil && t.SystemNote != "" {
+		note := t.SystemNote
+		req.SystemNote = &note
+	}
+}
+
+// SetConversationTemplate configures the defaults new conversations start
+// with. A request's own model, cwd, tools or system note take precedence.
+func (s *Server) SetConversationTemplate(t ConversationTemplate) {
+	t.Model = strings.TrimSpace(t.Model)
+	t.Cwd = strings.TrimSpace(t.Cwd)
+	t.SystemNote = strings.TrimSpace(t.SystemNote)
+	var tools []string
+	for _, name := range t.Tools {
+		if name = strings.TrimSpace(name); name != "" {
+			tools = append(tools, name)
+		}
+	}
+	t.Tools = tools
+	s.newConvTemplate = t
+}
+
+// SetUploadTypes configures which media types (e.g. "image/png") /api/upload
+// accepts, judged by the file's content rather than its name; other uploads
+// get a 415. An empty list uses DefaultUploadTypes.
+func (s *Server) SetUploadTypes(types []string) {
+	if len(types) == 0 {
+		types = DefaultUploadTypes
+	}
+	s.uploadTypes = make(map[string]bool, len(types))
+	for _, t := range types {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			s.uploadTypes[t] = true
+		}
+	}
+}
+
+// SetRegenerateSlugOnEdit configures whether editing a conversation's first
+// user message regenerates its slug. It is on by default; slugs the user
+// set by renaming the conversation are never regenerated.
+func (s *Server) SetRegenerateSlugOnEdit(enabled bool) {
+	s.keepSlugOnEdit = !enabled
+}
+
+// SetHeuristicSlugs configures whether a conversation's slug is derived from
+// its first message when no model can generate one. It is on by default, so
+// conversations are titled even without a working LLM.
+func (s *Server) SetHeuristicSlugs(enabled bool) {
+	s.noHeuristicSlugs = !enabled
+}
+
+// SetSlugPrompt configures the prompt template used for slug generation.
+// An empty template uses slug.DefaultPromptTemplate.
+func (s *Server) SetSlugPrompt(promptTemplate string) {
+	s.slugPrompt = promptTemplate
+}
+
 // SetSlackAPI enables the Slack tool for all conversations.
 func (s *Server) SetSlackAPI(api claudetool.SlackAPI) {
 	s.toolSetConfig.SlackAPI = api
@@ -305,6 +633,8 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/api/conversations/new", http.HandlerFunc(s.handleNewConversation))            // Small response
 	mux.Handle("/api/conversations/distill", http.HandlerFunc(s.handleDistillConversation))    // Small response
 	mux.Handle("/api/conversations/distill-replace", http.HandlerFunc(s.handleDistillReplace)) // Small response
+	mux.Handle("/api/conversations/bulk", http.HandlerFunc(s.handleBulkConversationAction))    // Small response
+	mux.Handle("/api/conversations/import", http.HandlerFunc(s.handleImportConversation))      // Small response
 	mux.Handle("/api/conversation/", http.StripPrefix("/api/conversation", s.conversationMux()))
 	mux.Handle("/api/conversation-by-slug/", gzipHandler(http.HandlerFunc(s.handleConversationBySlug)))
 	mux.Handle("/api/validate-cwd", http.HandlerFunc(s.handleValidateCwd)) // Small response
@@ -319,6 +649,7 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/upload", s.handleUpload)
 	mux.HandleFunc("/api/upload-to-cwd", s.handleUploadToCwd)                                                                  // Binary uploads
 	mux.HandleFunc("/api/read", s.handleRead)                                                                      // Serves images from disk
+	mux.HandleFunc("DELETE /api/read", s.handleDeleteAsset)                                                        // Deletes screenshots and uploads
 	mux.HandleFunc("GET /api/message/{message_id}/image/{content_index}/{toolresult_index}", s.handleMessageImage) // Serves images from DB
 	mux.Handle("/api/write-file", http.HandlerFunc(s.handleWriteFile))                                             // Small response
 	mux.Handle("/api/user-agents-md", http.HandlerFunc(s.handleUserAgentsMd))                                      // Small response
@@ -329,6 +660,10 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/api/custom-models/", http.HandlerFunc(s.handleCustomModel))
 	mux.Handle("/api/custom-models-test", http.HandlerFunc(s.handleTestModel))
 
+	// Folders API
+	mux.Handle("/api/folders", http.HandlerFunc(s.handleFolders))
+	mux.Handle("/api/folders/", http.HandlerFunc(s.handleFolder))
+
 	// Notification channels API
 	mux.Handle("/api/notification-channels", http.HandlerFunc(s.handleNotificationChannels))
 	mux.Handle("/api/notification-channels/", http.HandlerFunc(s.handleNotificationChannel))
@@ -339,10 +674,22 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("POST /api/push/subscribe", http.HandlerFunc(s.handlePushSubscribe))
 	mux.Handle("POST /api/push/unsubscribe", http.HandlerFunc(s.handlePushUnsubscribe))
 
+	// Admin: regenerate slugs for untitled conversations
+	mux.Handle("POST /api/admin/regenerate-slugs", http.HandlerFunc(s.handleSlugBackfill))
+
+	// Admin: inspect and evict in-memory conversation managers
+	mux.Handle("GET /api/admin/active-conversations", http.HandlerFunc(s.handleActiveConversations))
+	mux.Handle("POST /api/admin/active-conversations/{id}/evict", http.HandlerFunc(s.handleEvictConversation))
+
 	// Models API (dynamic list refresh)
 	mux.Handle("/api/models", http.HandlerFunc(s.handleModels))
+	mux.Handle("/api/config", http.HandlerFunc(s.handleConfig))
 	mux.Handle("/api/host-icon", http.HandlerFunc(s.handleHostIcon))
 
+	// Liveness and readiness probes
+	mux.Handle("GET /healthz", http.HandlerFunc(s.handleHealthz))
+	mux.Handle("GET /readyz", http.HandlerFunc(s.handleReadyz))
+
 	// Version endpoints
 	mux.Handle("GET /version", http.HandlerFunc(s.handleVersion))
 	mux.Handle("GET /version-check", http.HandlerFunc(s.handleVersionCheck))
@@ -362,6 +709,10 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("GET /debug/llm_requests/{id}/request_full", http.HandlerFunc(s.handleDebugLLMRequestBodyFull))
 	mux.Handle("GET /debug/llm_requests/{id}/response", http.HandlerFunc(s.handleDebugLLMResponseBody))
 
+	if s.metrics != nil {
+		mux.Handle("GET /metrics", http.HandlerFunc(s.handleMetrics))
+	}
+
 	// pprof endpoints
 	mux.Handle("GET /debug/pprof/", http.HandlerFunc(pprof.Index))
 	mux.Handle("GET /debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
@@ -426,7 +777,9 @@ func (s *Server) handleValidateCwd(w http.ResponseWriter, r *http.Request) {
 type DirectoryEntry struct {
 	Name           string `json:"name"`
 	IsDir          bool   `json:"is_dir"`
+	Size           int64  `json:"size,omitempty"` // files only
 	GitHeadSubject string `json:"git_head_subject,omitempty"`
+	modTime        time.Time
 }
 
 // ListDirectoryResponse is the response from the list-directory endpoint
@@ -438,14 +791,33 @@ type ListDirectoryResponse struct {
 	GitWorktreeRoot string           `json:"git_worktree_root,omitempty"`
 }
 
-// handleListDirectory lists the contents of a directory for the directory picker
+// handleListDirectory lists the contents of a directory for the directory picker.
+// Query parameters:
+//   - include_files=1: list files, with their size, as well as directories
+//   - sort=name|mtime: order within directories and files (default name;
+//     mtime is newest first). Directories always come before files.
+//   - show_hidden=1: list hidden files; hidden directories are always listed,
+//     after the others
 func (s *Server) handleListDirectory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	path := r.URL.Query().Get("path")
+	query := r.URL.Query()
+	includeFiles := query.Get("include_files") == "1"
+	showHidden := query.Get("show_hidden") == "1"
+	sortBy := query.Get("sort")
+	switch sortBy {
+	case "":
+		sortBy = "name"
+	case "name", "mtime":
+	default:
+		http.Error(w, "sort must be name or mtime", http.StatusBadRequest)
+		return
+	}
+
+	path := query.Get("path")
 	if path == "" {
 		// Default to home directory or root
 		homeDir, err := os.UserHomeDir()
@@ -503,35 +875,54 @@ func (s *Server) handleListDirectory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build response with only directories (for directory picker)
+	// Build response with directories, and files if asked for
 	var entries []DirectoryEntry
 	for _, entry := range dirEntries {
-		// Only include directories
-		if entry.IsDir() {
-			dirEntry := DirectoryEntry{
-				Name:  entry.Name(),
-				IsDir: true,
+		if !entry.IsDir() && (!includeFiles || (!showHidden && strings.HasPrefix(entry.Name(), "."))) {
+			continue
+		}
+		dirEntry := DirectoryEntry{
+			Name:  entry.Name(),
+			IsDir: entry.IsDir(),
+		}
+		if !entry.IsDir() || sortBy == "mtime" {
+			info, err := entry.Info()
+			if err != nil {
+				continue // Removed since the directory was read
+			}
+			if !entry.IsDir() {
+				dirEntry.Size = info.Size()
 			}
+			dirEntry.modTime = info.ModTime()
+		}
 
-			// Check if this is a git repo root and get HEAD commit subject
+		// Check if this is a git repo root and get HEAD commit subject
+		if entry.IsDir() {
 			entryPath := filepath.Join(path, entry.Name())
 			if isGitRepo(entryPath) {
 				if subject := getGitHeadSubject(entryPath); subject != "" {
 					dirEntry.GitHeadSubject = subject
 				}
 			}
-
-			entries = append(entries, dirEntry)
 		}
+
+		entries = append(entries, dirEntry)
 	}
 
-	// Sort entries: non-hidden first, then hidden (.*), alphabetically within each group
+	// Sort entries: directories before files, non-hidden before hidden (.*),
+	// then by name or newest first within each group
 	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
 		iHidden := strings.HasPrefix(entries[i].Name, ".")
 		jHidden := strings.HasPrefix(entries[j].Name, ".")
 		if iHidden != jHidden {
 			return !iHidden // non-hidden comes first
 		}
+		if sortBy == "mtime" && !entries[i].modTime.Equal(entries[j].modTime) {
+			return entries[i].modTime.After(entries[j].modTime)
+		}
 		return entries[i].Name < entries[j].Name
 	})
 
@@ -714,27 +1105,31 @@ func (s *Server) handleCreateDirectory(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getOrCreateConversationManager(ctx context.Context, conversationID string) (*ConversationManager, error) {
 	manager, err, _ := s.conversationGroup.Do(conversationID, func() (*ConversationManager, error) {
 		s.mu.Lock()
-		defer s.mu.Unlock()
-		if manager, exists := s.activeConversations[conversationID]; exists {
+		manager, exists := s.activeConversations[conversationID]
+		s.mu.Unlock()
+		if exists {
 			manager.Touch()
 			return manager, nil
 		}
 
 		recordMessage := func(ctx context.Context, message llm.Message, usage llm.Usage) error {
-			return s.recordMessage(ctx, conversationID, message, usage)
+			return s.recordMessage(ctx, conversationID, message, usage, messageUserData(ctx))
 		}
 
 		onStateChange := func(state ConversationState) {
 			s.publishConversationState(state)
 		}
 
-		manager := NewConversationManager(conversationID, s.db, s.logger, s.toolSetConfig, recordMessage, onStateChange)
+		manager = NewConversationManager(conversationID, s.db, s.logger, s.toolSetConfig, recordMessage, onStateChange)
 		manager.alwaysOnSkills = s.alwaysOnSkills
-		if err := manager.Hydrate(ctx); err != nil {
+		manager.maxRepeatedToolErrs = s.maxRepeatedToolErrs
+		if err := s.hydrate(ctx, manager); err != nil {
 			return nil, err
 		}
 
+		s.mu.Lock()
 		s.activeConversations[conversationID] = manager
+		s.mu.Unlock()
 		return manager, nil
 	})
 	if err != nil {
@@ -743,20 +1138,34 @@ func (s *Server) getOrCreateConversationManager(ctx context.Context, conversatio
 	return manager, nil
 }
 
+// hydrate hydrates a new conversation manager once fewer than the configured
+// number of hydrations are running. The database is not touched while s.mu
+// is held, so one slow hydration doesn't hold up every other request.
+func (s *Server) hydrate(ctx context.Context, manager *ConversationManager) error {
+	select {
+	case s.hydrationSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.hydrationSem }()
+	return manager.Hydrate(ctx)
+}
+
 // getOrCreateSubagentConversationManager is like getOrCreateConversationManager but
 // uses a toolSetConfig with SubagentDepth incremented by 1, preventing subagents
 // from spawning their own subagents (when MaxSubagentDepth is 1).
 func (s *Server) getOrCreateSubagentConversationManager(ctx context.Context, conversationID string) (*ConversationManager, error) {
 	manager, err, _ := s.conversationGroup.Do(conversationID, func() (*ConversationManager, error) {
 		s.mu.Lock()
-		defer s.mu.Unlock()
-		if manager, exists := s.activeConversations[conversationID]; exists {
+		manager, exists := s.activeConversations[conversationID]
+		s.mu.Unlock()
+		if exists {
 			manager.Touch()
 			return manager, nil
 		}
 
 		recordMessage := func(ctx context.Context, message llm.Message, usage llm.Usage) error {
-			return s.recordMessage(ctx, conversationID, message, usage)
+			return s.recordMessage(ctx, conversationID, message, usage, messageUserData(ctx))
 		}
 
 		onStateChange := func(state ConversationState) {
@@ -767,12 +1176,15 @@ func (s *Server) getOrCreateSubagentConversationManager(ctx context.Context, con
 		subagentConfig := s.toolSetConfig
 		subagentConfig.SubagentDepth = s.toolSetConfig.SubagentDepth + 1
 
-		manager := NewConversationManager(conversationID, s.db, s.logger, subagentConfig, recordMessage, onStateChange)
-		if err := manager.Hydrate(ctx); err != nil {
+		manager = NewConversationManager(conversationID, s.db, s.logger, subagentConfig, recordMessage, onStateChange)
+		manager.maxRepeatedToolErrs = s.maxRepeatedToolErrs
+		if err := s.hydrate(ctx, manager); err != nil {
 			return nil, err
 		}
 
+		s.mu.Lock()
 		s.activeConversations[conversationID] = manager
+		s.mu.Unlock()
 		return manager, nil
 	})
 	if err != nil {
@@ -828,6 +1240,12 @@ func (s *Server) recordMessage(ctx context.Context, conversationID string, messa
 	// Extract display data from content items
 	displayDataToStore := ExtractDisplayData(message)
 
+	// Price the message when it's recorded, so the conversation's cost stays
+	// right if its model changes later. A cost the gateway reported is kept.
+	if !usage.IsZero() && usage.CostUSD == 0 {
+		usage.CostUSD = s.estimateCost(ctx, conversationID, usage)
+	}
+
 	// Create message
 	var ud interface{}
 	if len(userData) > 0 {
@@ -846,11 +1264,24 @@ func (s *Server) recordMessage(ctx context.Context, conversationID string, messa
 		return fmt.Errorf("failed to create message: %w", err)
 	}
 
-	// Update conversation's last updated timestamp for correct ordering
+	// Update conversation's last updated timestamp for correct ordering,
+	// and add this message's usage to the conversation's running totals
 	if err := s.db.QueriesTx(ctx, func(q *generated.Queries) error {
-		return q.UpdateConversationTimestamp(ctx, conversationID)
+		if err := q.UpdateConversationTimestamp(ctx, conversationID); err != nil {
+			return err
+		}
+		if usage.IsZero() {
+			return nil
+		}
+		return q.AddConversationUsage(ctx, generated.AddConversationUsageParams{
+			InputTokensTotal:  int64(usage.InputTokens + usage.CacheCreationInputTokens),
+			OutputTokensTotal: int64(usage.OutputTokens),
+			CachedTokensTotal: int64(usage.CacheReadInputTokens),
+			CostUsdTotal:      usage.CostUSD,
+			ConversationID:    conversationID,
+		})
 	}); err != nil {
-		s.logger.Warn("Failed to update conversation timestamp", "conversationID", conversationID, "error", err)
+		s.logger.Warn("Failed to update conversation timestamp and usage", "conversationID", conversationID, "error", err)
 	}
 
 	// Touch active manager activity time if present
@@ -869,6 +1300,19 @@ func (s *Server) recordMessage(ctx context.Context, conversationID string, messa
 	return nil
 }
 
+type messageUserDataKey struct{}
+
+// withMessageUserData returns a context under which a conversation manager's
+// recordMessage stores userData as the message's user_data.
+func withMessageUserData(ctx context.Context, userData any) context.Context {
+	return context.WithValue(ctx, messageUserDataKey{}, userData)
+}
+
+// messageUserData returns the user_data set by withMessageUserData, or nil.
+func messageUserData(ctx context.Context) any {
+	return ctx.Value(messageUserDataKey{})
+}
+
 // getMessageType determines the message type from an LLM message
 func (s *Server) getMessageType(message llm.Message) (db.MessageType, error) {
 	// System-generated errors are stored as error type
@@ -920,12 +1364,7 @@ func (s *Server) notifySubscribers(ctx context.Context, conversationID string) {
 	}
 
 	// Get conversation data only (no messages needed for metadata-only updates)
-	var conversation generated.Conversation
-	err := s.db.Queries(ctx, func(q *generated.Queries) error {
-		var err error
-		conversation, err = q.GetConversation(ctx, conversationID)
-		return err
-	})
+	conversation, err := s.getConversationForNotify(ctx, conversationID)
 	if err != nil {
 		s.logger.Error("Failed to get conversation data for notification", "conversationID", conversationID, "error", err)
 		return
@@ -958,20 +1397,16 @@ func (s *Server) notifySubscribersNewMessage(ctx context.Context, conversationID
 		return
 	}
 
-	// Get conversation data for the response
-	var conversation generated.Conversation
-	err := s.db.Queries(ctx, func(q *generated.Queries) error {
-		var err error
-		conversation, err = q.GetConversation(ctx, conversationID)
-		return err
-	})
+	// Get conversation data for the response. If it can't be read, still
+	// publish the message: dropping it would leave subscribers without it.
+	conversation, err := s.getConversationForNotify(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to get conversation data for notification", "conversationID", conversationID, "error", err)
-		return
+		s.logger.Warn("Publishing message without conversation data", "conversationID", conversationID, "error", err)
 	}
 
 	// Convert the single new message to API format
 	apiMessages := toAPIMessages([]generated.Message{*newMsg})
+	s.pairWithPrevious(ctx, []generated.Message{*newMsg}, apiMessages)
 
 	// Update agent working state based on message type
 	if isAgentEndOfTurn(newMsg) {
@@ -992,10 +1427,12 @@ func (s *Server) notifySubscribersNewMessage(ctx context.Context, conversationID
 	manager.subpub.Publish(newMsg.SequenceID, streamData)
 
 	// Also notify conversation list subscribers about the update (updated_at changed)
-	s.publishConversationListUpdate(ConversationListUpdate{
-		Type:         "update",
-		Conversation: &conversation,
-	})
+	if err == nil {
+		s.publishConversationListUpdate(ConversationListUpdate{
+			Type:         "update",
+			Conversation: &conversation,
+		})
+	}
 }
 
 // broadcastMessageUpdate sends an updated existing message to all subscribers via Broadcast.
@@ -1011,15 +1448,9 @@ func (s *Server) broadcastMessageUpdate(ctx context.Context, conversationID stri
 		return
 	}
 
-	var conversation generated.Conversation
-	err := s.db.Queries(ctx, func(q *generated.Queries) error {
-		var err error
-		conversation, err = q.GetConversation(ctx, conversationID)
-		return err
-	})
+	conversation, err := s.getConversationForNotify(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to get conversation data for broadcast", "conversationID", conversationID, "error", err)
-		return
+		s.logger.Warn("Broadcasting message update without conversation data", "conversationID", conversationID, "error", err)
 	}
 
 	apiMessages := toAPIMessages([]generated.Message{*updatedMsg})
@@ -1029,10 +1460,43 @@ func (s *Server) broadcastMessageUpdate(ctx context.Context, conversationID stri
 	}
 	manager.subpub.Broadcast(streamData)
 
-	s.publishConversationListUpdate(ConversationListUpdate{
-		Type:         "update",
-		Conversation: &conversation,
-	})
+	if err == nil {
+		s.publishConversationListUpdate(ConversationListUpdate{
+			Type:         "update",
+			Conversation: &conversation,
+		})
+	}
+}
+
+// notifyReadAttempts and notifyRetryDelay bound how hard
+// getConversationForNotify tries before giving up; the delay doubles each time.
+var (
+	notifyReadAttempts = 3
+	notifyRetryDelay   = 50 * time.Millisecond
+)
+
+// getConversationForNotify reads a conversation for a subscriber
+// notification, retrying briefly so a transient DB error doesn't drop it.
+func (s *Server) getConversationForNotify(ctx context.Context, conversationID string) (generated.Conversation, error) {
+	var conversation generated.Conversation
+	var err error
+	delay := notifyRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = s.db.Queries(ctx, func(q *generated.Queries) error {
+			var err error
+			conversation, err = q.GetConversation(ctx, conversationID)
+			return err
+		})
+		if err == nil || errors.Is(err, sql.ErrNoRows) || attempt >= notifyReadAttempts {
+			return conversation, err
+		}
+		select {
+		case <-ctx.Done():
+			return conversation, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
 }
 
 // publishConversationListUpdate broadcasts a conversation list update to ALL active
@@ -1225,15 +1689,16 @@ func (s *Server) publishConversationState(state ConversationState) {
 	}
 }
 
-// getWorkingConversations returns a map of conversation IDs that are currently working.
-func (s *Server) getWorkingConversations() map[string]bool {
+// getWorkingConversations returns the IDs of conversations that are currently
+// working, mapped to when their current turn started.
+func (s *Server) getWorkingConversations() map[string]time.Time {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	working := make(map[string]bool)
+	working := make(map[string]time.Time)
 	for id, manager := range s.activeConversations {
-		if manager.IsAgentWorking() {
-			working[id] = true
+		if since := manager.WorkingSince(); !since.IsZero() {
+			working[id] = since
 		}
 	}
 	return working
@@ -1251,7 +1716,9 @@ func (s *Server) IsAgentWorking(conversationID string) bool {
 	return manager.IsAgentWorking()
 }
 
-// Cleanup removes inactive conversation managers
+// Cleanup removes inactive conversation managers. Managers with live stream
+// subscribers are kept regardless of idle time, so viewers don't have their
+// stream closed out from under them.
 func (s *Server) Cleanup() {
 	// Collect managers to clean up under the lock, but don't call stopLoop
 	// while holding s.mu. stopLoop can block on browser shutdown, and holding
@@ -1262,11 +1729,11 @@ func (s *Server) Cleanup() {
 	s.mu.Lock()
 	now := time.Now()
 	for id, manager := range s.activeConversations {
-		// Remove managers that have been inactive for more than 30 minutes
+		// Remove managers that have been inactive for longer than the idle timeout
 		manager.mu.Lock()
 		lastActivity := manager.lastActivity
 		manager.mu.Unlock()
-		if now.Sub(lastActivity) > 30*time.Minute {
+		if now.Sub(lastActivity) > s.idleTimeout && manager.subpub.SubscriberCount() == 0 {
 			toCleanup = append(toCleanup, manager)
 			toCleanupIDs = append(toCleanupIDs, id)
 			delete(s.activeConversations, id)
@@ -1298,21 +1765,43 @@ func (s *Server) StartWithListener(listener net.Listener) error {
 }
 
 // StartWithListeners starts the HTTP server on the given TCP listener and optionally
-// also on a Unix socket. The TCP listener gets full middleware (CSRF, requireHeader, logger).
+// also on a Unix socket. The TCP listener gets full middleware (CSRF, requireHeader, bearer auth, rate limits, logger).
 // The Unix socket listener gets only the logger middleware (no CSRF, no requireHeader)
 // since it is local and trusted.
 func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string) error {
 	// Set up shared mux with routes
 	mux := http.NewServeMux()
 	s.RegisterRoutes(mux)
+	var handler http.Handler = mux
+	if s.metrics != nil {
+		handler = s.metrics.Middleware(handler)
+	}
 
 	// TCP handler: full middleware (applied in reverse order: last added = first executed)
-	tcpHandler := LoggerMiddleware(s.logger)(mux)
+	tcpHandler := LoggerMiddleware(s.logger)(handler)
 	cop := http.NewCrossOriginProtection()
+	for _, origin := range s.corsOrigins {
+		if err := cop.AddTrustedOrigin(origin); err != nil {
+			return fmt.Errorf("invalid CORS origin: %w", err)
+		}
+	}
 	tcpHandler = cop.Handler(tcpHandler)
 	if s.requireHeader != "" {
 		tcpHandler = RequireHeaderMiddleware(s.requireHeader)(tcpHandler)
 	}
+	if len(s.authTokens) > 0 {
+		tcpHandler = BearerAuthMiddleware(s.authTokens)(tcpHandler)
+	}
+	tcpHandler = RateLimitMiddleware(RateLimitConfig{
+		Write:     s.writeRateLimit,
+		Read:      s.readRateLimit,
+		KeyHeader: s.requireHeader,
+	})(tcpHandler)
+	// Preflights carry no credentials or custom headers, so CORS is answered
+	// before the required header or bearer token is checked.
+	tcpHandler = CORSMiddleware(s.corsOrigins)(tcpHandler)
+	tcpHandler = BasePathMiddleware(s.basePath)(tcpHandler)
+	tcpHandler = RequestIDMiddleware(tcpHandler)
 
 	tcpServer := &http.Server{
 		Handler: tcpHandler,
@@ -1381,7 +1870,7 @@ func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string)
 		}
 
 		// Unix socket handler: relaxed middleware (only logger, no CSRF or requireHeader)
-		socketHandler := LoggerMiddleware(s.logger)(mux)
+		socketHandler := RequestIDMiddleware(LocalSocketMiddleware(LoggerMiddleware(s.logger)(handler)))
 
 		socketServer = &http.Server{
 			Handler: socketHandler,
@@ -1411,6 +1900,10 @@ func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string)
 	// Signal background routines to stop
 	close(s.shutdownCh)
 
+	// End open conversation streams, which would otherwise hold up Shutdown
+	// until its timeout
+	s.closeStreams()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
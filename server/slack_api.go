@@ -23,9 +23,7 @@ func NewSlackConversationAPI(s *Server) *SlackConversationAPI {
 
 // NewConversation creates a new Shelley conversation and sends the first message.
 func (a *SlackConversationAPI) NewConversation(ctx context.Context, message, model string) (string, error) {
-	if model == "" {
-		model = a.server.defaultModel
-	}
+	model = a.server.resolveModel(model)
 
 	llmService, err := a.server.llmManager.GetService(model)
 	if err != nil {
@@ -53,7 +51,7 @@ func (a *SlackConversationAPI) NewConversation(ctx context.Context, message, mod
 		Content: []llm.Content{{Type: llm.ContentTypeText, Text: message}},
 	}
 
-	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, model, userMessage)
+	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, model, userMessage, llm.Params{}, nil)
 	if err != nil {
 		return "", fmt.Errorf("accept user message: %w", err)
 	}
@@ -63,7 +61,7 @@ func (a *SlackConversationAPI) NewConversation(ctx context.Context, message, mod
 			ctx := context.WithoutCancel(ctx)
 			slugCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
 			defer cancel()
-			_, err := slug.GenerateSlug(slugCtx, a.server.llmManager, a.server.db, a.server.logger, convID, message, model)
+			_, err := slug.GenerateSlug(slugCtx, a.server.llmManager, a.server.db, a.server.logger, convID, message, model, a.server.slugPrompt, !a.server.noHeuristicSlugs)
 			if err != nil {
 				a.server.logger.Warn("failed to generate slug", "conversation_id", convID, "error", err)
 			} else {
@@ -77,9 +75,7 @@ func (a *SlackConversationAPI) NewConversation(ctx context.Context, message, mod
 
 // SendMessage sends a message to an existing conversation.
 func (a *SlackConversationAPI) SendMessage(ctx context.Context, conversationID, message, model string) error {
-	if model == "" {
-		model = a.server.defaultModel
-	}
+	model = a.server.resolveModel(model)
 
 	llmService, err := a.server.llmManager.GetService(model)
 	if err != nil {
@@ -96,7 +92,7 @@ func (a *SlackConversationAPI) SendMessage(ctx context.Context, conversationID,
 		Content: []llm.Content{{Type: llm.ContentTypeText, Text: message}},
 	}
 
-	_, err = manager.AcceptUserMessage(ctx, llmService, model, userMessage)
+	_, err = manager.AcceptUserMessage(ctx, llmService, model, userMessage, llm.Params{}, nil)
 	if err != nil {
 		return fmt.Errorf("accept user message: %w", err)
 	}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/slug"
+)
+
+// slugBackfillInterval spaces out LLM calls when regenerating slugs in bulk
+var slugBackfillInterval = time.Second
+
+// defaultSlugBackfillLimit is how many conversations one backfill run
+// processes when no limit is given; maxSlugBackfillLimit caps the limit.
+const (
+	defaultSlugBackfillLimit = 500
+	maxSlugBackfillLimit     = 5000
+)
+
+var errNoUserText = errors.New("conversation has no user message text")
+
+// SlugBackfillStatus reports the progress of a bulk slug regeneration
+type SlugBackfillStatus struct {
+	Running    bool       `json:"running"`
+	Total      int        `json:"total"`
+	Done       int        `json:"done"`
+	Failed     int        `json:"failed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// slugBackfill tracks the single bulk slug regeneration that may run at a time
+type slugBackfill struct {
+	mu     sync.Mutex
+	status SlugBackfillStatus
+}
+
+func (b *slugBackfill) snapshot() SlugBackfillStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
+
+// handleSlugBackfill starts regenerating slugs for untitled conversations in
+// the background (POST, optional ?limit=N, at most maxSlugBackfillLimit).
+// Only one run may be in progress; a second POST gets 409 Conflict.
+func (s *Server) handleSlugBackfill(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	limit := int64(defaultSlugBackfillLimit)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if limit > maxSlugBackfillLimit {
+		limit = maxSlugBackfillLimit
+	}
+
+	s.slugBackfill.mu.Lock()
+	if s.slugBackfill.status.Running {
+		s.slugBackfill.mu.Unlock()
+		http.Error(w, "Slug regeneration already running", http.StatusConflict)
+		return
+	}
+	conversations, err := s.db.ListConversationsWithoutSlug(r.Context(), limit)
+	if err != nil {
+		s.slugBackfill.mu.Unlock()
+		s.logger.ErrorContext(r.Context(), "Failed to list conversations without slug", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	s.slugBackfill.status = SlugBackfillStatus{
+		Running:   true,
+		Total:     len(conversations),
+		StartedAt: &now,
+	}
+	status := s.slugBackfill.status
+	s.slugBackfill.mu.Unlock()
+
+	s.logger.InfoContext(r.Context(), "Regenerating slugs for untitled conversations", "count", len(conversations))
+	go s.runSlugBackfill(context.Background(), conversations)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// runSlugBackfill regenerates slugs one conversation at a time, waiting
+// slugBackfillInterval between them. It stops early on server shutdown.
+func (s *Server) runSlugBackfill(ctx context.Context, conversations []generated.Conversation) {
+	defer func() {
+		s.slugBackfill.mu.Lock()
+		now := time.Now()
+		s.slugBackfill.status.Running = false
+		s.slugBackfill.status.FinishedAt = &now
+		s.slugBackfill.mu.Unlock()
+	}()
+
+	for i, conv := range conversations {
+		if i > 0 {
+			select {
+			case <-s.shutdownCh:
+				return
+			case <-time.After(slugBackfillInterval):
+			}
+		}
+
+		_, err := s.regenerateSlug(ctx, conv)
+		s.slugBackfill.mu.Lock()
+		s.slugBackfill.status.Done++
+		if err != nil {
+			s.slugBackfill.status.Failed++
+		}
+		s.slugBackfill.mu.Unlock()
+		if err != nil {
+			s.logger.Warn("Failed to regenerate slug", "conversationID", conv.ConversationID, "error", err)
+			continue
+		}
+		go s.notifySubscribers(ctx, conv.ConversationID)
+	}
+}
+
+// RegenerateSlugResponse is the response to POST /conversation/<id>/regenerate-slug
+type RegenerateSlugResponse struct {
+	Slug string `json:"slug"`
+}
+
+// handleRegenerateSlug handles POST /conversation/<id>/regenerate-slug. It
+// derives a new slug from the first user message, replacing one the user
+// chose by renaming the conversation.
+func (s *Server) handleRegenerateSlug(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if conv.SlugUserSet {
+		if conv, err = s.db.MarkConversationSlugAuto(ctx, conversationID); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to mark slug as generated", "conversationID", conversationID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer func() {
+			// Put the user's name back if no new slug replaced it
+			if err != nil {
+				if _, err := s.db.RenameConversation(context.WithoutCancel(ctx), conversationID, userSlugOf(conv)); err != nil {
+					s.logger.ErrorContext(ctx, "Failed to restore user-set slug", "conversationID", conversationID, "error", err)
+				}
+			}
+		}()
+	}
+
+	newSlug, err := s.regenerateSlug(ctx, *conv)
+	if errors.Is(err, errNoUserText) {
+		http.Error(w, "Conversation has no user message to generate a slug from", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to regenerate slug", "conversationID", conversationID, "error", err)
+		http.Error(w, "Failed to regenerate slug", http.StatusInternalServerError)
+		return
+	}
+
+	if updated, err := s.db.GetConversationByID(ctx, conversationID); err == nil {
+		go s.publishConversationListUpdate(ConversationListUpdate{
+			Type:         "update",
+			Conversation: updated,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RegenerateSlugResponse{Slug: newSlug})
+}
+
+// userSlugOf returns a conversation's slug, or "" if it has none.
+func userSlugOf(conv *generated.Conversation) string {
+	if conv.Slug == nil {
+		return ""
+	}
+	return *conv.Slug
+}
+
+// regenerateSlug generates a slug for a conversation from its first user message.
+func (s *Server) regenerateSlug(ctx context.Context, conv generated.Conversation) (string, error) {
+	messages, err := s.db.ListMessagesByType(ctx, conv.ConversationID, db.MessageTypeUser)
+	if err != nil {
+		return "", err
+	}
+	text := firstUserText(messages)
+	if text == "" {
+		return "", errNoUserText
+	}
+	var modelID string
+	if conv.Model != nil {
+		modelID = *conv.Model
+	}
+	slugCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	return slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conv.ConversationID, text, modelID, s.slugPrompt, !s.noHeuristicSlugs)
+}
+
+// regenerateSlugAfterEdit regenerates a conversation's slug after the user
+// message with sequence ID editedSeq was edited, if that is the message the
+// slug is generated from. It does nothing if the user chose the slug by
+// renaming the conversation or regeneration on edit is disabled, and reports
+// whether it regenerated the slug.
+func (s *Server) regenerateSlugAfterEdit(ctx context.Context, conversationID string, editedSeq int64) (bool, error) {
+	if s.keepSlugOnEdit {
+		return false, nil
+	}
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		return false, err
+	}
+	if conv.SlugUserSet || conv.ParentConversationID != nil {
+		return false, nil
+	}
+	messages, err := s.db.ListMessagesByType(ctx, conversationID, db.MessageTypeUser)
+	if err != nil {
+		return false, err
+	}
+	if msg := firstUserTextMessage(messages); msg == nil || msg.SequenceID != editedSeq {
+		return false, nil
+	}
+	if _, err := s.regenerateSlug(ctx, *conv); err != nil {
+		return false, err
+	}
+	go s.notifySubscribers(context.WithoutCancel(ctx), conversationID)
+	return true, nil
+}
+
+// firstUserText returns the text of the first user message that has any.
+func firstUserText(messages []generated.Message) string {
+	if msg := firstUserTextMessage(messages); msg != nil {
+		return messageText(*msg)
+	}
+	return ""
+}
+
+// firstUserTextMessage returns the first message that has any text, or nil.
+func firstUserTextMessage(messages []generated.Message) *generated.Message {
+	for i := range messages {
+		if messageText(messages[i]) != "" {
+			return &messages[i]
+		}
+	}
+	return nil
+}
+
+// messageText joins the text content of a message's llm_data.
+func messageText(msg generated.Message) string {
+	if msg.LlmData == nil {
+		return ""
+	}
+	var llmMsg llm.Message
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, content := range llmMsg.Content {
+		if content.Type == llm.ContentTypeText && content.Text != "" {
+			parts = append(parts, content.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
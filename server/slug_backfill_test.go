@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+func TestSlugBackfill(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	oldInterval := slugBackfillInterval
+	slugBackfillInterval = 0
+	t.Cleanup(func() { slugBackfillInterval = oldInterval })
+
+	untitled, err := h.db.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	if _, err := h.db.CreateMessage(ctx, db.CreateMessageParams{
+		ConversationID: untitled.ConversationID,
+		Type:           db.MessageTypeUser,
+		LLMData: llm.Message{
+			Role:    llm.MessageRoleUser,
+			Content: []llm.Content{{Type: llm.ContentTypeText, Text: "Fix the flaky login test"}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+	// A conversation with no user text is counted as failed.
+	if _, err := h.db.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{}); err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/regenerate-slugs", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for unauthenticated request, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	LocalSocketMiddleware(mux).ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/regenerate-slugs", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var status SlugBackfillStatus
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status = h.server.slugBackfill.snapshot()
+		if !status.Running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for slug regeneration")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if status.Total != 2 || status.Done != 2 || status.Failed != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	conv, err := h.db.GetConversationByID(ctx, untitled.ConversationID)
+	if err != nil {
+		t.Fatalf("failed to get conversation: %v", err)
+	}
+	if conv.Slug == nil || *conv.Slug == "" {
+		t.Fatal("expected slug to be regenerated")
+	}
+}
+
+func TestSlugBackfillInvalidLimit(t *testing.T) {
+	h := NewTestHarness(t)
+	handler := LocalSocketMiddleware(http.HandlerFunc(h.server.handleSlugBackfill))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/regenerate-slugs?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/regenerate-slugs?limit=1000000", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202 for a clamped limit, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegenerateSlugAfterEdit(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	original := "original-slug"
+	conv, err := h.db.CreateConversation(ctx, &original, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	var seqs []int64
+	for _, text := range []string{"Fix the flaky login test", "Also update the docs"} {
+		msg, err := h.db.CreateMessage(ctx, db.CreateMessageParams{
+			ConversationID: conv.ConversationID,
+			Type:           db.MessageTypeUser,
+			LLMData: llm.Message{
+				Role:    llm.MessageRoleUser,
+				Content: []llm.Content{{Type: llm.ContentTypeText, Text: text}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create message: %v", err)
+		}
+		seqs = append(seqs, msg.SequenceID)
+	}
+	slugOf := func() string {
+		t.Helper()
+		c, err := h.db.GetConversationByID(ctx, conv.ConversationID)
+		if err != nil {
+			t.Fatalf("failed to get conversation: %v", err)
+		}
+		if c.Slug == nil {
+			return ""
+		}
+		return *c.Slug
+	}
+
+	// Editing a later message leaves the slug alone.
+	if did, err := h.server.regenerateSlugAfterEdit(ctx, conv.ConversationID, seqs[1]); err != nil || did {
+		t.Errorf("expected no regeneration for a later message, got %v, %v", did, err)
+	}
+	if got := slugOf(); got != original {
+		t.Errorf("expected slug %q, got %q", original, got)
+	}
+
+	// Disabled regeneration leaves it alone too.
+	h.server.SetRegenerateSlugOnEdit(false)
+	if did, err := h.server.regenerateSlugAfterEdit(ctx, conv.ConversationID, seqs[0]); err != nil || did {
+		t.Errorf("expected no regeneration when disabled, got %v, %v", did, err)
+	}
+	h.server.SetRegenerateSlugOnEdit(true)
+
+	// Editing the first message regenerates an automatic slug.
+	if did, err := h.server.regenerateSlugAfterEdit(ctx, conv.ConversationID, seqs[0]); err != nil || !did {
+		t.Fatalf("expected regeneration for the first message, got %v, %v", did, err)
+	}
+	regenerated := slugOf()
+	if regenerated == "" || regenerated == original {
+		t.Errorf("expected a regenerated slug, got %q", regenerated)
+	}
+
+	// A slug the user chose is kept.
+	req := httptest.NewRequest("POST", "/api/conversation/"+conv.ConversationID+"/rename", strings.NewReader(`{"slug":"my-title"}`))
+	w := httptest.NewRecorder()
+	h.server.handleRenameConversation(w, req, conv.ConversationID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("rename failed: %d %s", w.Code, w.Body.String())
+	}
+	var renamed generated.Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &renamed); err != nil || !renamed.SlugUserSet {
+		t.Errorf("expected a user-set slug after rename, got %+v (%v)", renamed, err)
+	}
+	if did, err := h.server.regenerateSlugAfterEdit(ctx, conv.ConversationID, seqs[0]); err != nil || did {
+		t.Errorf("expected no regeneration of a user-set slug, got %v, %v", did, err)
+	}
+	if got := slugOf(); got != "my-title" {
+		t.Errorf("expected slug %q, got %q", "my-title", got)
+	}
+}
+
+func TestRegenerateSlugEndpoint(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	conv, err := h.db.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	if _, err := h.db.CreateMessage(ctx, db.CreateMessageParams{
+		ConversationID: conv.ConversationID,
+		Type:           db.MessageTypeUser,
+		LLMData: llm.Message{
+			Role:    llm.MessageRoleUser,
+			Content: []llm.Content{{Type: llm.ContentTypeText, Text: "Fix the flaky login test"}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+	if _, err := h.db.RenameConversation(ctx, conv.ConversationID, "stuff"); err != nil {
+		t.Fatalf("failed to rename conversation: %v", err)
+	}
+
+	mux := h.server.conversationMux()
+	req := httptest.NewRequest("POST", "/"+conv.ConversationID+"/regenerate-slug", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp RegenerateSlugResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Slug == "" || resp.Slug == "stuff" {
+		t.Errorf("expected a new slug, got %q", resp.Slug)
+	}
+
+	updated, err := h.db.GetConversationByID(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("failed to get conversation: %v", err)
+	}
+	if updated.Slug == nil || *updated.Slug != resp.Slug {
+		t.Errorf("expected stored slug %q, got %v", resp.Slug, updated.Slug)
+	}
+	if updated.SlugUserSet {
+		t.Error("expected the regenerated slug not to be user-set")
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/missing/regenerate-slug", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing conversation, got %d", w.Code)
+	}
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -193,3 +196,283 @@ func TestStreamResumeWithLastSequenceID(t *testing.T) {
 		}
 	})
 }
+
+// TestStreamMaxDurationSendsReconnect verifies that a stream is closed after
+// the configured max duration with a final reconnect event.
+func TestStreamMaxDurationSendsReconnect(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	server.SetMaxStreamDuration(200 * time.Millisecond)
+
+	conv, err := database.CreateConversation(context.Background(), nil, false, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/api/conversation/"+conv.ConversationID+"/stream", nil).WithContext(ctx)
+	w := newFlusherRecorder()
+
+	start := time.Now()
+	server.handleStreamConversation(w, req, conv.ConversationID)
+	if ctx.Err() != nil {
+		t.Fatal("stream did not close before the request context expired")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("stream closed after %v, before the max duration", elapsed)
+	}
+
+	var events []StreamResponse
+	for _, line := range strings.Split(w.getString(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var event StreamResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("Failed to parse event %q: %v", data, err)
+		}
+		events = append(events, event)
+	}
+	if len(events) < 2 {
+		t.Fatalf("expected an initial event and a reconnect event, got %d events", len(events))
+	}
+	if events[0].Reconnect {
+		t.Error("initial event should not ask the client to reconnect")
+	}
+	if !events[len(events)-1].Reconnect {
+		t.Errorf("expected the last event to ask the client to reconnect, got %+v", events[len(events)-1])
+	}
+}
+
+// TestStreamEndsOnShutdown verifies that shutting down ends open streams with
+// a shutdown event, so http.Server.Shutdown doesn't wait out its timeout.
+func TestStreamEndsOnShutdown(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+
+	conv, err := database.CreateConversation(context.Background(), nil, false, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/conversation/" + conv.ConversationID + "/stream")
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Read events until the stream ends
+	events := make(chan StreamResponse, 10)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event StreamResponse
+			if err := json.Unmarshal([]byte(data), &event); err == nil {
+				events <- event
+			}
+		}
+	}()
+
+	select {
+	case <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the initial event")
+	}
+
+	start := time.Now()
+	server.closeStreams()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ts.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Shutdown took %v", elapsed)
+	}
+
+	var last StreamResponse
+	for event := range events {
+		last = event
+	}
+	if last.Type != "shutdown" {
+		t.Errorf("expected the last event to be a shutdown event, got %+v", last)
+	}
+}
+
+// TestStreamGzip verifies that a client accepting gzip gets a compressed
+// stream whose events can be decoded as they are sent, and that other
+// clients get it uncompressed.
+func TestStreamGzip(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	server.heartbeatInterval = 50 * time.Millisecond
+
+	conv, err := database.CreateConversation(context.Background(), nil, false, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for _, gzipped := range []bool{true, false} {
+		t.Run(fmt.Sprintf("gzip=%v", gzipped), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/api/conversation/"+conv.ConversationID+"/stream", nil)
+			if gzipped {
+				// Setting the header ourselves stops the transport decompressing.
+				req.Header.Set("Accept-Encoding", "gzip")
+			} else {
+				req.Header.Set("Accept-Encoding", "identity")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to open stream: %v", err)
+			}
+			defer resp.Body.Close()
+			if got := resp.Header.Get("Content-Encoding"); (got == "gzip") != gzipped {
+				t.Fatalf("Content-Encoding = %q with gzipped=%v", got, gzipped)
+			}
+
+			// The stream stays open, so every event must arrive decodable
+			// without waiting for the compressor to fill or close.
+			events := make(chan StreamResponse, 10)
+			go func() {
+				defer close(events)
+				var body io.Reader = resp.Body
+				if gzipped {
+					gr, err := gzip.NewReader(resp.Body)
+					if err != nil {
+						return
+					}
+					body = gr
+				}
+				scanner := bufio.NewScanner(body)
+				scanner.Buffer(nil, 1<<20)
+				for scanner.Scan() {
+					data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+					if !ok {
+						continue
+					}
+					var event StreamResponse
+					if err := json.Unmarshal([]byte(data), &event); err == nil {
+						events <- event
+					}
+				}
+			}()
+
+			for i := range 3 {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						t.Fatalf("stream ended after %d events", i)
+					}
+					if event.Conversation.ConversationID != conv.ConversationID {
+						t.Errorf("event %d is for conversation %q", i, event.Conversation.ConversationID)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("timed out waiting for event %d", i)
+				}
+			}
+		})
+	}
+}
+
+// TestStreamSendsHeartbeatWhenIdle verifies that an idle stream sends
+// heartbeats so proxies don't drop the connection.
+func TestStreamSendsHeartbeatWhenIdle(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	server.heartbeatInterval = 50 * time.Millisecond
+	server.SetMaxStreamDuration(300 * time.Millisecond)
+
+	conv, err := database.CreateConversation(context.Background(), nil, false, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/api/conversation/"+conv.ConversationID+"/stream", nil).WithContext(ctx)
+	w := newFlusherRecorder()
+	server.handleStreamConversation(w, req, conv.ConversationID)
+
+	var heartbeats int
+	for i, line := range strings.Split(w.getString(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || i == 0 {
+			// The first event is the initial state, which is also a heartbeat.
+			continue
+		}
+		var event StreamResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("Failed to parse event %q: %v", data, err)
+		}
+		if event.Heartbeat {
+			heartbeats++
+			if event.ConversationState == nil || event.Conversation.ConversationID != conv.ConversationID {
+				t.Errorf("expected heartbeat with conversation state, got %+v", event)
+			}
+		}
+	}
+	if heartbeats == 0 {
+		t.Fatal("expected heartbeats on an idle stream, got none")
+	}
+}
+
+// TestStreamSubscriberLimit verifies that streams beyond the per-conversation
+// subscriber limit are turned away with a 503.
+func TestStreamSubscriberLimit(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	server.SetMaxStreamSubscribers(1)
+
+	conv, err := database.CreateConversation(context.Background(), nil, false, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/api/conversation/"+conv.ConversationID+"/stream", nil).WithContext(ctx)
+		server.handleStreamConversation(newFlusherRecorder(), req, conv.ConversationID)
+	}()
+	waitFor(t, 5*time.Second, func() bool {
+		server.mu.Lock()
+		manager := server.activeConversations[conv.ConversationID]
+		server.mu.Unlock()
+		return manager != nil && manager.subpub.SubscriberCount() == 1
+	})
+
+	req := httptest.NewRequest("GET", "/api/conversation/"+conv.ConversationID+"/stream", nil)
+	w := httptest.NewRecorder()
+	server.handleStreamConversation(w, req, conv.ConversationID)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 over the subscriber limit, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+
+	cancel()
+	<-done
+}
@@ -38,17 +38,15 @@ func (r *SubagentRunner) RunSubagent(ctx context.Context, conversationID, prompt
 	}
 
 	// Use the parent's model if provided, otherwise fall back to server default
-	if modelID == "" {
-		modelID = s.defaultModel
-	}
+	modelID = s.resolveModel(modelID)
 	if modelID == "" && s.predictableOnly {
 		modelID = "predictable"
 	}
 
 	// Persist model on the subagent conversation record
-	// UpdateConversationModel only sets the model if it's NULL, so this is safe for re-sends
+	// SetConversationModelIfUnset only sets the model if it's NULL, so this is safe for re-sends
 	if modelID != "" {
-		if err := s.db.UpdateConversationModel(ctx, conversationID, modelID); err != nil {
+		if err := s.db.SetConversationModelIfUnset(ctx, conversationID, modelID); err != nil {
 			s.logger.Warn("Failed to persist model on subagent conversation", "error", err, "conversationID", conversationID)
 		}
 	}
@@ -62,7 +60,7 @@ func (r *SubagentRunner) RunSubagent(ctx context.Context, conversationID, prompt
 	// If the subagent is currently working, stop it first before sending new message
 	if manager.IsAgentWorking() {
 		s.logger.Info("Subagent is working, stopping before sending new message", "conversationID", conversationID)
-		if err := manager.CancelConversation(ctx); err != nil {
+		if err := manager.CancelConversation(ctx, CancelUserData{CancelledBy: CancelledByParent}); err != nil {
 			s.logger.Error("Failed to cancel subagent conversation", "error", err)
 			// Continue anyway - we still want to send the new message
 		}
@@ -79,7 +77,7 @@ func (r *SubagentRunner) RunSubagent(ctx context.Context, conversationID, prompt
 	}
 
 	// Accept the user message (this starts processing)
-	_, err = manager.AcceptUserMessage(ctx, llmService, modelID, userMessage)
+	_, err = manager.AcceptUserMessage(ctx, llmService, modelID, userMessage, llm.Params{}, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to accept user message: %w", err)
 	}
@@ -377,7 +375,7 @@ func (s *Server) handleGetSubagents(w http.ResponseWriter, r *http.Request, conv
 	ctx := r.Context()
 	subagents, err := s.db.GetSubagents(ctx, conversationID)
 	if err != nil {
-		s.logger.Error("Failed to get subagents", "conversationID", conversationID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get subagents", "conversationID", conversationID, "error", err)
 		http.Error(w, "Failed to get subagents", 500)
 		return
 	}
@@ -385,7 +383,7 @@ func (s *Server) handleGetSubagents(w http.ResponseWriter, r *http.Request, conv
 	// Get subagent counts so the UI knows which subagents have their own children
 	subagentCounts, err := s.db.GetSubagentCounts(ctx)
 	if err != nil {
-		s.logger.Error("Failed to get subagent counts", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get subagent counts", "error", err)
 		// Non-fatal, continue with zero counts
 		subagentCounts = make(map[string]int64)
 	}
@@ -395,9 +393,12 @@ func (s *Server) handleGetSubagents(w http.ResponseWriter, r *http.Request, conv
 	for i, sub := range subagents {
 		result[i] = ConversationWithState{
 			Conversation:  sub,
-			Working:       workingStates[sub.ConversationID],
 			SubagentCount: subagentCounts[sub.ConversationID],
 		}
+		if since, ok := workingStates[sub.ConversationID]; ok {
+			result[i].Working = true
+			result[i].WorkingSince = &since
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1,10 +1,12 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"testing"
 
+	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
 	"shelley.exe.dev/llm"
 )
@@ -239,3 +241,22 @@ func TestBuildConversationSummary_SkipsSystemMessages(t *testing.T) {
 		t.Error("Summary should include user messages")
 	}
 }
+
+func TestSubagentManagerInheritsMaxRepeatedToolErrors(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	server.SetMaxRepeatedToolErrors(7)
+	ctx := context.Background()
+
+	conv, err := database.CreateConversation(ctx, nil, false, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	manager, err := server.getOrCreateSubagentConversationManager(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("failed to create subagent manager: %v", err)
+	}
+	if manager.maxRepeatedToolErrs != 7 {
+		t.Errorf("expected the subagent to halt after 7 repeated tool errors, got %d", manager.maxRepeatedToolErrs)
+	}
+}
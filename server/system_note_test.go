@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+func TestSystemNotePrependedToSystemPrompt(t *testing.T) {
+	h := NewTestHarness(t)
+
+	h.NewConversation("echo: before note", "/tmp")
+	h.WaitResponse()
+
+	body, _ := json.Marshal(SystemNoteRequest{Note: "always respond in French"})
+	req := httptest.NewRequest("POST", "/api/conversation/"+h.convID+"/system-note", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	h.server.handleSetSystemNote(w, req, h.convID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var conv generated.Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &conv); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if conv.SystemNote == nil || *conv.SystemNote != "always respond in French" {
+		t.Fatalf("expected system note in conversation payload, got %v", conv.SystemNote)
+	}
+
+	h.llm.ClearRequests()
+	h.Chat("echo: after note")
+	h.WaitResponse()
+
+	var found bool
+	for _, r := range h.llm.GetRecentRequests() {
+		if len(r.System) == 0 || !lastUserTextIs(r, "echo: after note") {
+			continue
+		}
+		found = true
+		if !strings.Contains(r.System[0].Text, "always respond in French") {
+			t.Errorf("expected pinned note as first system block, got %q", r.System[0].Text)
+		}
+	}
+	if !found {
+		t.Fatal("did not find LLM request for the chat message")
+	}
+}
+
+func TestSetSystemNoteNotFound(t *testing.T) {
+	h := NewTestHarness(t)
+
+	req := httptest.NewRequest("POST", "/api/conversation/nope/system-note", strings.NewReader(`{"note": "x"}`))
+	w := httptest.NewRecorder()
+	h.server.handleSetSystemNote(w, req, "nope")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// lastUserTextIs reports whether the last message of req is a user message with the given text.
+func lastUserTextIs(req *llm.Request, text string) bool {
+	if len(req.Messages) == 0 {
+		return false
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != llm.MessageRoleUser {
+		return false
+	}
+	for _, c := range last.Content {
+		if c.Type == llm.ContentTypeText && strings.TrimSpace(c.Text) == text {
+			return true
+		}
+	}
+	return false
+}
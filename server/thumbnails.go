@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"shelley.exe.dev/llm/imageutil"
+)
+
+// Bounds on the max dimension /api/read?thumb=N accepts.
+const (
+	minThumbnailDim = 16
+	maxThumbnailDim = 1024
+)
+
+// thumbnailExtensions are the file types /api/read can make thumbnails of.
+var thumbnailExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
+// serveThumbnail writes a JPEG of f scaled to fit within maxDim, from the
+// thumbnail cache if an earlier request made it.
+func (s *Server) serveThumbnail(w http.ResponseWriter, r *http.Request, f *os.File, info fs.FileInfo, maxDim int) {
+	var cachePath string
+	if s.thumbnailCacheDir != "" {
+		// Keyed like the ETag, so a re-captured screenshot gets a new thumbnail
+		sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%d\x00%d\x00%d", f.Name(), info.Size(), info.ModTime().UnixNano(), maxDim))
+		cachePath = filepath.Join(s.thumbnailCacheDir, hex.EncodeToString(sum[:16])+".jpg")
+		if thumb, err := os.ReadFile(cachePath); err == nil {
+			writeThumbnail(w, r, info, thumb)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		http.Error(w, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+	thumb, err := imageutil.Thumbnail(data, maxDim)
+	if errors.Is(err, imageutil.ErrImageTooLarge) {
+		http.Error(w, "image too large for a thumbnail", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, "not a valid image", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if cachePath != "" {
+		if err := writeFileAtomic(cachePath, thumb); err != nil {
+			s.logger.WarnContext(r.Context(), "Failed to cache thumbnail", "path", cachePath, "error", err)
+		}
+	}
+	writeThumbnail(w, r, info, thumb)
+}
+
+func writeThumbnail(w http.ResponseWriter, r *http.Request, info fs.FileInfo, thumb []byte) {
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeContent(w, r, "", info.ModTime(), bytes.NewReader(thumb))
+}
+
+// writeFileAtomic writes data to path via a temporary file, so a concurrent
+// reader never sees a partial file. It creates path's directory if needed.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
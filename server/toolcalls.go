@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+// APIToolCall pairs a tool call with its result, so clients don't have to
+// match tool_use and tool_result content across messages themselves.
+// It is attached to the message that carries the result. Neither the input
+// nor the output is copied, since both can be large and are already in
+// llm_data: ToolUseMessageID and ToolUseIndex locate the tool_use, and
+// ResultIndex the tool_result in this message.
+type APIToolCall struct {
+	ToolUseID string `json:"tool_use_id"`
+	// ToolName, ToolUseMessageID and ToolUseIndex are empty if the call
+	// could not be found.
+	ToolName         string     `json:"tool_name,omitempty"`
+	ToolUseMessageID string     `json:"tool_use_message_id,omitempty"`
+	ToolUseIndex     int        `json:"tool_use_index"`
+	ResultIndex      int        `json:"result_index"`
+	Error            bool       `json:"error,omitempty"`
+	StartTime        *time.Time `json:"start_time,omitempty"`
+	EndTime          *time.Time `json:"end_time,omitempty"`
+}
+
+// toolUseRef locates a tool_use in llm_data.
+type toolUseRef struct {
+	name      string
+	messageID string
+	index     int
+}
+
+// pairToolCalls sets ToolCalls on each API message whose llm_data has tool
+// results. toolUses holds calls seen before messages[0], keyed by tool_use
+// ID; it is extended with the calls in messages as they are walked.
+func pairToolCalls(messages []generated.Message, apiMessages []APIMessage, toolUses map[string]toolUseRef) {
+	for i, msg := range messages {
+		llmMsg, ok := parseLLMData(msg)
+		if !ok {
+			continue
+		}
+		var calls []APIToolCall
+		for j, c := range llmMsg.Content {
+			switch c.Type {
+			case llm.ContentTypeToolUse:
+				toolUses[c.ID] = toolUseRef{name: c.ToolName, messageID: msg.MessageID, index: j}
+			case llm.ContentTypeToolResult:
+				call := APIToolCall{
+					ToolUseID:   c.ToolUseID,
+					ResultIndex: j,
+					Error:       c.ToolError,
+					StartTime:   c.ToolUseStartTime,
+					EndTime:     c.ToolUseEndTime,
+				}
+				if use, ok := toolUses[c.ToolUseID]; ok {
+					call.ToolName = use.name
+					call.ToolUseMessageID = use.messageID
+					call.ToolUseIndex = use.index
+				}
+				calls = append(calls, call)
+			}
+		}
+		apiMessages[i].ToolCalls = calls
+	}
+}
+
+// toolUsesIn returns the tool calls in msg, keyed by tool_use ID.
+func toolUsesIn(msg *generated.Message) map[string]toolUseRef {
+	toolUses := make(map[string]toolUseRef)
+	if msg == nil {
+		return toolUses
+	}
+	if llmMsg, ok := parseLLMData(*msg); ok {
+		for i, c := range llmMsg.Content {
+			if c.Type == llm.ContentTypeToolUse {
+				toolUses[c.ID] = toolUseRef{name: c.ToolName, messageID: msg.MessageID, index: i}
+			}
+		}
+	}
+	return toolUses
+}
+
+// hasUnpairedToolCalls reports whether msg has a tool result whose call was
+// not found, which happens when the call is in an earlier message.
+func hasUnpairedToolCalls(msg APIMessage) bool {
+	for _, call := range msg.ToolCalls {
+		if call.ToolUseMessageID == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// pairWithPrevious fills in tool calls for results at the start of messages
+// whose calls are in the message just before it. Tool results always follow
+// their calls directly, so only the first message can be affected.
+func (s *Server) pairWithPrevious(ctx context.Context, messages []generated.Message, apiMessages []APIMessage) {
+	if len(messages) == 0 || !hasUnpairedToolCalls(apiMessages[0]) {
+		return
+	}
+	var prev []generated.Message
+	err := s.db.Queries(ctx, func(q *generated.Queries) error {
+		var err error
+		prev, err = db.ListMessagesPage(ctx, q, messages[0].ConversationID, db.MessagePageOptions{
+			BeforeSequenceID: messages[0].SequenceID,
+			Limit:            1,
+			Descending:       true,
+		})
+		return err
+	})
+	if err != nil || len(prev) == 0 {
+		return
+	}
+	pairToolCalls(messages[:1], apiMessages[:1], toolUsesIn(&prev[0]))
+}
+
+func parseLLMData(msg generated.Message) (llm.Message, bool) {
+	var llmMsg llm.Message
+	if msg.LlmData == nil {
+		return llmMsg, false
+	}
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return llmMsg, false
+	}
+	return llmMsg, true
+}
+
+// toolResultText joins the text parts of a tool result.
+func toolResultText(contents []llm.Content) string {
+	var parts []string
+	for _, c := range contents {
+		if c.Text != "" {
+			parts = append(parts, c.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
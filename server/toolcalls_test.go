@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+func TestToolCallsPairing(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	h.NewConversation("bash: echo hi", "/tmp")
+	h.WaitToolResult()
+	h.WaitResponse()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/conversation/"+h.convID, nil)
+	w := httptest.NewRecorder()
+	h.server.handleGetConversation(w, req, h.convID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp StreamResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	var call *APIToolCall
+	var resultMsg APIMessage
+	for _, msg := range resp.Messages {
+		if len(msg.ToolCalls) > 0 {
+			call = &msg.ToolCalls[0]
+			resultMsg = msg
+		}
+	}
+	if call == nil {
+		t.Fatal("expected a message with tool calls")
+	}
+	resultSeq := resultMsg.SequenceID
+	if call.ToolName != "bash" || call.ToolUseMessageID == "" {
+		t.Errorf("expected the bash call to be paired, got %+v", call)
+	}
+	if call.Error || call.StartTime == nil {
+		t.Errorf("unexpected tool result: %+v", call)
+	}
+
+	// Neither input nor output is copied; the call points into llm_data
+	if raw, _ := json.Marshal(call); strings.Contains(string(raw), `"input"`) || strings.Contains(string(raw), `"output"`) {
+		t.Errorf("expected no copied input or output, got %s", raw)
+	}
+	contentAt := func(messageID string, index int) llm.Content {
+		t.Helper()
+		for _, msg := range resp.Messages {
+			if msg.MessageID != messageID {
+				continue
+			}
+			var llmMsg llm.Message
+			if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+				t.Fatalf("failed to parse llm_data: %v", err)
+			}
+			if index >= len(llmMsg.Content) {
+				t.Fatalf("index %d out of range of %d contents", index, len(llmMsg.Content))
+			}
+			return llmMsg.Content[index]
+		}
+		t.Fatalf("message %q not found", messageID)
+		return llm.Content{}
+	}
+	use := contentAt(call.ToolUseMessageID, call.ToolUseIndex)
+	if use.ID != call.ToolUseID || !strings.Contains(string(use.ToolInput), "echo hi") {
+		t.Errorf("expected tool_use_index to point at the bash call, got %+v", use)
+	}
+	result := contentAt(resultMsg.MessageID, call.ResultIndex)
+	if result.ToolUseID != call.ToolUseID || !strings.Contains(toolResultText(result.ToolResult), "hi") {
+		t.Errorf("expected result_index to point at the bash result, got %+v", result)
+	}
+
+	// A result converted on its own is paired with the call before it,
+	// as when it is streamed.
+	ctx := context.Background()
+	var messages []generated.Message
+	if err := h.db.Queries(ctx, func(q *generated.Queries) error {
+		var err error
+		messages, err = q.ListMessages(ctx, h.convID)
+		return err
+	}); err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	for _, msg := range messages {
+		if msg.SequenceID != resultSeq {
+			continue
+		}
+		single := []generated.Message{msg}
+		apiMessages := toAPIMessages(single)
+		if !hasUnpairedToolCalls(apiMessages[0]) {
+			t.Fatal("expected the result alone to be unpaired")
+		}
+		h.server.pairWithPrevious(ctx, single, apiMessages)
+		if got := apiMessages[0].ToolCalls[0]; got.ToolName != "bash" || got.ToolUseMessageID != call.ToolUseMessageID {
+			t.Errorf("expected the bash call after pairing with the previous message, got %+v", got)
+		}
+	}
+}
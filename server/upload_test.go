@@ -3,12 +3,16 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -46,15 +50,18 @@ func TestUploadEndpoint(t *testing.T) {
 		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
 	}
 
-	var response map[string]string
+	var response UploadResponse
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("failed to parse response: %v", err)
 	}
 
-	path, ok := response["path"]
-	if !ok {
+	path := response.Path
+	if path == "" {
 		t.Fatal("response missing 'path' field")
 	}
+	if len(response.Paths) != 1 || response.Paths[0] != path {
+		t.Errorf("expected paths to hold just %s, got %v", path, response.Paths)
+	}
 
 	// Verify the path is in the screenshot directory
 	if !strings.HasPrefix(path, browse.ScreenshotDir) {
@@ -80,6 +87,66 @@ func TestUploadEndpoint(t *testing.T) {
 	os.Remove(path)
 }
 
+func TestUploadEndpointMultipleFiles(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+
+	upload := func(files map[string][]byte, order []string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for _, name := range order {
+			part, err := writer.CreateFormFile("file", name)
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			part.Write(files[name])
+		}
+		writer.Close()
+		req := httptest.NewRequest("POST", "/api/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		server.handleUpload(w, req)
+		return w
+	}
+
+	files := map[string][]byte{"a.png": pngHeader, "b.gif": []byte("GIF89a"), "c.jpg": jpegHeader}
+	order := []string{"a.png", "b.gif", "c.jpg"}
+	w := upload(files, order)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response UploadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(response.Paths) != len(order) {
+		t.Fatalf("expected %d paths, got %v", len(order), response.Paths)
+	}
+	if response.Path != response.Paths[0] {
+		t.Errorf("expected path to be the first of paths, got %s", response.Path)
+	}
+	for i, path := range response.Paths {
+		defer os.Remove(path)
+		if ext := filepath.Ext(order[i]); filepath.Ext(path) != ext {
+			t.Errorf("paths[%d] = %s, want extension %s", i, path, ext)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read uploaded file: %v", err)
+		}
+		if !bytes.Equal(data, files[order[i]]) {
+			t.Errorf("paths[%d] content = %q, want %q", i, data, files[order[i]])
+		}
+	}
+
+	// The size limit applies to all files together.
+	half := slices.Concat(pngHeader, make([]byte, maxUploadSize/2))
+	w = upload(map[string][]byte{"big1.png": half, "big2.png": half}, []string{"big1.png", "big2.png"})
+	if w.Code == http.StatusOK {
+		t.Errorf("expected files over the total size limit to be rejected")
+	}
+}
+
 func TestUploadEndpointMethodNotAllowed(t *testing.T) {
 	t.Parallel()
 	server, _, _ := newTestServer(t)
@@ -144,12 +211,12 @@ func TestUploadedFileCanBeReadViaReadEndpoint(t *testing.T) {
 		t.Fatalf("upload failed: %s", uploadW.Body.String())
 	}
 
-	var uploadResponse map[string]string
+	var uploadResponse UploadResponse
 	if err := json.Unmarshal(uploadW.Body.Bytes(), &uploadResponse); err != nil {
 		t.Fatalf("failed to parse upload response: %v", err)
 	}
 
-	path := uploadResponse["path"]
+	path := uploadResponse.Path
 
 	// Now try to read the file via the read endpoint
 	readReq := httptest.NewRequest("GET", "/api/read?path="+path, nil)
@@ -181,49 +248,96 @@ func TestUploadedFileCanBeReadViaReadEndpoint(t *testing.T) {
 	os.Remove(path)
 }
 
-func TestUploadPreservesFileExtension(t *testing.T) {
+func TestReadEndpointRange(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+
+	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
+		t.Fatalf("failed to create screenshot dir: %v", err)
+	}
+	f, err := os.CreateTemp(browse.ScreenshotDir, "range-*.mp4")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("0123456789"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	f.Close()
+
+	req := httptest.NewRequest("GET", "/api/read?path="+f.Name(), nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w := httptest.NewRecorder()
+	server.handleRead(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Body.String(); got != "0123" {
+		t.Errorf("expected partial body %q, got %q", "0123", got)
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 0-3/10" {
+		t.Errorf("expected Content-Range %q, got %q", "bytes 0-3/10", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "video/mp4" {
+		t.Errorf("expected Content-Type video/mp4, got %s", got)
+	}
+}
+
+// Magic bytes that http.DetectContentType recognizes.
+var (
+	pngHeader  = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+	jpegHeader = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46}
+)
+
+// uploadOne posts a single file to handleUpload.
+func uploadOne(t *testing.T, server *Server, filename string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	server.handleUpload(w, req)
+	return w
+}
+
+func TestUploadExtensionFromContent(t *testing.T) {
 	t.Parallel()
 	server, _, _ := newTestServer(t)
 
 	testCases := []struct {
 		filename string
+		content  []byte
 		wantExt  string
 	}{
-		{"photo.png", ".png"},
-		{"image.jpeg", ".jpeg"},
-		{"screenshot.gif", ".gif"},
-		{"document.pdf", ".pdf"},
-		{"noextension", ""},
+		{"photo.png", pngHeader, ".png"},
+		{"image.jpeg", jpegHeader, ".jpg"},
+		{"screenshot.gif", []byte("GIF89a"), ".gif"},
+		{"shell.php", pngHeader, ".png"},
+		{"noextension", pngHeader, ".png"},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.filename, func(t *testing.T) {
-			body := &bytes.Buffer{}
-			writer := multipart.NewWriter(body)
-
-			part, err := writer.CreateFormFile("file", tc.filename)
-			if err != nil {
-				t.Fatalf("failed to create form file: %v", err)
-			}
-			part.Write([]byte("test content"))
-			writer.Close()
-
-			req := httptest.NewRequest("POST", "/api/upload", body)
-			req.Header.Set("Content-Type", writer.FormDataContentType())
-			w := httptest.NewRecorder()
-
-			server.handleUpload(w, req)
-
+			w := uploadOne(t, server, tc.filename, tc.content)
 			if w.Code != http.StatusOK {
-				t.Fatalf("expected status 200, got %d", w.Code)
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
 			}
 
-			var response map[string]string
+			var response UploadResponse
 			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 				t.Fatalf("failed to parse response: %v", err)
 			}
 
-			path := response["path"]
+			path := response.Path
 			ext := filepath.Ext(path)
 			if ext != tc.wantExt {
 				t.Errorf("expected extension %q, got %q", tc.wantExt, ext)
@@ -234,3 +348,348 @@ func TestUploadPreservesFileExtension(t *testing.T) {
 		})
 	}
 }
+
+func TestUploadRejectsDisallowedTypes(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+
+	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
+		t.Fatalf("failed to create screenshot dir: %v", err)
+	}
+	before, err := filepath.Glob(filepath.Join(browse.ScreenshotDir, "upload_*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := map[string][]byte{
+		"notes.txt": []byte("just some text"),
+		"shell.png": []byte("<?php system($_GET['cmd']); ?>"),
+		"page.html": []byte("<html><script>alert(1)</script></html>"),
+	}
+	for filename, content := range payloads {
+		if w := uploadOne(t, server, filename, content); w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("%s: expected status 415, got %d: %s", filename, w.Code, w.Body.String())
+		}
+	}
+
+	// Nothing new was saved. Other tests upload in parallel, so only
+	// look for files with the rejected content.
+	after, err := filepath.Glob(filepath.Join(browse.ScreenshotDir, "upload_*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range after {
+		if slices.Contains(before, path) {
+			continue
+		}
+		data, _ := os.ReadFile(path)
+		for filename, content := range payloads {
+			if bytes.Equal(data, content) {
+				t.Errorf("%s: rejected upload was saved as %s", filename, path)
+			}
+		}
+	}
+
+	// Text is accepted once it's configured, and saved as .txt.
+	server.SetUploadTypes([]string{"text/plain"})
+	w := uploadOne(t, server, "notes.md", payloads["notes.txt"])
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for an allowed type, got %d: %s", w.Code, w.Body.String())
+	}
+	var response UploadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	defer os.Remove(response.Path)
+	if ext := filepath.Ext(response.Path); ext != ".txt" {
+		t.Errorf("expected extension .txt, got %q", ext)
+	}
+	if w := uploadOne(t, server, "photo.png", pngHeader); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status 415 for PNG once not allowed, got %d", w.Code)
+	}
+}
+
+func TestReadEndpointExtensionAllowlist(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+
+	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
+		t.Fatalf("failed to create screenshot dir: %v", err)
+	}
+	var paths []string
+	for _, pattern := range []string{"allow-*.json", "allow-*.PNG"} {
+		f, err := os.CreateTemp(browse.ScreenshotDir, pattern)
+		if err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		defer os.Remove(f.Name())
+		f.WriteString("content")
+		f.Close()
+		paths = append(paths, f.Name())
+	}
+	jsonPath, pngPath := paths[0], paths[1]
+
+	read := func(path string) int {
+		w := httptest.NewRecorder()
+		server.handleRead(w, httptest.NewRequest("GET", "/api/read?path="+path, nil))
+		return w.Code
+	}
+
+	if code := read(jsonPath); code != http.StatusForbidden {
+		t.Errorf("expected status 403 for .json by default, got %d", code)
+	}
+	if code := read(pngPath); code != http.StatusOK {
+		t.Errorf("expected status 200 for .PNG by default, got %d", code)
+	}
+
+	// CPU profiles are served for speedscope whatever the allowlist says
+	if err := os.MkdirAll(browse.ConsoleLogsDir, 0o755); err != nil {
+		t.Fatalf("failed to create console logs dir: %v", err)
+	}
+	profile, err := os.CreateTemp(browse.ConsoleLogsDir, "cpu_profile_*.json")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer os.Remove(profile.Name())
+	profile.WriteString("{}")
+	profile.Close()
+	if code := read(profile.Name()); code != http.StatusOK {
+		t.Errorf("expected status 200 for a saved CPU profile, got %d", code)
+	}
+
+	server.SetReadExtensions([]string{"json"})
+	if code := read(jsonPath); code != http.StatusOK {
+		t.Errorf("expected status 200 for allowed .json, got %d", code)
+	}
+	if code := read(pngPath); code != http.StatusForbidden {
+		t.Errorf("expected status 403 for .PNG once not allowed, got %d", code)
+	}
+}
+
+func TestReadEndpointSymlinks(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+
+	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
+		t.Fatalf("failed to create screenshot dir: %v", err)
+	}
+	f, err := os.CreateTemp(browse.ScreenshotDir, "target-*.png")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("inside")
+	f.Close()
+
+	outside := filepath.Join(t.TempDir(), "secret.png")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	link := func(target string) string {
+		name := strings.TrimSuffix(f.Name(), ".png") + "-link-" + filepath.Base(target)
+		if err := os.Symlink(target, name); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+		t.Cleanup(func() { os.Remove(name) })
+		return name
+	}
+	read := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.handleRead(w, httptest.NewRequest("GET", "/api/read?path="+path, nil))
+		return w
+	}
+
+	if w := read(link(outside)); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a symlink escaping the screenshot dir, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := read(link("/etc/passwd.png")); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a dangling symlink, got %d", w.Code)
+	}
+	// A symlink to another file in the directory is still served.
+	if w := read(link(f.Name())); w.Code != http.StatusOK || w.Body.String() != "inside" {
+		t.Errorf("expected a symlink within the screenshot dir to be served, got %d: %q", w.Code, w.Body.String())
+	}
+}
+
+func TestReadEndpointETag(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+
+	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
+		t.Fatalf("failed to create screenshot dir: %v", err)
+	}
+	f, err := os.CreateTemp(browse.ScreenshotDir, "etag-*.png")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("first"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	f.Close()
+
+	read := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/read?path="+f.Name(), nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		server.handleRead(w, req)
+		return w
+	}
+
+	w := read("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+
+	if w := read(etag); w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for matching ETag, got %d", w.Code)
+	}
+
+	// Re-capturing the file changes the ETag.
+	if err := os.WriteFile(f.Name(), []byte("second capture"), 0o644); err != nil {
+		t.Fatalf("failed to overwrite file: %v", err)
+	}
+	w = read(etag)
+	if w.Code != http.StatusOK || w.Body.String() != "second capture" {
+		t.Errorf("expected fresh content after overwrite, got %d: %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Error("expected a new ETag after overwrite")
+	}
+}
+
+func TestReadEndpointThumbnail(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+	server.SetThumbnailCacheDir(t.TempDir())
+
+	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
+		t.Fatalf("failed to create screenshot dir: %v", err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 1200, 800))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 7)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.CreateTemp(browse.ScreenshotDir, "thumb-*.png")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(buf.Bytes())
+	f.Close()
+
+	read := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.handleRead(w, httptest.NewRequest("GET", "/api/read?path="+f.Name()+query, nil))
+		return w
+	}
+
+	full := read("")
+	for range 2 { // the second is served from the cache
+		w := read("&thumb=200")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" {
+			t.Errorf("expected Content-Type image/jpeg, got %s", ct)
+		}
+		if w.Body.Len() >= full.Body.Len() {
+			t.Errorf("expected the thumbnail (%d bytes) to be smaller than the original (%d bytes)", w.Body.Len(), full.Body.Len())
+		}
+		config, err := jpeg.DecodeConfig(w.Body)
+		if err != nil {
+			t.Fatalf("failed to decode thumbnail: %v", err)
+		}
+		if config.Width != 200 || config.Height != 133 {
+			t.Errorf("expected a 200x133 thumbnail, got %dx%d", config.Width, config.Height)
+		}
+		if w.Header().Get("ETag") == full.Header().Get("ETag") {
+			t.Error("expected the thumbnail to have its own ETag")
+		}
+	}
+
+	for _, thumb := range []string{"0", "8", "5000", "big"} {
+		if w := read("&thumb=" + thumb); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for thumb=%s, got %d", thumb, w.Code)
+		}
+	}
+}
+
+func TestDeleteAsset(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	del := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/read?path="+path, nil))
+		return w
+	}
+
+	if err := os.MkdirAll(browse.ScreenshotDir, 0o755); err != nil {
+		t.Fatalf("failed to create screenshot dir: %v", err)
+	}
+	f, err := os.CreateTemp(browse.ScreenshotDir, "delete-*.png")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	w := del(f.Name())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp["status"] != "deleted" {
+		t.Errorf("unexpected response %s: %v", w.Body.String(), err)
+	}
+	if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+		t.Errorf("expected file to be removed, stat error: %v", err)
+	}
+
+	if w := del(f.Name()); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for missing file, got %d", w.Code)
+	}
+
+	outside := filepath.Join(t.TempDir(), "keep.txt")
+	if err := os.WriteFile(outside, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{outside, browse.ScreenshotDir + "/../" + filepath.Base(outside)} {
+		if w := del(path); w.Code != http.StatusForbidden {
+			t.Errorf("expected status 403 for %s, got %d", path, w.Code)
+		}
+	}
+	// A symlinked directory inside the screenshot dir doesn't reach outside it
+	link := filepath.Join(browse.ScreenshotDir, "delete-link-"+filepath.Base(t.TempDir()))
+	if err := os.Symlink(filepath.Dir(outside), link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	defer os.Remove(link)
+	if w := del(filepath.Join(link, filepath.Base(outside))); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 through a symlinked directory, got %d", w.Code)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("expected file outside the screenshot dir to remain: %v", err)
+	}
+
+	// GET is still handled by handleRead.
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/read?path="+outside, nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected GET to still be handled by handleRead, got %d", w.Code)
+	}
+}
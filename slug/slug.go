@@ -2,13 +2,17 @@ package slug
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
 	"strings"
+	"text/template"
 	"time"
 
 	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/models"
 )
@@ -20,30 +24,121 @@ type LLMServiceProvider interface {
 	GetModelInfo(modelID string) *models.ModelInfo
 }
 
+// LockRetries is how many times a slug update is retried when SQLite reports
+// the database as busy or locked. LockRetryBackoff is the delay before the
+// first retry; it doubles on each subsequent one.
+var (
+	LockRetries      = 5
+	LockRetryBackoff = 50 * time.Millisecond
+)
+
+// PromptData is what slug prompt templates are executed with.
+type PromptData struct {
+	UserMessage string // the message the conversation starts with
+}
+
+// MessagePlaceholder is the original way for a slug prompt template to
+// include the user message. It is still accepted, and means the same as
+// {{.UserMessage}}.
+const MessagePlaceholder = "{{message}}"
+
+// DefaultPromptTemplate is the prompt used to ask the LLM for a slug. Custom
+// prompts are text/template templates executed with PromptData.
+const DefaultPromptTemplate = `Generate a short, descriptive slug (2-6 words, lowercase, hyphen-separated) for a conversation that starts with this user message:
+
+{{.UserMessage}}
+
+The slug should:
+- Be concise and descriptive
+- Use only lowercase letters, numbers, and hyphens
+- Capture the main topic or intent
+- Be suitable as a filename or URL path
+
+Respond with only the slug, nothing else.`
+
+// ValidatePromptTemplate checks that a custom slug prompt template parses
+// and includes the user message.
+func ValidatePromptTemplate(promptTemplate string) error {
+	const probe = "\x00user message\x00"
+	prompt, err := renderPrompt(promptTemplate, probe)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(prompt, probe) {
+		return fmt.Errorf("slug prompt template must include the user message with {{.UserMessage}}")
+	}
+	return nil
+}
+
+// renderPrompt executes promptTemplate (empty uses DefaultPromptTemplate)
+// for userMessage.
+func renderPrompt(promptTemplate, userMessage string) (string, error) {
+	if promptTemplate == "" {
+		promptTemplate = DefaultPromptTemplate
+	}
+	tmpl, err := template.New("slug prompt").
+		Funcs(template.FuncMap{"message": func() string { return userMessage }}).
+		Parse(promptTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid slug prompt template: %w", err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, PromptData{UserMessage: userMessage}); err != nil {
+		return "", fmt.Errorf("invalid slug prompt template: %w", err)
+	}
+	return buf.String(), nil
+}
+
 // GenerateSlug generates a slug for a conversation and updates the database
 // If conversationModelID is provided, it will be used as a fallback if no model is tagged with "slug"
-func GenerateSlug(ctx context.Context, llmProvider LLMServiceProvider, database *db.DB, logger *slog.Logger, conversationID, userMessage, conversationModelID string) (string, error) {
-	baseSlug, err := generateSlugText(ctx, llmProvider, logger, userMessage, conversationModelID)
+// promptTemplate is a text/template for the prompt sent to the LLM, executed with PromptData
+// (empty uses DefaultPromptTemplate).
+// If allowHeuristicSlug is set and every model fails, the slug is derived from the message
+// itself with FallbackSlug, so conversations are titled even without a working LLM.
+// If the user has named the conversation, its slug is left alone and returned unchanged.
+func GenerateSlug(ctx context.Context, llmProvider LLMServiceProvider, database *db.DB, logger *slog.Logger, conversationID, userMessage, conversationModelID, promptTemplate string, allowHeuristicSlug bool) (string, error) {
+	if conv, err := database.GetConversationByID(ctx, conversationID); err == nil && conv.SlugUserSet {
+		logger.Debug("Keeping user-set slug", "conversationID", conversationID)
+		return userSlug(conv), nil
+	}
+
+	baseSlug, err := generateSlugText(ctx, llmProvider, logger, userMessage, conversationModelID, promptTemplate)
 	if err != nil {
-		return "", err
+		if !allowHeuristicSlug {
+			return "", err
+		}
+		baseSlug = FallbackSlug(userMessage)
+		if baseSlug == "" {
+			return "", err
+		}
+		logger.Warn("LLM slug generation failed, deriving slug from message", "conversationID", conversationID, "slug", baseSlug, "error", err)
 	}
 
-	// Try to update with the base slug first, then with numeric suffixes if needed
+	// Try the base slug first, then "-1" so the common single collision stays
+	// readable, then random suffixes, any one of which is very likely free
 	slug := baseSlug
-	for attempt := 0; attempt < 100; attempt++ {
-		_, err = database.UpdateConversationSlug(ctx, conversationID, slug)
+	for attempt := 0; attempt < maxSlugAttempts; attempt++ {
+		conv, err := updateSlug(ctx, database, logger, conversationID, slug)
 		if err == nil {
 			// Success!
 			logger.Info("Generated slug for conversation", "conversationID", conversationID, "slug", slug)
 			return slug, nil
 		}
+		if errors.Is(err, db.ErrSlugUserSet) {
+			// The user renamed the conversation while the slug was being generated.
+			logger.Debug("Keeping user-set slug", "conversationID", conversationID)
+			return userSlug(conv), nil
+		}
 
 		// Check if this is a unique constraint violation
 		if strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") ||
 			strings.Contains(strings.ToLower(err.Error()), "unique constraint") ||
 			strings.Contains(strings.ToLower(err.Error()), "duplicate") {
-			// Try with a numeric suffix
-			slug = fmt.Sprintf("%s-%d", baseSlug, attempt+1)
+			if attempt == 0 {
+				slug = baseSlug + "-1"
+			} else {
+				slug = baseSlug + "-" + randomSuffix()
+			}
 			continue
 		}
 
@@ -51,8 +146,58 @@ func GenerateSlug(ctx context.Context, llmProvider LLMServiceProvider, database
 		return "", fmt.Errorf("failed to update conversation slug: %w", err)
 	}
 
-	// If we've tried 100 times and still failed, give up
-	return "", fmt.Errorf("failed to generate unique slug after 100 attempts")
+	return "", fmt.Errorf("failed to generate unique slug after %d attempts", maxSlugAttempts)
+}
+
+// maxSlugAttempts bounds how many slugs GenerateSlug tries before giving up.
+const maxSlugAttempts = 10
+
+// suffixAlphabet is what random slug suffixes are made of (base36).
+const suffixAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// randomSuffix returns four random base36 characters to make a slug unique.
+func randomSuffix() string {
+	var b [4]byte
+	rand.Read(b[:])
+	for i := range b {
+		b[i] = suffixAlphabet[int(b[i])%len(suffixAlphabet)]
+	}
+	return string(b[:])
+}
+
+// updateSlug sets the conversation's slug, retrying with backoff while the database is locked.
+// It never replaces a slug the user set; see db.DB.UpdateConversationAutoSlug.
+func updateSlug(ctx context.Context, database *db.DB, logger *slog.Logger, conversationID, slug string) (*generated.Conversation, error) {
+	backoff := LockRetryBackoff
+	for retry := 0; ; retry++ {
+		conv, err := database.UpdateConversationAutoSlug(ctx, conversationID, slug)
+		if err == nil || !isLockError(err) || retry >= LockRetries {
+			return conv, err
+		}
+		logger.Debug("Database locked while updating slug, retrying", "conversationID", conversationID, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func userSlug(conv *generated.Conversation) string {
+	if conv.Slug == nil {
+		return ""
+	}
+	return *conv.Slug
+}
+
+// isLockError reports whether err is a transient SQLite busy/locked error.
+func isLockError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "sqlite_busy") ||
+		strings.Contains(msg, "sqlite_locked") ||
+		strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked")
 }
 
 // generateSlugText generates a human-readable slug for a conversation based on the user message
@@ -61,13 +206,13 @@ func GenerateSlug(ctx context.Context, llmProvider LLMServiceProvider, database
 // 2. Try models tagged with "slug" (try the LLM call; if it fails, continue)
 // 3. Try models tagged with "slug-backup"
 // 4. Fall back to the conversation's model (conversationModelID)
-func generateSlugText(ctx context.Context, llmProvider LLMServiceProvider, logger *slog.Logger, userMessage, conversationModelID string) (string, error) {
+func generateSlugText(ctx context.Context, llmProvider LLMServiceProvider, logger *slog.Logger, userMessage, conversationModelID, promptTemplate string) (string, error) {
 	// If conversation is using predictable model, use it for slug generation too
 	if conversationModelID == "predictable" {
 		llmService, err := llmProvider.GetService("predictable")
 		if err == nil {
 			logger.Debug("Using predictable model for slug generation")
-			return callSlugLLM(ctx, llmService, userMessage)
+			return callSlugLLM(ctx, llmService, userMessage, promptTemplate)
 		}
 		logger.Debug("Predictable model not available for slug generation", "error", err)
 	}
@@ -85,7 +230,7 @@ func generateSlugText(ctx context.Context, llmProvider LLMServiceProvider, logge
 				continue
 			}
 			logger.Debug("Trying model for slug generation", "model", modelID, "tag", tag)
-			slug, err := callSlugLLM(ctx, llmService, userMessage)
+			slug, err := callSlugLLM(ctx, llmService, userMessage, promptTemplate)
 			if err == nil {
 				return slug, nil
 			}
@@ -98,7 +243,7 @@ func generateSlugText(ctx context.Context, llmProvider LLMServiceProvider, logge
 		llmService, err := llmProvider.GetService(conversationModelID)
 		if err == nil {
 			logger.Debug("Using conversation model for slug generation", "model", conversationModelID)
-			return callSlugLLM(ctx, llmService, userMessage)
+			return callSlugLLM(ctx, llmService, userMessage, promptTemplate)
 		}
 		logger.Debug("Conversation model not available for slug generation", "model", conversationModelID, "error", err)
 	}
@@ -117,18 +262,11 @@ func hasTag(tags, tag string) bool {
 }
 
 // callSlugLLM calls an LLM service to generate a slug from a user message.
-func callSlugLLM(ctx context.Context, llmService llm.Service, userMessage string) (string, error) {
-	slugPrompt := fmt.Sprintf(`Generate a short, descriptive slug (2-6 words, lowercase, hyphen-separated) for a conversation that starts with this user message:
-
-%s
-
-The slug should:
-- Be concise and descriptive
-- Use only lowercase letters, numbers, and hyphens
-- Capture the main topic or intent
-- Be suitable as a filename or URL path
-
-Respond with only the slug, nothing else.`, userMessage)
+func callSlugLLM(ctx context.Context, llmService llm.Service, userMessage, promptTemplate string) (string, error) {
+	slugPrompt, err := renderPrompt(promptTemplate, userMessage)
+	if err != nil {
+		return "", err
+	}
 
 	message := llm.Message{
 		Role: llm.MessageRoleUser,
@@ -170,6 +308,23 @@ Respond with only the slug, nothing else.`, userMessage)
 	return slug, nil
 }
 
+// FallbackSlug derives a slug directly from the first few words of a message,
+// for use when no LLM is available. It returns "" if nothing usable remains.
+func FallbackSlug(message string) string {
+	var words []string
+	for _, w := range strings.Fields(message) {
+		w = Sanitize(w)
+		if w == "" {
+			continue
+		}
+		words = append(words, w)
+		if len(words) == 6 || len(strings.Join(words, "-")) >= 40 {
+			break
+		}
+	}
+	return Sanitize(strings.Join(words, "-"))
+}
+
 // Sanitize cleans a string to be a valid slug
 func Sanitize(input string) string {
 	// Convert to lowercase
@@ -2,10 +2,15 @@ package slug
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/llm"
@@ -148,7 +153,7 @@ func TestGenerateSlug_DatabaseIntegration(t *testing.T) {
 	}
 
 	// Generate first slug - should succeed with "test-slug"
-	slug1, err := GenerateSlug(ctx, mockLLM, database, logger, conv1.ConversationID, "Test message", "test-model")
+	slug1, err := GenerateSlug(ctx, mockLLM, database, logger, conv1.ConversationID, "Test message", "test-model", "", false)
 	if err != nil {
 		t.Fatalf("Failed to generate first slug: %v", err)
 	}
@@ -163,7 +168,7 @@ func TestGenerateSlug_DatabaseIntegration(t *testing.T) {
 	}
 
 	// Generate second slug - should get "test-slug-1" due to conflict
-	slug2, err := GenerateSlug(ctx, mockLLM, database, logger, conv2.ConversationID, "Test message", "test-model")
+	slug2, err := GenerateSlug(ctx, mockLLM, database, logger, conv2.ConversationID, "Test message", "test-model", "", false)
 	if err != nil {
 		t.Fatalf("Failed to generate second slug: %v", err)
 	}
@@ -177,13 +182,13 @@ func TestGenerateSlug_DatabaseIntegration(t *testing.T) {
 		t.Fatalf("Failed to create third conversation: %v", err)
 	}
 
-	// Generate third slug - should get "test-slug-2" due to conflict
-	slug3, err := GenerateSlug(ctx, mockLLM, database, logger, conv3.ConversationID, "Test message", "test-model")
+	// Generate third slug - both "test-slug" and "test-slug-1" are taken, so it gets a random suffix
+	slug3, err := GenerateSlug(ctx, mockLLM, database, logger, conv3.ConversationID, "Test message", "test-model", "", false)
 	if err != nil {
 		t.Fatalf("Failed to generate third slug: %v", err)
 	}
-	if slug3 != "test-slug-2" {
-		t.Errorf("Expected third slug to be 'test-slug-2', got %q", slug3)
+	if !regexp.MustCompile(`^test-slug-[0-9a-z]{4}$`).MatchString(slug3) {
+		t.Errorf("Expected third slug to match 'test-slug-xxxx', got %q", slug3)
 	}
 
 	// Verify all slugs are different
@@ -194,6 +199,326 @@ func TestGenerateSlug_DatabaseIntegration(t *testing.T) {
 	t.Logf("Successfully generated unique slugs: %q, %q, %q", slug1, slug2, slug3)
 }
 
+// TestGenerateSlug_KeepsUserSetSlug tests that a conversation the user has
+// renamed keeps its name.
+func TestGenerateSlug_KeepsUserSetSlug(t *testing.T) {
+	database, err := db.New(db.Config{DSN: t.TempDir() + "/slug_test.db"})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if err := database.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := database.RenameConversation(ctx, conv.ConversationID, "my-name"); err != nil {
+		t.Fatalf("Failed to rename conversation: %v", err)
+	}
+
+	mockLLM := &MockLLMProvider{Service: &MockLLMService{ResponseText: "generated-slug"}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	got, err := GenerateSlug(ctx, mockLLM, database, logger, conv.ConversationID, "Test message", "test-model", "", false)
+	if err != nil {
+		t.Fatalf("GenerateSlug failed: %v", err)
+	}
+	if got != "my-name" {
+		t.Errorf("Expected user-set slug %q to be returned, got %q", "my-name", got)
+	}
+
+	conv, err = database.GetConversationByID(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if conv.Slug == nil || *conv.Slug != "my-name" {
+		t.Errorf("Expected slug to stay %q, got %v", "my-name", conv.Slug)
+	}
+
+	// The guarded update refuses to replace it too.
+	if _, err := database.UpdateConversationAutoSlug(ctx, conv.ConversationID, "other"); !errors.Is(err, db.ErrSlugUserSet) {
+		t.Errorf("Expected ErrSlugUserSet, got %v", err)
+	}
+}
+
+// TestGenerateSlug_RetriesWhileLocked tests that a slug update survives another
+// connection holding the write lock for longer than the busy timeout.
+func TestGenerateSlug_RetriesWhileLocked(t *testing.T) {
+	tempDB := t.TempDir() + "/slug_locked_test.db"
+	database, err := db.New(db.Config{DSN: tempDB})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if err := database.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	// Hold the write lock from a separate connection past the 1s busy timeout.
+	other, err := sql.Open("sqlite", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open second connection: %v", err)
+	}
+	defer other.Close()
+	lockConn, err := other.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := lockConn.ExecContext(ctx, "BEGIN IMMEDIATE;"); err != nil {
+		t.Fatalf("Failed to take write lock: %v", err)
+	}
+	go func() {
+		time.Sleep(1500 * time.Millisecond)
+		lockConn.ExecContext(ctx, "ROLLBACK;")
+		lockConn.Close()
+	}()
+
+	mockLLM := &MockLLMProvider{Service: &MockLLMService{ResponseText: "locked-slug"}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	slug, err := GenerateSlug(ctx, mockLLM, database, logger, conv.ConversationID, "Test message", "test-model", "", false)
+	if err != nil {
+		t.Fatalf("Expected slug generation to survive lock contention, got: %v", err)
+	}
+	if slug != "locked-slug" {
+		t.Errorf("Expected slug 'locked-slug', got %q", slug)
+	}
+}
+
+// promptRecordingService records the prompt it was sent
+type promptRecordingService struct {
+	MockLLMService
+	prompt string
+}
+
+func (m *promptRecordingService) Do(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	m.prompt = req.Messages[0].Content[0].Text
+	return m.MockLLMService.Do(ctx, req)
+}
+
+func TestCallSlugLLM_PromptTemplate(t *testing.T) {
+	svc := &promptRecordingService{MockLLMService: MockLLMService{ResponseText: "titulo-corto"}}
+
+	if _, err := callSlugLLM(context.Background(), svc, "fix the login bug", ""); err != nil {
+		t.Fatalf("callSlugLLM failed: %v", err)
+	}
+	if !strings.Contains(svc.prompt, "2-6 words") || !strings.Contains(svc.prompt, "fix the login bug") {
+		t.Errorf("expected default prompt with message, got %q", svc.prompt)
+	}
+
+	slug, err := callSlugLLM(context.Background(), svc, "fix the login bug", "Título corto en español para: {{message}}")
+	if err != nil {
+		t.Fatalf("callSlugLLM failed: %v", err)
+	}
+	if svc.prompt != "Título corto en español para: fix the login bug" {
+		t.Errorf("expected custom prompt, got %q", svc.prompt)
+	}
+	if slug != "titulo-corto" {
+		t.Errorf("expected slug 'titulo-corto', got %q", slug)
+	}
+
+	// Templates get the message as .UserMessage, and can use template actions
+	tmpl := `Slug for {{printf "%q" .UserMessage}}{{if gt (len .UserMessage) 5}} (long){{end}}`
+	if _, err := callSlugLLM(context.Background(), svc, "fix the login bug", tmpl); err != nil {
+		t.Fatalf("callSlugLLM failed: %v", err)
+	}
+	if svc.prompt != `Slug for "fix the login bug" (long)` {
+		t.Errorf("expected rendered template, got %q", svc.prompt)
+	}
+
+	if _, err := callSlugLLM(context.Background(), svc, "fix the login bug", "{{.Missing}}"); err == nil {
+		t.Error("expected error for a template that fails to execute")
+	}
+}
+
+// TestGenerateSlug_PromptTemplate tests that a custom template is rendered
+// and sent to the LLM when generating a conversation's slug
+func TestGenerateSlug_PromptTemplate(t *testing.T) {
+	tempDB := t.TempDir() + "/slug_template_test.db"
+	database, err := db.New(db.Config{DSN: tempDB})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if err := database.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	svc := &promptRecordingService{MockLLMService: MockLLMService{ResponseText: "ticket-login"}}
+	provider := &promptRecordingProvider{service: svc}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	slug, err := GenerateSlug(ctx, provider, database, logger, conv.ConversationID, "fix the login bug", "test-model", "Prefix with the ticket area: {{.UserMessage}}", false)
+	if err != nil {
+		t.Fatalf("GenerateSlug failed: %v", err)
+	}
+	if svc.prompt != "Prefix with the ticket area: fix the login bug" {
+		t.Errorf("expected the rendered template to reach the LLM, got %q", svc.prompt)
+	}
+	if slug != "ticket-login" {
+		t.Errorf("expected slug 'ticket-login', got %q", slug)
+	}
+}
+
+// promptRecordingProvider serves one promptRecordingService as every model
+type promptRecordingProvider struct {
+	service *promptRecordingService
+}
+
+func (p *promptRecordingProvider) GetService(modelID string) (llm.Service, error) {
+	return p.service, nil
+}
+
+func (p *promptRecordingProvider) GetAvailableModels() []string {
+	return []string{"test-model"}
+}
+
+func (p *promptRecordingProvider) GetModelInfo(modelID string) *models.ModelInfo {
+	return nil
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	for _, valid := range []string{"Name this: {{message}}", "Name this: {{.UserMessage}}", DefaultPromptTemplate} {
+		if err := ValidatePromptTemplate(valid); err != nil {
+			t.Errorf("expected valid template %q, got %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"Name this conversation", "Name this: {{.UserMessage", "Name this: {{.Message}}", "{{if .UserMessage}}ok{{end}}"} {
+		if err := ValidatePromptTemplate(invalid); err == nil {
+			t.Errorf("expected error for template %q", invalid)
+		}
+	}
+}
+
+func TestFallbackSlug(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Fix the login bug", "fix-the-login-bug"},
+		{"Please help me refactor the database layer of this app today", "please-help-me-refactor-the-database"},
+		{"Supercalifragilisticexpialidocious antidisestablishmentarianism words", "supercalifragilisticexpialidocious-antidisestablishmentarian"},
+		{"what's wrong with `main.go`?", "whats-wrong-with-maingo"},
+		{"日本語だけ", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := FallbackSlug(test.input); got != test.expected {
+			t.Errorf("FallbackSlug(%q) = %q, expected %q", test.input, got, test.expected)
+		}
+	}
+}
+
+// TestGenerateSlug_FallbackWhenLLMUnavailable tests that a slug is still set when no model works
+func TestGenerateSlug_FallbackWhenLLMUnavailable(t *testing.T) {
+	tempDB := t.TempDir() + "/slug_fallback_test.db"
+	database, err := db.New(db.Config{DSN: tempDB})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if err := database.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	slug, err := GenerateSlug(ctx, &MockLLMProviderWithServiceError{}, database, logger, conv.ConversationID, "Fix the login bug", "test-model", "", true)
+	if err != nil {
+		t.Fatalf("Expected fallback slug, got error: %v", err)
+	}
+	if slug != "fix-the-login-bug" {
+		t.Errorf("Expected fallback slug 'fix-the-login-bug', got %q", slug)
+	}
+
+	// Nothing usable in the message: the LLM error is returned.
+	if _, err := GenerateSlug(ctx, &MockLLMProviderWithServiceError{}, database, logger, conv.ConversationID, "!!!", "test-model", "", true); err == nil {
+		t.Error("Expected error when no slug can be derived")
+	}
+}
+
+// TestGenerateSlug_HeuristicWithoutModels tests the heuristic slug when the provider has no usable models
+func TestGenerateSlug_HeuristicWithoutModels(t *testing.T) {
+	tempDB := t.TempDir() + "/slug_heuristic_test.db"
+	database, err := db.New(db.Config{DSN: tempDB})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if err := database.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	message := "Why does `go test` hang on CI_builds? Please investigate the flaky runner"
+
+	// Without the heuristic, there is nothing to fall back on.
+	if _, err := GenerateSlug(ctx, &MockLLMProviderWithError{}, database, logger, conv.ConversationID, message, "", "", false); err == nil {
+		t.Fatal("Expected error with no usable models and no heuristic")
+	}
+
+	slug, err := GenerateSlug(ctx, &MockLLMProviderWithError{}, database, logger, conv.ConversationID, message, "", "", true)
+	if err != nil {
+		t.Fatalf("Expected heuristic slug, got error: %v", err)
+	}
+	if want := "why-does-go-test-hang-on"; slug != want {
+		t.Errorf("Expected heuristic slug %q, got %q", want, slug)
+	}
+	if slug != Sanitize(slug) {
+		t.Errorf("Heuristic slug %q is not sanitized", slug)
+	}
+	updated, err := database.GetConversationByID(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.Slug == nil || *updated.Slug != slug {
+		t.Errorf("Expected conversation slug %q, got %v", slug, updated.Slug)
+	}
+}
+
+func TestIsLockError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("Tx begin: database is locked (5) (SQLITE_BUSY)"), true},
+		{errors.New("database table is locked"), true},
+		{errors.New("UNIQUE constraint failed: conversations.slug"), false},
+		{errors.New("sql: database is closed"), false},
+	}
+	for _, test := range tests {
+		if got := isLockError(test.err); got != test.expected {
+			t.Errorf("isLockError(%q) = %v, expected %v", test.err, got, test.expected)
+		}
+	}
+}
+
 // MockLLMServiceWithError provides a mock LLM service that returns an error
 type MockLLMServiceWithError struct{}
 
@@ -248,7 +573,7 @@ func TestGenerateSlug_LLMError(t *testing.T) {
 	}))
 
 	// Test that LLM error is properly propagated (pass a model ID so we get a service)
-	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model")
+	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model", "")
 	if err == nil {
 		t.Error("Expected error from LLM service, got nil")
 	}
@@ -266,7 +591,7 @@ func TestGenerateSlug_NoModelsAvailable(t *testing.T) {
 	}))
 
 	// Test that error is returned when no models are available
-	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "")
+	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "", "")
 	if err == nil {
 		t.Error("Expected error when no models available, got nil")
 	}
@@ -284,7 +609,7 @@ func TestGenerateSlug_EmptyResponse(t *testing.T) {
 		Level: slog.LevelWarn,
 	}))
 
-	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model")
+	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model", "")
 	if err == nil {
 		t.Error("Expected error for empty LLM response, got nil")
 	}
@@ -338,7 +663,7 @@ func TestGenerateSlug_SanitizationError(t *testing.T) {
 		Level: slog.LevelWarn,
 	}))
 
-	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model")
+	_, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model", "")
 	if err == nil {
 		t.Error("Expected error for empty slug after sanitization, got nil")
 	}
@@ -394,7 +719,7 @@ func TestGenerateSlug_DatabaseError(t *testing.T) {
 	}
 	closedDB.Close()
 
-	_, err = GenerateSlug(ctx, mockLLM, closedDB, logger, "test-conversation-id", "Test message", "test-model")
+	_, err = GenerateSlug(ctx, mockLLM, closedDB, logger, "test-conversation-id", "Test message", "test-model", "", false)
 	if err == nil {
 		t.Error("Expected database error, got nil")
 	}
@@ -414,7 +739,7 @@ func TestGenerateSlug_PredictableModel(t *testing.T) {
 	}))
 
 	// Test that predictable model is used when conversationModelID is "predictable"
-	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "predictable")
+	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "predictable", "")
 	if err != nil {
 		t.Fatalf("Failed to generate slug with predictable model: %v", err)
 	}
@@ -437,7 +762,7 @@ func TestGenerateSlug_ConversationModelFallback(t *testing.T) {
 	}))
 
 	// Test that fallback to conversation model works when no slug-tagged models exist
-	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "my-custom-model")
+	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "my-custom-model", "")
 	if err != nil {
 		t.Fatalf("Failed to generate slug with conversation model fallback: %v", err)
 	}
@@ -485,7 +810,7 @@ func TestGenerateSlug_FallbackToSlugBackup(t *testing.T) {
 		Level: slog.LevelDebug,
 	}))
 
-	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "")
+	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "", "")
 	if err != nil {
 		t.Fatalf("Expected fallback to slug-backup model, got error: %v", err)
 	}
@@ -555,7 +880,7 @@ func TestGenerateSlug_ThinkingBlocks(t *testing.T) {
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
 
-	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model")
+	slug, err := generateSlugText(context.Background(), mockLLM, logger, "Test message", "test-model", "")
 	if err != nil {
 		t.Fatalf("Failed to generate slug with thinking blocks: %v", err)
 	}
@@ -2,15 +2,38 @@ package subpub
 
 import (
 	"context"
+	"errors"
 	"sync"
 )
 
+// ErrTooManySubscribers is returned by TrySubscribe when the SubPub already
+// has the maximum number of subscribers.
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// DefaultTopic is the topic Subscribe, TrySubscribe and Publish use.
+const DefaultTopic = ""
+
 type SubPub[K any] struct {
 	mu          sync.Mutex
 	subscribers []*subscriber[K]
+
+	// replay is a ring buffer of the most recently published messages, so
+	// a subscriber whose index is behind them catches up on subscribing.
+	// It is nil if replay is off.
+	replay     []entry[K]
+	replayNext int // where the next entry goes
+	replayLen  int // number of entries in use
+}
+
+// entry is a message published at an index on a topic.
+type entry[K any] struct {
+	topic   string
+	idx     int64
+	message K
 }
 
 type subscriber[K any] struct {
+	topic  string
 	idx    int64
 	ch     chan K
 	ctx    context.Context
@@ -23,24 +46,68 @@ func New[K any]() *SubPub[K] {
 	}
 }
 
+// NewWithReplay is like New, but keeps the last size published messages and
+// replays those after a new subscriber's index before any new ones. This
+// covers messages published between reading a snapshot and subscribing
+// from its last index. Broadcasts aren't kept.
+func NewWithReplay[K any](size int) *SubPub[K] {
+	sp := New[K]()
+	if size > 0 {
+		sp.replay = make([]entry[K], size)
+	}
+	return sp
+}
+
 // Subscribe registers an interest in messages after the given index, subject to the
 // expiration/cancellation of the provided context. The returned function blocks
 // until a new message, and can return false as the second arguent if the subscription
 // is done for.
 func (sp *SubPub[K]) Subscribe(ctx context.Context, idx int64) func() (K, bool) {
+	return sp.SubscribeTopic(ctx, DefaultTopic, idx)
+}
+
+// SubscribeTopic is like Subscribe, but only receives messages published to
+// topic, plus broadcasts.
+func (sp *SubPub[K]) SubscribeTopic(ctx context.Context, topic string, idx int64) func() (K, bool) {
+	next, _ := sp.TrySubscribeTopic(ctx, topic, idx, 0)
+	return next
+}
+
+// TrySubscribe is like Subscribe, but fails with ErrTooManySubscribers if
+// there are already max live subscribers. A max of 0 means no limit.
+func (sp *SubPub[K]) TrySubscribe(ctx context.Context, idx int64, max int) (func() (K, bool), error) {
+	return sp.TrySubscribeTopic(ctx, DefaultTopic, idx, max)
+}
+
+// TrySubscribeTopic is like SubscribeTopic, but fails with
+// ErrTooManySubscribers if there are already max live subscribers across
+// all topics. A max of 0 means no limit.
+func (sp *SubPub[K]) TrySubscribeTopic(ctx context.Context, topic string, idx int64, max int) (func() (K, bool), error) {
+	sp.mu.Lock()
+	if max > 0 && sp.liveSubscribers() >= max {
+		sp.mu.Unlock()
+		return nil, ErrTooManySubscribers
+	}
+
 	// Create a child context so we can cancel the subscription independently
 	subCtx, cancel := context.WithCancel(ctx)
 
+	missed := sp.missed(topic, idx)
+
 	// Buffered channel to avoid blocking publishers
-	ch := make(chan K, 10)
+	ch := make(chan K, 10+len(missed))
 	sub := &subscriber[K]{
+		topic:  topic,
 		idx:    idx,
 		ch:     ch,
 		ctx:    subCtx,
 		cancel: cancel,
 	}
+	for _, e := range missed {
+		ch <- e.message
+		sub.idx = e.idx
+	}
 
-	sp.mu.Lock()
 	sp.subscribers = append(sp.subscribers, sub)
 	sp.mu.Unlock()
 
@@ -65,15 +132,28 @@ func (sp *SubPub[K]) Subscribe(ctx context.Context, idx int64) func() (K, bool)
 			var zero K
 			return zero, false
 		}
-	}
+	}, nil
 }
 
 // Publish sends a message to all subscribers waiting for messages after the given index.
 // Subscribers that are "behind" should get a disconnection message.
 func (sp *SubPub[K]) Publish(idx int64, message K) {
+	sp.PublishTopic(DefaultTopic, idx, message)
+}
+
+// PublishTopic is like Publish, but only sends to subscribers of topic.
+// Indexes are tracked per subscriber, so each topic can number its
+// messages independently.
+func (sp *SubPub[K]) PublishTopic(topic string, idx int64, message K) {
 	sp.mu.Lock()
 	defer sp.mu.Unlock()
 
+	if sp.replay != nil {
+		sp.replay[sp.replayNext] = entry[K]{topic: topic, idx: idx, message: message}
+		sp.replayNext = (sp.replayNext + 1) % len(sp.replay)
+		sp.replayLen = min(sp.replayLen+1, len(sp.replay))
+	}
+
 	// Notify subscribers and filter out disconnected ones
 	remaining := sp.subscribers[:0]
 	for _, sub := range sp.subscribers {
@@ -86,8 +166,8 @@ func (sp *SubPub[K]) Publish(idx int64, message K) {
 		default:
 		}
 
-		// Only send to subscribers waiting for messages after an index < idx
-		if sub.idx < idx {
+		// Only send to subscribers of this topic waiting for messages after an index < idx
+		if sub.topic == topic && sub.idx < idx {
 			// Try to send the message
 			select {
 			case sub.ch <- message:
@@ -100,14 +180,14 @@ func (sp *SubPub[K]) Publish(idx int64, message K) {
 				sub.cancel()
 			}
 		} else {
-			// This subscriber is not interested yet (already has this index or beyond)
+			// This subscriber is on another topic or not interested yet (already has this index or beyond)
 			remaining = append(remaining, sub)
 		}
 	}
 	sp.subscribers = remaining
 }
 
-// Broadcast sends a message to ALL subscribers regardless of their current index.
+// Broadcast sends a message to ALL subscribers regardless of their topic or current index.
 // This is used for out-of-band notifications like conversation list updates.
 func (sp *SubPub[K]) Broadcast(message K) {
 	sp.mu.Lock()
@@ -133,3 +213,85 @@ func (sp *SubPub[K]) Broadcast(message K) {
 	}
 	sp.subscribers = remaining
 }
+
+// Rewind forgets messages published after idx, for when they have been
+// withdrawn and their indexes will be reused: they are dropped from the
+// replay buffer, and subscribers already past idx go back to it, so they
+// receive the messages published at those indexes next.
+func (sp *SubPub[K]) Rewind(idx int64) {
+	sp.RewindTopic(DefaultTopic, idx)
+}
+
+// RewindTopic is like Rewind, but only for topic.
+func (sp *SubPub[K]) RewindTopic(topic string, idx int64) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if sp.replay != nil {
+		var kept []entry[K]
+		start := sp.replayNext - sp.replayLen + len(sp.replay)
+		for i := range sp.replayLen {
+			e := sp.replay[(start+i)%len(sp.replay)]
+			if e.topic != topic || e.idx <= idx {
+				kept = append(kept, e)
+			}
+		}
+		clear(sp.replay)
+		copy(sp.replay, kept)
+		sp.replayLen = len(kept)
+		sp.replayNext = len(kept) % len(sp.replay)
+	}
+
+	for _, sub := range sp.subscribers {
+		if sub.topic == topic && sub.idx > idx {
+			sub.idx = idx
+		}
+	}
+}
+
+// missed returns the replay buffer's entries on topic after idx, oldest
+// first. sp.mu must be held.
+func (sp *SubPub[K]) missed(topic string, idx int64) []entry[K] {
+	var missed []entry[K]
+	start := sp.replayNext - sp.replayLen + len(sp.replay)
+	for i := range sp.replayLen {
+		e := sp.replay[(start+i)%len(sp.replay)]
+		if e.topic == topic && e.idx > idx {
+			missed = append(missed, e)
+		}
+	}
+	return missed
+}
+
+// SubscriberCount returns the number of subscribers whose context is still live.
+func (sp *SubPub[K]) SubscriberCount() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.liveSubscribers()
+}
+
+// liveSubscribers counts subscribers whose context is still live. sp.mu must
+// be held.
+func (sp *SubPub[K]) liveSubscribers() int {
+	n := 0
+	for _, sub := range sp.subscribers {
+		if sub.ctx.Err() == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Close disconnects all current subscribers. Their next function returns
+// false once any buffered messages are drained. The SubPub remains usable
+// and accepts new subscriptions afterwards.
+func (sp *SubPub[K]) Close() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	for _, sub := range sp.subscribers {
+		close(sub.ch)
+		sub.cancel()
+	}
+	sp.subscribers = sp.subscribers[:0]
+}
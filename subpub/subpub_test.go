@@ -365,3 +365,195 @@ func TestSubPubSubscriberContextDoneDuringPublish(t *testing.T) {
 		t.Error("Expected closed channel after context cancellation")
 	}
 }
+
+// TestSubPubClose tests that Close disconnects all subscribers
+func TestSubPubClose(t *testing.T) {
+	sp := New[string]()
+	ctx := context.Background()
+
+	next1 := sp.Subscribe(ctx, 0)
+	next2 := sp.Subscribe(ctx, 0)
+	if n := sp.SubscriberCount(); n != 2 {
+		t.Fatalf("Expected 2 subscribers, got %d", n)
+	}
+
+	sp.Close()
+	if n := sp.SubscriberCount(); n != 0 {
+		t.Errorf("Expected 0 subscribers after Close, got %d", n)
+	}
+	if _, ok := next1(); ok {
+		t.Error("Expected first subscriber to be disconnected")
+	}
+	if _, ok := next2(); ok {
+		t.Error("Expected second subscriber to be disconnected")
+	}
+
+	// New subscriptions still work after Close.
+	next3 := sp.Subscribe(ctx, 0)
+	sp.Publish(1, "after")
+	if msg, ok := next3(); !ok || msg != "after" {
+		t.Errorf("Expected message after Close, got %q, %v", msg, ok)
+	}
+}
+
+// TestSubPubTrySubscribeLimit tests that TrySubscribe enforces its limit and
+// frees a slot when a subscriber goes away
+func TestSubPubTrySubscribeLimit(t *testing.T) {
+	sp := New[string]()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	if _, err := sp.TrySubscribe(ctx1, 0, 2); err != nil {
+		t.Fatalf("First subscription failed: %v", err)
+	}
+	if _, err := sp.TrySubscribe(context.Background(), 0, 2); err != nil {
+		t.Fatalf("Second subscription failed: %v", err)
+	}
+	if _, err := sp.TrySubscribe(context.Background(), 0, 2); err != ErrTooManySubscribers {
+		t.Fatalf("Expected ErrTooManySubscribers, got %v", err)
+	}
+
+	cancel1()
+	if _, err := sp.TrySubscribe(context.Background(), 0, 2); err != nil {
+		t.Errorf("Expected a slot after a subscriber left, got %v", err)
+	}
+
+	// A limit of 0 means no limit.
+	if _, err := sp.TrySubscribe(context.Background(), 0, 0); err != nil {
+		t.Errorf("Expected no limit, got %v", err)
+	}
+}
+
+// TestSubPubTopics tests that a subscriber only receives messages published
+// to its own topic, plus broadcasts
+func TestSubPubTopics(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sp := New[string]()
+		ctx := context.Background()
+
+		nextA := sp.SubscribeTopic(ctx, "a", 0)
+		nextB := sp.SubscribeTopic(ctx, "b", 0)
+		nextDefault := sp.Subscribe(ctx, 0)
+
+		sp.PublishTopic("a", 1, "for a")
+		sp.PublishTopic("b", 1, "for b")
+		sp.Publish(1, "for default")
+		sp.Broadcast("for all")
+
+		for _, tc := range []struct {
+			name string
+			next func() (string, bool)
+			want []string
+		}{
+			{"a", nextA, []string{"for a", "for all"}},
+			{"b", nextB, []string{"for b", "for all"}},
+			{"default", nextDefault, []string{"for default", "for all"}},
+		} {
+			for _, want := range tc.want {
+				msg, ok := tc.next()
+				if !ok || msg != want {
+					t.Errorf("Topic %s: expected %q, got %q, %v", tc.name, want, msg, ok)
+				}
+			}
+		}
+
+		// Nothing else is queued for topic a
+		go sp.PublishTopic("a", 2, "next for a")
+		if msg, ok := nextA(); !ok || msg != "next for a" {
+			t.Errorf("Topic a: expected %q, got %q, %v", "next for a", msg, ok)
+		}
+	})
+}
+
+// TestSubPubTopicIndexes tests that an index published on one topic doesn't
+// advance subscribers of another
+func TestSubPubTopicIndexes(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sp := New[int]()
+		ctx := context.Background()
+
+		nextA := sp.SubscribeTopic(ctx, "a", 0)
+		sp.PublishTopic("b", 10, 10)
+		sp.PublishTopic("a", 1, 1)
+
+		if msg, ok := nextA(); !ok || msg != 1 {
+			t.Errorf("Expected 1, got %d, %v", msg, ok)
+		}
+	})
+}
+
+// TestSubPubReplay tests that a subscriber with an older index is sent the
+// buffered messages it missed before new ones
+func TestSubPubReplay(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sp := NewWithReplay[int](2)
+		ctx := context.Background()
+
+		sp.Publish(1, 1)
+		sp.Publish(2, 2)
+		sp.Publish(3, 3)
+		sp.PublishTopic("other", 4, 4)
+
+		// Index 1 has fallen out of the buffer, and the other topic's
+		// message isn't replayed
+		next := sp.Subscribe(ctx, 0)
+		go sp.Publish(5, 5)
+		for _, want := range []int{3, 5} {
+			if msg, ok := next(); !ok || msg != want {
+				t.Errorf("Expected %d, got %d, %v", want, msg, ok)
+			}
+		}
+
+		// A subscriber that is up to date gets nothing replayed
+		next = sp.Subscribe(ctx, 5)
+		go sp.Publish(6, 6)
+		if msg, ok := next(); !ok || msg != 6 {
+			t.Errorf("Expected 6, got %d, %v", msg, ok)
+		}
+	})
+}
+
+// TestSubPubNoReplay tests that New keeps no replay buffer
+func TestSubPubNoReplay(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sp := New[int]()
+		ctx := context.Background()
+
+		sp.Publish(1, 1)
+		next := sp.Subscribe(ctx, 0)
+		go sp.Publish(2, 2)
+		if msg, ok := next(); !ok || msg != 2 {
+			t.Errorf("Expected 2, got %d, %v", msg, ok)
+		}
+	})
+}
+
+func TestSubPubRewind(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sp := NewWithReplay[int](4)
+		ctx := context.Background()
+
+		next := sp.Subscribe(ctx, 0)
+		for i := 1; i <= 3; i++ {
+			sp.Publish(int64(i), i)
+			if msg, ok := next(); !ok || msg != i {
+				t.Fatalf("Expected %d, got %d, %v", i, msg, ok)
+			}
+		}
+
+		// Messages 2 and 3 are withdrawn and index 2 is reused
+		sp.Rewind(1)
+		go sp.Publish(2, 20)
+		if msg, ok := next(); !ok || msg != 20 {
+			t.Errorf("Expected 20 after rewinding, got %d, %v", msg, ok)
+		}
+
+		// The withdrawn messages aren't replayed
+		late := sp.Subscribe(ctx, 1)
+		go sp.Publish(3, 30)
+		for _, want := range []int{20, 30} {
+			if msg, ok := late(); !ok || msg != want {
+				t.Errorf("Expected %d, got %d, %v", want, msg, ok)
+			}
+		}
+	})
+}
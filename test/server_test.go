@@ -912,6 +912,13 @@ func (m *inspectableLLMManager) HasModel(modelID string) bool {
 	return modelID == "predictable"
 }
 
+func (m *inspectableLLMManager) ResolveModel(modelID string) string {
+	if modelID == models.DefaultAlias {
+		return "predictable"
+	}
+	return modelID
+}
+
 func (m *inspectableLLMManager) GetModelInfo(modelID string) *models.ModelInfo {
 	return nil
 }
@@ -995,7 +1002,7 @@ func TestScreenshotRouteServesImage(t *testing.T) {
 
 	// Create a fake screenshot file in the expected location
 	id := "testshot"
-	path := browse.GetScreenshotPath(id)
+	path := browse.GetScreenshotPath(id, "png")
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		t.Fatalf("Failed to create screenshot dir: %v", err)
 	}
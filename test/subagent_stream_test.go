@@ -60,6 +60,13 @@ func (m *fakeLLMManager) HasModel(modelID string) bool {
 	return modelID == "predictable"
 }
 
+func (m *fakeLLMManager) ResolveModel(modelID string) string {
+	if modelID == models.DefaultAlias {
+		return "predictable"
+	}
+	return modelID
+}
+
 func (m *fakeLLMManager) GetModelInfo(modelID string) *models.ModelInfo {
 	return nil
 }
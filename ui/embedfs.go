@@ -1,13 +1,18 @@
 package ui
 
 import (
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -112,3 +117,57 @@ func Checksums() map[string]string {
 	}
 	return checksums
 }
+
+// VerifyChecksums checks that every embedded .gz asset hashes to its entry in
+// dist/checksums.json, so a build whose assets and manifest drifted apart is
+// caught at startup rather than as stale-cache bugs from mismatched ETags.
+func VerifyChecksums() error {
+	sub, err := fs.Sub(Dist, "dist")
+	if err != nil {
+		return err
+	}
+	return verifyChecksums(sub)
+}
+
+func verifyChecksums(fsys fs.FS) error {
+	gzFiles, err := fs.Glob(fsys, "*.gz")
+	if err != nil {
+		return err
+	}
+	data, err := fs.ReadFile(fsys, "checksums.json")
+	if errors.Is(err, fs.ErrNotExist) && len(gzFiles) == 0 {
+		// Nothing was compressed, so there is nothing to check.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read checksums.json: %w", err)
+	}
+	var checksums map[string]string
+	if err := json.Unmarshal(data, &checksums); err != nil {
+		return fmt.Errorf("parse checksums.json: %w", err)
+	}
+
+	var problems []string
+	for _, gz := range gzFiles {
+		if _, ok := checksums[strings.TrimSuffix(gz, ".gz")]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: no checksum", gz))
+		}
+	}
+	for file, want := range checksums {
+		content, err := fs.ReadFile(fsys, file+".gz")
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s.gz: %v", file, err))
+			continue
+		}
+		// Same truncated SHA-256 of the compressed file that scripts/build.js writes.
+		sum := sha256.Sum256(content)
+		if got := hex.EncodeToString(sum[:])[:16]; got != want {
+			problems = append(problems, fmt.Sprintf("%s.gz: checksum %s, manifest says %s", file, got, want))
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return fmt.Errorf("embedded UI assets don't match checksums.json:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
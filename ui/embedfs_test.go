@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	sum := sha256.Sum256([]byte("compressed js"))
+	hash := hex.EncodeToString(sum[:])[:16]
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "no compressed assets",
+			fsys: fstest.MapFS{"index.html": {Data: []byte("<html>")}},
+		},
+		{
+			name: "matching",
+			fsys: fstest.MapFS{
+				"main.js.gz":     {Data: []byte("compressed js")},
+				"checksums.json": {Data: []byte(`{"main.js": "` + hash + `"}`)},
+			},
+		},
+		{
+			name: "mismatched",
+			fsys: fstest.MapFS{
+				"main.js.gz":     {Data: []byte("rebuilt js")},
+				"checksums.json": {Data: []byte(`{"main.js": "` + hash + `"}`)},
+			},
+			wantErr: "main.js.gz: checksum",
+		},
+		{
+			name: "asset missing from manifest",
+			fsys: fstest.MapFS{
+				"main.js.gz":     {Data: []byte("compressed js")},
+				"main.css.gz":    {Data: []byte("compressed css")},
+				"checksums.json": {Data: []byte(`{"main.js": "` + hash + `"}`)},
+			},
+			wantErr: "main.css.gz: no checksum",
+		},
+		{
+			name: "manifest entry without asset",
+			fsys: fstest.MapFS{
+				"checksums.json": {Data: []byte(`{"main.js": "` + hash + `"}`)},
+			},
+			wantErr: "main.js.gz:",
+		},
+		{
+			name:    "missing manifest",
+			fsys:    fstest.MapFS{"main.js.gz": {Data: []byte("compressed js")}},
+			wantErr: "read checksums.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChecksums(tt.fsys)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyChecksums() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyChecksums() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -127,6 +127,8 @@ async function build() {
     // The server will decompress on-the-fly for the rare clients that don't support gzip
     log('\nGenerating gzip compressed files...');
     const filesToCompress = ['monaco-editor.js', 'editor.worker.js', 'diffs-worker.js', 'main.js', 'monaco-editor.css', 'styles.css', 'main.css'];
+    // Source maps get the same gzip/ETag treatment as the bundles they map
+    filesToCompress.push(...fs.readdirSync('dist').filter(f => f.endsWith('.map')).sort());
     const checksums = {};
     let totalOrigSize = 0;
     let totalGzSize = 0;
@@ -165,7 +167,7 @@ async function build() {
     if (verbose) {
       console.log('\nOther files:');
       const otherFiles = fs.readdirSync('dist').filter(f =>
-        (f.endsWith('.ttf') || f.endsWith('.map')) && !f.endsWith('.gz')
+        f.endsWith('.ttf')
       );
       for (const file of otherFiles.sort()) {
         const stats = fs.statSync(`dist/${file}`);
@@ -10,6 +10,7 @@ import NotificationsModal from "./components/NotificationsModal";
 import HomeFeed from "./components/HomeFeed";
 import { Conversation, ConversationWithState, ConversationListUpdate } from "./types";
 import { api } from "./services/api";
+import { appPathname } from "./services/basePath";
 import { conversationCache } from "./services/conversationCache";
 import { useI18n } from "./i18n";
 
@@ -59,7 +60,7 @@ function isGeneratedId(slug: string | null): boolean {
 
 // Get slug from the current URL path (expects /c/<slug> format)
 function getSlugFromPath(): string | null {
-  const path = window.location.pathname;
+  const path = appPathname();
   // Check for /c/<slug> format
   if (path.startsWith("/c/")) {
     const slug = path.slice(3); // Remove "/c/" prefix
@@ -71,11 +72,11 @@ function getSlugFromPath(): string | null {
 }
 
 function isInboxPath(): boolean {
-  return window.location.pathname === "/inbox";
+  return appPathname() === "/inbox";
 }
 
 function isNewPath(): boolean {
-  return window.location.pathname === "/new";
+  return appPathname() === "/new";
 }
 
 // Capture the initial slug from URL BEFORE React renders, so it won't be affected
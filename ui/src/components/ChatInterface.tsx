@@ -1482,6 +1482,24 @@ function ChatInterface({
 
       try {
         const streamResponse: StreamResponse = JSON.parse(event.data);
+
+        // The server is closing this stream (max connection age); resume
+        // right away from the last sequence ID we've seen.
+        if (streamResponse.reconnect) {
+          eventSource.close();
+          if (eventSourceRef.current === eventSource) {
+            eventSourceRef.current = null;
+          }
+          setupMessageStream();
+          return;
+        }
+
+        // The server is shutting down; the stream's error handler reconnects
+        // with backoff once the connection closes.
+        if (streamResponse.type === "shutdown") {
+          return;
+        }
+
         const incomingMessages = Array.isArray(streamResponse.messages)
           ? streamResponse.messages
           : [];
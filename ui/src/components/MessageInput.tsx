@@ -228,15 +228,18 @@ function MessageInput({
     if (!canQueue && !autoQueue) setShowQueueMenu(false);
   }, [canQueue, autoQueue]);
 
-  const uploadFile = async (file: File) => {
-    // Add a loading indicator at the end of the current message
-    const loadingText = `[uploading ${file.name}...]`;
-    setMessage((prev) => (prev ? prev + " " : "") + loadingText);
+  // Uploads files in a single request, so several dropped files don't go one at a time.
+  const uploadFiles = async (files: File[]) => {
+    // Add a loading indicator per file at the end of the current message
+    const loadingTexts = files.map((file) => `[uploading ${file.name}...]`);
+    setMessage((prev) => (prev ? prev + " " : "") + loadingTexts.join(" "));
     setUploadsInProgress((prev) => prev + 1);
 
     try {
       const formData = new FormData();
-      formData.append("file", file);
+      for (const file of files) {
+        formData.append("file", file);
+      }
 
       const response = await fetch("/api/upload", {
         method: "POST",
@@ -247,15 +250,23 @@ function MessageInput({
         throw new Error(`Upload failed: ${response.statusText}`);
       }
 
-      const data = await response.json();
+      const data: { path: string; paths: string[] } = await response.json();
 
-      // Replace the loading placeholder with the actual file path
-      setMessage((currentMessage) => currentMessage.replace(loadingText, `[${data.path}]`));
+      // Replace the loading placeholders with the actual file paths
+      setMessage((currentMessage) => {
+        let msg = currentMessage;
+        loadingTexts.forEach((text, i) => {
+          msg = msg.replace(text, `[${data.paths[i]}]`);
+        });
+        return msg;
+      });
     } catch (error) {
-      console.error("Failed to upload file:", error);
-      // Replace loading indicator with error message
+      console.error("Failed to upload files:", error);
+      // Replace loading indicators with error message
       const errorText = `[upload failed: ${error instanceof Error ? error.message : "unknown error"}]`;
-      setMessage((currentMessage) => currentMessage.replace(loadingText, errorText));
+      setMessage((currentMessage) =>
+        loadingTexts.reduce((msg, text) => msg.replace(text, errorText), currentMessage),
+      );
     } finally {
       setUploadsInProgress((prev) => prev - 1);
     }
@@ -272,8 +283,8 @@ function MessageInput({
           const file = item.getAsFile();
           if (file) {
             event.preventDefault();
-            // Fire and forget - uploadFile handles state updates internally.
-            uploadFile(file);
+            // Fire and forget - uploadFiles handles state updates internally.
+            uploadFiles([file]);
             return;
           }
         }
@@ -304,11 +315,7 @@ function MessageInput({
     setDragCounter(0);
 
     if (event.dataTransfer && event.dataTransfer.files.length > 0) {
-      // Process all dropped files
-      for (let i = 0; i < event.dataTransfer.files.length; i++) {
-        const file = event.dataTransfer.files[i];
-        await uploadFile(file);
-      }
+      await uploadFiles(Array.from(event.dataTransfer.files));
     }
   };
 
@@ -320,10 +327,7 @@ function MessageInput({
     const files = event.target.files;
     if (!files || files.length === 0) return;
 
-    for (let i = 0; i < files.length; i++) {
-      const file = files[i];
-      await uploadFile(file);
-    }
+    await uploadFiles(Array.from(files));
 
     // Reset input so same file can be selected again
     event.target.value = "";
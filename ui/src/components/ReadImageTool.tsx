@@ -1,5 +1,6 @@
 import React, { useState } from "react";
 import { LLMContent } from "../types";
+import { withBasePath } from "../services/basePath";
 
 interface ReadImageToolProps {
   toolInput?: unknown; // { path: string }
@@ -45,10 +46,13 @@ function ReadImageTool({
 
   const filename = getPath(toolInput) || getId(toolInput) || "image";
 
-  // Build image URL from the tool result's image content.
+  // Build image URLs from the tool result's image content; reading a
+  // directory returns several images.
   // The server replaces inline base64 data with a URL to /api/message/{id}/image/...
-  const imageUrl =
-    toolResult && toolResult.length >= 2 ? toolResult[1]?.DisplayImageURL : undefined;
+  const imageUrls = (toolResult || [])
+    .map((content) => content.DisplayImageURL)
+    .filter((url): url is string => !!url)
+    .map((url) => withBasePath(url));
 
   const isComplete = !isRunning && toolResult !== undefined;
 
@@ -92,23 +96,25 @@ function ReadImageTool({
 
       {isExpanded && (
         <div className="screenshot-tool-details">
-          {isComplete && !hasError && imageUrl && (
+          {isComplete && !hasError && imageUrls.length > 0 && (
             <div className="screenshot-tool-section">
               {executionTime && (
                 <div className="screenshot-tool-label">
-                  <span>Image:</span>
+                  <span>{imageUrls.length > 1 ? `${imageUrls.length} images:` : "Image:"}</span>
                   <span className="screenshot-tool-time">{executionTime}</span>
                 </div>
               )}
-              <div className="screenshot-tool-image-container">
-                <a href={imageUrl} target="_blank" rel="noopener noreferrer">
-                  <img
-                    src={imageUrl}
-                    alt={`Image: ${filename}`}
-                    className="tool-image-responsive"
-                  />
-                </a>
-              </div>
+              {imageUrls.map((imageUrl) => (
+                <div key={imageUrl} className="screenshot-tool-image-container">
+                  <a href={imageUrl} target="_blank" rel="noopener noreferrer">
+                    <img
+                      src={imageUrl}
+                      alt={`Image: ${filename}`}
+                      className="tool-image-responsive"
+                    />
+                  </a>
+                </div>
+              ))}
             </div>
           )}
 
@@ -1,5 +1,6 @@
 import React, { useState } from "react";
 import { LLMContent } from "../types";
+import { withBasePath } from "../services/basePath";
 
 interface ScreenshotToolProps {
   // For tool_use (pending state)
@@ -62,8 +63,9 @@ function ScreenshotTool({
 
   // Construct image URL from the tool result's image content.
   // The server replaces inline base64 data with a URL to /api/message/{id}/image/...
-  const imageUrl =
+  const displayImageUrl =
     toolResult && toolResult.length >= 2 ? toolResult[1]?.DisplayImageURL : undefined;
+  const imageUrl = displayImageUrl ? withBasePath(displayImageUrl) : undefined;
 
   const isComplete = !isRunning && toolResult !== undefined;
 
@@ -14,6 +14,15 @@ export interface Conversation {
 	parent_conversation_id: string | null;
 	model: string | null;
 	conversation_options: string;
+	system_note: string | null;
+	slug_user_set: boolean;
+	forked_from: string | null;
+	input_tokens_total: number;
+	output_tokens_total: number;
+	cached_tokens_total: number;
+	pinned: boolean;
+	folder_id: string | null;
+	cost_usd_total: number;
 }
 
 export interface Usage {
@@ -38,6 +47,24 @@ export interface PRInfo {
 	in_merge_queue: boolean;
 }
 
+export interface MessageTimingForTS {
+	since_previous_ms?: number | null;
+	llm_ms?: number | null;
+	tool_ms?: number | null;
+	tools?: { [key: string]: number } | null;
+}
+
+export interface ApiToolCallForTS {
+	tool_use_id: string;
+	tool_name?: string;
+	tool_use_message_id?: string;
+	tool_use_index: number;
+	result_index: number;
+	error?: boolean;
+	start_time?: string | null;
+	end_time?: string | null;
+}
+
 export interface ApiMessageForTS {
 	message_id: string;
 	conversation_id: string;
@@ -49,12 +76,15 @@ export interface ApiMessageForTS {
 	created_at: string;
 	display_data?: string | null;
 	end_of_turn?: boolean | null;
+	timing?: MessageTimingForTS | null;
+	tool_calls?: ApiToolCallForTS[] | null;
 }
 
 export interface ConversationStateForTS {
 	conversation_id: string;
 	working: boolean;
 	model?: string;
+	working_since?: string;
 }
 
 export interface NotificationEventForTS {
@@ -84,12 +114,14 @@ export interface ConversationWithStateForTS {
 	model: string | null;
 	conversation_options: string;
 	working: boolean;
+	working_since?: string;
 	git_repo_root?: string;
 	git_worktree_root?: string;
 	git_commit?: string;
 	git_subject?: string;
 	subagent_count: number;
 	pr_info?: PRInfo | null;
+	tags?: string[] | null;
 }
 
 export type MessageType = 'user' | 'agent' | 'tool' | 'error' | 'system' | 'gitinfo';
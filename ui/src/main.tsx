@@ -5,22 +5,44 @@ import { initializeTheme } from "./services/theme";
 import { initializeNotifications } from "./services/notifications";
 import { MarkdownProvider } from "./contexts/MarkdownContext";
 import { I18nProvider } from "./i18n";
+import { installBasePath } from "./services/basePath";
 
-// Apply theme before render to avoid flash
-initializeTheme();
+// A static index.html (e.g. served by a CDN) has no injected init data, so
+// fetch it from the server before anything reads it. The URL is relative to
+// this script, which the server serves under the base path, since the base
+// path itself is part of the init data.
+async function loadInitData(): Promise<void> {
+  if (window.__SHELLEY_INIT__) return;
+  try {
+    const res = await fetch(new URL("api/config", import.meta.url));
+    if (res.ok) {
+      window.__SHELLEY_INIT__ = await res.json();
+    }
+  } catch (err) {
+    console.error("Failed to load init data:", err);
+  }
+}
 
-// Initialize notification system (includes favicon)
-initializeNotifications();
+loadInitData().then(() => {
+  // Route root-relative URLs under the base path before anything fetches
+  installBasePath();
 
-// Render main app
-const rootContainer = document.getElementById("root");
-if (!rootContainer) throw new Error("Root container not found");
+  // Apply theme before render to avoid flash
+  initializeTheme();
 
-const root = createRoot(rootContainer);
-root.render(
-  <I18nProvider>
-    <MarkdownProvider>
-      <App />
-    </MarkdownProvider>
-  </I18nProvider>,
-);
+  // Initialize notification system (includes favicon)
+  initializeNotifications();
+
+  // Render main app
+  const rootContainer = document.getElementById("root");
+  if (!rootContainer) throw new Error("Root container not found");
+
+  const root = createRoot(rootContainer);
+  root.render(
+    <I18nProvider>
+      <MarkdownProvider>
+        <App />
+      </MarkdownProvider>
+    </I18nProvider>,
+  );
+});
@@ -0,0 +1,69 @@
+// Support for serving Shelley under a URL prefix behind a reverse proxy.
+// The server sends the prefix as base_path in the init data; it is empty
+// when Shelley is served at the root. installBasePath re-reads it, for init
+// data fetched from /api/config after this module loaded.
+
+export let basePath: string = window.__SHELLEY_INIT__?.base_path ?? "";
+
+// withBasePath prefixes a root-relative path ("/api/...") with the base path.
+// Absolute URLs, protocol-relative URLs and relative paths are returned as is.
+export function withBasePath(path: string): string {
+  if (!basePath || !path.startsWith("/") || path.startsWith("//") || path.startsWith(basePath + "/")) {
+    return path;
+  }
+  return basePath + path;
+}
+
+// appPathname returns the current path with the base path removed, so
+// routing code can keep matching paths like "/c/<slug>".
+export function appPathname(): string {
+  const path = window.location.pathname;
+  if (basePath && path.startsWith(basePath)) {
+    return path.slice(basePath.length) || "/";
+  }
+  return path;
+}
+
+function rewriteURL(url: string | URL): string | URL {
+  return typeof url === "string" ? withBasePath(url) : url;
+}
+
+// installBasePath routes root-relative fetch, EventSource, WebSocket and
+// history URLs under the base path, so the rest of the app can keep using
+// paths like "/api/conversations". It does nothing when there is no base path.
+export function installBasePath(): void {
+  basePath = window.__SHELLEY_INIT__?.base_path ?? "";
+  if (!basePath) return;
+
+  const originalFetch = window.fetch.bind(window);
+  window.fetch = (input: RequestInfo | URL, init?: RequestInit) =>
+    originalFetch(typeof input === "string" ? withBasePath(input) : input, init);
+
+  const OriginalEventSource = window.EventSource;
+  window.EventSource = class extends OriginalEventSource {
+    constructor(url: string | URL, init?: EventSourceInit) {
+      super(rewriteURL(url), init);
+    }
+  };
+
+  const OriginalWebSocket = window.WebSocket;
+  window.WebSocket = class extends OriginalWebSocket {
+    constructor(url: string | URL, protocols?: string | string[]) {
+      if (typeof url === "string" && url.startsWith("ws")) {
+        const u = new URL(url);
+        if (u.host === window.location.host) {
+          u.pathname = withBasePath(u.pathname);
+          url = u.toString();
+        }
+      }
+      super(url, protocols);
+    }
+  };
+
+  const pushState = window.history.pushState.bind(window.history);
+  const replaceState = window.history.replaceState.bind(window.history);
+  window.history.pushState = (data: unknown, unused: string, url?: string | URL | null) =>
+    pushState(data, unused, url == null ? url : rewriteURL(url));
+  window.history.replaceState = (data: unknown, unused: string, url?: string | URL | null) =>
+    replaceState(data, unused, url == null ? url : rewriteURL(url));
+}
@@ -4,6 +4,7 @@ import { faviconNotificationHandler } from "./handlers/favicon";
 import { browserNotificationHandler } from "./handlers/browser";
 import { setChannelEnabled } from "./preferences";
 import { pushApi } from "../api";
+import { withBasePath } from "../basePath";
 
 export { handleNotificationEvent } from "./handlers";
 export { isChannelEnabled, setChannelEnabled } from "./preferences";
@@ -86,7 +87,7 @@ export async function unsubscribeFromWebPush(): Promise<void> {
 
 function registerServiceWorker(): void {
   if (!("serviceWorker" in navigator)) return;
-  navigator.serviceWorker.register("/sw.js", { scope: "/" }).catch((err) => {
+  navigator.serviceWorker.register(withBasePath("/sw.js"), { scope: withBasePath("/") }).catch((err) => {
     console.warn("Service worker registration failed:", err);
   });
 }
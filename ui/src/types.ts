@@ -67,8 +67,20 @@ export interface ChatRequest {
   conversation_options?: {
     type?: "normal" | "orchestrator";
     subagent_backend?: "shelley" | "claude-cli" | "codex-cli";
+    tools?: string[];
   };
+  system_note?: string;
   queue?: boolean;
+  params?: {
+    temperature?: number;
+    top_p?: number;
+    max_tokens?: number;
+    stop_sequences?: string[];
+  };
+  system_prompt?: {
+    text: string;
+    mode?: "append" | "replace";
+  };
 }
 // Notification event types
 export type NotificationEventType = "agent_done" | "agent_error";
@@ -100,6 +112,8 @@ export interface StreamResponse extends Omit<StreamResponseForTS, "messages"> {
   notification_event?: NotificationEvent;
   tool_progress?: ToolProgress;
   stream_delta?: StreamDelta;
+  reconnect?: boolean;
+  type?: "shutdown";
 }
 
 // Link represents a custom link that can be added to the UI
@@ -121,6 +135,7 @@ export interface InitData {
   user_agents_md_path?: string;
   notification_channel_types?: import("./services/api").ChannelTypeInfo[];
   cli_agents?: string[]; // Available CLI agents (e.g., "claude-cli", "codex-cli")
+  base_path?: string; // URL prefix when served behind a reverse proxy (e.g., "/shelley")
 }
 
 // Extend Window interface to include our init data